@@ -1,24 +1,51 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/web3dev6/simplebank/audit"
 	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
 	"github.com/web3dev6/simplebank/token"
 )
 
+// accountResponse is an account with its balances in major units of its currency
+// note* balance is the ledger balance, available_balance leaves out the funds held by authorized transfers
+type accountResponse struct {
+	ID               int64       `json:"id"`
+	Owner            string      `json:"owner"`
+	Balance          money.Money `json:"balance"`
+	AvailableBalance money.Money `json:"available_balance"`
+	Currency         string      `json:"currency"`
+	Product          string      `json:"product"`
+	CreatedAt        time.Time   `json:"created_at"`
+}
+
+func newAccountResponse(account db.Account) accountResponse {
+	return accountResponse{
+		ID:               account.ID,
+		Owner:            account.Owner,
+		Balance:          money.Money{Amount: account.Balance, Currency: account.Currency},
+		AvailableBalance: money.Money{Amount: account.AvailableBalance(), Currency: account.Currency},
+		Currency:         account.Currency,
+		Product:          account.Product,
+		CreatedAt:        account.CreatedAt,
+	}
+}
+
 type createAccountRequest struct {
 	// Owner    string `json:"owner" binding:"required"` - It comes via auth payload - filled by auth middleware
 	Currency string `json:"currency" binding:"required,currency"`
+	Product  string `json:"product" binding:"omitempty,oneof=checking savings"` // checking if empty, see GET /account_products
 }
 
 func (server *Server) createAccount(ctx *gin.Context) {
 	var req createAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		abortWithErrorResponse(ctx, http.StatusBadRequest, err)
+		abortWithErrorResponse(ctx, bindingError(err))
 		return
 	}
 
@@ -27,20 +54,17 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		Owner:    authPayload.Username,
 		Currency: req.Currency,
 		Balance:  0,
+		Product:  req.Product,
 	}
 	account, err := server.store.CreateAccount(ctx, arg)
 	if err != nil {
-		errCode := db.ErrorCode(err)
-		// owner must ref to a user (FK), and {owner-currency}pair shouldn't already exist (UNIQUE)
-		if errCode == db.ForeignKeyViolation || errCode == db.UniqueViolation {
-			abortWithErrorResponse(ctx, http.StatusForbidden, err)
-			return
-		}
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		// owner must ref to a user (FK), and {owner-currency-product}set shouldn't already exist (UNIQUE) - both conflicts
+		abortWithErrorResponse(ctx, db.DomainError(err, "account"))
 		return
 	}
+	server.audit(ctx, audit.Event{Action: audit.ActionAccountCreated, Actor: authPayload.Username, ResourceType: audit.ResourceAccount, ResourceID: strconv.FormatInt(account.ID, 10), After: account})
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
 }
 
 type getAccountRequest struct {
@@ -50,18 +74,14 @@ type getAccountRequest struct {
 func (server *Server) getAccount(ctx *gin.Context) {
 	var req getAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		abortWithErrorResponse(ctx, http.StatusBadRequest, err)
+		abortWithErrorResponse(ctx, bindingError(err))
 		return
 	}
 
 	// note* unit test fails if we don't call server.store.GetAccount
 	account, err := server.store.GetAccount(ctx, req.ID)
 	if err != nil {
-		if errors.Is(err, db.ErrRecordNotFound) || errors.Is(err, sql.ErrNoRows) {
-			abortWithErrorResponse(ctx, http.StatusNotFound, err)
-			return
-		}
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, db.DomainError(err, "account"))
 		return
 	}
 	// note* unit test fails if we account is not the same as expected
@@ -69,11 +89,11 @@ func (server *Server) getAccount(ctx *gin.Context) {
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if account.Owner != authPayload.Username {
-		abortWithErrorResponse(ctx, http.StatusUnauthorized, ErrFetchingUnauthorizedAccount)
+		abortWithErrorResponse(ctx, ErrFetchingUnauthorizedAccount)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
 }
 
 type listAccountsRequest struct {
@@ -84,7 +104,7 @@ type listAccountsRequest struct {
 func (server *Server) listAccounts(ctx *gin.Context) {
 	var req listAccountsRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		abortWithErrorResponse(ctx, http.StatusBadRequest, err)
+		abortWithErrorResponse(ctx, bindingError(err))
 		return
 	}
 
@@ -96,9 +116,13 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 	}
 	accounts, err := server.store.ListAccounts(ctx, arg)
 	if err != nil {
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, db.DomainError(err, "account"))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, accounts)
+	rsp := make([]accountResponse, len(accounts))
+	for i, account := range accounts {
+		rsp[i] = newAccountResponse(account)
+	}
+	ctx.JSON(http.StatusOK, rsp)
 }
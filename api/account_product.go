@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/token"
+)
+
+// listAccountProducts lists the products an account can be opened with, and their interest rates
+// note* the internal ones, accounts of the bank itself, are left out
+func (server *Server) listAccountProducts(ctx *gin.Context) {
+	products, err := server.store.ListAccountProducts(ctx)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "account products"))
+		return
+	}
+	public := make([]db.AccountProduct, 0, len(products))
+	for _, product := range products {
+		if !product.Internal {
+			public = append(public, product)
+		}
+	}
+	ctx.JSON(http.StatusOK, public)
+}
+
+type updateAccountProductUri struct {
+	Code string `uri:"code" binding:"required"`
+}
+
+type updateAccountProductRequest struct {
+	// in basis points, 200 is 2.00% a year - a pointer, so 0 isn't taken as missing
+	AnnualInterestRateBps *int32 `json:"annual_interest_rate_bps" binding:"required,min=0,max=10000"`
+}
+
+// updateAccountProduct changes the annual interest rate of a product
+// note* the interest accrued so far is kept, the new rate applies from the next day accrued
+func (server *Server) updateAccountProduct(ctx *gin.Context) {
+	var uri updateAccountProductUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+	var req updateAccountProductRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+
+	before, err := server.store.GetAccountProduct(ctx, uri.Code)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "account product"))
+		return
+	}
+	if before.Internal {
+		abortWithErrorResponse(ctx, apperr.Validation("internal account product",
+			apperr.FieldViolation{Field: "code", Description: "internal products earn no interest"}))
+		return
+	}
+	after, err := server.store.UpdateAccountProductRate(ctx, db.UpdateAccountProductRateParams{
+		Code:                  uri.Code,
+		AnnualInterestRateBps: *req.AnnualInterestRateBps,
+	})
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "account product"))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	server.audit(ctx, audit.Event{
+		Action:       audit.ActionAccountProductUpdated,
+		Actor:        authPayload.Username,
+		ResourceType: audit.ResourceAccountProduct,
+		ResourceID:   after.Code,
+		Before:       before,
+		After:        after,
+	})
+
+	ctx.JSON(http.StatusOK, after)
+}
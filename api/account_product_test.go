@@ -0,0 +1,190 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/util"
+)
+
+func TestListAccountProductsAPI(t *testing.T) {
+	products := []db.AccountProduct{
+		{Code: db.ProductChecking, DisplayName: "Checking", AnnualInterestRateBps: 0},
+		{Code: db.ProductInterestExpense, DisplayName: "Interest expense", Internal: true},
+		{Code: db.ProductSavings, DisplayName: "Savings", AnnualInterestRateBps: 200},
+	}
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListAccountProducts(gomock.Any()).Times(1).Return(products, nil)
+
+	server := newTestServer(t, store, nil)
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/account_products", nil)
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var listed []db.AccountProduct
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &listed))
+	// the internal one is left out
+	require.Equal(t, []db.AccountProduct{products[0], products[2]}, listed)
+}
+
+func TestUpdateAccountProductAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	depositor, _ := randomUser(t)
+	depositor.Role = util.DepositorRole
+
+	savings := db.AccountProduct{Code: db.ProductSavings, DisplayName: "Savings", AnnualInterestRateBps: 200}
+	updated := savings
+	updated.AnnualInterestRateBps = 350
+
+	testCases := []struct {
+		name          string
+		code          string
+		body          gin.H
+		user          db.User
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: db.ProductSavings,
+			body: gin.H{"annual_interest_rate_bps": 350},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetAccountProduct(gomock.Any(), gomock.Eq(db.ProductSavings)).Times(1).Return(savings, nil)
+				arg := db.UpdateAccountProductRateParams{Code: db.ProductSavings, AnnualInterestRateBps: 350}
+				store.EXPECT().UpdateAccountProductRate(gomock.Any(), gomock.Eq(arg)).Times(1).Return(updated, nil)
+				store.EXPECT().CreateAuditEventTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var product db.AccountProduct
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &product))
+				require.Equal(t, updated, product)
+			},
+		},
+		{
+			// 0 is a rate, not a missing one
+			name: "ZeroRate",
+			code: db.ProductSavings,
+			body: gin.H{"annual_interest_rate_bps": 0},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetAccountProduct(gomock.Any(), gomock.Eq(db.ProductSavings)).Times(1).Return(savings, nil)
+				arg := db.UpdateAccountProductRateParams{Code: db.ProductSavings, AnnualInterestRateBps: 0}
+				store.EXPECT().UpdateAccountProductRate(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.AccountProduct{Code: db.ProductSavings}, nil)
+				store.EXPECT().CreateAuditEventTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InternalProduct",
+			code: db.ProductInterestExpense,
+			body: gin.H{"annual_interest_rate_bps": 100},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetAccountProduct(gomock.Any(), gomock.Eq(db.ProductInterestExpense)).Times(1).
+					Return(db.AccountProduct{Code: db.ProductInterestExpense, Internal: true}, nil)
+				store.EXPECT().UpdateAccountProductRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			code: "premium",
+			body: gin.H{"annual_interest_rate_bps": 100},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetAccountProduct(gomock.Any(), gomock.Eq("premium")).Times(1).Return(db.AccountProduct{}, db.ErrRecordNotFound)
+				store.EXPECT().UpdateAccountProductRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "RateTooHigh",
+			code: db.ProductSavings,
+			body: gin.H{"annual_interest_rate_bps": 10001},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().UpdateAccountProductRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingRate",
+			code: db.ProductSavings,
+			body: gin.H{},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().UpdateAccountProductRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotAdmin",
+			code: db.ProductSavings,
+			body: gin.H{"annual_interest_rate_bps": 350},
+			user: depositor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(depositor.Username)).Times(1).Return(depositor, nil)
+				store.EXPECT().UpdateAccountProductRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			url := fmt.Sprintf("/admin/account_products/%s", tc.code)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/hibiken/asynq"
 	"github.com/stretchr/testify/require"
@@ -139,7 +140,8 @@ func TestGetAccountApiWithFullCoverage(t *testing.T) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				// authenticated, but not the owner
+				require.Equal(t, http.StatusForbidden, recorder.Code)
 			},
 		},
 		{
@@ -198,12 +200,98 @@ func TestGetAccountApiWithFullCoverage(t *testing.T) {
 	}
 }
 
+func TestCreateAccountAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	account.Balance = 0
+	savings := account
+	savings.Product = db.ProductSavings
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			// checking, the db default
+			name: "OK",
+			body: gin.H{"currency": account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountParams{Owner: user.Username, Currency: account.Currency}
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+				store.EXPECT().CreateAuditEventTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name: "Savings",
+			body: gin.H{"currency": account.Currency, "product": db.ProductSavings},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountParams{Owner: user.Username, Currency: account.Currency, Product: db.ProductSavings}
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(arg)).Times(1).Return(savings, nil)
+				store.EXPECT().CreateAuditEventTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, savings)
+			},
+		},
+		{
+			// internal products can't be opened
+			name: "InternalProduct",
+			body: gin.H{"currency": account.Currency, "product": db.ProductInterestExpense},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Duplicate",
+			body: gin.H{"currency": account.Currency, "product": db.ProductSavings},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, db.ErrUniqueViolation)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
 func randomAccount(owner string) db.Account {
 	return db.Account{
 		ID:       util.RandomInt(1, 1000),
 		Owner:    owner,
 		Balance:  util.RandomBalance(),
 		Currency: util.RandomCurrency(),
+		Product:  db.ProductChecking,
 	}
 }
 
@@ -211,9 +299,10 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, expectedAccount d
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
 
-	var accountFromBody db.Account
+	// note* the balance comes back in major units, decoded to minor units again
+	var accountFromBody accountResponse
 	err = json.Unmarshal(data, &accountFromBody)
 	require.NoError(t, err)
 
-	require.Equal(t, expectedAccount, accountFromBody)
+	require.Equal(t, newAccountResponse(expectedAccount), accountFromBody)
 }
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/token"
+	"github.com/web3dev6/simplebank/util"
+)
+
+func TestListAuditEventsAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	depositor, _ := randomUser(t)
+	depositor.Role = util.DepositorRole
+
+	event := db.AuditEvent{
+		ID:           1,
+		Action:       audit.ActionUserLogin,
+		Actor:        depositor.Username,
+		ResourceType: audit.ResourceSession,
+		ResourceID:   util.RandomString(12),
+		PrevHash:     make([]byte, sha256.Size),
+		CreatedAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+	event.Hash = db.AuditEventHash(event)
+
+	testCases := []struct {
+		name          string
+		query         string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: fmt.Sprintf("page_id=1&page_size=5&actor=%s&action=%s", depositor.Username, audit.ActionUserLogin),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				arg := db.ListAuditEventsParams{Limit: 5, Offset: 0}
+				arg.Actor.String, arg.Actor.Valid = depositor.Username, true
+				arg.Action.String, arg.Action.Valid = audit.ActionUserLogin, true
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.AuditEvent{event}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				data, err := io.ReadAll(recorder.Body)
+				require.NoError(t, err)
+				var events []auditEventResponse
+				require.NoError(t, json.Unmarshal(data, &events))
+				require.Equal(t, []auditEventResponse{newAuditEventResponse(event)}, events)
+			},
+		},
+		{
+			name:  "NotAdmin",
+			query: "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, depositor.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(depositor.Username)).Times(1).Return(depositor, nil)
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:  "NoAuthorization",
+			query: "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			// usernames may have underscores, like the ones created over grpc
+			name:  "ActorWithUnderscore",
+			query: "page_id=1&page_size=5&actor=jane_doe",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				arg := db.ListAuditEventsParams{Limit: 5, Offset: 0}
+				arg.Actor.String, arg.Actor.Valid = "jane_doe", true
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.AuditEvent{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidActor",
+			query: "page_id=1&page_size=5&actor=Jane-Doe",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "page_id=1&page_size=1000",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil) // taskDistributor not used by admin routes
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/audit_events?"+tc.query, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
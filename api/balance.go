@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web3dev6/simplebank/apperr"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
+	"github.com/web3dev6/simplebank/token"
+)
+
+type getBalanceAtUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type getBalanceAtRequest struct {
+	At string `form:"at" binding:"required,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+type balanceAtResponse struct {
+	AccountID int64       `json:"account_id"`
+	At        time.Time   `json:"at"`
+	Balance   money.Money `json:"balance"` // ledger balance, the holds at the time aren't known
+}
+
+// getBalanceAt returns the balance of the user's account at a past time, e.g. at=2026-09-30T23:59:59Z
+func (server *Server) getBalanceAt(ctx *gin.Context) {
+	var uri getBalanceAtUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+	var req getBalanceAtRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+	// note* already validated by the binding
+	at, _ := time.Parse(time.RFC3339, req.At)
+	if at.After(time.Now()) {
+		abortWithErrorResponse(ctx, apperr.Validation("invalid time",
+			apperr.FieldViolation{Field: "at", Description: "must not be in the future"}))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "account"))
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		abortWithErrorResponse(ctx, ErrFetchingUnauthorizedAccount)
+		return
+	}
+
+	balance, err := server.store.GetBalanceAt(ctx, account.ID, at)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "account"))
+		return
+	}
+	ctx.JSON(http.StatusOK, balanceAtResponse{
+		AccountID: account.ID,
+		At:        at,
+		Balance:   money.Money{Amount: balance, Currency: account.Currency},
+	})
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/worker"
+)
+
+func TestGetBalanceAtApi(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	at := time.Date(2026, time.September, 30, 23, 59, 59, 0, time.UTC)
+
+	testcases := []struct {
+		name          string
+		at            string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			at:       at.Format(time.RFC3339),
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Eq(account.ID), gomock.Eq(at)).Times(1).Return(int64(12345), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp balanceAtResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, account.ID, rsp.AccountID)
+				require.True(t, at.Equal(rsp.At))
+				require.Equal(t, int64(12345), rsp.Balance.Amount)
+				require.Equal(t, account.Currency, rsp.Balance.Currency)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			at:       at.Format(time.RFC3339),
+			username: other.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "AccountNotFound",
+			at:       at.Format(time.RFC3339),
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidTime",
+			at:       "2026-09-30",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "FutureTime",
+			at:       time.Now().Add(time.Hour).Format(time.RFC3339),
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "BalanceAccountNotFound",
+			at:       at.Format(time.RFC3339),
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(int64(0), db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			at:       at.Format(time.RFC3339),
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(int64(0), errors.New("conn reset"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			taskDistributor := worker.NewRedisTaskDistributor(asynq.RedisClientOpt{
+				Addr: "0.0.0.0:6379",
+			})
+			server := newTestServer(t, store, taskDistributor)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/balance?%s", account.ID, url.Values{"at": {tc.at}}.Encode())
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/token"
+)
+
+// listCurrencies lists the enabled currencies, with the minor units their amounts are in - from the registry
+func (server *Server) listCurrencies(ctx *gin.Context) {
+	currencies := make([]db.Currency, 0)
+	for _, currency := range server.currencies.List() {
+		if currency.Enabled {
+			currencies = append(currencies, currency)
+		}
+	}
+	ctx.JSON(http.StatusOK, currencies)
+}
+
+// listAllCurrencies lists every currency, enabled or not - from the db, so it's never stale
+func (server *Server) listAllCurrencies(ctx *gin.Context) {
+	currencies, err := server.store.ListCurrencies(ctx)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "currencies"))
+		return
+	}
+	ctx.JSON(http.StatusOK, currencies)
+}
+
+type updateCurrencyUri struct {
+	Code string `uri:"code" binding:"required,alpha,len=3,uppercase"`
+}
+
+type updateCurrencyRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"` // a pointer, so false isn't taken as missing
+}
+
+// updateCurrency enables or disables a currency
+// note* disabling takes no money away - existing accounts keep their balances, but no new accounts or transfers are allowed
+func (server *Server) updateCurrency(ctx *gin.Context) {
+	var uri updateCurrencyUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+	var req updateCurrencyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+
+	before, err := server.store.GetCurrency(ctx, uri.Code)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "currency"))
+		return
+	}
+	after, err := server.store.UpdateCurrencyEnabled(ctx, db.UpdateCurrencyEnabledParams{
+		Code:    uri.Code,
+		Enabled: *req.Enabled,
+	})
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "currency"))
+		return
+	}
+	// other servers pick the change up on their next refresh
+	server.currencies.Put(after)
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	server.audit(ctx, audit.Event{
+		Action:       audit.ActionCurrencyUpdated,
+		Actor:        authPayload.Username,
+		ResourceType: audit.ResourceCurrency,
+		ResourceID:   after.Code,
+		Before:       before,
+		After:        after,
+	})
+
+	ctx.JSON(http.StatusOK, after)
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/util"
+)
+
+func TestUpdateCurrencyAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	depositor, _ := randomUser(t)
+	depositor.Role = util.DepositorRole
+
+	eur := db.Currency{Code: util.EUR, MinorUnits: 2, Enabled: true, DisplayName: "Euro"}
+	disabledEur := eur
+	disabledEur.Enabled = false
+
+	testCases := []struct {
+		name          string
+		code          string
+		body          gin.H
+		user          db.User
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: util.EUR,
+			body: gin.H{"enabled": false},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetCurrency(gomock.Any(), gomock.Eq(util.EUR)).Times(1).Return(eur, nil)
+				arg := db.UpdateCurrencyEnabledParams{Code: util.EUR, Enabled: false}
+				store.EXPECT().UpdateCurrencyEnabled(gomock.Any(), gomock.Eq(arg)).Times(1).Return(disabledEur, nil)
+				store.EXPECT().CreateAuditEventTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var currency db.Currency
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &currency))
+				require.Equal(t, disabledEur, currency)
+			},
+		},
+		{
+			name: "NotFound",
+			code: "GBP",
+			body: gin.H{"enabled": true},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetCurrency(gomock.Any(), gomock.Eq("GBP")).Times(1).Return(db.Currency{}, db.ErrRecordNotFound)
+				store.EXPECT().UpdateCurrencyEnabled(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "MissingEnabled",
+			code: util.EUR,
+			body: gin.H{},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().UpdateCurrencyEnabled(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			code: "eur",
+			body: gin.H{"enabled": true},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().UpdateCurrencyEnabled(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotAdmin",
+			code: util.EUR,
+			body: gin.H{"enabled": false},
+			user: depositor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(depositor.Username)).Times(1).Return(depositor, nil)
+				store.EXPECT().UpdateCurrencyEnabled(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil) // taskDistributor not used by admin routes
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			url := fmt.Sprintf("/admin/currencies/%s", tc.code)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDisabledCurrency(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store, nil)
+
+	serve := func(method, url string, body gin.H) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			var err error
+			data, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+		request, err := http.NewRequest(method, url, bytes.NewReader(data))
+		require.NoError(t, err)
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	listed := func() (codes []string) {
+		recorder := serve(http.MethodGet, "/currencies", nil)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var currencies []db.Currency
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &currencies))
+		for _, currency := range currencies {
+			codes = append(codes, currency.Code)
+		}
+		return codes
+	}
+	require.Equal(t, []string{util.EUR, util.INR, util.USD}, listed())
+
+	// disabled through the admin endpoint - this server's registry is updated right away
+	eur := db.Currency{Code: util.EUR, MinorUnits: 2, Enabled: true, DisplayName: "Euro"}
+	disabledEur := eur
+	disabledEur.Enabled = false
+	store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+	store.EXPECT().GetCurrency(gomock.Any(), gomock.Eq(util.EUR)).Times(1).Return(eur, nil)
+	store.EXPECT().UpdateCurrencyEnabled(gomock.Any(), gomock.Any()).Times(1).Return(disabledEur, nil)
+	store.EXPECT().CreateAuditEventTx(gomock.Any(), gomock.Any()).Times(1)
+	require.Equal(t, http.StatusOK, serve(http.MethodPatch, "/admin/currencies/EUR", gin.H{"enabled": false}).Code)
+
+	require.Equal(t, []string{util.INR, util.USD}, listed())
+	store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+	recorder := serve(http.MethodPost, "/accounts", gin.H{"currency": util.EUR})
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"name":"currency"`)
+}
@@ -1,15 +1,16 @@
 package api
 
-import "errors"
+import "github.com/web3dev6/simplebank/apperr"
 
-var ErrMissingAuthHeader = errors.New("missing authorization header")
-var ErrInvalidAuthHeaderFormat = errors.New("invalid authorization header format")
-var ErrUnsupportedAuthType = errors.New("unsupported authorization type in authorization header")
-var ErrFetchingUnauthorizedAccount = errors.New("account doesn't belong to the authenticated user")
-var ErrTransferringMoneyFromUnauthorizedAccount = errors.New("account doesn't belong to the authenticated user")
-var ErrUpdatingUserInfoFromUnauthorizedUser = errors.New("mismatch in username from authToken and update_request")
-var ErrSessionNotFound = errors.New("session not found")
-var ErrBlockedSession = errors.New("blocked user session")
-var ErrIncorrectSessionUser = errors.New("incorrect username for session")
-var ErrIncorrectSessionToken = errors.New("incorrect refresh_token for session")
-var ErrExpiredSession = errors.New("session has expired")
+var ErrFetchingUnauthorizedAccount = apperr.Forbidden("account doesn't belong to the authenticated user")
+var ErrTransferringMoneyFromUnauthorizedAccount = apperr.Forbidden("account doesn't belong to the authenticated user")
+var ErrUpdatingUserInfoFromUnauthorizedUser = apperr.Forbidden("mismatch in username from authToken and update_request")
+var ErrSessionNotFound = apperr.NotFound("session not found")
+var ErrBlockedSession = apperr.Unauthenticated("blocked user session")
+var ErrIncorrectSessionUser = apperr.Unauthenticated("incorrect username for session")
+var ErrIncorrectSessionToken = apperr.Unauthenticated("incorrect refresh_token for session")
+var ErrExpiredSession = apperr.Unauthenticated("session has expired")
+var ErrIncorrectPassword = apperr.Unauthenticated("incorrect user password")
+var ErrAdminOnly = apperr.Forbidden("only admins can access this resource")
+var ErrAccessingUnauthorizedHold = apperr.Forbidden("hold doesn't involve an account of the authenticated user")
+var ErrCapturingUnauthorizedHold = apperr.Forbidden("only the owner of the hold's to account can capture it")
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
+	"github.com/web3dev6/simplebank/token"
+)
+
+/*
+   Holds - card-style transfers, reserved first & settled later
+	- POST /holds authorizes a transfer from the user's account, holding the amount until it expires (HOLD_DURATION)
+	- POST /holds/:id/capture moves the held amount, or less of it, to the to account - by the owner of the to account
+	- POST /holds/:id/void releases the hold - by the owner of either account
+   Note* the worker voids the holds nobody captured or voided before they expired
+*/
+
+// holdResponse is a hold with its amounts in major units of its accounts' currency
+type holdResponse struct {
+	ID             int64       `json:"id"`
+	AccountID      int64       `json:"account_id"`
+	ToAccountID    int64       `json:"to_account_id"`
+	Amount         money.Money `json:"amount"`
+	Status         string      `json:"status"`
+	CapturedAmount money.Money `json:"captured_amount"`
+	TransferID     *int64      `json:"transfer_id"` // set once captured
+	ExpiresAt      time.Time   `json:"expires_at"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+
+	// copied to the transfer once captured
+	Memo      string          `json:"memo"`
+	Reference string          `json:"reference"`
+	Metadata  json.RawMessage `json:"metadata"`
+}
+
+func newHoldResponse(hold db.Hold, currency string) holdResponse {
+	rsp := holdResponse{
+		ID:             hold.ID,
+		AccountID:      hold.AccountID,
+		ToAccountID:    hold.ToAccountID,
+		Amount:         money.Money{Amount: hold.Amount, Currency: currency},
+		Status:         hold.Status,
+		CapturedAmount: money.Money{Amount: hold.CapturedAmount, Currency: currency},
+		ExpiresAt:      hold.ExpiresAt,
+		CreatedAt:      hold.CreatedAt,
+		UpdatedAt:      hold.UpdatedAt,
+		Memo:           hold.Memo,
+		Reference:      hold.Reference,
+		Metadata:       hold.Metadata,
+	}
+	if hold.TransferID.Valid {
+		rsp.TransferID = &hold.TransferID.Int64
+	}
+	return rsp
+}
+
+type authorizeTransferResponse struct {
+	Hold        holdResponse    `json:"hold"`
+	FromAccount accountResponse `json:"from_account"`
+}
+
+// authorizeTransfer holds the amount of a transfer on the from account, it's moved only once captured
+// note* takes the same request as createTransfer
+func (server *Server) authorizeTransfer(ctx *gin.Context) {
+	var req transferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+	amount, err := parsePositiveAmount("amount", req.Amount, req.Currency)
+	if err != nil {
+		abortWithErrorResponse(ctx, err)
+		return
+	}
+	if err := validateTransferDetails("", req.details()); err != nil {
+		abortWithErrorResponse(ctx, err)
+		return
+	}
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountId, req.Currency)
+	if !valid {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		abortWithErrorResponse(ctx, ErrTransferringMoneyFromUnauthorizedAccount)
+		return
+	}
+	toAccount, valid := server.toAccount(ctx, req)
+	if !valid {
+		return
+	}
+
+	// the available balance is checked by the store, on the locked account
+	result, err := server.store.AuthorizeTransferTx(ctx, db.AuthorizeTransferTxParams{
+		FromAccountID:   req.FromAccountId,
+		ToAccountID:     toAccount.ID,
+		Amount:          amount.Amount,
+		ExpiresAt:       time.Now().Add(server.config.HoldDuration),
+		TransferDetails: req.details(),
+		Audit: audit.InTx(ctx.Request.Context(), func(result db.AuthorizeTransferTxResult) audit.Event {
+			return server.auditEvent(ctx, audit.Event{
+				Action:       audit.ActionHoldAuthorized,
+				Actor:        authPayload.Username,
+				ResourceType: audit.ResourceHold,
+				ResourceID:   strconv.FormatInt(result.Hold.ID, 10),
+				Before:       fromAccount,
+				After:        result,
+			})
+		}),
+	})
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "hold"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, authorizeTransferResponse{
+		Hold:        newHoldResponse(result.Hold, req.Currency),
+		FromAccount: newAccountResponse(result.FromAccount),
+	})
+}
+
+type holdUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// authorizedHold returns the hold of the uri & its from & to accounts, if one of them is the user's
+func (server *Server) authorizedHold(ctx *gin.Context) (hold db.Hold, fromAccount db.Account, toAccount db.Account, ok bool) {
+	var uri holdUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+
+	hold, err := server.store.GetHold(ctx, uri.ID)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "hold"))
+		return
+	}
+	fromAccount, err = server.store.GetAccount(ctx, hold.AccountID)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, fmt.Sprintf("account [%d]", hold.AccountID)))
+		return
+	}
+	toAccount, err = server.store.GetAccount(ctx, hold.ToAccountID)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, fmt.Sprintf("account [%d]", hold.ToAccountID)))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username && toAccount.Owner != authPayload.Username {
+		abortWithErrorResponse(ctx, ErrAccessingUnauthorizedHold)
+		return
+	}
+	return hold, fromAccount, toAccount, true
+}
+
+// getHold returns a hold to the owner of either of its accounts
+func (server *Server) getHold(ctx *gin.Context) {
+	hold, fromAccount, _, ok := server.authorizedHold(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newHoldResponse(hold, fromAccount.Currency))
+}
+
+type captureHoldRequest struct {
+	// note* a decimal string in major units like a transfer's, leave it out to capture the whole hold
+	Amount string `json:"amount"`
+}
+
+type captureHoldResponse struct {
+	Hold     holdResponse       `json:"hold"`
+	Transfer transferTxResponse `json:"transfer"`
+}
+
+// captureHold transfers the held amount, or less of it, to the user's account - the rest of the hold is released
+func (server *Server) captureHold(ctx *gin.Context) {
+	hold, fromAccount, toAccount, ok := server.authorizedHold(ctx)
+	if !ok {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if toAccount.Owner != authPayload.Username {
+		abortWithErrorResponse(ctx, ErrCapturingUnauthorizedHold)
+		return
+	}
+	var req captureHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+	var amount money.Money
+	if req.Amount != "" {
+		var err error
+		amount, err = parsePositiveAmount("amount", req.Amount, fromAccount.Currency)
+		if err != nil {
+			abortWithErrorResponse(ctx, err)
+			return
+		}
+	}
+
+	result, err := server.store.CaptureHoldTx(ctx, db.CaptureHoldTxParams{
+		HoldID: hold.ID,
+		Amount: amount.Amount,
+		Audit: audit.InTx(ctx.Request.Context(), func(result db.CaptureHoldTxResult) audit.Event {
+			return server.auditEvent(ctx, audit.Event{
+				Action:       audit.ActionHoldCaptured,
+				Actor:        authPayload.Username,
+				ResourceType: audit.ResourceHold,
+				ResourceID:   strconv.FormatInt(hold.ID, 10),
+				Before:       hold,
+				After:        result,
+			})
+		}),
+	})
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "hold"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, captureHoldResponse{
+		Hold: newHoldResponse(result.Hold, fromAccount.Currency),
+		Transfer: newTransferTxResponse(db.TransferTxResult{
+			Transfer:    result.Transfer,
+			FromAccount: result.FromAccount,
+			ToAccount:   result.ToAccount,
+			FromEntry:   result.FromEntry,
+			ToEntry:     result.ToEntry,
+		}, fromAccount.Currency).withoutFrom(), // captured by the payee, the payer's account isn't theirs to see
+	})
+}
+
+// voidHold releases a hold without moving any money - by the owner of either account
+func (server *Server) voidHold(ctx *gin.Context) {
+	hold, fromAccount, _, ok := server.authorizedHold(ctx)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := server.store.VoidHoldTx(ctx, db.VoidHoldTxParams{
+		HoldID: hold.ID,
+		Audit: audit.InTx(ctx.Request.Context(), func(result db.VoidHoldTxResult) audit.Event {
+			return server.auditEvent(ctx, audit.Event{
+				Action:       audit.ActionHoldVoided,
+				Actor:        authPayload.Username,
+				ResourceType: audit.ResourceHold,
+				ResourceID:   strconv.FormatInt(hold.ID, 10),
+				Before:       hold,
+				After:        result.Hold,
+			})
+		}),
+	})
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "hold"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newHoldResponse(result.Hold, fromAccount.Currency))
+}
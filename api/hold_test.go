@@ -0,0 +1,306 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/money"
+	"github.com/web3dev6/simplebank/util"
+	"github.com/web3dev6/simplebank/worker"
+)
+
+func TestHoldApi(t *testing.T) {
+	payer, _ := randomUser(t)
+	payee, _ := randomUser(t)
+	stranger, _ := randomUser(t)
+
+	fromAccount := randomAccount(payer.Username)
+	fromAccount.Currency = util.USD
+	fromAccount.Balance = 10000
+	toAccount := randomAccount(payee.Username)
+	toAccount.ID = fromAccount.ID + 1
+	toAccount.Currency = util.USD
+
+	hold := db.Hold{
+		ID:          3,
+		AccountID:   fromAccount.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      2500,
+		Status:      db.HoldStatusAuthorized,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	heldAccount := fromAccount
+	heldAccount.HeldAmount = hold.Amount
+
+	// stubs the lookups of authorizedHold
+	getHold := func(store *mockdb.MockStore) {
+		store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(heldAccount, nil)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+	}
+
+	testcases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		user          db.User
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Authorize",
+			method: http.MethodPost,
+			url:    "/holds",
+			body:   gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": "25", "currency": util.USD},
+			user:   payer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().AuthorizeTransferTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ any, arg db.AuthorizeTransferTxParams) (db.AuthorizeTransferTxResult, error) {
+						require.Equal(t, int64(2500), arg.Amount)
+						// the test server holds for an hour
+						require.WithinDuration(t, time.Now().Add(time.Hour), arg.ExpiresAt, time.Minute)
+						return db.AuthorizeTransferTxResult{Hold: hold, FromAccount: heldAccount}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp authorizeTransferResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, hold.ID, rsp.Hold.ID)
+				require.Equal(t, db.HoldStatusAuthorized, rsp.Hold.Status)
+				require.Equal(t, money.Money{Amount: 10000, Currency: util.USD}, rsp.FromAccount.Balance)
+				require.Equal(t, money.Money{Amount: 7500, Currency: util.USD}, rsp.FromAccount.AvailableBalance)
+			},
+		},
+		{
+			name:   "AuthorizeFromUnauthorizedAccount",
+			method: http.MethodPost,
+			url:    "/holds",
+			body:   gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": "25", "currency": util.USD},
+			user:   payee,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().AuthorizeTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "AuthorizeInsufficientFunds",
+			method: http.MethodPost,
+			url:    "/holds",
+			body:   gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": "25", "currency": util.USD},
+			user:   payer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().AuthorizeTransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.AuthorizeTransferTxResult{}, apperr.InsufficientFunds("available balance is lower than the amount to hold"))
+				store.EXPECT().CreateAuditEventTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:       "GetByPayee",
+			method:     http.MethodGet,
+			url:        fmt.Sprintf("/holds/%d", hold.ID),
+			user:       payee,
+			buildStubs: getHold,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp holdResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, money.Money{Amount: 2500, Currency: util.USD}, rsp.Amount)
+				require.Nil(t, rsp.TransferID)
+			},
+		},
+		{
+			name:       "GetByStranger",
+			method:     http.MethodGet,
+			url:        fmt.Sprintf("/holds/%d", hold.ID),
+			user:       stranger,
+			buildStubs: getHold,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "PartialCapture",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body:   gin.H{"amount": "20"},
+			user:   payee,
+			buildStubs: func(store *mockdb.MockStore) {
+				getHold(store)
+				arg := db.CaptureHoldTxParams{HoldID: hold.ID, Amount: 2000}
+				captured := hold
+				captured.Status = db.HoldStatusCaptured
+				captured.CapturedAmount = 2000
+				captured.TransferID = pgtype.Int8{Int64: 9, Valid: true}
+				store.EXPECT().CaptureHoldTx(gomock.Any(), EqAuditedTxParams(arg, audit.ActionHoldCaptured)).Times(1).
+					Return(db.CaptureHoldTxResult{
+						Hold:        captured,
+						Transfer:    db.Transfer{ID: 9, FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 2000},
+						FromAccount: db.Account{ID: fromAccount.ID, Owner: payer.Username, Balance: 8000, Currency: util.USD},
+						ToAccount:   db.Account{ID: toAccount.ID, Owner: payee.Username, Balance: 2000, Currency: util.USD},
+						FromEntry:   db.Entry{ID: 1, AccountID: fromAccount.ID, Amount: -2000},
+						ToEntry:     db.Entry{ID: 2, AccountID: toAccount.ID, Amount: 2000},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp struct {
+					Hold     holdResponse               `json:"hold"`
+					Transfer map[string]json.RawMessage `json:"transfer"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				// captured by the payee - only their own account, not the payer's balance
+				require.Contains(t, rsp.Transfer, "to_account")
+				require.NotContains(t, rsp.Transfer, "from_account")
+				require.NotContains(t, rsp.Transfer, "from_entry")
+				require.NotContains(t, recorder.Body.String(), payer.Username)
+				require.Equal(t, db.HoldStatusCaptured, rsp.Hold.Status)
+				require.Equal(t, money.Money{Amount: 2000, Currency: util.USD}, rsp.Hold.CapturedAmount)
+				require.NotNil(t, rsp.Hold.TransferID)
+				require.Equal(t, int64(9), *rsp.Hold.TransferID)
+			},
+		},
+		{
+			name:   "FullCapture",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body:   gin.H{},
+			user:   payee,
+			buildStubs: func(store *mockdb.MockStore) {
+				getHold(store)
+				arg := db.CaptureHoldTxParams{HoldID: hold.ID}
+				store.EXPECT().CaptureHoldTx(gomock.Any(), EqAuditedTxParams(arg, audit.ActionHoldCaptured)).Times(1).Return(db.CaptureHoldTxResult{Hold: hold}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "CaptureByPayer",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body:   gin.H{},
+			user:   payer,
+			buildStubs: func(store *mockdb.MockStore) {
+				getHold(store)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "CaptureExpired",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body:   gin.H{},
+			user:   payee,
+			buildStubs: func(store *mockdb.MockStore) {
+				getHold(store)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.CaptureHoldTxResult{}, apperr.Conflict("hold has expired"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:   "VoidByPayer",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/void", hold.ID),
+			user:   payer,
+			buildStubs: func(store *mockdb.MockStore) {
+				getHold(store)
+				voided := hold
+				voided.Status = db.HoldStatusVoided
+				store.EXPECT().VoidHoldTx(gomock.Any(), EqAuditedTxParams(db.VoidHoldTxParams{HoldID: hold.ID}, audit.ActionHoldVoided)).Times(1).
+					Return(db.VoidHoldTxResult{Hold: voided, FromAccount: fromAccount}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp holdResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, db.HoldStatusVoided, rsp.Status)
+			},
+		},
+		{
+			name:   "VoidByStranger",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/void", hold.ID),
+			user:   stranger,
+			buildStubs: func(store *mockdb.MockStore) {
+				getHold(store)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "NotFound",
+			method: http.MethodPost,
+			url:    "/holds/404/void",
+			user:   payer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(int64(404))).Times(1).Return(db.Hold{}, db.ErrRecordNotFound)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			taskDistributor := worker.NewRedisTaskDistributor(asynq.RedisClientOpt{
+				Addr: "0.0.0.0:6379",
+			})
+			server := newTestServer(t, store, taskDistributor)
+			recorder := httptest.NewRecorder()
+
+			var data []byte
+			if tc.body != nil {
+				var err error
+				data, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,14 +1,18 @@
 package api
 
 import (
+	"fmt"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	_ "github.com/lib/pq"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/currency"
 	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/middleware"
 	"github.com/web3dev6/simplebank/util"
 	"github.com/web3dev6/simplebank/worker"
 )
@@ -18,15 +22,61 @@ func newTestServer(t *testing.T, store db.Store, taskDistributor worker.TaskDist
 		TokenSymmetricKey:   util.RandomString(32),
 		AccessTokenDuration: time.Minute,
 		TokenMakerType:      "PASETO",
+		HoldDuration:        time.Hour,
 	}
 
-	server, err := NewServer(config, store, taskDistributor)
+	server, err := NewServer(config, store, testCurrencies(), taskDistributor, middleware.NewPipeline(config))
 	require.NoError(t, err)
 
 	return server
 }
 
+// testCurrencies returns a registry of the currencies the migrations create, all enabled
+func testCurrencies() *currency.Registry {
+	return currency.NewStaticRegistry(
+		db.Currency{Code: util.USD, MinorUnits: 2, Enabled: true, DisplayName: "US Dollar"},
+		db.Currency{Code: util.EUR, MinorUnits: 2, Enabled: true, DisplayName: "Euro"},
+		db.Currency{Code: util.INR, MinorUnits: 2, Enabled: true, DisplayName: "Indian Rupee"},
+	)
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 	os.Exit(m.Run())
 }
+
+type eqAuditedTxParamsMatcher struct {
+	arg    interface{}
+	action string
+}
+
+// passes iff -> the params of a money tx equal arg but for their Audit func -> which must be set, auditing action
+// note* Audit is called with a zero result, the mocked tx never calls it
+func (e eqAuditedTxParamsMatcher) Matches(x interface{}) bool {
+	value := reflect.ValueOf(x)
+	if value.Type() != reflect.TypeOf(e.arg) {
+		return false
+	}
+	audit := value.FieldByName("Audit")
+	if audit.IsNil() {
+		return false
+	}
+	out := audit.Call([]reflect.Value{reflect.Zero(audit.Type().In(0))})
+	if !out[1].IsNil() || out[0].Interface().(db.CreateAuditEventTxParams).Action != e.action {
+		return false
+	}
+
+	// the rest of the params as is
+	params := reflect.New(value.Type()).Elem()
+	params.Set(value)
+	params.FieldByName("Audit").Set(reflect.Zero(audit.Type()))
+	return reflect.DeepEqual(e.arg, params.Interface())
+}
+
+func (e eqAuditedTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v audited as %s", e.arg, e.action)
+}
+
+func EqAuditedTxParams(arg interface{}, action string) gomock.Matcher {
+	return eqAuditedTxParamsMatcher{arg, action}
+}
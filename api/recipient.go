@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+)
+
+/*
+   Pay by username or email - users don't know the ids of other users' accounts
+	- a transfer (or hold) can name its recipient by username or verified email instead of to_account_id,
+	  the recipient's account in the transfer's currency is looked up by the (owner, currency) unique index
+	- GET /recipients/preview shows the masked full name of a recipient, to confirm before transferring
+   Note* an unverified email may not be its owner's - it's not found, same as an unknown one
+*/
+
+// resolveRecipient returns the account in currency of the user with the username or verified email recipient
+func (server *Server) resolveRecipient(ctx context.Context, recipient string, currency string) (db.Account, db.User, error) {
+	var user db.User
+	var err error
+	if strings.Contains(recipient, "@") {
+		user, err = server.store.GetUserByEmail(ctx, recipient)
+		if err == nil && !user.IsEmailVerified {
+			err = db.ErrRecordNotFound
+		}
+	} else {
+		user, err = server.store.GetUser(ctx, recipient)
+	}
+	if err != nil {
+		return db.Account{}, db.User{}, db.DomainError(err, "recipient")
+	}
+
+	account, err := server.store.GetAccountByOwnerAndCurrency(ctx, db.GetAccountByOwnerAndCurrencyParams{
+		Owner:    user.Username,
+		Currency: currency,
+	})
+	if err != nil {
+		return db.Account{}, db.User{}, db.DomainError(err, fmt.Sprintf("%s account of the recipient", currency))
+	}
+	return account, user, nil
+}
+
+// toAccount returns the to account of req - by its id, or resolved from its recipient
+func (server *Server) toAccount(ctx *gin.Context, req transferRequest) (db.Account, bool) {
+	if req.Recipient == "" {
+		return server.validAccount(ctx, req.ToAccountId, req.Currency)
+	}
+	account, _, err := server.resolveRecipient(ctx, req.Recipient, req.Currency)
+	if err != nil {
+		abortWithErrorResponse(ctx, err)
+		return account, false
+	}
+	return account, true
+}
+
+type previewRecipientRequest struct {
+	Recipient string `form:"recipient" binding:"required,max=200"`
+	Currency  string `form:"currency" binding:"required,currency"`
+}
+
+type previewRecipientResponse struct {
+	Recipient string `json:"recipient"`
+	Name      string `json:"name"` // masked, e.g. J*** D***
+	Currency  string `json:"currency"`
+}
+
+// previewRecipient checks a recipient can be paid in a currency, showing only their masked name
+// note* no account id nor username in the response - a preview by email doesn't tell who owns it
+func (server *Server) previewRecipient(ctx *gin.Context) {
+	var req previewRecipientRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+
+	_, user, err := server.resolveRecipient(ctx, req.Recipient, req.Currency)
+	if err != nil {
+		abortWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, previewRecipientResponse{
+		Recipient: req.Recipient,
+		Name:      maskName(user.FullName),
+		Currency:  req.Currency,
+	})
+}
+
+// maskName keeps the first letter of each word of name, e.g. "John Doe" is "J*** D***"
+// note* always 3 stars, the length of a name tells about it too
+func maskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		first, _ := utf8.DecodeRuneInString(word)
+		words[i] = string(first) + "***"
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/util"
+	"github.com/web3dev6/simplebank/worker"
+)
+
+func TestPreviewRecipientApi(t *testing.T) {
+	user, _ := randomUser(t)
+	recipient, _ := randomUser(t)
+	recipient.FullName = "John Doe"
+	recipient.IsEmailVerified = true
+	account := randomAccount(recipient.Username)
+	account.Currency = util.USD
+
+	unverified := recipient
+	unverified.IsEmailVerified = false
+
+	testcases := []struct {
+		name          string
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "ByUsername",
+			query: url.Values{"recipient": {recipient.Username}, "currency": {util.USD}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(recipient.Username)).Times(1).Return(recipient, nil)
+				arg := db.GetAccountByOwnerAndCurrencyParams{Owner: recipient.Username, Currency: util.USD}
+				store.EXPECT().GetAccountByOwnerAndCurrency(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp previewRecipientResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, "J*** D***", rsp.Name)
+				require.Equal(t, util.USD, rsp.Currency)
+				// nothing that identifies the account
+				var fields map[string]interface{}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &fields))
+				require.Len(t, fields, 3)
+			},
+		},
+		{
+			name:  "ByVerifiedEmail",
+			query: url.Values{"recipient": {recipient.Email}, "currency": {util.USD}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(recipient.Email)).Times(1).Return(recipient, nil)
+				store.EXPECT().GetAccountByOwnerAndCurrency(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "UnverifiedEmail",
+			query: url.Values{"recipient": {recipient.Email}, "currency": {util.USD}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(recipient.Email)).Times(1).Return(unverified, nil)
+				store.EXPECT().GetAccountByOwnerAndCurrency(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "NoAccountInCurrency",
+			query: url.Values{"recipient": {recipient.Username}, "currency": {util.EUR}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(recipient.Username)).Times(1).Return(recipient, nil)
+				store.EXPECT().GetAccountByOwnerAndCurrency(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "UnsupportedCurrency",
+			query: url.Values{"recipient": {recipient.Username}, "currency": {"JPY"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			taskDistributor := worker.NewRedisTaskDistributor(asynq.RedisClientOpt{
+				Addr: "0.0.0.0:6379",
+			})
+			server := newTestServer(t, store, taskDistributor)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/recipients/preview?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestMaskName(t *testing.T) {
+	require.Equal(t, "J*** D***", maskName("John Doe"))
+	require.Equal(t, "J*** D***", maskName("  Jo   Doe-Smith "))
+	require.Equal(t, "É***", maskName("Élodie"))
+	require.Equal(t, "", maskName(""))
+}
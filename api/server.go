@@ -2,13 +2,19 @@ package api
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
+	"github.com/web3dev6/simplebank/currency"
 	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/logging"
+	"github.com/web3dev6/simplebank/middleware"
 	token "github.com/web3dev6/simplebank/token"
 	"github.com/web3dev6/simplebank/util"
 	"github.com/web3dev6/simplebank/worker"
@@ -17,14 +23,16 @@ import (
 // Server serves HTTP requests for our banking service
 type Server struct {
 	store           db.Store               // do the transfer_tx
+	currencies      *currency.Registry     // currencies requests can be made in
 	tokenMaker      token.Maker            // manage tokens for users
 	router          *gin.Engine            // send to correct handler for processing
 	config          util.Config            // store config used to start the server
 	taskDistributor worker.TaskDistributor // To create tasks in redis queue
+	pipeline        *middleware.Pipeline   // cross-cutting concerns shared with the gRPC & gateway servers
 }
 
 // NewServer creates a new HTTP server and setup routing for service
-func NewServer(config util.Config, store db.Store, taskDistributor worker.TaskDistributor) (*Server, error) {
+func NewServer(config util.Config, store db.Store, currencies *currency.Registry, taskDistributor worker.TaskDistributor, pipeline *middleware.Pipeline) (*Server, error) {
 	// token maker for auth handling from config
 	var tokenMaker token.Maker
 	var err error
@@ -33,6 +41,8 @@ func NewServer(config util.Config, store db.Store, taskDistributor worker.TaskDi
 		tokenMaker, err = token.NewJWTMaker(config.TokenSymmetricKey)
 	case "PASETO":
 		tokenMaker, err = token.NewPasetoMaker(config.TokenSymmetricKey)
+	default:
+		err = fmt.Errorf("unknown token maker type %q", config.TokenMakerType)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
@@ -41,31 +51,44 @@ func NewServer(config util.Config, store db.Store, taskDistributor worker.TaskDi
 	// server instance with store, tokenMaker & config
 	server := &Server{
 		store:           store,
+		currencies:      currencies,
 		tokenMaker:      tokenMaker,
 		config:          config,
 		taskDistributor: taskDistributor,
+		pipeline:        pipeline,
 	}
 	// 	Gin Validator binding - register "currency" as a validator tag
+	validatorCurrencies.Store(currencies)
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("reversal_reason", validReversalReason)
+		v.RegisterValidation("username", validUsername)
+		// report invalid fields by their request names
+		v.RegisterTagNameFunc(requestFieldName)
 	}
 
 	// setup router with routes
-	server.setupRouter()
+	if err := server.setupRouter(); err != nil {
+		return nil, err
+	}
 
 	return server, nil
 }
 
-func (server *Server) setupRouter() {
+func (server *Server) setupRouter() error {
 	//  Default Gin router
 	// router := gin.Default()
 
 	// Initialize custom Gin for enabling structured logger
 	gin.SetMode(gin.ReleaseMode)
 	gin.DefaultErrorWriter = os.Stderr
-	router := gin.New()            // empty engine
-	router.Use(loggerMiddleware()) // adds our new middleware
-	router.Use(gin.Recovery())     // adds the default recovery middleware
+	router := gin.New() // empty engine
+	// note* gin believes x-forwarded-for from any proxy by default - a client could set it to dodge the rate limit
+	if err := router.SetTrustedProxies(server.config.TrustedProxyList()); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	router.Use(server.pipeline.Gin()) // adds the shared pipeline - rate-limit & structured logger
+	router.Use(gin.Recovery())        // adds the default recovery middleware
 
 	// authRoutes filter requests through our authMiddleware returned authHandler first
 	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
@@ -75,29 +98,68 @@ func (server *Server) setupRouter() {
 	router.POST("/users/login", server.loginUser)
 	router.GET("/users/verify_email", server.verifyUserEmail)
 	router.POST("/tokens/renew_access", server.renewAccessToken)
+	router.GET("/currencies", server.listCurrencies)
+	router.GET("/account_products", server.listAccountProducts)
 
 	// add protected routes to authRoutes
 	authRoutes.GET("/users", server.getUserDetails)
 	authRoutes.POST("/accounts", server.createAccount)
 	authRoutes.GET("/accounts/:id", server.getAccount)
 	authRoutes.GET("/accounts", server.listAccounts)
+	authRoutes.GET("/accounts/:id/statement", server.getStatement)
+	authRoutes.GET("/accounts/:id/balance", server.getBalanceAt)
 	authRoutes.PATCH("/users", server.updateUser)
 
 	authRoutes.POST("/transfers", server.createTransfer)
+	authRoutes.GET("/transfers", server.listTransfers)
+	authRoutes.GET("/recipients/preview", server.previewRecipient)
+	authRoutes.POST("/transfers/batch", server.createBatchTransfer)
+
+	authRoutes.POST("/holds", server.authorizeTransfer)
+	authRoutes.GET("/holds/:id", server.getHold)
+	authRoutes.POST("/holds/:id/capture", server.captureHold)
+	authRoutes.POST("/holds/:id/void", server.voidHold)
+
+	// add admin routes - authenticated & an admin user
+	adminRoutes := router.Group("/admin").Use(authMiddleware(server.tokenMaker), server.adminMiddleware())
+	adminRoutes.GET("/audit_events", server.listAuditEvents)
+	adminRoutes.GET("/audit_events/verify", server.verifyAuditChain)
+	adminRoutes.GET("/currencies", server.listAllCurrencies)
+	adminRoutes.PATCH("/currencies/:code", server.updateCurrency)
+	adminRoutes.PATCH("/account_products/:code", server.updateAccountProduct)
+	adminRoutes.POST("/transfers/:id/reverse", server.reverseTransfer)
 
 	server.router = router
+	return nil
+}
+
+// Handler returns the router serving all routes - mounted on an http.Server by the caller, which owns its lifecycle (listen & graceful shutdown)
+func (server *Server) Handler() http.Handler {
+	return server.router
+}
+
+// errorResponse maps err to an RFC 7807 problem - echoing the request id, so a client can quote it when reporting the error
+func errorResponse(ctx *gin.Context, err error) apperr.Problem {
+	return apperr.NewProblem(err, ctx.Request.URL.Path, logging.RequestID(ctx.Request.Context()))
 }
 
-// Start runs the http server on a specified address
-func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+// abortWithErrorResponse responds with err as problem+json, the full err (with its cause) is only logged
+func abortWithErrorResponse(ctx *gin.Context, err error) {
+	problem := errorResponse(ctx, err)
+	ctx.Error(err)
+	// note* set before rendering, Gin keeps an already set Content-Type
+	ctx.Header("Content-Type", apperr.ProblemContentType)
+	ctx.AbortWithStatusJSON(problem.Status, problem)
 }
 
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+// audit records event in the audit log, with the client ip & user agent of the request
+func (server *Server) audit(ctx *gin.Context, event audit.Event) {
+	audit.Record(ctx.Request.Context(), server.store, server.auditEvent(ctx, event))
 }
 
-func abortWithErrorResponse(ctx *gin.Context, httpErrCode int, err error) {
-	ctx.AbortWithError(httpErrCode, err)
-	ctx.JSON(httpErrCode, errorResponse(err))
+// auditEvent returns event with the client ip & user agent of the request - for the events of money txs, see audit.InTx
+func (server *Server) auditEvent(ctx *gin.Context, event audit.Event) audit.Event {
+	event.ClientIP = ctx.ClientIP()
+	event.UserAgent = ctx.Request.UserAgent()
+	return event
 }
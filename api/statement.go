@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web3dev6/simplebank/apperr"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/statement"
+	"github.com/web3dev6/simplebank/token"
+)
+
+// maxStatementDays is the longest period of a statement download
+const maxStatementDays = 366
+
+var statementContentTypes = map[string]string{
+	"csv": "text/csv; charset=utf-8",
+	"pdf": "application/pdf",
+}
+
+type getStatementUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type getStatementRequest struct {
+	// days, both included - e.g. from=2026-09-01&to=2026-09-30 is september
+	From   string `form:"from" binding:"required,datetime=2006-01-02"`
+	To     string `form:"to" binding:"required,datetime=2006-01-02"`
+	Format string `form:"format" binding:"omitempty,oneof=csv pdf"`
+}
+
+// getStatement downloads the statement of the user's account over a period, as a csv (default) or pdf file
+// note* days in UTC, the same as the entries' timestamps in the statement
+func (server *Server) getStatement(ctx *gin.Context) {
+	var uri getStatementUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+	var req getStatementRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+	if req.Format == "" {
+		req.Format = "csv"
+	}
+	// note* already validated as dates by the binding
+	from, _ := time.Parse(statement.DateFormat, req.From)
+	lastDay, _ := time.Parse(statement.DateFormat, req.To)
+	to := lastDay.AddDate(0, 0, 1)
+	switch {
+	case !from.Before(to):
+		abortWithErrorResponse(ctx, apperr.Validation("invalid statement period",
+			apperr.FieldViolation{Field: "to", Description: "must not be before from"}))
+		return
+	case to.Sub(from) > maxStatementDays*24*time.Hour:
+		abortWithErrorResponse(ctx, apperr.Validation("invalid statement period",
+			apperr.FieldViolation{Field: "to", Description: fmt.Sprintf("must be at most %d days after from", maxStatementDays)}))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "account"))
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		abortWithErrorResponse(ctx, ErrFetchingUnauthorizedAccount)
+		return
+	}
+
+	st, err := statement.Build(ctx, server.store, account, from, to)
+	if err != nil {
+		abortWithErrorResponse(ctx, err)
+		return
+	}
+	// written to a buffer first, so a failure is still an error response rather than half a file
+	var buf bytes.Buffer
+	if req.Format == "pdf" {
+		err = st.WritePDF(&buf)
+	} else {
+		err = st.WriteCSV(&buf)
+	}
+	if err != nil {
+		abortWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, st.Filename(req.Format)))
+	ctx.Data(http.StatusOK, statementContentTypes[req.Format], buf.Bytes())
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/worker"
+)
+
+func TestGetStatementApi(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	from := time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	entries := []db.Entry{
+		{ID: 1, AccountID: account.ID, Amount: 1000, CreatedAt: from.Add(time.Hour)},
+		{ID: 2, AccountID: account.ID, Amount: -300, CreatedAt: from.Add(2 * time.Hour)},
+	}
+	september := url.Values{"from": {"2026-09-01"}, "to": {"2026-09-30"}}
+	withFormat := func(query url.Values, format string) url.Values {
+		q := url.Values{"format": {format}}
+		for key, values := range query {
+			q[key] = values
+		}
+		return q
+	}
+
+	testcases := []struct {
+		name          string
+		accountID     int64
+		query         url.Values
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "CSV",
+			accountID: account.ID,
+			query:     september,
+			username:  user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Eq(account.ID), gomock.Eq(from.Add(-time.Microsecond))).
+					Times(1).Return(int64(500), nil)
+				store.EXPECT().ListEntriesBetween(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListEntriesBetweenParams) ([]db.Entry, error) {
+						// to is included, up to the end of its day
+						require.Equal(t, from, arg.FromTime)
+						require.Equal(t, to, arg.ToTime)
+						return entries, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+				require.Equal(t, fmt.Sprintf(`attachment; filename="statement-%d-2026-09-01-2026-09-30.csv"`, account.ID),
+					recorder.Header().Get("Content-Disposition"))
+
+				rows, err := csv.NewReader(recorder.Body).ReadAll()
+				require.NoError(t, err)
+				require.Len(t, rows, 5)
+				require.Equal(t, "opening balance", rows[1][2])
+				require.Equal(t, "closing balance", rows[4][2])
+				require.Equal(t, rows[3][5], rows[4][5])
+			},
+		},
+		{
+			name:      "PDF",
+			accountID: account.ID,
+			query:     withFormat(september, "pdf"),
+			username:  user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(int64(500), nil)
+				store.EXPECT().ListEntriesBetween(gomock.Any(), gomock.Any()).Times(1).Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/pdf", recorder.Header().Get("Content-Type"))
+				require.True(t, strings.HasSuffix(recorder.Header().Get("Content-Disposition"), `.pdf"`))
+				require.True(t, strings.HasPrefix(recorder.Body.String(), "%PDF-"))
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			query:     september,
+			username:  other.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "AccountNotFound",
+			accountID: account.ID,
+			query:     september,
+			username:  user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidFormat",
+			accountID: account.ID,
+			query:     withFormat(september, "xlsx"),
+			username:  user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidDate",
+			accountID: account.ID,
+			query:     url.Values{"from": {"2026-09-01"}, "to": {"30/09/2026"}},
+			username:  user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "ToBeforeFrom",
+			accountID: account.ID,
+			query:     url.Values{"from": {"2026-09-30"}, "to": {"2026-09-01"}},
+			username:  user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "PeriodTooLong",
+			accountID: account.ID,
+			query:     url.Values{"from": {"2025-01-01"}, "to": {"2026-09-30"}},
+			username:  user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			taskDistributor := worker.NewRedisTaskDistributor(asynq.RedisClientOpt{
+				Addr: "0.0.0.0:6379",
+			})
+			server := newTestServer(t, store, taskDistributor)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/statement?%s", tc.accountID, tc.query.Encode())
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
 )
 
 type renewAccessTokenRequest struct {
@@ -19,7 +22,7 @@ type renewAccessTokenResponse struct {
 func (server *Server) renewAccessToken(ctx *gin.Context) {
 	var req renewAccessTokenRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		abortWithErrorResponse(ctx, http.StatusBadRequest, err)
+		abortWithErrorResponse(ctx, bindingError(err))
 		return
 	}
 
@@ -27,7 +30,7 @@ func (server *Server) renewAccessToken(ctx *gin.Context) {
 	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
 	if err != nil {
 		// token is invalid or expired
-		abortWithErrorResponse(ctx, http.StatusUnauthorized, err)
+		abortWithErrorResponse(ctx, apperr.Unauthenticated(err.Error()))
 		return
 	}
 
@@ -35,37 +38,42 @@ func (server *Server) renewAccessToken(ctx *gin.Context) {
 	session, err := server.store.GetSession(ctx, refreshPayload.ID)
 	if err != nil {
 		// session not found from sessionId (which is refreshToken's uuid)
-		abortWithErrorResponse(ctx, http.StatusNotFound, ErrSessionNotFound)
+		if apperr.Is(db.DomainError(err, "session"), apperr.CodeNotFound) {
+			abortWithErrorResponse(ctx, ErrSessionNotFound.WithCause(err))
+			return
+		}
+		abortWithErrorResponse(ctx, apperr.Internal(err))
 		return
 	}
 
 	// if session found, check if this session is not blocked
 	if session.IsBlocked {
-		abortWithErrorResponse(ctx, http.StatusUnauthorized, ErrBlockedSession)
+		abortWithErrorResponse(ctx, ErrBlockedSession)
 		return
 	}
 	// also check if RefreshToken's Username is same as corresponding session's Username (from db)
 	if session.Username != refreshPayload.Username {
-		abortWithErrorResponse(ctx, http.StatusUnauthorized, ErrIncorrectSessionUser)
+		abortWithErrorResponse(ctx, ErrIncorrectSessionUser)
 		return
 	}
 	// also check if RefreshToken is same as corresponding session's RefreshToken (from db)
 	if session.RefreshToken != req.RefreshToken {
-		abortWithErrorResponse(ctx, http.StatusUnauthorized, ErrIncorrectSessionToken)
+		abortWithErrorResponse(ctx, ErrIncorrectSessionToken)
 		return
 	}
 	// note* token expiration is already checked for in VerifyToken (*Payload.Valid()), still check for rare case
 	if time.Now().After(refreshPayload.ExpiresAt) {
-		abortWithErrorResponse(ctx, http.StatusUnauthorized, ErrExpiredSession)
+		abortWithErrorResponse(ctx, ErrExpiredSession)
 		return
 	}
 
 	// issue a new accessToken
 	newAccessToken, accessPayload, err := server.tokenMaker.CreateToken(refreshPayload.Username, server.config.AccessTokenDuration)
 	if err != nil {
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, apperr.Internal(err))
 		return
 	}
+	server.audit(ctx, audit.Event{Action: audit.ActionTokenRenewed, Actor: session.Username, ResourceType: audit.ResourceSession, ResourceID: session.ID.String()})
 
 	// send ok response if all ok WITH renewAccessToken Response
 	resp := renewAccessTokenResponse{
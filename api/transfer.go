@@ -1,74 +1,462 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
 	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
 	"github.com/web3dev6/simplebank/token"
 )
 
 type transferRequest struct {
-	FromAccountId int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountId   int64  `json:"to_account_id" binding:"required,min=1"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`       // gt=0 will work even when we have decimals, min=1 won't
-	Currency      string `json:"currency" binding:"required,currency"` // using custom validtor currency
+	FromAccountId int64 `json:"from_account_id" binding:"required,min=1"`
+	// the to account, either by id or by the username or verified email of its owner - see resolveRecipient
+	ToAccountId int64  `json:"to_account_id" binding:"required_without=Recipient,excluded_with=Recipient,omitempty,min=1"`
+	Recipient   string `json:"recipient" binding:"required_without=ToAccountId,excluded_with=ToAccountId,omitempty,max=200"`
+	// note* a decimal string in major units of the currency, e.g. "12.34" USD is 1234 cents
+	// not a json number - a float can't hold every amount exactly, & old clients sending minor units fail instead of moving 100x
+	Amount   string `json:"amount" binding:"required"`
+	Currency string `json:"currency" binding:"required,currency"` // using custom validtor currency
+
+	// optional, their limits are checked against db.TransferDetails
+	Memo      string          `json:"memo"`
+	Reference string          `json:"reference"` // e.g. an invoice number, GET /transfers?reference= finds it
+	Metadata  json.RawMessage `json:"metadata"`  // any json object
+}
+
+func (req transferRequest) details() db.TransferDetails {
+	return db.TransferDetails{Memo: req.Memo, Reference: req.Reference, Metadata: req.Metadata}
+}
+
+type transferResponse struct {
+	ID            int64           `json:"id"`
+	FromAccountID int64           `json:"from_account_id"`
+	ToAccountID   int64           `json:"to_account_id"`
+	Amount        money.Money     `json:"amount"`
+	Memo          string          `json:"memo"`
+	Reference     string          `json:"reference"`
+	Metadata      json.RawMessage `json:"metadata"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+func newTransferResponse(transfer db.Transfer, currency string) transferResponse {
+	return transferResponse{
+		ID:            transfer.ID,
+		FromAccountID: transfer.FromAccountID,
+		ToAccountID:   transfer.ToAccountID,
+		Amount:        money.Money{Amount: transfer.Amount, Currency: currency},
+		Memo:          transfer.Memo,
+		Reference:     transfer.Reference,
+		Metadata:      transfer.Metadata,
+		CreatedAt:     transfer.CreatedAt,
+	}
+}
+
+type entryResponse struct {
+	ID        int64           `json:"id"`
+	AccountID int64           `json:"account_id"`
+	Amount    money.Money     `json:"amount"`
+	Memo      string          `json:"memo"`
+	Reference string          `json:"reference"`
+	Metadata  json.RawMessage `json:"metadata"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func newEntryResponse(entry db.Entry, currency string) entryResponse {
+	return entryResponse{
+		ID:        entry.ID,
+		AccountID: entry.AccountID,
+		Amount:    money.Money{Amount: entry.Amount, Currency: currency},
+		Memo:      entry.Memo,
+		Reference: entry.Reference,
+		Metadata:  entry.Metadata,
+		CreatedAt: entry.CreatedAt,
+	}
+}
+
+// transferTxResponse is the result of a transfer, every amount in major units of the transfer's currency
+// note* the side of an account the caller doesn't own is left out - see withoutFrom & withoutTo
+type transferTxResponse struct {
+	Transfer    transferResponse `json:"transfer"`
+	FromAccount *accountResponse `json:"from_account,omitempty"`
+	ToAccount   *accountResponse `json:"to_account,omitempty"`
+	FromEntry   *entryResponse   `json:"from_entry,omitempty"`
+	ToEntry     *entryResponse   `json:"to_entry,omitempty"`
+}
+
+func newTransferTxResponse(result db.TransferTxResult, currency string) transferTxResponse {
+	fromAccount, toAccount := newAccountResponse(result.FromAccount), newAccountResponse(result.ToAccount)
+	fromEntry, toEntry := newEntryResponse(result.FromEntry, currency), newEntryResponse(result.ToEntry, currency)
+	return transferTxResponse{
+		Transfer:    newTransferResponse(result.Transfer, currency),
+		FromAccount: &fromAccount,
+		ToAccount:   &toAccount,
+		FromEntry:   &fromEntry,
+		ToEntry:     &toEntry,
+	}
+}
+
+// withoutFrom leaves out the from account & entry - the payer's balance isn't the payee's business
+func (rsp transferTxResponse) withoutFrom() transferTxResponse {
+	rsp.FromAccount, rsp.FromEntry = nil, nil
+	return rsp
+}
+
+// withoutTo leaves out the to account & entry - the payee's balance isn't the payer's business
+func (rsp transferTxResponse) withoutTo() transferTxResponse {
+	rsp.ToAccount, rsp.ToEntry = nil, nil
+	return rsp
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
 	var req transferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		abortWithErrorResponse(ctx, http.StatusBadRequest, err)
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+	amount, err := parsePositiveAmount("amount", req.Amount, req.Currency)
+	if err != nil {
+		abortWithErrorResponse(ctx, err)
+		return
+	}
+	if err := validateTransferDetails("", req.details()); err != nil {
+		abortWithErrorResponse(ctx, err)
 		return
 	}
 
 	fromAccount, valid := server.validAccount(ctx, req.FromAccountId, req.Currency)
+	if !valid {
+		return
+	}
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if fromAccount.Owner != authPayload.Username {
-		abortWithErrorResponse(ctx, http.StatusUnauthorized, ErrTransferringMoneyFromUnauthorizedAccount)
+		abortWithErrorResponse(ctx, ErrTransferringMoneyFromUnauthorizedAccount)
 		return
 	}
-	if !valid {
+	// note* a pre-check on the balance read above - TransferTx checks it again on the locked account
+	// held funds can't be transferred, only captured by their hold
+	if fromAccount.AvailableBalance() < amount.Amount {
+		err := apperr.InsufficientFunds(fmt.Sprintf("account [%d] available balance is lower than the transfer amount", fromAccount.ID))
+		abortWithErrorResponse(ctx, err)
 		return
 	}
-	_, valid = server.validAccount(ctx, req.FromAccountId, req.Currency)
+	toAccount, valid := server.toAccount(ctx, req)
 	if !valid {
 		return
 	}
 
 	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountId,
-		ToAccountID:   req.ToAccountId,
-		Amount:        req.Amount,
+		FromAccountID:   req.FromAccountId,
+		ToAccountID:     toAccount.ID,
+		Amount:          amount.Amount,
+		TransferDetails: req.details(),
+		Audit: audit.InTx(ctx.Request.Context(), func(result db.TransferTxResult) audit.Event {
+			return server.auditEvent(ctx, audit.Event{
+				Action:       audit.ActionTransferCreated,
+				Actor:        authPayload.Username,
+				ResourceType: audit.ResourceTransfer,
+				ResourceID:   strconv.FormatInt(result.Transfer.ID, 10),
+				Before:       gin.H{"from_account": fromAccount, "to_account": toAccount},
+				After:        result,
+			})
+		}),
 	}
 	result, err := server.store.TransferTx(ctx, arg)
 	if err != nil {
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, db.DomainError(err, "transfer"))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	rsp := newTransferTxResponse(result, req.Currency)
+	if req.Recipient != "" {
+		// note* paid by username or email, the payee's account stays as hidden as in the recipient preview
+		rsp = rsp.withoutTo()
+	}
+	ctx.JSON(http.StatusOK, rsp)
 }
 
 func (server *Server) validAccount(ctx *gin.Context, accountId int64, currency string) (db.Account, bool) {
 	account, err := server.store.GetAccount(ctx, accountId)
 	if err != nil {
-		if errors.Is(err, db.ErrRecordNotFound) || errors.Is(err, sql.ErrNoRows) {
-			abortWithErrorResponse(ctx, http.StatusNotFound, err)
-			return account, false
-		}
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, db.DomainError(err, fmt.Sprintf("account [%d]", accountId)))
+		return account, false
+	}
+	// note* the bank's internal accounts (e.g. interest expense) are only moved by the bank itself
+	if account.Owner == db.SystemUsername {
+		abortWithErrorResponse(ctx, db.DomainError(db.ErrRecordNotFound, fmt.Sprintf("account [%d]", accountId)))
 		return account, false
 	}
 
 	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch, account currency:%s, transfer currency:%s", account.ID, account.Currency, currency)
-		abortWithErrorResponse(ctx, http.StatusBadRequest, err)
+		err := apperr.Validation(
+			fmt.Sprintf("account [%d] currency mismatch, account currency:%s, transfer currency:%s", account.ID, account.Currency, currency),
+			apperr.FieldViolation{Field: "currency", Description: "must match the currency of both accounts"},
+		)
+		abortWithErrorResponse(ctx, err)
 		return account, false
 	}
 
 	return account, true
 }
+
+type batchTransferLegRequest struct {
+	FromAccountId int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountId   int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount        string `json:"amount" binding:"required"` // a decimal string in major units, like a transfer's
+
+	// optional, like a transfer's
+	Memo      string          `json:"memo"`
+	Reference string          `json:"reference"`
+	Metadata  json.RawMessage `json:"metadata"`
+}
+
+type batchTransferRequest struct {
+	Currency string                    `json:"currency" binding:"required,currency"`
+	Legs     []batchTransferLegRequest `json:"legs" binding:"required,min=1,max=100,dive"` // max is db.MaxBatchLegs
+}
+
+// batchTransferResponse is the result of a batch transfer, every amount in major units of the batch's currency
+// note* only the authenticated user's accounts are in it, the balances of the accounts paid into are not theirs to see
+type batchTransferResponse struct {
+	Transfers []transferResponse `json:"transfers"`
+	Entries   []entryResponse    `json:"entries"`
+	Accounts  []accountResponse  `json:"accounts"`
+}
+
+func newBatchTransferResponse(result db.BatchTransferTxResult, currency string, owner string) batchTransferResponse {
+	rsp := batchTransferResponse{
+		Transfers: make([]transferResponse, 0, len(result.Transfers)),
+		Entries:   make([]entryResponse, 0, len(result.Entries)),
+		Accounts:  make([]accountResponse, 0, len(result.Accounts)),
+	}
+	for _, transfer := range result.Transfers {
+		rsp.Transfers = append(rsp.Transfers, newTransferResponse(transfer, currency))
+	}
+	for _, entry := range result.Entries {
+		rsp.Entries = append(rsp.Entries, newEntryResponse(entry, currency))
+	}
+	for _, account := range result.Accounts {
+		if account.Owner == owner {
+			rsp.Accounts = append(rsp.Accounts, newAccountResponse(account))
+		}
+	}
+	return rsp
+}
+
+// createBatchTransfer moves money along every leg of the request, all-or-nothing - e.g. payroll from one account to many
+// note* the user must own the from account of every leg, the balances are checked by the store on the locked accounts
+func (server *Server) createBatchTransfer(ctx *gin.Context) {
+	var req batchTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.BatchTransferTxParams{
+		Currency: req.Currency,
+		Legs:     make([]db.BatchTransferLeg, 0, len(req.Legs)),
+	}
+	for i, leg := range req.Legs {
+		amount, err := parsePositiveAmount(fmt.Sprintf("legs[%d].amount", i), leg.Amount, req.Currency)
+		if err != nil {
+			abortWithErrorResponse(ctx, err)
+			return
+		}
+		details := db.TransferDetails{Memo: leg.Memo, Reference: leg.Reference, Metadata: leg.Metadata}
+		if err := validateTransferDetails(fmt.Sprintf("legs[%d].", i), details); err != nil {
+			abortWithErrorResponse(ctx, err)
+			return
+		}
+		arg.Legs = append(arg.Legs, db.BatchTransferLeg{
+			FromAccountID:   leg.FromAccountId,
+			ToAccountID:     leg.ToAccountId,
+			Amount:          amount.Amount,
+			TransferDetails: details,
+		})
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	fromAccounts := make(map[int64]db.Account)
+	for _, leg := range arg.Legs {
+		if _, ok := fromAccounts[leg.FromAccountID]; ok {
+			continue
+		}
+		fromAccount, valid := server.validAccount(ctx, leg.FromAccountID, req.Currency)
+		if !valid {
+			return
+		}
+		if fromAccount.Owner != authPayload.Username {
+			abortWithErrorResponse(ctx, ErrTransferringMoneyFromUnauthorizedAccount)
+			return
+		}
+		fromAccounts[leg.FromAccountID] = fromAccount
+	}
+
+	arg.Audit = audit.InTx(ctx.Request.Context(), func(result db.BatchTransferTxResult) audit.Event {
+		return server.auditEvent(ctx, audit.Event{
+			Action:       audit.ActionTransferBatchCreated,
+			Actor:        authPayload.Username,
+			ResourceType: audit.ResourceTransfer,
+			ResourceID:   audit.BatchResourceID(result.Transfers),
+			Before:       gin.H{"from_accounts": fromAccounts},
+			After:        result,
+		})
+	})
+	result, err := server.store.BatchTransferTx(ctx, arg)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "transfer"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newBatchTransferResponse(result, req.Currency, authPayload.Username))
+}
+
+type reverseTransferUri struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type reverseTransferRequest struct {
+	// note* a decimal string in major units like a transfer's, leave it out to reverse all that's not reversed yet
+	Amount string `json:"amount"`
+	Reason string `json:"reason" binding:"required,reversal_reason"`
+}
+
+type transferReversalResponse struct {
+	ID                 int64       `json:"id"`
+	OriginalTransferID int64       `json:"original_transfer_id"`
+	ReversalTransferID int64       `json:"reversal_transfer_id"`
+	Amount             money.Money `json:"amount"`
+	Reason             string      `json:"reason"`
+	ReversedBy         string      `json:"reversed_by"`
+	CreatedAt          time.Time   `json:"created_at"`
+}
+
+// reverseTransferResponse is the result of a reversal, every amount in major units of the transfer's currency
+type reverseTransferResponse struct {
+	Reversal        transferReversalResponse `json:"reversal"`
+	Transfer        transferResponse         `json:"transfer"` // the compensating transfer
+	FromAccount     accountResponse          `json:"from_account"`
+	ToAccount       accountResponse          `json:"to_account"`
+	FromEntry       entryResponse            `json:"from_entry"`
+	ToEntry         entryResponse            `json:"to_entry"`
+	RemainingAmount money.Money              `json:"remaining_amount"`
+}
+
+func newReverseTransferResponse(result db.ReverseTransferTxResult, currency string) reverseTransferResponse {
+	return reverseTransferResponse{
+		Reversal: transferReversalResponse{
+			ID:                 result.Reversal.ID,
+			OriginalTransferID: result.Reversal.OriginalTransferID,
+			ReversalTransferID: result.Reversal.ReversalTransferID,
+			Amount:             money.Money{Amount: result.Reversal.Amount, Currency: currency},
+			Reason:             result.Reversal.Reason,
+			ReversedBy:         result.Reversal.ReversedBy,
+			CreatedAt:          result.Reversal.CreatedAt,
+		},
+		Transfer:        newTransferResponse(result.Transfer, currency),
+		FromAccount:     newAccountResponse(result.FromAccount),
+		ToAccount:       newAccountResponse(result.ToAccount),
+		FromEntry:       newEntryResponse(result.FromEntry, currency),
+		ToEntry:         newEntryResponse(result.ToEntry, currency),
+		RemainingAmount: money.Money{Amount: result.RemainingAmount, Currency: currency},
+	}
+}
+
+// reverseTransfer moves a transfer's money back, in full or in part (a refund) - admins only
+// note* the store rejects reversing more than what's not reversed yet, so a transfer can't be reversed twice
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var uri reverseTransferUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+	var req reverseTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+
+	// the currency of the transfer, to parse the amount in
+	transfer, err := server.store.GetTransfer(ctx, uri.ID)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "transfer"))
+		return
+	}
+	fromAccount, err := server.store.GetAccount(ctx, transfer.FromAccountID)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, fmt.Sprintf("account [%d]", transfer.FromAccountID)))
+		return
+	}
+	var amount money.Money
+	if req.Amount != "" {
+		amount, err = parsePositiveAmount("amount", req.Amount, fromAccount.Currency)
+		if err != nil {
+			abortWithErrorResponse(ctx, err)
+			return
+		}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := server.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{
+		TransferID: transfer.ID,
+		Amount:     amount.Amount,
+		Reason:     req.Reason,
+		ReversedBy: authPayload.Username,
+		Audit: audit.InTx(ctx.Request.Context(), func(result db.ReverseTransferTxResult) audit.Event {
+			return server.auditEvent(ctx, audit.Event{
+				Action:       audit.ActionTransferReversed,
+				Actor:        authPayload.Username,
+				ResourceType: audit.ResourceTransfer,
+				ResourceID:   strconv.FormatInt(transfer.ID, 10),
+				Before:       transfer,
+				After:        result,
+			})
+		}),
+	})
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "transfer"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newReverseTransferResponse(result, fromAccount.Currency))
+}
+
+type listTransfersRequest struct {
+	Reference string `form:"reference" binding:"required,max=64"` // max is db.MaxReferenceLength
+	PageID    int32  `form:"page_id" binding:"required,min=1"`
+	PageSize  int32  `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listTransfers finds the user's transfers by their external reference - from or to any of the user's accounts
+func (server *Server) listTransfers(ctx *gin.Context) {
+	var req listTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithErrorResponse(ctx, bindingError(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	rows, err := server.store.ListTransfersByReference(ctx, db.ListTransfersByReferenceParams{
+		Reference: req.Reference,
+		Owner:     authPayload.Username,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "transfer"))
+		return
+	}
+
+	rsp := make([]transferResponse, len(rows))
+	for i, row := range rows {
+		rsp[i] = newTransferResponse(row.Transfer, row.Currency)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,649 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/money"
+	"github.com/web3dev6/simplebank/util"
+	"github.com/web3dev6/simplebank/worker"
+)
+
+func TestCreateTransferApi(t *testing.T) {
+	user, _ := randomUser(t)
+	fromAccount := randomAccount(user.Username)
+	fromAccount.Currency = util.INR
+	fromAccount.Balance = 10000
+	toAccount := randomAccount("receiver")
+	toAccount.ID = fromAccount.ID + 1
+	toAccount.Currency = util.INR
+
+	testcases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			// note* rupees in the request, paise in the store
+			body: gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": "12.34", "currency": util.INR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				arg := db.TransferTxParams{FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 1234}
+				store.EXPECT().TransferTx(gomock.Any(), EqAuditedTxParams(arg, audit.ActionTransferCreated)).Times(1).
+					Return(db.TransferTxResult{
+						Transfer:  db.Transfer{ID: 1, FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 1234},
+						FromEntry: db.Entry{ID: 1, AccountID: fromAccount.ID, Amount: -1234},
+						ToEntry:   db.Entry{ID: 2, AccountID: toAccount.ID, Amount: 1234},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp struct {
+					Transfer struct {
+						Amount json.RawMessage `json:"amount"`
+					} `json:"transfer"`
+					FromEntry entryResponse `json:"from_entry"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.JSONEq(t, `{"amount":"12.34","currency":"INR"}`, string(rsp.Transfer.Amount))
+				require.Equal(t, money.Money{Amount: -1234, Currency: util.INR}, rsp.FromEntry.Amount)
+			},
+		},
+		{
+			name: "WithDetails",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          "1",
+				"currency":        util.INR,
+				"memo":            "rent, march",
+				"reference":       "INV-0042",
+				"metadata":        gin.H{"flat": "4B"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ any, arg db.TransferTxParams) (db.TransferTxResult, error) {
+						require.Equal(t, "rent, march", arg.Memo)
+						require.Equal(t, "INV-0042", arg.Reference)
+						require.JSONEq(t, `{"flat":"4B"}`, string(arg.Metadata))
+						transfer := db.Transfer{ID: 1, FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 100,
+							Memo: arg.Memo, Reference: arg.Reference, Metadata: arg.Metadata}
+						return db.TransferTxResult{Transfer: transfer}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp struct {
+					Transfer transferResponse `json:"transfer"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, "INV-0042", rsp.Transfer.Reference)
+				require.JSONEq(t, `{"flat":"4B"}`, string(rsp.Transfer.Metadata))
+			},
+		},
+		{
+			name: "InvalidDetails",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          "1",
+				"currency":        util.INR,
+				"memo":            strings.Repeat("m", db.MaxMemoLength+1),
+				"metadata":        []string{"not", "an", "object"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"name":"memo"`)
+				require.Contains(t, recorder.Body.String(), `"name":"metadata"`)
+			},
+		},
+		{
+			name: "ToRecipient",
+			body: gin.H{"from_account_id": fromAccount.ID, "recipient": "receiver", "amount": "1", "currency": util.INR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq("receiver")).Times(1).Return(db.User{Username: "receiver"}, nil)
+				arg := db.GetAccountByOwnerAndCurrencyParams{Owner: "receiver", Currency: util.INR}
+				store.EXPECT().GetAccountByOwnerAndCurrency(gomock.Any(), gomock.Eq(arg)).Times(1).Return(toAccount, nil)
+				transferArg := db.TransferTxParams{FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 100}
+				store.EXPECT().TransferTx(gomock.Any(), EqAuditedTxParams(transferArg, audit.ActionTransferCreated)).Times(1).
+					Return(db.TransferTxResult{
+						Transfer:    db.Transfer{ID: 1, FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 100},
+						FromAccount: fromAccount,
+						ToAccount:   toAccount,
+						FromEntry:   db.Entry{ID: 1, AccountID: fromAccount.ID, Amount: -100},
+						ToEntry:     db.Entry{ID: 2, AccountID: toAccount.ID, Amount: 100},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				// the recipient's account is as hidden as in the preview - no owner, no balance
+				var rsp map[string]json.RawMessage
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Contains(t, rsp, "from_account")
+				require.NotContains(t, rsp, "to_account")
+				require.NotContains(t, rsp, "to_entry")
+				require.NotContains(t, recorder.Body.String(), toAccount.Owner)
+			},
+		},
+		{
+			name: "UnknownRecipient",
+			body: gin.H{"from_account_id": fromAccount.ID, "recipient": "nobody@example.com", "amount": "1", "currency": util.INR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq("nobody@example.com")).Times(1).Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "ToAccountAndRecipient",
+			body: gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "recipient": "receiver", "amount": "1", "currency": util.INR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoToAccountNorRecipient",
+			body: gin.H{"from_account_id": fromAccount.ID, "amount": "1", "currency": util.INR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"name":"to_account_id"`)
+			},
+		},
+		{
+			name: "TooPrecise",
+			body: gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": "12.345", "currency": util.INR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"name":"amount"`)
+			},
+		},
+		{
+			name: "NotPositive",
+			body: gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": "-1", "currency": util.INR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NumericAmount",
+			// a json number could be minor units from an old client - rejected rather than guessed
+			body: gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": 1234, "currency": util.INR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnsupportedCurrency",
+			body: gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": "1", "currency": "JPY"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			// the bank's internal accounts can't be paid into by users
+			name: "ToSystemAccount",
+			body: gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID + 1, "amount": "1", "currency": util.INR},
+			buildStubs: func(store *mockdb.MockStore) {
+				systemAccount := randomAccount(db.SystemUsername)
+				systemAccount.ID = toAccount.ID + 1
+				systemAccount.Currency = util.INR
+				systemAccount.Product = db.ProductInterestExpense
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(systemAccount.ID)).Times(1).Return(systemAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: gin.H{"from_account_id": fromAccount.ID, "to_account_id": toAccount.ID, "amount": "100.01", "currency": util.INR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			taskDistributor := worker.NewRedisTaskDistributor(asynq.RedisClientOpt{
+				Addr: "0.0.0.0:6379",
+			})
+			server := newTestServer(t, store, taskDistributor)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateBatchTransferApi(t *testing.T) {
+	user, _ := randomUser(t)
+	payer := randomAccount(user.Username)
+	payer.Currency = util.USD
+	payer.Balance = 10000
+	otherPayer := randomAccount("other")
+	otherPayer.ID = payer.ID + 1
+	otherPayer.Currency = util.USD
+	payee1 := randomAccount("payee1")
+	payee1.ID = payer.ID + 2
+	payee1.Currency = util.USD
+	payee2 := randomAccount("payee2")
+	payee2.ID = payer.ID + 3
+	payee2.Currency = util.USD
+
+	legs := []gin.H{
+		{"from_account_id": payer.ID, "to_account_id": payee1.ID, "amount": "10.50"},
+		{"from_account_id": payer.ID, "to_account_id": payee2.ID, "amount": "20"},
+	}
+
+	testcases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"currency": util.USD, "legs": legs},
+			buildStubs: func(store *mockdb.MockStore) {
+				// note* the payer is read once for both legs
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(payer.ID)).Times(1).Return(payer, nil)
+				arg := db.BatchTransferTxParams{
+					Currency: util.USD,
+					Legs: []db.BatchTransferLeg{
+						{FromAccountID: payer.ID, ToAccountID: payee1.ID, Amount: 1050},
+						{FromAccountID: payer.ID, ToAccountID: payee2.ID, Amount: 2000},
+					},
+				}
+				paidPayer := payer
+				paidPayer.Balance -= 3050
+				store.EXPECT().BatchTransferTx(gomock.Any(), EqAuditedTxParams(arg, audit.ActionTransferBatchCreated)).Times(1).
+					Return(db.BatchTransferTxResult{
+						Transfers: []db.Transfer{
+							{ID: 1, FromAccountID: payer.ID, ToAccountID: payee1.ID, Amount: 1050},
+							{ID: 2, FromAccountID: payer.ID, ToAccountID: payee2.ID, Amount: 2000},
+						},
+						Entries: []db.Entry{
+							{ID: 1, AccountID: payer.ID, Amount: -1050},
+							{ID: 2, AccountID: payee1.ID, Amount: 1050},
+							{ID: 3, AccountID: payer.ID, Amount: -2000},
+							{ID: 4, AccountID: payee2.ID, Amount: 2000},
+						},
+						Accounts: []db.Account{paidPayer, payee1, payee2},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp struct {
+					Transfers []struct {
+						ID     int64       `json:"id"`
+						Amount money.Money `json:"amount"`
+					} `json:"transfers"`
+					Entries  []entryResponse   `json:"entries"`
+					Accounts []accountResponse `json:"accounts"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp.Transfers, 2)
+				require.Equal(t, money.Money{Amount: 1050, Currency: util.USD}, rsp.Transfers[0].Amount)
+				require.Len(t, rsp.Entries, 4)
+				// only the user's own account, not the payees'
+				require.Len(t, rsp.Accounts, 1)
+				require.Equal(t, payer.ID, rsp.Accounts[0].ID)
+				require.Equal(t, money.Money{Amount: payer.Balance - 3050, Currency: util.USD}, rsp.Accounts[0].Balance)
+			},
+		},
+		{
+			name: "UnauthorizedLeg",
+			body: gin.H{"currency": util.USD, "legs": append(legs, gin.H{"from_account_id": otherPayer.ID, "to_account_id": payee1.ID, "amount": "1"})},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(payer.ID)).Times(1).Return(payer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(otherPayer.ID)).Times(1).Return(otherPayer, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidLegAmount",
+			body: gin.H{"currency": util.USD, "legs": append(legs, gin.H{"from_account_id": payer.ID, "to_account_id": payee1.ID, "amount": "0.001"})},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"name":"legs[2].amount"`)
+			},
+		},
+		{
+			name: "NoLegs",
+			body: gin.H{"currency": util.USD, "legs": []gin.H{}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: gin.H{"currency": util.USD, "legs": legs},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(payer.ID)).Times(1).Return(payer, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.BatchTransferTxResult{}, apperr.InsufficientFunds("balance too low"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReverseTransferApi(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	depositor, _ := randomUser(t)
+	depositor.Role = util.DepositorRole
+
+	fromAccount := randomAccount("payer")
+	fromAccount.Currency = util.USD
+	toAccount := randomAccount("payee")
+	toAccount.ID = fromAccount.ID + 1
+	toAccount.Currency = util.USD
+	transfer := db.Transfer{ID: 7, FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 5000}
+
+	testcases := []struct {
+		name          string
+		body          gin.H
+		user          db.User
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "PartialRefund",
+			body: gin.H{"amount": "12.50", "reason": db.ReversalReasonCustomerRequest},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				arg := db.ReverseTransferTxParams{
+					TransferID: transfer.ID,
+					Amount:     1250,
+					Reason:     db.ReversalReasonCustomerRequest,
+					ReversedBy: admin.Username,
+				}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), EqAuditedTxParams(arg, audit.ActionTransferReversed)).Times(1).
+					Return(db.ReverseTransferTxResult{
+						Reversal:        db.TransferReversal{ID: 1, OriginalTransferID: transfer.ID, ReversalTransferID: 8, Amount: 1250, Reason: arg.Reason, ReversedBy: admin.Username},
+						Transfer:        db.Transfer{ID: 8, FromAccountID: toAccount.ID, ToAccountID: fromAccount.ID, Amount: 1250},
+						FromAccount:     toAccount,
+						ToAccount:       fromAccount,
+						RemainingAmount: 3750,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp reverseTransferResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, transfer.ID, rsp.Reversal.OriginalTransferID)
+				require.Equal(t, toAccount.ID, rsp.Transfer.FromAccountID)
+				require.Equal(t, money.Money{Amount: 1250, Currency: util.USD}, rsp.Transfer.Amount)
+				require.Equal(t, money.Money{Amount: 3750, Currency: util.USD}, rsp.RemainingAmount)
+			},
+		},
+		{
+			name: "FullReversal",
+			body: gin.H{"reason": db.ReversalReasonDuplicate},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				// note* no amount, the store reverses all that's not reversed yet
+				arg := db.ReverseTransferTxParams{TransferID: transfer.ID, Reason: db.ReversalReasonDuplicate, ReversedBy: admin.Username}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), EqAuditedTxParams(arg, audit.ActionTransferReversed)).Times(1).Return(db.ReverseTransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyReversed",
+			body: gin.H{"reason": db.ReversalReasonDuplicate},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.ReverseTransferTxResult{}, apperr.Conflict("transfer [7] is already fully reversed"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidReason",
+			body: gin.H{"reason": "changed_my_mind"},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"name":"reason"`)
+			},
+		},
+		{
+			name: "TransferNotFound",
+			body: gin.H{"reason": db.ReversalReasonFraud},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(db.Transfer{}, db.ErrRecordNotFound)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "NotAdmin",
+			body: gin.H{"reason": db.ReversalReasonFraud},
+			user: depositor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(depositor.Username)).Times(1).Return(depositor, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			url := fmt.Sprintf("/admin/transfers/%d/reverse", transfer.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListTransfersApi(t *testing.T) {
+	user, _ := randomUser(t)
+	transfer := db.Transfer{ID: 5, FromAccountID: 1, ToAccountID: 2, Amount: 990, Reference: "INV-0042", Metadata: json.RawMessage(`{}`)}
+
+	testcases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "reference=INV-0042&page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListTransfersByReferenceParams{Reference: "INV-0042", Owner: user.Username, Limit: 5, Offset: 5}
+				store.EXPECT().ListTransfersByReference(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return([]db.ListTransfersByReferenceRow{{Transfer: transfer, Currency: util.EUR}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp []transferResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, transfer.ID, rsp[0].ID)
+				require.Equal(t, money.Money{Amount: 990, Currency: util.EUR}, rsp[0].Amount)
+				require.Equal(t, transfer.Reference, rsp[0].Reference)
+			},
+		},
+		{
+			name:  "NoReference",
+			query: "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListTransfersByReference(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"name":"reference"`)
+			},
+		},
+		{
+			name:  "ReferenceTooLong",
+			query: "reference=" + strings.Repeat("r", db.MaxReferenceLength+1) + "&page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListTransfersByReference(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			taskDistributor := worker.NewRedisTaskDistributor(asynq.RedisClientOpt{
+				Addr: "0.0.0.0:6379",
+			})
+			server := newTestServer(t, store, taskDistributor)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/transfers?"+tc.query, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
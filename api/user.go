@@ -1,8 +1,6 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -11,6 +9,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
 	db "github.com/web3dev6/simplebank/db/sqlc"
 	"github.com/web3dev6/simplebank/token"
 	"github.com/web3dev6/simplebank/util"
@@ -45,12 +46,13 @@ func newUserResponse(user db.User) userResponse {
 func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		abortWithErrorResponse(ctx, http.StatusBadRequest, err)
+		abortWithErrorResponse(ctx, bindingError(err))
 		return
 	}
 	hashedPassword, err := util.HashPassword(req.Password)
 	if err != nil {
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, apperr.Internal(err))
+		return
 	}
 
 	// using SQL DB Tx with CreateUserTx - as user shouldn't be created if taskDistributor fails - rollback
@@ -84,12 +86,8 @@ func (server *Server) createUser(ctx *gin.Context) {
 	// now call CreateUserTx, instead of CreateUser directly
 	txResult, err := server.store.CreateUserTx(ctx, arg)
 	if err != nil {
-		// username and email must be unique (UNIQUE)
-		if db.ErrorCode(err) == db.UniqueViolation {
-			abortWithErrorResponse(ctx, http.StatusForbidden, err)
-			return
-		}
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		// username and email must be unique (UNIQUE) - a conflict
+		abortWithErrorResponse(ctx, db.DomainError(err, "username or email"))
 		return
 	}
 
@@ -102,11 +100,7 @@ func (server *Server) getUserDetails(ctx *gin.Context) {
 
 	user, err := server.store.GetUser(ctx, authPayload.Username)
 	if err != nil {
-		if errors.Is(err, db.ErrRecordNotFound) || errors.Is(err, sql.ErrNoRows) {
-			abortWithErrorResponse(ctx, http.StatusNotFound, err)
-			return
-		}
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, db.DomainError(err, "user"))
 		return
 	}
 
@@ -131,35 +125,36 @@ type loginUserResponse struct {
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		abortWithErrorResponse(ctx, http.StatusBadRequest, err)
+		abortWithErrorResponse(ctx, bindingError(err))
 		return
 	}
 
 	// get user from db
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
-		if errors.Is(err, db.ErrRecordNotFound) || errors.Is(err, sql.ErrNoRows) {
-			abortWithErrorResponse(ctx, http.StatusNotFound, err)
-			return
+		err = db.DomainError(err, "user")
+		if apperr.Is(err, apperr.CodeNotFound) {
+			server.audit(ctx, audit.Event{Action: audit.ActionUserLoginFailed, Actor: req.Username, ResourceType: audit.ResourceUser, ResourceID: req.Username})
 		}
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, err)
 		return
 	}
 
 	// check password and create tokens if all ok, or error out
 	err = util.CheckPassword(req.Password, user.HashedPassword)
 	if err != nil {
-		abortWithErrorResponse(ctx, http.StatusUnauthorized, err)
+		server.audit(ctx, audit.Event{Action: audit.ActionUserLoginFailed, Actor: user.Username, ResourceType: audit.ResourceUser, ResourceID: user.Username})
+		abortWithErrorResponse(ctx, ErrIncorrectPassword.WithCause(err))
 		return
 	}
 	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
 	if err != nil {
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, apperr.Internal(err))
 		return
 	}
 	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.RefreshTokenDuration)
 	if err != nil {
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, apperr.Internal(err))
 		return
 	}
 
@@ -174,9 +169,10 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		ExpiresAt:    refreshPayload.ExpiresAt,
 	})
 	if err != nil {
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, db.DomainError(err, "session"))
 		return
 	}
+	server.audit(ctx, audit.Event{Action: audit.ActionUserLogin, Actor: user.Username, ResourceType: audit.ResourceSession, ResourceID: session.ID.String()})
 
 	// send ok response if all ok WITH loginUserResponse
 	resp := loginUserResponse{
@@ -201,14 +197,21 @@ func (server *Server) updateUser(ctx *gin.Context) {
 	// get updateUserRequest
 	var req updateUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		abortWithErrorResponse(ctx, http.StatusBadRequest, err)
+		abortWithErrorResponse(ctx, bindingError(err))
 		return
 	}
 
 	// check if authorized user from access_token
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if req.Username != authPayload.Username {
-		abortWithErrorResponse(ctx, http.StatusUnauthorized, ErrUpdatingUserInfoFromUnauthorizedUser)
+		abortWithErrorResponse(ctx, ErrUpdatingUserInfoFromUnauthorizedUser)
+		return
+	}
+
+	// user before the update - for the audit log
+	oldUser, err := server.store.GetUser(ctx, req.Username)
+	if err != nil {
+		abortWithErrorResponse(ctx, db.DomainError(err, "user"))
 		return
 	}
 
@@ -219,14 +222,14 @@ func (server *Server) updateUser(ctx *gin.Context) {
 	}
 	if req.FullName != nil {
 		// set hash_password
-		arg.FullName = sql.NullString{
+		arg.FullName = pgtype.Text{
 			String: *req.FullName,
 			Valid:  true,
 		}
 	}
 	if req.Email != nil {
 		// set email
-		arg.Email = sql.NullString{
+		arg.Email = pgtype.Text{
 			String: *req.Email,
 			Valid:  true,
 		}
@@ -235,14 +238,15 @@ func (server *Server) updateUser(ctx *gin.Context) {
 		// hash password
 		hashedPassword, err := util.HashPassword(*req.Password)
 		if err != nil {
-			abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+			abortWithErrorResponse(ctx, apperr.Internal(err))
+			return
 		}
 		// set hash_password
-		arg.HashedPassword = sql.NullString{
+		arg.HashedPassword = pgtype.Text{
 			String: hashedPassword,
 			Valid:  true,
 		}
-		arg.PasswordChangedAt = sql.NullTime{
+		arg.PasswordChangedAt = pgtype.Timestamptz{
 			Time:  time.Now(),
 			Valid: true,
 		}
@@ -251,13 +255,17 @@ func (server *Server) updateUser(ctx *gin.Context) {
 	// update user in db
 	user, err := server.store.UpdateUser(ctx, arg)
 	if err != nil {
-		if db.ErrorCode(err) == db.ErrRecordNotFound.Error() {
-			abortWithErrorResponse(ctx, http.StatusNotFound, err)
-			return
-		}
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, db.DomainError(err, "user"))
 		return
 	}
+	server.audit(ctx, audit.Event{
+		Action:       audit.ActionUserUpdated,
+		Actor:        authPayload.Username,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   user.Username,
+		Before:       audit.UserSnapshot(oldUser),
+		After:        audit.UserSnapshot(user),
+	})
 
 	// return resp
 	resp := newUserResponse(user)
@@ -270,7 +278,7 @@ func (server *Server) verifyUserEmail(ctx *gin.Context) {
 	eId, err := strconv.Atoi(emailId)
 	if err != nil {
 		// log.Err(err).Msg("error in strconv.Atoi")
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		abortWithErrorResponse(ctx, apperr.Validation("invalid parameters", apperr.FieldViolation{Field: "email_id", Description: "must be a number"}).WithCause(err))
 		return
 	}
 	secretCode := ctx.Query("secret_code")
@@ -284,7 +292,8 @@ func (server *Server) verifyUserEmail(ctx *gin.Context) {
 	})
 	if err != nil {
 		// log.Err(err).Msg("error in server.store.VerifyEmailTx")
-		abortWithErrorResponse(ctx, http.StatusInternalServerError, err)
+		// note* no row for a wrong secret_code, an already used or an expired one
+		abortWithErrorResponse(ctx, db.DomainError(err, "verify email"))
 		return
 	}
 
@@ -15,6 +15,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/hibiken/asynq"
 	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/apperr"
 	db "github.com/web3dev6/simplebank/db/sqlc"
 	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
 	"github.com/web3dev6/simplebank/util"
@@ -175,7 +176,18 @@ func TestCreateUserAPI(t *testing.T) {
 					Return(db.CreateUserTxResult{User: db.User{}}, db.ErrUniqueViolation)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Equal(t, apperr.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+				var problem apperr.Problem
+				err := json.Unmarshal(recorder.Body.Bytes(), &problem)
+				require.NoError(t, err)
+				require.Equal(t, apperr.CodeConflict, problem.Code)
+				require.Equal(t, http.StatusConflict, problem.Status)
+				require.Equal(t, "/users", problem.Instance)
+				require.NotEmpty(t, problem.RequestID)
+				// the raw postgres error never reaches the client
+				require.NotContains(t, recorder.Body.String(), "SQLSTATE")
 			},
 		},
 		{
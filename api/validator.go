@@ -1,15 +1,114 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
 	"github.com/go-playground/validator/v10"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/currency"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
 	"github.com/web3dev6/simplebank/util"
 )
 
+// validatorCurrencies holds the *currency.Registry the "currency" tag checks against, set by NewServer
+// note* gin's validator is global & caches every struct with its tag funcs the first time it's validated,
+// so the func can't close over a server's registry - a later server (e.g. in tests) would be checked against the first one's
+var validatorCurrencies atomic.Value
+
+// validCurrency checks a currency is enabled in the registry of the server
 var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
-	if currency, ok := fieldLevel.Field().Interface().(string); ok {
-		// check currency is supported
-		return util.IsSupportedCurrency(currency)
+	currencies, ok := validatorCurrencies.Load().(*currency.Registry)
+	if !ok {
+		return false
+	}
+	if code, ok := fieldLevel.Field().Interface().(string); ok {
+		// note* the registry only has currencies with a known minor unit exponent, so their amounts can be parsed
+		return currencies.IsEnabled(code)
+	}
+	return false
+}
+
+// validUsername checks a string could be a username - of a user created here (alphanum) or over grpc (util.IsValidUsername)
+var validUsername validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if username, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsValidUsername(username) || isAlphanumeric(username)
+	}
+	return false
+}
+
+// isAlphanumeric - the alphanum binding of usernames, ascii letters & digits
+var isAlphanumeric = regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString
 
+// validReversalReason checks a reversal reason is one of the store's reason codes
+var validReversalReason validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if reason, ok := fieldLevel.Field().Interface().(string); ok {
+		return db.IsReversalReason(reason)
 	}
 	return false
 }
+
+// parsePositiveAmount parses a request amount in major units of currency, to a validation error of field if it's invalid or not positive
+func parsePositiveAmount(field string, value string, currency string) (money.Money, error) {
+	amount, err := money.Parse(value, currency)
+	if err == nil && !amount.IsPositive() {
+		err = fmt.Errorf("amount %s must be positive", amount)
+	}
+	if err != nil {
+		return money.Money{}, apperr.Validation("invalid amount", apperr.FieldViolation{Field: field, Description: err.Error()}).WithCause(err)
+	}
+	return amount, nil
+}
+
+// validateTransferDetails returns a validation error of the fields of details over their limits, named with prefix
+func validateTransferDetails(prefix string, details db.TransferDetails) error {
+	violations := details.Validate()
+	if len(violations) == 0 {
+		return nil
+	}
+	for i := range violations {
+		violations[i].Field = prefix + violations[i].Field
+	}
+	return apperr.Validation("invalid transfer details", violations...)
+}
+
+// requestFieldName names a request field by its json, uri or form tag - the name the client sent it with
+func requestFieldName(field reflect.StructField) string {
+	for _, tagKey := range []string{"json", "uri", "form"} {
+		name := strings.SplitN(field.Tag.Get(tagKey), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// bindingError maps an error from ctx.ShouldBind* to a validation error, listing every invalid field
+func bindingError(err error) error {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		// malformed body or params - nothing more specific to report
+		return apperr.Validation("malformed request").WithCause(err)
+	}
+
+	violations := make([]apperr.FieldViolation, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		rule := fieldErr.Tag()
+		if fieldErr.Param() != "" {
+			rule = fmt.Sprintf("%s=%s", rule, fieldErr.Param())
+		}
+		violations = append(violations, apperr.FieldViolation{
+			Field:       fieldErr.Field(),
+			Description: fmt.Sprintf("failed on the '%s' rule", rule),
+		})
+	}
+	return apperr.Validation("invalid parameters", violations...).WithCause(err)
+}
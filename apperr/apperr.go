@@ -0,0 +1,115 @@
+package apperr
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+   Domain errors shared by every handler (Gin & gRPC) - each one has a stable machine-readable Code,
+   mapped once to an http problem+json (http.go) and to a grpc status with details (grpc.go)
+   Note* Message is shown to clients, the underlying cause (Err) is only logged - db & driver messages never leak
+*/
+
+// Code is the stable, machine-readable identifier of an error - clients may switch on it
+type Code string
+
+const (
+	CodeNotFound          Code = "not_found"
+	CodeForbidden         Code = "forbidden"
+	CodeUnauthenticated   Code = "unauthenticated"
+	CodeInsufficientFunds Code = "insufficient_funds"
+	CodeValidation        Code = "validation_failed"
+	CodeConflict          Code = "conflict"
+	CodeRateLimited       Code = "rate_limited"
+	CodeInternal          Code = "internal"
+)
+
+const internalMessage = "internal server error"
+
+// FieldViolation describes one invalid request field
+type FieldViolation struct {
+	Field       string `json:"name"`
+	Description string `json:"reason"`
+}
+
+// Error is a domain error
+type Error struct {
+	Code       Code
+	Message    string           // safe to show to clients
+	Violations []FieldViolation // invalid fields, for CodeValidation
+	Err        error            // underlying cause - logged, never shown to clients
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithCause returns a copy of e with err as its underlying cause
+// Note* copies, so package level errors (var ErrX = apperr.Forbidden(...)) are never mutated
+func (e *Error) WithCause(err error) *Error {
+	errWithCause := *e
+	errWithCause.Err = err
+	return &errWithCause
+}
+
+func NotFound(message string) *Error {
+	return &Error{Code: CodeNotFound, Message: message}
+}
+
+func Forbidden(message string) *Error {
+	return &Error{Code: CodeForbidden, Message: message}
+}
+
+func Unauthenticated(message string) *Error {
+	return &Error{Code: CodeUnauthenticated, Message: message}
+}
+
+func InsufficientFunds(message string) *Error {
+	return &Error{Code: CodeInsufficientFunds, Message: message}
+}
+
+func Validation(message string, violations ...FieldViolation) *Error {
+	return &Error{Code: CodeValidation, Message: message, Violations: violations}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Code: CodeConflict, Message: message}
+}
+
+func RateLimited(message string) *Error {
+	return &Error{Code: CodeRateLimited, Message: message}
+}
+
+// Internal wraps an unexpected err - clients only see a generic message
+func Internal(err error) *Error {
+	return &Error{Code: CodeInternal, Message: internalMessage, Err: err}
+}
+
+// From returns err as a domain error
+// grpc status errors (e.g. raised by the gateway runtime itself) keep their code & message, anything else is internal
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	if st, ok := status.FromError(err); ok && st.Code() != codes.Unknown {
+		return &Error{Code: codeFromGrpc(st.Code()), Message: st.Message(), Err: err}
+	}
+	return Internal(err)
+}
+
+// Is reports whether err is a domain error with the given code
+func Is(err error, code Code) bool {
+	var appErr *Error
+	return errors.As(err, &appErr) && appErr.Code == code
+}
@@ -0,0 +1,90 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMapping(t *testing.T) {
+	testCases := []struct {
+		err        *Error
+		httpStatus int
+		grpcCode   codes.Code
+	}{
+		{NotFound("account not found"), http.StatusNotFound, codes.NotFound},
+		{Forbidden("not the owner"), http.StatusForbidden, codes.PermissionDenied},
+		{Unauthenticated("token has expired"), http.StatusUnauthorized, codes.Unauthenticated},
+		{InsufficientFunds("balance too low"), http.StatusUnprocessableEntity, codes.FailedPrecondition},
+		{Validation("invalid parameters"), http.StatusBadRequest, codes.InvalidArgument},
+		{Conflict("user already exists"), http.StatusConflict, codes.AlreadyExists},
+		{RateLimited("slow down"), http.StatusTooManyRequests, codes.ResourceExhausted},
+		{Internal(errors.New("pq: connection refused")), http.StatusInternalServerError, codes.Internal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.err.Code), func(t *testing.T) {
+			problem := NewProblem(tc.err, "/accounts/1", "req-1")
+			require.Equal(t, tc.httpStatus, problem.Status)
+			require.Equal(t, tc.err.Code, problem.Code)
+			require.Equal(t, "urn:simplebank:problem:"+string(tc.err.Code), problem.Type)
+			require.Equal(t, "req-1", problem.RequestID)
+
+			st, ok := status.FromError(tc.err)
+			require.True(t, ok)
+			require.Equal(t, tc.grpcCode, st.Code())
+
+			// the mapping is reversible, for grpc errors raised outside of the handlers
+			require.Equal(t, tc.err.Code, From(st.Err()).Code)
+		})
+	}
+}
+
+func TestInternalHidesCause(t *testing.T) {
+	err := Internal(errors.New("pq: password authentication failed"))
+	require.Contains(t, err.Error(), "pq:")
+
+	problem := NewProblem(err, "/users", "")
+	require.Equal(t, internalMessage, problem.Detail)
+
+	st, _ := status.FromError(err)
+	require.Equal(t, internalMessage, st.Message())
+
+	// plain errors are internal too
+	require.Equal(t, CodeInternal, From(errors.New("boom")).Code)
+}
+
+func TestWithCause(t *testing.T) {
+	errForbidden := Forbidden("not the owner")
+	cause := errors.New("owner mismatch")
+
+	err := errForbidden.WithCause(cause)
+	require.ErrorIs(t, err, cause)
+	require.Nil(t, errForbidden.Err)
+	require.True(t, Is(err, CodeForbidden))
+	require.False(t, Is(cause, CodeForbidden))
+}
+
+func TestValidationDetails(t *testing.T) {
+	err := Validation("invalid parameters", FieldViolation{Field: "email", Description: "must be an email"})
+
+	recorder := httptest.NewRecorder()
+	WriteProblem(recorder, NewProblem(err, "/users", "req-1"))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, ProblemContentType, recorder.Header().Get("Content-Type"))
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.Equal(t, []interface{}{map[string]interface{}{"name": "email", "reason": "must be an email"}}, body["invalid_params"])
+
+	st, _ := status.FromError(err)
+	require.Len(t, st.Details(), 2)
+	require.Equal(t, "VALIDATION_FAILED", st.Details()[0].(*errdetails.ErrorInfo).Reason)
+	require.Equal(t, "email", st.Details()[1].(*errdetails.BadRequest).FieldViolations[0].Field)
+}
@@ -0,0 +1,84 @@
+package apperr
+
+import (
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorInfoDomain is the domain of the errdetails.ErrorInfo attached to every grpc error
+const ErrorInfoDomain = "simplebank"
+
+// GrpcCode returns the grpc code of code
+func GrpcCode(code Code) codes.Code {
+	switch code {
+	case CodeNotFound:
+		return codes.NotFound
+	case CodeForbidden:
+		return codes.PermissionDenied
+	case CodeUnauthenticated:
+		return codes.Unauthenticated
+	case CodeInsufficientFunds:
+		return codes.FailedPrecondition
+	case CodeValidation:
+		return codes.InvalidArgument
+	case CodeConflict:
+		return codes.AlreadyExists
+	case CodeRateLimited:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+func codeFromGrpc(grpcCode codes.Code) Code {
+	switch grpcCode {
+	case codes.NotFound:
+		return CodeNotFound
+	case codes.PermissionDenied:
+		return CodeForbidden
+	case codes.Unauthenticated:
+		return CodeUnauthenticated
+	case codes.FailedPrecondition:
+		return CodeInsufficientFunds
+	case codes.InvalidArgument, codes.OutOfRange:
+		return CodeValidation
+	case codes.AlreadyExists:
+		return CodeConflict
+	case codes.ResourceExhausted:
+		return CodeRateLimited
+	default:
+		return CodeInternal
+	}
+}
+
+// GRPCStatus maps e to a grpc status - ErrorInfo with the code as reason, plus BadRequest for invalid fields
+// Note* implements the interface status.FromError looks for, so handlers just return *Error
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(GrpcCode(e.Code), e.Message)
+	errorInfo := &errdetails.ErrorInfo{
+		Reason: strings.ToUpper(string(e.Code)),
+		Domain: ErrorInfoDomain,
+	}
+
+	var stWithDetails *status.Status
+	var err error
+	if len(e.Violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, violation := range e.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+		stWithDetails, err = st.WithDetails(errorInfo, badRequest)
+	} else {
+		stWithDetails, err = st.WithDetails(errorInfo)
+	}
+	if err != nil {
+		return st
+	}
+	return stWithDetails
+}
@@ -0,0 +1,69 @@
+package apperr
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 7807 error bodies
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix + Code is the type uri of a problem - a stable identifier, not meant to be dereferenced
+const problemTypePrefix = "urn:simplebank:problem:"
+
+// Problem is an RFC 7807 problem details body, extended with the error code, request id & invalid params
+type Problem struct {
+	Type          string           `json:"type"`
+	Title         string           `json:"title"`
+	Status        int              `json:"status"`
+	Detail        string           `json:"detail,omitempty"`
+	Instance      string           `json:"instance,omitempty"`
+	Code          Code             `json:"code"`
+	RequestID     string           `json:"request_id,omitempty"`
+	InvalidParams []FieldViolation `json:"invalid_params,omitempty"`
+}
+
+// HttpStatus returns the http status of code
+func HttpStatus(code Code) int {
+	switch code {
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeForbidden:
+		return http.StatusForbidden
+	case CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case CodeInsufficientFunds:
+		return http.StatusUnprocessableEntity
+	case CodeValidation:
+		return http.StatusBadRequest
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// NewProblem maps err to a Problem - instance is the request path
+func NewProblem(err error, instance string, requestID string) Problem {
+	appErr := From(err)
+	statusCode := HttpStatus(appErr.Code)
+	return Problem{
+		Type:          problemTypePrefix + string(appErr.Code),
+		Title:         http.StatusText(statusCode),
+		Status:        statusCode,
+		Detail:        appErr.Message,
+		Instance:      instance,
+		Code:          appErr.Code,
+		RequestID:     requestID,
+		InvalidParams: appErr.Violations,
+	}
+}
+
+// WriteProblem writes problem as the response - for plain net/http handlers
+func WriteProblem(res http.ResponseWriter, problem Problem) {
+	res.Header().Set("Content-Type", ProblemContentType)
+	res.WriteHeader(problem.Status)
+	json.NewEncoder(res).Encode(problem)
+}
@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/logging"
+)
+
+/*
+   Audit log of security & money-movement events
+   Note* events are appended to the audit_events hash chain (see db.CreateAuditEventTx), VerifyAuditChain detects edits & deletions
+        money movements are audited inside their own tx with InTx - no committed transfer without its event
+        the rest is recorded best-effort with Record - a failure is logged, it never fails the request being audited
+*/
+
+// actions
+const (
+	ActionUserLogin       = "user.login"
+	ActionUserLoginFailed = "user.login_failed"
+	ActionTokenRenewed    = "session.token_renewed"
+	ActionUserUpdated     = "user.updated"
+	ActionAccountCreated  = "account.created"
+	ActionAccountFunded   = "account.funded"
+	ActionTransferCreated = "transfer.created"
+	ActionCurrencyUpdated = "currency.updated"
+
+	ActionTransferBatchCreated  = "transfer.batch_created" // one event for all the transfers of the batch
+	ActionTransferReversed      = "transfer.reversed"
+	ActionHoldAuthorized        = "hold.authorized"
+	ActionHoldCaptured          = "hold.captured"
+	ActionHoldVoided            = "hold.voided"
+	ActionAccountProductUpdated = "account_product.updated"
+)
+
+// resource types
+const (
+	ResourceUser           = "user"
+	ResourceSession        = "session"
+	ResourceAccount        = "account"
+	ResourceTransfer       = "transfer"
+	ResourceCurrency       = "currency"
+	ResourceHold           = "hold"
+	ResourceAccountProduct = "account_product"
+)
+
+// Event is an audited action of actor on a resource
+type Event struct {
+	Action       string
+	Actor        string // username, or the attempted one for failed logins
+	ResourceType string
+	ResourceID   string
+	ClientIP     string
+	UserAgent    string
+	Before       interface{} // state before the action, nil if none - marshalled to json
+	After        interface{} // state after the action, nil if none - marshalled to json
+}
+
+// Record appends event to the audit log, with the request id from ctx
+func Record(ctx context.Context, store db.Store, event Event) {
+	logger := logging.Logger(ctx)
+
+	arg, err := params(ctx, event)
+	if err != nil {
+		logger.Error().Err(err).Str("action", event.Action).Msg("cannot marshal audit event")
+		return
+	}
+	_, err = store.CreateAuditEventTx(ctx, arg)
+	if err != nil {
+		logger.Error().Err(err).Str("action", event.Action).Str("actor", event.Actor).Msg("cannot record audit event")
+	}
+}
+
+// InTx returns the audit func of a money tx - the event of its result is appended in the tx, with the request id from ctx
+// note* a failure to append it fails the tx, nothing is moved without its audit event
+func InTx[R any](ctx context.Context, event func(result R) Event) db.AuditFunc[R] {
+	return func(result R) (db.CreateAuditEventTxParams, error) {
+		return params(ctx, event(result))
+	}
+}
+
+// params returns the audit event to append for event, with the request id from ctx
+func params(ctx context.Context, event Event) (db.CreateAuditEventTxParams, error) {
+	before, err := marshal(event.Before)
+	if err != nil {
+		return db.CreateAuditEventTxParams{}, err
+	}
+	after, err := marshal(event.After)
+	if err != nil {
+		return db.CreateAuditEventTxParams{}, err
+	}
+	return db.CreateAuditEventTxParams{
+		Action:       event.Action,
+		Actor:        event.Actor,
+		ResourceType: event.ResourceType,
+		ResourceID:   event.ResourceID,
+		ClientIp:     event.ClientIP,
+		UserAgent:    event.UserAgent,
+		RequestID:    logging.RequestID(ctx),
+		Before:       before,
+		After:        after,
+	}, nil
+}
+
+// BatchResourceID is the resource id of the event of a batch - the ids of its transfers, comma separated
+func BatchResourceID(transfers []db.Transfer) string {
+	ids := make([]string, 0, len(transfers))
+	for _, transfer := range transfers {
+		ids = append(ids, strconv.FormatInt(transfer.ID, 10))
+	}
+	return strings.Join(ids, ",")
+}
+
+func marshal(value interface{}) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
+
+// User is the audited state of a user - never the password hash
+type User struct {
+	Username          string    `json:"username"`
+	FullName          string    `json:"full_name"`
+	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
+
+// UserSnapshot returns the audited state of user
+func UserSnapshot(user db.User) User {
+	return User{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
+		PasswordChangedAt: user.PasswordChangedAt,
+	}
+}
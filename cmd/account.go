@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
+)
+
+var accountFundArgs struct {
+	accountID int64
+	amount    string
+}
+
+var accountCmd = &cobra.Command{
+	Use:   "account",
+	Short: "Manage accounts",
+}
+
+var accountFundCmd = &cobra.Command{
+	Use:     "fund",
+	Short:   "Deposit money into an account, recorded as an entry & in the audit log",
+	Args:    cobra.NoArgs,
+	PreRunE: requireValidConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arg := accountFundArgs
+		if arg.accountID < 1 {
+			return fmt.Errorf("invalid account id %d", arg.accountID)
+		}
+		pools, store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer pools.Close()
+
+		ctx := context.Background()
+		account, err := store.GetAccount(ctx, arg.accountID)
+		if err != nil {
+			return db.DomainError(err, fmt.Sprintf("account [%d]", arg.accountID))
+		}
+		amount, err := money.Parse(arg.amount, account.Currency)
+		if err != nil {
+			return err
+		}
+		if !amount.IsPositive() {
+			return fmt.Errorf("invalid amount %s, must be positive", amount)
+		}
+
+		result, err := store.FundAccountTx(ctx, db.FundAccountTxParams{
+			AccountID: arg.accountID,
+			Amount:    amount.Amount,
+			Audit: audit.InTx(ctx, func(result db.FundAccountTxResult) audit.Event {
+				return audit.Event{
+					Action:       audit.ActionAccountFunded,
+					Actor:        cliActor(),
+					ResourceType: audit.ResourceAccount,
+					ResourceID:   strconv.FormatInt(account.ID, 10),
+					Before:       account,
+					After:        result,
+				}
+			}),
+		})
+		if err != nil {
+			return fmt.Errorf("cannot fund account: %w", err)
+		}
+
+		return printJSON(cmd, result)
+	},
+}
+
+func init() {
+	flags := accountFundCmd.Flags()
+	flags.Int64Var(&accountFundArgs.accountID, "account-id", 0, "id of the account to fund")
+	flags.StringVar(&accountFundArgs.amount, "amount", "", "amount to deposit, in major units of the account's currency (e.g. 12.34)")
+	for _, flag := range []string{"account-id", "amount"} {
+		accountFundCmd.MarkFlagRequired(flag)
+	}
+
+	accountCmd.AddCommand(accountFundCmd)
+	rootCmd.AddCommand(accountCmd)
+}
+
+// cliActor names the operator running a cli command in the audit log - the os user, not an app user
+func cliActor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return "cli:" + user
+	}
+	return "cli"
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective config in the app.env format, secrets redacted - then its validation problems, if any",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		for _, file := range config.Files {
+			fmt.Fprintf(out, "# from %s\n", file)
+		}
+		if err := config.Redacted().WriteEnv(out); err != nil {
+			return err
+		}
+		return config.Validate()
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+}
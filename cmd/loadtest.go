@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/web3dev6/simplebank/loadtest"
+)
+
+var loadtestArgs struct {
+	options  loadtest.Options
+	target   string
+	address  string
+	timeout  time.Duration
+	maxConns int32
+}
+
+var loadtestCmd = &cobra.Command{
+	Use:   "loadtest",
+	Short: "Simulate many users transferring between hot & cold accounts, then verify the ledger",
+	Long: `Simulate many users transferring between hot & cold accounts & report throughput, p50/p99 latency,
+deadlocks & serialization failures - then verify that no money was created or lost.
+Every run creates its own users & funded accounts in the main db.
+
+--target store  runs the transfers in-process with the store's TransferTx (default)
+--target http   runs them against a running http server at --address, logging in as each user
+                (the server's rate limit applies, & deadlocks only show up as errors there - its retries are in
+                its simple_bank_db_tx_retries_total metric)
+Note* there's no single transfer rpc yet (only BatchTransfer), so a grpc target isn't available.`,
+	Args:    cobra.NoArgs,
+	PreRunE: requireValidConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arg := loadtestArgs
+		if err := arg.options.Validate(); err != nil {
+			return err
+		}
+		if arg.options.Seed == 0 {
+			arg.options.Seed = time.Now().UnixNano()
+		}
+
+		// note* workers beyond the pool size wait for a conn - keeps the db under its max_connections
+		if arg.maxConns > 0 {
+			config.DbMaxConns = arg.maxConns
+		}
+		// note* the store retries deadlocks & serialization failures itself, they're counted here
+		retries := &loadtest.RetryCounter{}
+		pools, store, err := openPrimaryStore(retries.OnRetry)
+		if err != nil {
+			return err
+		}
+		defer pools.Close()
+
+		var target loadtest.Target
+		switch arg.target {
+		case "store":
+			target = loadtest.StoreTarget{Store: store, Retries: retries}
+		case "http":
+			address := arg.address
+			if address == "" {
+				address = "http://" + config.HttpServerAddress
+			}
+			target = loadtest.NewHttpTarget(address, arg.timeout)
+		default:
+			return fmt.Errorf("unknown target %q, must be store or http", arg.target)
+		}
+
+		ctx, stop := signalContext()
+		defer stop()
+
+		accounts, err := loadtest.Setup(ctx, store, arg.options)
+		if err != nil {
+			return err
+		}
+		log.Info().Str("target", arg.target).Int("concurrency", arg.options.Concurrency).Msg("load test started")
+		report := loadtest.Run(ctx, target, accounts, arg.options)
+
+		// note* verified even when interrupted - the ledger must balance whenever transfers stop
+		conservation, err := loadtest.Verify(cmd.Context(), store, accounts, arg.options.Funding)
+		if err != nil {
+			return err
+		}
+		loadtest.Print(cmd.OutOrStdout(), report, conservation)
+		if !conservation.OK() {
+			return fmt.Errorf("ledger balance not conserved")
+		}
+		return nil
+	},
+}
+
+func init() {
+	flags := loadtestCmd.Flags()
+	options := &loadtestArgs.options
+	flags.IntVar(&options.Users, "users", 1000, "number of users, each with one account")
+	flags.IntVar(&options.HotAccounts, "hot-accounts", 10, "number of hot accounts")
+	flags.Float64Var(&options.HotRatio, "hot-ratio", 0.8, "chance of each side of a transfer being a hot account")
+	flags.IntVar(&options.Concurrency, "concurrency", 50, "concurrent workers")
+	flags.IntVar(&options.Transfers, "transfers", 0, "number of transfers, 0 runs for --duration instead")
+	flags.DurationVar(&options.Duration, "duration", 30*time.Second, "how long to run, without --transfers")
+	flags.Int64Var(&options.MaxAmount, "max-amount", 10, "transfers are of 1 up to this amount, in minor units (e.g. cents)")
+	flags.Int64Var(&options.Funding, "funding", 1_000_000, "initial balance of every account, in minor units")
+	flags.StringVar(&options.Currency, "currency", "USD", "currency of every account")
+	flags.StringVar(&options.Password, "password", "secret", "password of every user")
+	flags.Int64Var(&options.Seed, "seed", 0, "seed of the transfer sequence, random by default")
+	flags.StringVar(&loadtestArgs.target, "target", "store", "where transfers run: store or http")
+	flags.StringVar(&loadtestArgs.address, "address", "", "base url of the http server, http://HTTP_SERVER_ADDRESS by default")
+	flags.DurationVar(&loadtestArgs.timeout, "timeout", 10*time.Second, "timeout of each http request")
+	flags.Int32Var(&loadtestArgs.maxConns, "max-conns", 0, "max open db conns, DB_MAX_CONNS by default")
+	rootCmd.AddCommand(loadtestCmd)
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var migrateTestDb bool
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate the main db (DB_SOURCE_MAIN) with the migrations at MIGRATION_URL - or the test db with --test",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:     "up",
+	Short:   "Apply all pending migrations",
+	Args:    cobra.NoArgs,
+	PreRunE: requireValidConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return migrateUp(migrationDbSource())
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:     "down [N]",
+	Short:   "Roll back the last N migrations, 1 by default",
+	Args:    cobra.MaximumNArgs(1),
+	PreRunE: requireValidConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps := 1
+		if len(args) == 1 {
+			var err error
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q, must be a positive number", args[0])
+			}
+		}
+		return runMigration(migrationDbSource(), func(migration *migrate.Migrate) error {
+			return migration.Steps(-steps)
+		})
+	},
+}
+
+var migrateToCmd = &cobra.Command{
+	Use:     "to N",
+	Short:   "Migrate up or down to version N",
+	Args:    cobra.ExactArgs(1),
+	PreRunE: requireValidConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %w", args[0], err)
+		}
+		return runMigration(migrationDbSource(), func(migration *migrate.Migrate) error {
+			return migration.Migrate(uint(version))
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:     "status",
+	Short:   "Print the db's migration version & the pending migrations",
+	Args:    cobra.NoArgs,
+	PreRunE: requireValidConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		versions, err := migrationVersions(config.MigrationUrl)
+		if err != nil {
+			return err
+		}
+
+		migration, err := migrate.New(config.MigrationUrl, migrationDbSource())
+		if err != nil {
+			return fmt.Errorf("cannot create new migrate instance: %w", err)
+		}
+		defer migration.Close()
+
+		version, dirty, err := migration.Version()
+		if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+			return err
+		}
+		applied := err == nil // no migration applied yet otherwise
+
+		out := cmd.OutOrStdout()
+		if applied {
+			fmt.Fprintf(out, "version: %d\n", version)
+		} else {
+			fmt.Fprintln(out, "version: none")
+		}
+		fmt.Fprintf(out, "dirty: %t\n", dirty)
+		if len(versions) > 0 {
+			fmt.Fprintf(out, "latest: %d\n", versions[len(versions)-1])
+		}
+		var pending []uint
+		for _, v := range versions {
+			if !applied || v > version {
+				pending = append(pending, v)
+			}
+		}
+		fmt.Fprintf(out, "pending: %v\n", pending)
+		return nil
+	},
+}
+
+func init() {
+	migrateCmd.PersistentFlags().BoolVar(&migrateTestDb, "test", false, "migrate the test db (DB_SOURCE_TEST) instead of the main one")
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateToCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// migrationDbSource returns the db source migrate commands run against
+// note* the test db is only ever touched when asked for explicitly
+func migrationDbSource() string {
+	if migrateTestDb {
+		return config.DbSourceTest
+	}
+	return config.DbSourceMain
+}
+
+// migrateUp applies all pending migrations to the db at dbSource
+func migrateUp(dbSource string) error {
+	return runMigration(dbSource, func(migration *migrate.Migrate) error {
+		return migration.Up()
+	})
+}
+
+// runMigration runs fn on a migrate instance for dbSource, no change is not an error
+func runMigration(dbSource string, fn func(migration *migrate.Migrate) error) error {
+	if dbSource == "" {
+		return errors.New("no db source to migrate, set DB_SOURCE_MAIN or DB_SOURCE_TEST for --test")
+	}
+	migration, err := migrate.New(config.MigrationUrl, dbSource)
+	if err != nil {
+		return fmt.Errorf("cannot create new migrate instance: %w", err)
+	}
+	defer migration.Close()
+
+	if err = fn(migration); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	version, dirty, err := migration.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+	log.Info().Uint("version", version).Bool("dirty", dirty).Bool("test_db", migrateTestDb).Msg("db migrate success")
+	return nil
+}
+
+// migrationVersions returns the versions of all migrations at migrationURL, in order
+func migrationVersions(migrationURL string) ([]uint, error) {
+	src, err := source.Open(migrationURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open migration source: %w", err)
+	}
+	defer src.Close()
+
+	var versions []uint
+	version, err := src.First()
+	for err == nil {
+		versions = append(versions, version)
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return versions, nil
+}
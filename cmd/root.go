@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/metrics"
+	"github.com/web3dev6/simplebank/util"
+)
+
+/*
+   simplebank cli
+	serve [http|grpc|gateway|all]    run the api servers (+ the task processor, unless --worker=false)
+	worker                           run the task processor alone
+	migrate up|down|status|to N      migrate the main db, or the test db with --test
+	user create                      create a user, e.g. an admin
+	account fund                     deposit money into an account
+	seed                             fill the db with synthetic users, accounts & transfers
+	loadtest                         simulate concurrent transfers & verify the ledger
+	config print                     print the effective config, secrets redacted
+   Note* every command reads the same layered config (see util.LoadConfig), from --config-dir
+*/
+
+// interruptSignals trigger a graceful shutdown of every component
+var interruptSignals = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+	syscall.SIGINT,
+}
+
+var (
+	configDir string
+	// config is loaded before any command runs
+	config util.Config
+)
+
+var rootCmd = &cobra.Command{
+	Use:           "simplebank",
+	Short:         "Simple Bank - accounts, transfers & the services around them",
+	SilenceUsage:  true, // usage is printed for wrong args/flags only, not for every failed run
+	SilenceErrors: true, // Execute logs the error
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
+		config, err = util.LoadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		setupLogger(config)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configDir, "config-dir", ".", "directory of the app.env/app.yaml... config files")
+}
+
+// Execute runs the command given in os.Args
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatal().Err(err).Msg("simplebank failed")
+	}
+}
+
+// requireValidConfig fails fast on a missing or invalid value, instead of a nil token maker or listener at runtime
+func requireValidConfig(cmd *cobra.Command, args []string) error {
+	return config.Validate()
+}
+
+// setupLogger configures zerolog for the environment
+func setupLogger(config util.Config) {
+	if config.Environment == "development" {
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+		// *** To customize the configuration and formatting:
+		// output := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+		// output.FormatLevel = func(i interface{}) string {
+		// 	return strings.ToUpper(fmt.Sprintf("| %-6s|", i))
+		// }
+		// output.FormatMessage = func(i interface{}) string {
+		// 	return fmt.Sprintf("MSG %s", i)
+		// }
+		// output.FormatFieldName = func(i interface{}) string {
+		// 	return fmt.Sprintf("| FIELD %s:", i)
+		// }
+		// output.FormatFieldValue = func(i interface{}) string {
+		// 	return strings.ToLower(fmt.Sprintf("%s", i))
+		// }
+		// log.Logger = zerolog.New(output).With().Timestamp().Logger()
+	} else if config.Environment == "production" {
+		zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	}
+}
+
+// signalContext is cancelled on the first interrupt signal - every component starts its graceful shutdown from there
+// note* a second signal after stop() is restored to the default behaviour and kills the process right away
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), interruptSignals...)
+}
+
+// dbPools are the conn pools of a store
+type dbPools struct {
+	main    *pgxpool.Pool
+	replica *pgxpool.Pool // nil without DB_SOURCE_REPLICA
+}
+
+// Close closes every pool, waiting for their acquired conns to be released
+func (pools *dbPools) Close() {
+	pools.main.Close()
+	if pools.replica != nil {
+		pools.replica.Close()
+	}
+}
+
+// openStore opens conn pools to the main db & its replica, if any - callers close the returned pools
+func openStore() (*dbPools, db.Store, error) {
+	return openDBStore(true, nil)
+}
+
+// openPrimaryStore opens a store reading everything from the main db, even with a DB_SOURCE_REPLICA
+// note* for commands checking what they just wrote (seed, loadtest) - a lagging replica would show them stale rows
+// onRetry is called on every retried tx, see db.TxConfig
+func openPrimaryStore(onRetry func(code string)) (*dbPools, db.Store, error) {
+	return openDBStore(false, onRetry)
+}
+
+func openDBStore(withReplica bool, onRetry func(code string)) (*dbPools, db.Store, error) {
+	transferIsolation, err := db.ParseIsolationLevel(config.DbTransferIsolation)
+	if err != nil {
+		return nil, nil, err
+	}
+	poolConfig := db.PoolConfig{
+		MaxConns:        config.DbMaxConns,
+		MinConns:        config.DbMinConns,
+		MaxConnLifetime: config.DbMaxConnLifetime,
+		MaxConnIdleTime: config.DbMaxConnIdleTime,
+	}
+	txConfig := db.TxConfig{
+		Timeout:           config.DbTxTimeout,
+		MaxRetries:        config.DbTxMaxRetries,
+		RetryDelay:        config.DbTxRetryDelay,
+		TransferIsolation: transferIsolation,
+		OnRetry:           onRetry,
+	}
+
+	pools := &dbPools{}
+	pools.main, err = db.NewPool(context.Background(), config.DbSourceMain, poolConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !withReplica || config.DbSourceReplica == "" {
+		return pools, db.NewStore(pools.main, txConfig), nil
+	}
+
+	pools.replica, err = db.NewPool(context.Background(), config.DbSourceReplica, poolConfig)
+	if err != nil {
+		pools.main.Close()
+		return nil, nil, fmt.Errorf("replica: %w", err)
+	}
+	store := db.NewReplicatedStore(pools.main, pools.replica, db.ReplicaConfig{
+		MaxLag:        config.DbReplicaMaxLag,
+		CheckInterval: config.DbReplicaInterval,
+	}, txConfig)
+	return pools, store, nil
+}
+
+// registerDBStats exports the stats of every pool
+// note* the replica isn't a readiness check, reads fall back to the main db without it
+func registerDBStats(pools *dbPools) error {
+	err := metrics.RegisterDBStats(pools.main, "simple_bank")
+	if err != nil || pools.replica == nil {
+		return err
+	}
+	return metrics.RegisterDBStats(pools.replica, "simple_bank_replica")
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/web3dev6/simplebank/seed"
+	"github.com/web3dev6/simplebank/util"
+)
+
+var seedArgs struct {
+	seed      int64
+	users     int
+	transfers int
+	password  string
+}
+
+var seedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Fill the main db with synthetic users, accounts in every currency & transfers between them - the same data for the same --seed",
+	Long: `Fill the main db with synthetic users, accounts in every currency & transfers between them, for load tests & demos.
+The data is generated from --seed - seeding an empty db twice with the same seed gives the same users, balances & transfers.
+Users already there are reused, so running it again adds funding & transfers to them.`,
+	Args:    cobra.NoArgs,
+	PreRunE: requireValidConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arg := seedArgs
+		if arg.users < 1 || arg.transfers < 0 {
+			return fmt.Errorf("invalid --users %d or --transfers %d", arg.users, arg.transfers)
+		}
+
+		pools, store, err := openPrimaryStore(nil)
+		if err != nil {
+			return err
+		}
+		defer pools.Close()
+
+		// note* hashed once - bcrypt per user would dominate the seeding time
+		hashedPassword, err := util.HashPassword(arg.password)
+		if err != nil {
+			return err
+		}
+
+		plan := seed.NewPlan(arg.seed, arg.users, arg.transfers)
+		log.Info().Int64("seed", arg.seed).Int("users", len(plan.Users)).Int("transfers", len(plan.Transfers)).Msg("seeding db")
+
+		ctx, stop := signalContext()
+		defer stop()
+		result, err := seed.Apply(ctx, store, plan, hashedPassword)
+		log.Info().
+			Int("users_created", result.UsersCreated).
+			Int("users_existing", result.UsersExisting).
+			Int("accounts_created", result.AccountsCreated).
+			Int("transfers", result.Transfers).
+			Msg("seeded db")
+		return err
+	},
+}
+
+func init() {
+	flags := seedCmd.Flags()
+	flags.Int64Var(&seedArgs.seed, "seed", 1, "seed of the generated data")
+	flags.IntVar(&seedArgs.users, "users", 10, "number of users")
+	flags.IntVar(&seedArgs.transfers, "transfers", 100, "number of transfers")
+	flags.StringVar(&seedArgs.password, "password", "secret", "password of every seeded user")
+	rootCmd.AddCommand(seedCmd)
+}
@@ -0,0 +1,405 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/hibiken/asynq"
+	"github.com/rakyll/statik/fs"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/web3dev6/simplebank/api"
+	"github.com/web3dev6/simplebank/currency"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	_ "github.com/web3dev6/simplebank/doc/statik"
+	"github.com/web3dev6/simplebank/gapi"
+	"github.com/web3dev6/simplebank/health"
+	"github.com/web3dev6/simplebank/metrics"
+	"github.com/web3dev6/simplebank/middleware"
+	"github.com/web3dev6/simplebank/pb"
+	"github.com/web3dev6/simplebank/tracing"
+	"github.com/web3dev6/simplebank/util"
+	"github.com/web3dev6/simplebank/worker"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// serverTypes maps the serve args to SERVER_TYPE values
+var serverTypes = map[string]string{
+	"http":    "HTTP",
+	"grpc":    "GRPC",
+	"gateway": "GRPC_GATEWAY",
+	"all":     "ALL",
+}
+
+var (
+	serveWithWorker  bool
+	serveWithMigrate bool
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [http|grpc|gateway|all]",
+	Short: "Run the api servers - of SERVER_TYPE if no server is given",
+	Long: `Run the api servers:
+  http     Gin http server on HTTP_SERVER_ADDRESS
+  grpc     gRPC server on GRPC_SERVER_ADDRESS
+  gateway  gRPC http-gateway on HTTP_SERVER_ADDRESS & gRPC server on GRPC_SERVER_ADDRESS
+  all      Gin on HTTP_SERVER_ADDRESS, gateway on GATEWAY_SERVER_ADDRESS & gRPC on GRPC_SERVER_ADDRESS
+The task processor runs in the same process unless --worker=false, then run it with "simplebank worker".`,
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"http", "grpc", "gateway", "all"},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			config.ServerType = serverTypes[args[0]]
+		}
+		return requireValidConfig(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serve()
+	},
+}
+
+func init() {
+	serveCmd.Flags().BoolVar(&serveWithWorker, "worker", true, "run the task processor in the same process")
+	serveCmd.Flags().BoolVar(&serveWithMigrate, "migrate", false, "migrate the main db up before serving - for development, run \"simplebank migrate up\" as a release step otherwise")
+	rootCmd.AddCommand(serveCmd)
+}
+
+func serve() error {
+	ctx, stop := signalContext()
+	defer stop()
+
+	// tracing - spans exported to stdout or an otlp collector, no-op if TRACING_EXPORTER is empty
+	shutdownTracing, err := tracing.Init(ctx, config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot init tracing")
+	}
+
+	// open conn pool to db
+	pools, store, err := openStore()
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot connect to db")
+	}
+
+	// note* only the main db - the test db is migrated by "simplebank migrate up --test", never by a server
+	if serveWithMigrate {
+		if err = migrateUp(config.DbSourceMain); err != nil {
+			log.Fatal().Err(err).Msg("failed to run the migrate up")
+		}
+	}
+
+	// export the conn pool stats - open/idle/in-use conns, wait count & duration
+	err = registerDBStats(pools)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot register db stats metrics")
+	}
+
+	// currencies requests can be made in, cached for the validators & refreshed every CURRENCY_CACHE_TTL
+	currencies := currency.NewRegistry(store, config.CurrencyCacheTTL)
+	if err = currencies.Load(ctx); err != nil {
+		log.Fatal().Err(err).Msg("cannot load currencies")
+	}
+
+	// Redis config
+	redisOpt := asynq.RedisClientOpt{
+		Addr: config.RedisAddress,
+	}
+	// Redis task distributor
+	taskDistributor := worker.NewRedisTaskDistributor(redisOpt)
+	// Redis client for health checks, built from the same options asynq uses
+	redisClient := redisOpt.MakeRedisClient().(redis.UniversalClient)
+
+	// readiness checks - each dependency is reported individually on /readyz & grpc.health.v1.Health
+	healthChecker := health.NewChecker(0)
+	healthChecker.Register("postgres", health.PingCheck(pools.main))
+	healthChecker.Register("redis", health.RedisCheck(redisClient))
+	migrationCheck, err := health.MigrationCheck(pools.main, config.MigrationUrl)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create migration health check")
+	}
+	healthChecker.Register("migrations", migrationCheck)
+
+	// waitGroup runs every component - the first one to fail cancels ctx, which stops all the others
+	waitGroup, ctx := errgroup.WithContext(ctx)
+
+	// readiness fails as soon as shutdown starts, the servers stop only SHUTDOWN_DRAIN_DELAY later - so the load balancers
+	// stop routing new requests to them before they close their listeners
+	// note* serversCtx isn't derived from ctx, it's cancelled only once the delay is over
+	serversCtx, drained := context.WithCancel(context.Background())
+	defer drained()
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		healthChecker.Shutdown()
+		if config.ShutdownDrainDelay > 0 {
+			log.Info().Dur("delay", config.ShutdownDrainDelay).Msg("draining traffic before the servers stop")
+			time.Sleep(config.ShutdownDrainDelay)
+		}
+		drained()
+		return nil
+	})
+
+	// Redis task processor - non-blocking start, stopped on ctx.Done()
+	if serveWithWorker {
+		runTaskProcessor(ctx, waitGroup, config, redisOpt, store)
+	}
+
+	// one pipeline (rate-limit, logging, metrics & tracing) shared by every entry point
+	pipeline := middleware.NewPipeline(config)
+
+	// dedicated listener for metrics & health probes - reachable whatever the SERVER_TYPE
+	if config.MetricsServerAddress != "" {
+		runMetricsServer(serversCtx, waitGroup, config, healthChecker)
+	}
+
+	switch config.ServerType {
+	case "HTTP":
+		// run http server on 8080
+		runGinServer(serversCtx, waitGroup, config, store, currencies, taskDistributor, pipeline, healthChecker)
+	case "GRPC":
+		// run grpc server on 9090
+		runGrpcServer(serversCtx, waitGroup, config, store, currencies, taskDistributor, pipeline, healthChecker)
+	case "GRPC_GATEWAY":
+		// run grpc's http gateway server on 8080 & grpc server on 9090
+		runGatewayServer(serversCtx, waitGroup, config, config.HttpServerAddress, store, currencies, taskDistributor, pipeline, healthChecker)
+		runGrpcServer(serversCtx, waitGroup, config, store, currencies, taskDistributor, pipeline, healthChecker)
+	case "ALL":
+		// run Gin http server on 8080, grpc's http gateway server on 8081 & grpc server on 9090 - all in one process
+		runGinServer(serversCtx, waitGroup, config, store, currencies, taskDistributor, pipeline, healthChecker)
+		runGatewayServer(serversCtx, waitGroup, config, config.GatewayServerAddress, store, currencies, taskDistributor, pipeline, healthChecker)
+		runGrpcServer(serversCtx, waitGroup, config, store, currencies, taskDistributor, pipeline, healthChecker)
+	}
+
+	// block until every component has stopped
+	err = waitGroup.Wait()
+
+	// release shared resources only after all servers & the task processor have drained
+	if closeErr := taskDistributor.Close(); closeErr != nil {
+		log.Error().Err(closeErr).Msg("cannot close task distributor")
+	}
+	if closeErr := redisClient.Close(); closeErr != nil {
+		log.Error().Err(closeErr).Msg("cannot close redis client")
+	}
+	// note* waits for the acquired conns to be released
+	pools.Close()
+	flushTracing(shutdownTracing)
+
+	if err != nil {
+		return err
+	}
+	log.Info().Msg("graceful shutdown complete")
+	return nil
+}
+
+// flushTracing flushes the spans still buffered - ctx is already done when it's called, so bound it on its own
+func flushTracing(shutdownTracing func(context.Context) error) {
+	tracingCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Error().Err(err).Msg("cannot shutdown tracing")
+	}
+}
+
+func runGinServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store, currencies *currency.Registry, taskDistributor worker.TaskDistributor, pipeline *middleware.Pipeline, healthChecker *health.Checker) {
+	server, err := api.NewServer(config, store, currencies, taskDistributor, pipeline)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create server")
+	}
+
+	// create listener to listen to http requests on a specified http port
+	listener, err := net.Listen("tcp", config.HttpServerAddress)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create listener")
+	}
+
+	// health probes & metrics (see publicHandler) are served ahead of the router
+	httpServer := &http.Server{
+		Handler: publicHandler(config, healthChecker, server.Handler()),
+	}
+	runHttpServer(ctx, waitGroup, config, "Gin http-server", httpServer, listener)
+}
+
+func runGrpcServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store, currencies *currency.Registry, taskDistributor worker.TaskDistributor, pipeline *middleware.Pipeline, healthChecker *health.Checker) {
+	// create a simple_bank server struct which embeds pb.UnimplementedSimpleBankServer
+	server, err := gapi.NewServer(config, store, currencies, taskDistributor)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create server")
+	}
+
+	// grpc interceptor - shared pipeline (rate-limit & logger)
+	grpcPipeline := grpc.UnaryInterceptor(pipeline.UnaryServerInterceptor())
+
+	// grpcServer is a new grpc server instacnce, takes ServerOptions(interceptors like logger  )
+	grpcServer := grpc.NewServer(grpcPipeline)
+	// register simple_bank server(has unimplemented service) with this grpcServer
+	pb.RegisterSimpleBankServer(grpcServer, server)
+
+	// register the standard grpc.health.v1.Health service, its status mirrors the readiness checks
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	waitGroup.Go(func() error {
+		healthChecker.ServeGrpc(ctx, healthServer, health.GrpcCheckInterval, pb.SimpleBank_ServiceDesc.ServiceName)
+		return nil
+	})
+
+	// [optional]
+	// Register a grpc reflection for server
+	// Register registers the server reflection service on the given gRPC server
+	// Allows a grpc client to easily explore - what RPCs are available and how to cal them
+	reflection.Register(grpcServer)
+
+	// create listener to listen to gRPC requests on a specified grpc port
+	listener, err := net.Listen("tcp", config.GrpcServerAddress)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create listener")
+	}
+
+	waitGroup.Go(func() error {
+		// start server with listener
+		log.Info().Msgf("starting gRPC server at %s...", listener.Addr().String())
+		err := grpcServer.Serve(listener)
+		if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			log.Error().Err(err).Msg("gRPC server failed to serve")
+			return err
+		}
+		return nil
+	})
+
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		log.Info().Msg("graceful shutdown gRPC server")
+
+		// GracefulStop waits for pending RPCs to finish, Stop cancels them once the shutdown deadline is hit
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(config.ShutdownTimeout):
+			log.Warn().Msg("gRPC server shutdown deadline exceeded, forcing stop")
+			grpcServer.Stop()
+		}
+
+		log.Info().Msg("gRPC server is stopped")
+		return nil
+	})
+}
+
+func runGatewayServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, address string, store db.Store, currencies *currency.Registry, taskDistributor worker.TaskDistributor, pipeline *middleware.Pipeline, healthChecker *health.Checker) {
+	// create a simple_bank server struct which embeds pb.UnimplementedSimpleBankServer
+	server, err := gapi.NewServer(config, store, currencies, taskDistributor)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot register handler  server")
+	}
+
+	// jsonOptions for snake-case in names of json-fileds in response from gateway
+	jsonOption := runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+		MarshalOptions: protojson.MarshalOptions{
+			UseProtoNames: true,
+		},
+		UnmarshalOptions: protojson.UnmarshalOptions{
+			DiscardUnknown: true,
+		},
+	})
+	// create a grpcMux using grpc-gateway's runtime package with jsonOption, error bodies carry the request id
+	grpcMux := runtime.NewServeMux(jsonOption, runtime.WithErrorHandler(middleware.GatewayErrorHandler))
+
+	// register simple_bank server with above created grpcMux, along with the lifecycle context
+	// performs in-process translation between HTTP and gRPC - means HTTP request will call the gRPC handler func directly, skipping grpc interceptors
+	// note* that's why the shared pipeline wraps the http handler below instead
+	err = pb.RegisterSimpleBankHandlerServer(ctx, grpcMux, server)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create listener")
+	}
+
+	// create a http serveMux which takes http requests from client
+	mux := http.NewServeMux()
+	// to convert the http requests from client to grpcRequest, reroute them to grpcMux
+	mux.Handle("/", grpcMux)
+
+	// create a http-fs & serve auto-generated swagger docs for grpc-gateway server
+	// fs := http.FileServer(http.Dir("./doc/swagger"))
+	// mux.Handle("/swagger/", http.StripPrefix("/swagger/", fs)) // StripPrefix strips the route prefix of the url before passing the request to the static file server
+
+	// create a statik-fs - static data already embedded in binary in `make proto``, no need to read from disk(Dockerfile)
+	statikFs, err := fs.New() // alternatively, we can use NewWithNamespace func for custom ns
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create statik fs")
+	}
+	mux.Handle("/swagger/", http.StripPrefix("/swagger/", http.FileServer(statikFs)))
+
+	// create listener to listen to client http requests on a specified http-gateway port
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create listener")
+	}
+
+	// get http handler wrapped with the shared pipeline (rate-limit, logger & metrics) within the mux context, health probes & metrics (see publicHandler) served ahead of it
+	httpServer := &http.Server{
+		Handler: publicHandler(config, healthChecker, pipeline.Http(mux)),
+	}
+	runHttpServer(ctx, waitGroup, config, "gRPC http-gateway server", httpServer, listener)
+}
+
+// publicHandler serves the health probes ahead of handler - & /metrics, unless it has its own listener at METRICS_SERVER_ADDRESS
+// note* metrics aren't meant for clients, with a metrics server they're only reachable there
+func publicHandler(config util.Config, healthChecker *health.Checker, handler http.Handler) http.Handler {
+	if config.MetricsServerAddress == "" {
+		handler = metrics.Handler(handler)
+	}
+	return healthChecker.Handler(handler)
+}
+
+func runMetricsServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, healthChecker *health.Checker) {
+	listener, err := net.Listen("tcp", config.MetricsServerAddress)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create listener")
+	}
+
+	// serves only /metrics & the health probes
+	httpServer := &http.Server{
+		Handler: healthChecker.Handler(metrics.Handler(http.NotFoundHandler())),
+	}
+	runHttpServer(ctx, waitGroup, config, "metrics server", httpServer, listener)
+}
+
+// runHttpServer serves httpServer on listener until ctx is done, then drains in-flight requests within config.ShutdownTimeout
+func runHttpServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, name string, httpServer *http.Server, listener net.Listener) {
+	waitGroup.Go(func() error {
+		// start server with listener
+		log.Info().Msgf("starting %s at %s...", name, listener.Addr().String())
+		err := httpServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msgf("%s failed to serve", name)
+			return err
+		}
+		return nil
+	})
+
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		log.Info().Msgf("graceful shutdown %s", name)
+
+		// Shutdown stops accepting new conns and waits for in-flight requests - until the deadline
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+		err := httpServer.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to shutdown %s", name)
+			return err
+		}
+
+		log.Info().Msgf("%s is stopped", name)
+		return nil
+	})
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/spf13/cobra"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/gapi"
+	"github.com/web3dev6/simplebank/util"
+)
+
+var userCreateArgs struct {
+	username string
+	password string
+	fullName string
+	email    string
+	role     string
+}
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users",
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:     "create",
+	Short:   "Create a user - the only way to create an admin",
+	Args:    cobra.NoArgs,
+	PreRunE: requireValidConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		arg := userCreateArgs
+
+		// note* "-" reads the password from stdin, so it doesn't show in the shell history & process list
+		if arg.password == "-" {
+			password, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			if err != nil && password == "" {
+				return fmt.Errorf("cannot read password from stdin: %w", err)
+			}
+			arg.password = strings.TrimRight(password, "\r\n")
+		}
+
+		// same rules as the api
+		if err := gapi.ValidateUsername(arg.username); err != nil {
+			return fmt.Errorf("invalid username: %w", err)
+		}
+		if err := gapi.ValidatePassword(arg.password); err != nil {
+			return fmt.Errorf("invalid password: %w", err)
+		}
+		if err := gapi.ValidateFullname(arg.fullName); err != nil {
+			return fmt.Errorf("invalid full name: %w", err)
+		}
+		if err := gapi.ValidateEmail(arg.email); err != nil {
+			return fmt.Errorf("invalid email: %w", err)
+		}
+		if arg.role != util.DepositorRole && arg.role != util.AdminRole {
+			return fmt.Errorf("invalid role %q, must be one of %s/%s", arg.role, util.DepositorRole, util.AdminRole)
+		}
+
+		hashedPassword, err := util.HashPassword(arg.password)
+		if err != nil {
+			return err
+		}
+
+		pools, store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer pools.Close()
+
+		user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+			Username:       arg.username,
+			HashedPassword: hashedPassword,
+			FullName:       arg.fullName,
+			Email:          arg.email,
+			Role:           pgtype.Text{String: arg.role, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("cannot create user: %w", db.DomainError(err, "username or email"))
+		}
+
+		return printJSON(cmd, audit.UserSnapshot(user))
+	},
+}
+
+func init() {
+	flags := userCreateCmd.Flags()
+	flags.StringVar(&userCreateArgs.username, "username", "", "username, alpha-numeric")
+	flags.StringVar(&userCreateArgs.password, "password", "", `password, "-" to read it from stdin`)
+	flags.StringVar(&userCreateArgs.fullName, "full-name", "", "full name")
+	flags.StringVar(&userCreateArgs.email, "email", "", "email")
+	flags.StringVar(&userCreateArgs.role, "role", util.DepositorRole, "role, depositor or admin")
+	for _, flag := range []string{"username", "password", "full-name", "email"} {
+		userCreateCmd.MarkFlagRequired(flag)
+	}
+
+	userCmd.AddCommand(userCreateCmd)
+	rootCmd.AddCommand(userCmd)
+}
+
+// printJSON writes value to the command's stdout as indented json
+func printJSON(cmd *cobra.Command, value interface{}) error {
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
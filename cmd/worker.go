@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/health"
+	"github.com/web3dev6/simplebank/mail"
+	"github.com/web3dev6/simplebank/tracing"
+	"github.com/web3dev6/simplebank/util"
+	"github.com/web3dev6/simplebank/worker"
+	"golang.org/x/sync/errgroup"
+)
+
+var workerCmd = &cobra.Command{
+	Use:     "worker",
+	Short:   "Run the task processor alone - for api servers started with \"serve --worker=false\"",
+	Args:    cobra.NoArgs,
+	PreRunE: requireValidConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWorker()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(workerCmd)
+}
+
+func runWorker() error {
+	ctx, stop := signalContext()
+	defer stop()
+
+	// tracing - task spans continue the traces of the requests which enqueued them
+	shutdownTracing, err := tracing.Init(ctx, config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot init tracing")
+	}
+
+	pools, store, err := openStore()
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot connect to db")
+	}
+	err = registerDBStats(pools)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot register db stats metrics")
+	}
+
+	redisOpt := asynq.RedisClientOpt{
+		Addr: config.RedisAddress,
+	}
+	redisClient := redisOpt.MakeRedisClient().(redis.UniversalClient)
+
+	// the worker has no api, its probes & metrics are served on METRICS_SERVER_ADDRESS if set
+	healthChecker := health.NewChecker(0)
+	healthChecker.Register("postgres", health.PingCheck(pools.main))
+	healthChecker.Register("redis", health.RedisCheck(redisClient))
+
+	waitGroup, ctx := errgroup.WithContext(ctx)
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		healthChecker.Shutdown()
+		return nil
+	})
+	if config.MetricsServerAddress != "" {
+		runMetricsServer(ctx, waitGroup, config, healthChecker)
+	}
+
+	runTaskProcessor(ctx, waitGroup, config, redisOpt, store)
+
+	err = waitGroup.Wait()
+
+	if closeErr := redisClient.Close(); closeErr != nil {
+		log.Error().Err(closeErr).Msg("cannot close redis client")
+	}
+	// note* waits for the acquired conns to be released
+	pools.Close()
+	flushTracing(shutdownTracing)
+
+	if err != nil {
+		return err
+	}
+	log.Info().Msg("graceful shutdown complete")
+	return nil
+}
+
+func runTaskProcessor(ctx context.Context, waitGroup *errgroup.Group, config util.Config, redisOpt asynq.RedisClientOpt, store db.Store) {
+	// a mailer instance required for Redis TaskProcessor
+	mailer := mail.NewGmailSender(config.EmailSenderName, config.EmailSenderAddress, config.EmailSenderPassword)
+
+	taskProcessor := worker.NewRedisTaskProcessor(redisOpt, store, mailer, config)
+	log.Info().Msg("start taskProcessor")
+	err := taskProcessor.Start()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to start taskProcessor")
+	}
+
+	// periodic tasks, e.g. voiding the expired holds - processed by this or any other worker
+	scheduler, err := worker.NewScheduler(redisOpt, config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create scheduler")
+	}
+	err = scheduler.Start()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to start scheduler")
+	}
+
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		log.Info().Msg("graceful shutdown taskProcessor")
+		scheduler.Shutdown()
+
+		// blocks until active tasks finish or config.ShutdownTimeout passes
+		taskProcessor.Shutdown()
+
+		log.Info().Msg("taskProcessor is stopped")
+		return nil
+	})
+}
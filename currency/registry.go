@@ -0,0 +1,150 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
+)
+
+/*
+   Registry of the currencies table, cached in memory for the request validators
+	- loaded once before serving (Load), then refreshed in the background of lookups every refreshInterval
+	  so a currency enabled or disabled through another server is picked up within an interval
+	- Put updates the cache right away after a change made through this server
+   Note* a row's minor units must match its ISO 4217 exponent in the money package, a row that doesn't is skipped
+   Note* a refresh that read the table before a Put may undo it until the next refresh
+*/
+
+// Store is the part of db.Store the registry loads from
+type Store interface {
+	ListCurrencies(ctx context.Context) ([]db.Currency, error)
+}
+
+// Registry is a cache of the currencies table
+type Registry struct {
+	store           Store
+	refreshInterval time.Duration
+
+	mutex       sync.Mutex
+	currencies  map[string]db.Currency
+	refreshedAt time.Time
+	refreshing  bool
+}
+
+// NewRegistry creates an empty registry of the currencies in store - Load it before use
+func NewRegistry(store Store, refreshInterval time.Duration) *Registry {
+	return &Registry{
+		store:           store,
+		refreshInterval: refreshInterval,
+		currencies:      make(map[string]db.Currency),
+	}
+}
+
+// NewStaticRegistry creates a registry of currencies that's never refreshed - for tests & tools without a db
+func NewStaticRegistry(currencies ...db.Currency) *Registry {
+	registry := NewRegistry(nil, 0)
+	for _, currency := range currencies {
+		registry.Put(currency)
+	}
+	return registry
+}
+
+// Load reads all currencies from the store, replacing the cached ones
+func (r *Registry) Load(ctx context.Context) error {
+	currencies, err := r.store.ListCurrencies(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot load currencies: %w", err)
+	}
+
+	loaded := make(map[string]db.Currency, len(currencies))
+	for _, currency := range currencies {
+		if isoMinorUnits(currency) {
+			loaded[currency.Code] = currency
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.currencies = loaded
+	r.refreshedAt = time.Now()
+	return nil
+}
+
+// isoMinorUnits returns true if the minor units of currency are its ISO 4217 exponent - logged if they aren't
+func isoMinorUnits(currency db.Currency) bool {
+	if exponent, ok := money.Exponent(currency.Code); !ok || exponent != int(currency.MinorUnits) {
+		log.Warn().Str("currency", currency.Code).Int32("minor_units", currency.MinorUnits).
+			Msg("currency skipped, its minor units don't match ISO 4217")
+		return false
+	}
+	return true
+}
+
+// Put caches currency, e.g. after it was updated in the store
+func (r *Registry) Put(currency db.Currency) {
+	if !isoMinorUnits(currency) {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.currencies[currency.Code] = currency
+}
+
+// Get returns the currency of code, false if there's none
+func (r *Registry) Get(code string) (db.Currency, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.refreshIfStale()
+	currency, ok := r.currencies[code]
+	return currency, ok
+}
+
+// IsEnabled returns true if accounts can be opened & money moved in the currency of code
+func (r *Registry) IsEnabled(code string) bool {
+	currency, ok := r.Get(code)
+	return ok && currency.Enabled
+}
+
+// List returns all currencies, enabled or not, by code
+func (r *Registry) List() []db.Currency {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.refreshIfStale()
+	currencies := make([]db.Currency, 0, len(r.currencies))
+	for _, currency := range r.currencies {
+		currencies = append(currencies, currency)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+	return currencies
+}
+
+// refreshIfStale starts a refresh in the background when the last one is too old - with the mutex held
+func (r *Registry) refreshIfStale() {
+	if r.store == nil || r.refreshInterval <= 0 || r.refreshing || time.Since(r.refreshedAt) < r.refreshInterval {
+		return
+	}
+	r.refreshing = true
+	go r.refresh()
+}
+
+// refresh reloads the currencies, keeping the cached ones if it fails
+func (r *Registry) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.refreshInterval)
+	defer cancel()
+
+	err := r.Load(ctx)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err != nil {
+		log.Warn().Err(err).Msg("cannot refresh currencies, using the cached ones")
+		// note* retried after another interval, not on every lookup
+		r.refreshedAt = time.Now()
+	}
+	r.refreshing = false
+}
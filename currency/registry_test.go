@@ -0,0 +1,91 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+)
+
+var (
+	usd = db.Currency{Code: "USD", MinorUnits: 2, Enabled: true, DisplayName: "US Dollar"}
+	jpy = db.Currency{Code: "JPY", MinorUnits: 0, Enabled: false, DisplayName: "Japanese Yen"}
+)
+
+func TestLoad(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	// KWD has 3 minor units in ISO 4217, XYZ isn't a currency at all
+	kwd := db.Currency{Code: "KWD", MinorUnits: 2, Enabled: true}
+	xyz := db.Currency{Code: "XYZ", MinorUnits: 2, Enabled: true}
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return([]db.Currency{jpy, kwd, usd, xyz}, nil)
+
+	registry := NewRegistry(store, time.Hour)
+	require.NoError(t, registry.Load(context.Background()))
+
+	require.Equal(t, []db.Currency{jpy, usd}, registry.List())
+	require.True(t, registry.IsEnabled("USD"))
+	require.False(t, registry.IsEnabled("JPY"))
+	require.False(t, registry.IsEnabled("KWD"))
+	require.False(t, registry.IsEnabled("XYZ"))
+	currency, ok := registry.Get("JPY")
+	require.True(t, ok)
+	require.Equal(t, jpy, currency)
+
+	// a put takes effect right away, a row with wrong minor units is still skipped
+	enabledJpy := jpy
+	enabledJpy.Enabled = true
+	registry.Put(enabledJpy)
+	require.True(t, registry.IsEnabled("JPY"))
+	registry.Put(kwd)
+	require.False(t, registry.IsEnabled("KWD"))
+}
+
+func TestRefresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	disabledUsd := usd
+	disabledUsd.Enabled = false
+	var failures int64
+	gomock.InOrder(
+		store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return([]db.Currency{usd}, nil),
+		// disabled through another server - picked up by the next refresh
+		store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return([]db.Currency{disabledUsd}, nil),
+		// a failed refresh keeps the cached currencies, retried every interval
+		store.EXPECT().ListCurrencies(gomock.Any()).MinTimes(1).DoAndReturn(func(context.Context) ([]db.Currency, error) {
+			atomic.AddInt64(&failures, 1)
+			return nil, errors.New("conn refused")
+		}),
+	)
+
+	interval := 10 * time.Millisecond
+	registry := NewRegistry(store, interval)
+	require.NoError(t, registry.Load(context.Background()))
+	require.True(t, registry.IsEnabled("USD"))
+
+	time.Sleep(interval)
+	// note* the stale cache answers while the refresh runs in the background
+	require.True(t, registry.IsEnabled("USD"))
+	require.Eventually(t, func() bool { return !registry.IsEnabled("USD") }, time.Second, time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		_, ok := registry.Get("USD")
+		return ok && atomic.LoadInt64(&failures) > 0
+	}, time.Second, time.Millisecond)
+	require.Never(t, func() bool { return registry.IsEnabled("USD") }, 3*interval, time.Millisecond)
+	_, ok := registry.Get("USD")
+	require.True(t, ok)
+}
+
+func TestStaticRegistry(t *testing.T) {
+	registry := NewStaticRegistry(usd, jpy)
+	require.True(t, registry.IsEnabled("USD"))
+	require.False(t, registry.IsEnabled("JPY"))
+	require.False(t, registry.IsEnabled("EUR"))
+}
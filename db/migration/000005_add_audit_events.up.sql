@@ -0,0 +1,26 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'depositor';
+
+CREATE TABLE "audit_events" (
+  "id" bigserial PRIMARY KEY,
+  "action" varchar NOT NULL,
+  "actor" varchar NOT NULL,
+  "resource_type" varchar NOT NULL,
+  "resource_id" varchar NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "user_agent" varchar NOT NULL,
+  "request_id" varchar NOT NULL,
+  "before" json,
+  "after" json,
+  "prev_hash" bytea NOT NULL,
+  "hash" bytea UNIQUE NOT NULL,
+  "created_at" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "audit_events" ("actor");
+CREATE INDEX ON "audit_events" ("action");
+CREATE INDEX ON "audit_events" ("created_at");
+
+COMMENT ON COLUMN "users"."role" IS 'depositor or admin';
+COMMENT ON COLUMN "audit_events"."actor" IS 'username of the authenticated user, or the attempted one for failed logins';
+COMMENT ON COLUMN "audit_events"."before" IS 'json, not jsonb - kept byte for byte, the hash covers it';
+COMMENT ON COLUMN "audit_events"."hash" IS 'sha256 of prev_hash and all the other columns - editing or deleting a row breaks the chain';
@@ -0,0 +1,5 @@
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "accounts_currency_fkey";
+
+DROP TABLE IF EXISTS "currencies";
+
+COMMENT ON COLUMN "accounts"."currency" IS 'can use enum here later';
@@ -0,0 +1,19 @@
+CREATE TABLE "currencies" (
+  "code" varchar PRIMARY KEY,
+  "minor_units" int NOT NULL,
+  "enabled" bool NOT NULL DEFAULT true,
+  "display_name" varchar NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+INSERT INTO "currencies" ("code", "minor_units", "display_name") VALUES
+  ('USD', 2, 'US Dollar'),
+  ('EUR', 2, 'Euro'),
+  ('INR', 2, 'Indian Rupee');
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
+COMMENT ON COLUMN "accounts"."currency" IS 'new accounts only in enabled currencies';
+COMMENT ON COLUMN "currencies"."code" IS 'ISO 4217 code';
+COMMENT ON COLUMN "currencies"."minor_units" IS 'ISO 4217 exponent - 2 means an amount of 1234 is 12.34';
+COMMENT ON COLUMN "currencies"."enabled" IS 'disabled currencies take no new accounts nor transfers, existing balances stay';
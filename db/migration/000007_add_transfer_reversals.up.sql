@@ -0,0 +1,22 @@
+CREATE TABLE "transfer_reversals" (
+  "id" bigserial PRIMARY KEY,
+  "original_transfer_id" bigint NOT NULL,
+  "reversal_transfer_id" bigint UNIQUE NOT NULL,
+  "amount" bigint NOT NULL,
+  "reason" varchar NOT NULL,
+  "reversed_by" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "transfer_reversals_amount_check" CHECK ("amount" > 0),
+  CONSTRAINT "transfer_reversals_reason_check" CHECK ("reason" IN ('duplicate', 'fraud', 'customer_request', 'processing_error', 'other'))
+);
+
+CREATE INDEX ON "transfer_reversals" ("original_transfer_id");
+
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("original_transfer_id") REFERENCES "transfers" ("id");
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("reversal_transfer_id") REFERENCES "transfers" ("id");
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("reversed_by") REFERENCES "users" ("username");
+
+COMMENT ON COLUMN "transfer_reversals"."reversal_transfer_id" IS 'the compensating transfer, from the original to account back to the original from account';
+COMMENT ON COLUMN "transfer_reversals"."amount" IS 'partial refunds allowed, their sum never exceeds the original amount';
+COMMENT ON COLUMN "transfer_reversals"."reason" IS 'duplicate, fraud, customer_request, processing_error or other';
+COMMENT ON COLUMN "transfer_reversals"."reversed_by" IS 'username of the admin who reversed it';
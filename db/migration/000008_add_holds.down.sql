@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "holds";
+
+COMMENT ON COLUMN "accounts"."balance" IS NULL;
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "held_amount";
@@ -0,0 +1,29 @@
+ALTER TABLE "accounts" ADD COLUMN "held_amount" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_held_amount_check" CHECK ("held_amount" >= 0);
+
+CREATE TABLE "holds" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'authorized',
+  "captured_amount" bigint NOT NULL DEFAULT 0,
+  "transfer_id" bigint,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "holds_amount_check" CHECK ("amount" > 0),
+  CONSTRAINT "holds_status_check" CHECK ("status" IN ('authorized', 'captured', 'voided', 'expired'))
+);
+
+CREATE INDEX ON "holds" ("account_id");
+CREATE INDEX ON "holds" ("expires_at") WHERE "status" = 'authorized';
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+ALTER TABLE "holds" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+ALTER TABLE "holds" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+COMMENT ON COLUMN "accounts"."balance" IS 'ledger balance, the available balance is balance - held_amount';
+COMMENT ON COLUMN "accounts"."held_amount" IS 'sum of the authorized holds on the account';
+COMMENT ON COLUMN "holds"."status" IS 'authorized, then captured, voided or expired';
+COMMENT ON COLUMN "holds"."transfer_id" IS 'the transfer of the captured amount';
@@ -0,0 +1,11 @@
+ALTER TABLE "holds" DROP COLUMN IF EXISTS "metadata";
+ALTER TABLE "holds" DROP COLUMN IF EXISTS "reference";
+ALTER TABLE "holds" DROP COLUMN IF EXISTS "memo";
+
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "metadata";
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "reference";
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "memo";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "metadata";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reference";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "memo";
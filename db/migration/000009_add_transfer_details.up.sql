@@ -0,0 +1,28 @@
+ALTER TABLE "transfers" ADD COLUMN "memo" varchar NOT NULL DEFAULT '';
+ALTER TABLE "transfers" ADD COLUMN "reference" varchar NOT NULL DEFAULT '';
+ALTER TABLE "transfers" ADD COLUMN "metadata" jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_memo_check" CHECK (char_length("memo") <= 140);
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_reference_check" CHECK (char_length("reference") <= 64);
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_metadata_check" CHECK (jsonb_typeof("metadata") = 'object');
+
+ALTER TABLE "entries" ADD COLUMN "memo" varchar NOT NULL DEFAULT '';
+ALTER TABLE "entries" ADD COLUMN "reference" varchar NOT NULL DEFAULT '';
+ALTER TABLE "entries" ADD COLUMN "metadata" jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE "entries" ADD CONSTRAINT "entries_memo_check" CHECK (char_length("memo") <= 140);
+ALTER TABLE "entries" ADD CONSTRAINT "entries_reference_check" CHECK (char_length("reference") <= 64);
+ALTER TABLE "entries" ADD CONSTRAINT "entries_metadata_check" CHECK (jsonb_typeof("metadata") = 'object');
+
+ALTER TABLE "holds" ADD COLUMN "memo" varchar NOT NULL DEFAULT '';
+ALTER TABLE "holds" ADD COLUMN "reference" varchar NOT NULL DEFAULT '';
+ALTER TABLE "holds" ADD COLUMN "metadata" jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE "holds" ADD CONSTRAINT "holds_memo_check" CHECK (char_length("memo") <= 140);
+ALTER TABLE "holds" ADD CONSTRAINT "holds_reference_check" CHECK (char_length("reference") <= 64);
+ALTER TABLE "holds" ADD CONSTRAINT "holds_metadata_check" CHECK (jsonb_typeof("metadata") = 'object');
+
+CREATE INDEX ON "transfers" ("reference") WHERE "reference" <> '';
+CREATE INDEX ON "entries" ("reference") WHERE "reference" <> '';
+
+COMMENT ON COLUMN "transfers"."reference" IS 'external reference, e.g. an invoice number - empty for none';
+COMMENT ON COLUMN "transfers"."metadata" IS 'json object of at most 4KB, checked by the api';
+COMMENT ON COLUMN "entries"."reference" IS 'memo, reference & metadata are copied from the entry''s transfer';
+COMMENT ON COLUMN "holds"."reference" IS 'memo, reference & metadata are copied to the transfer of the capture';
@@ -0,0 +1,16 @@
+CREATE TABLE "statement_emails" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "period_start" timestamptz NOT NULL,
+  "period_end" timestamptz NOT NULL,
+  "sent_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "statement_emails_period_check" CHECK ("period_end" > "period_start")
+);
+
+CREATE UNIQUE INDEX ON "statement_emails" ("account_id", "period_start");
+
+ALTER TABLE "statement_emails" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+CREATE INDEX ON "entries" ("account_id", "created_at");
+
+COMMENT ON TABLE "statement_emails" IS 'monthly statements emailed, so a retried run does not send them again';
@@ -0,0 +1,12 @@
+CREATE TABLE "account_balance_snapshots" (
+  "account_id" bigint NOT NULL,
+  "snapshot_at" timestamptz NOT NULL,
+  "balance" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "snapshot_at")
+);
+
+ALTER TABLE "account_balance_snapshots" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+COMMENT ON COLUMN "account_balance_snapshots"."snapshot_at" IS 'midnight UTC of the day of the snapshot';
+COMMENT ON COLUMN "account_balance_snapshots"."balance" IS 'sum of the entries of the account created before snapshot_at';
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS "interest_accruals";
+DROP TABLE IF EXISTS "interest_postings";
+
+-- note* fails while the internal accounts have entries, they have to be cleared first
+DELETE FROM "accounts" WHERE "owner" = 'simplebank';
+DELETE FROM "users" WHERE "username" = 'simplebank';
+COMMENT ON COLUMN "users"."role" IS 'depositor or admin';
+
+DROP INDEX IF EXISTS "accounts_owner_currency_product_idx";
+CREATE UNIQUE INDEX ON "accounts" ("owner", "currency");
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "product";
+DROP TABLE IF EXISTS "account_products";
@@ -0,0 +1,79 @@
+CREATE TABLE "account_products" (
+  "code" varchar PRIMARY KEY,
+  "display_name" varchar NOT NULL,
+  "annual_interest_rate_bps" int NOT NULL DEFAULT 0,
+  "internal" bool NOT NULL DEFAULT false,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "account_products_annual_interest_rate_bps_check" CHECK ("annual_interest_rate_bps" BETWEEN 0 AND 10000)
+);
+
+INSERT INTO "account_products" ("code", "display_name", "annual_interest_rate_bps", "internal") VALUES
+  ('checking', 'Checking', 0, false),
+  ('savings', 'Savings', 200, false),
+  ('interest_expense', 'Interest expense', 0, true);
+
+ALTER TABLE "accounts" ADD COLUMN "product" varchar NOT NULL DEFAULT 'checking';
+ALTER TABLE "accounts" ADD FOREIGN KEY ("product") REFERENCES "account_products" ("code");
+
+-- an owner can have an account of each product in a currency
+DROP INDEX IF EXISTS "accounts_owner_currency_idx";
+CREATE UNIQUE INDEX ON "accounts" ("owner", "currency", "product");
+
+-- the owner of the bank's internal accounts, nobody can log in as it
+-- note* a real user who signed up as simplebank is never taken over - the migration stops, the user has to be renamed first
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email", "is_email_verified", "role") VALUES
+  ('simplebank', '!', 'Simple Bank', 'ledger@simplebank.internal', false, 'system')
+ON CONFLICT ("username") DO NOTHING;
+
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM "users" WHERE "username" = 'simplebank' AND "role" <> 'system') THEN
+    RAISE EXCEPTION 'username simplebank is taken by a user, rename it before migrating - it owns the bank''s internal accounts';
+  END IF;
+END $$;
+
+CREATE TABLE "interest_postings" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "period_start" timestamptz NOT NULL,
+  "period_end" timestamptz NOT NULL,
+  "accrued_micros" bigint NOT NULL DEFAULT 0,
+  "amount" bigint NOT NULL DEFAULT 0,
+  "carried_micros" bigint NOT NULL DEFAULT 0,
+  "transfer_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "interest_postings_amount_check" CHECK ("amount" >= 0)
+);
+
+CREATE UNIQUE INDEX ON "interest_postings" ("account_id", "period_start");
+
+ALTER TABLE "interest_postings" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+ALTER TABLE "interest_postings" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE TABLE "interest_accruals" (
+  "account_id" bigint NOT NULL,
+  "accrued_on" timestamptz NOT NULL,
+  "balance" bigint NOT NULL,
+  "annual_interest_rate_bps" int NOT NULL,
+  "amount_micros" bigint NOT NULL,
+  "posting_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "accrued_on")
+);
+
+CREATE INDEX ON "interest_accruals" ("account_id") WHERE "posting_id" IS NULL;
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("posting_id") REFERENCES "interest_postings" ("id");
+
+COMMENT ON COLUMN "accounts"."product" IS 'checking or savings, interest_expense for the internal accounts';
+COMMENT ON COLUMN "account_products"."annual_interest_rate_bps" IS 'in basis points - 200 is 2.00% a year';
+COMMENT ON COLUMN "account_products"."internal" IS 'accounts of the bank itself, owned by the simplebank user';
+COMMENT ON COLUMN "users"."role" IS 'depositor, admin or system';
+COMMENT ON COLUMN "interest_accruals"."accrued_on" IS 'midnight UTC of the day of the accrual';
+COMMENT ON COLUMN "interest_accruals"."balance" IS 'balance at the end of the day';
+COMMENT ON COLUMN "interest_accruals"."amount_micros" IS 'interest of the day in millionths of a minor unit';
+COMMENT ON COLUMN "interest_accruals"."posting_id" IS 'the posting that paid it, null until then';
+COMMENT ON COLUMN "interest_postings"."accrued_micros" IS 'accruals paid by the posting plus the carry of the previous one';
+COMMENT ON COLUMN "interest_postings"."amount" IS 'whole minor units paid, the rest of accrued_micros is carried';
+COMMENT ON COLUMN "interest_postings"."transfer_id" IS 'from the interest_expense account, null when nothing is paid';
@@ -0,0 +1,3 @@
+-- an account never spends its held funds - checked by the txs on the locked rows, enforced here for any that doesn't
+-- note* the internal interest_expense accounts go negative by design, they pay the interest out of the bank's pocket
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_balance_check" CHECK ("balance" >= "held_amount" OR "product" = 'interest_expense');
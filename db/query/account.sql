@@ -1,12 +1,26 @@
 -- name: CreateAccount :one
-INSERT INTO accounts (owner, balance, currency)
-VALUES ($1, $2, $3)
+-- note* an empty product is a checking account
+INSERT INTO accounts (owner, balance, currency, product)
+VALUES (
+        $1,
+        $2,
+        $3,
+        COALESCE(NULLIF(sqlc.arg(product)::varchar, ''), 'checking')
+    )
 RETURNING *;
 -- name: GetAccount :one
 SELECT *
 FROM accounts
 WHERE id = $1
 LIMIT 1;
+-- name: GetAccountByOwnerAndCurrency :one
+-- the checking account of an owner in a currency, at most one - see the (owner, currency, product) unique index
+SELECT *
+FROM accounts
+WHERE owner = $1
+    AND currency = $2
+    AND product = 'checking'
+LIMIT 1;
 -- name: GetAccountForUpdate :one
 SELECT *
 FROM accounts
@@ -39,3 +53,20 @@ DELETE FROM accounts
 WHERE id = $1;
 -- name: GetCountForAccounts :one
 SELECT COUNT(*) FROM accounts;
+-- name: AddAccountHeldAmount :one
+UPDATE accounts
+SET held_amount = held_amount + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: CreateInternalAccount :exec
+-- note* one per currency & internal product, created the first time it's needed
+INSERT INTO accounts (owner, balance, currency, product)
+VALUES (sqlc.arg(owner), 0, sqlc.arg(currency), sqlc.arg(product)) ON CONFLICT (owner, currency, product) DO NOTHING;
+-- name: GetInternalAccount :one
+SELECT *
+FROM accounts
+WHERE owner = sqlc.arg(owner)
+    AND currency = sqlc.arg(currency)
+    AND product = sqlc.arg(product)
+LIMIT 1;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- name: GetAccountProduct :one
+SELECT *
+FROM account_products
+WHERE code = $1
+LIMIT 1;
+-- name: ListAccountProducts :many
+SELECT *
+FROM account_products
+ORDER BY code;
+-- name: UpdateAccountProductRate :one
+UPDATE account_products
+SET annual_interest_rate_bps = $2,
+  updated_at = now()
+WHERE code = $1
+RETURNING *;
@@ -0,0 +1,28 @@
+-- name: CreateAuditEvent :one
+INSERT INTO audit_events (action, actor, resource_type, resource_id, client_ip, user_agent, request_id, before, after, prev_hash, hash, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING *;
+-- name: GetLastAuditEvent :one
+SELECT *
+FROM audit_events
+ORDER BY id DESC
+LIMIT 1;
+-- name: LockAuditChain :exec
+-- serializes appends to the hash chain, released on commit/rollback
+SELECT pg_advisory_xact_lock(sqlc.arg(lock_key)::bigint);
+-- name: ListAuditEvents :many
+SELECT *
+FROM audit_events
+WHERE (sqlc.narg(actor)::varchar IS NULL OR actor = sqlc.narg(actor))
+  AND (sqlc.narg(action)::varchar IS NULL OR action = sqlc.narg(action))
+  AND (sqlc.narg(from_time)::timestamptz IS NULL OR created_at >= sqlc.narg(from_time))
+  AND (sqlc.narg(to_time)::timestamptz IS NULL OR created_at < sqlc.narg(to_time))
+ORDER BY id DESC
+LIMIT $1
+OFFSET $2;
+-- name: ListAuditEventsAfter :many
+SELECT *
+FROM audit_events
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
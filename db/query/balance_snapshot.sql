@@ -0,0 +1,52 @@
+-- name: CreateBalanceSnapshots :one
+-- snapshots the balances at snapshot_at of the next max_accounts accounts after the after_id cursor
+-- each one is its latest snapshot before snapshot_at plus the entries since, all of them if it has none
+-- note* an existing snapshot is kept, a rerun of the same day only adds the missing ones
+WITH page AS (
+    SELECT accounts.id
+    FROM accounts
+    WHERE accounts.id > sqlc.arg(after_id)
+        AND accounts.created_at < sqlc.arg(snapshot_at)
+    ORDER BY accounts.id
+    LIMIT sqlc.arg(max_accounts)
+), inserted AS (
+    INSERT INTO account_balance_snapshots (account_id, snapshot_at, balance)
+    SELECT page.id,
+        sqlc.arg(snapshot_at),
+        COALESCE(prev.balance, 0) + COALESCE(
+            (
+                SELECT SUM(entries.amount)
+                FROM entries
+                WHERE entries.account_id = page.id
+                    AND entries.created_at >= COALESCE(prev.snapshot_at, '-infinity')
+                    AND entries.created_at < sqlc.arg(snapshot_at)
+            ),
+            0
+        )
+    FROM page
+        LEFT JOIN LATERAL (
+            SELECT snapshot_at,
+                balance
+            FROM account_balance_snapshots
+            WHERE account_id = page.id
+                AND snapshot_at < sqlc.arg(snapshot_at)
+            ORDER BY snapshot_at DESC
+            LIMIT 1
+        ) prev ON true ON CONFLICT (account_id, snapshot_at) DO NOTHING
+    RETURNING account_id
+)
+SELECT COUNT(*) AS accounts,
+    COALESCE(MAX(page.id), 0)::bigint AS last_account_id,
+    (
+        SELECT COUNT(*)
+        FROM inserted
+    ) AS snapshots
+FROM page;
+-- name: GetLatestBalanceSnapshot :one
+-- the latest snapshot of an account at or before at
+SELECT *
+FROM account_balance_snapshots
+WHERE account_id = sqlc.arg(account_id)
+    AND snapshot_at <= sqlc.arg(at)
+ORDER BY snapshot_at DESC
+LIMIT 1;
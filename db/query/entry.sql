@@ -1,6 +1,12 @@
 -- name: CreateEntry :one
-INSERT INTO entries (account_id, amount)
-VALUES ($1, $2)
+INSERT INTO entries (account_id, amount, memo, reference, metadata)
+VALUES (
+        $1,
+        $2,
+        sqlc.arg(memo),
+        sqlc.arg(reference),
+        COALESCE(sqlc.narg(metadata)::jsonb, '{}')
+    )
 RETURNING *;
 -- name: GetEntry :one
 SELECT *
@@ -12,4 +18,26 @@ SELECT *
 FROM entries
 WHERE account_id = $1
 ORDER BY id
-LIMIT $2 OFFSET $3;
\ No newline at end of file
+LIMIT $2 OFFSET $3;
+-- name: GetEntriesSum :one
+-- an account's balance must always equal the sum of its entries
+SELECT COALESCE(SUM(amount), 0)::bigint AS sum
+FROM entries
+WHERE account_id = $1;
+-- name: ListEntriesBetween :many
+-- the entries of a statement, in the order they were made
+SELECT *
+FROM entries
+WHERE account_id = sqlc.arg(account_id)
+    AND created_at >= sqlc.arg(from_time)
+    AND created_at < sqlc.arg(to_time)
+ORDER BY created_at,
+    id
+LIMIT sqlc.arg(max_entries);
+-- name: GetEntriesSumBetween :one
+-- the sum of the entries of an account from from_time, included, to to_time, included
+SELECT COALESCE(SUM(amount), 0)::bigint AS sum
+FROM entries
+WHERE account_id = sqlc.arg(account_id)
+    AND created_at >= sqlc.arg(from_time)
+    AND created_at <= sqlc.arg(to_time);
@@ -0,0 +1,46 @@
+-- name: CreateHold :one
+INSERT INTO holds (
+        account_id,
+        to_account_id,
+        amount,
+        expires_at,
+        memo,
+        reference,
+        metadata
+    )
+VALUES (
+        $1,
+        $2,
+        $3,
+        $4,
+        sqlc.arg(memo),
+        sqlc.arg(reference),
+        COALESCE(sqlc.narg(metadata)::jsonb, '{}')
+    )
+RETURNING *;
+-- name: GetHold :one
+SELECT *
+FROM holds
+WHERE id = $1
+LIMIT 1;
+-- name: GetHoldForUpdate :one
+SELECT *
+FROM holds
+WHERE id = $1
+LIMIT 1 FOR NO KEY
+UPDATE;
+-- name: UpdateHoldStatus :one
+UPDATE holds
+SET status = sqlc.arg(status),
+    captured_amount = sqlc.arg(captured_amount),
+    transfer_id = sqlc.narg(transfer_id),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+-- name: ListExpiredHolds :many
+SELECT *
+FROM holds
+WHERE status = 'authorized'
+    AND expires_at <= sqlc.arg(now)
+ORDER BY expires_at
+LIMIT sqlc.arg(max_holds);
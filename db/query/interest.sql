@@ -0,0 +1,81 @@
+-- name: ListAccountsForInterestAccrual :many
+-- the accounts of products paying interest, opened by the end of the day & not accrued yet for it, after the after_id cursor
+SELECT sqlc.embed(accounts),
+    account_products.annual_interest_rate_bps
+FROM accounts
+    JOIN account_products ON account_products.code = accounts.product
+WHERE accounts.id > sqlc.arg(after_id)
+    AND accounts.created_at < sqlc.arg(day_end)
+    AND account_products.annual_interest_rate_bps > 0
+    AND NOT account_products.internal
+    AND NOT EXISTS (
+        SELECT 1
+        FROM interest_accruals
+        WHERE interest_accruals.account_id = accounts.id
+            AND interest_accruals.accrued_on = sqlc.arg(accrued_on)
+    )
+ORDER BY accounts.id
+LIMIT sqlc.arg(max_accounts);
+-- name: CreateInterestAccrual :exec
+-- note* a day accrued twice, by runs racing each other, is accrued once
+INSERT INTO interest_accruals (
+        account_id,
+        accrued_on,
+        balance,
+        annual_interest_rate_bps,
+        amount_micros
+    )
+VALUES ($1, $2, $3, $4, $5) ON CONFLICT (account_id, accrued_on) DO NOTHING;
+-- name: ListAccountsWithUnpostedInterest :many
+-- the accounts with interest accrued before period_end & not posted yet, after the after_id cursor
+SELECT DISTINCT account_id
+FROM interest_accruals
+WHERE posting_id IS NULL
+    AND accrued_on < sqlc.arg(period_end)
+    AND account_id > sqlc.arg(after_id)
+ORDER BY account_id
+LIMIT sqlc.arg(max_accounts);
+-- name: CreateInterestPosting :one
+-- no rows if the period is already posted
+INSERT INTO interest_postings (account_id, period_start, period_end)
+VALUES ($1, $2, $3) ON CONFLICT (account_id, period_start) DO NOTHING
+RETURNING *;
+-- name: GetLastInterestPosting :one
+SELECT *
+FROM interest_postings
+WHERE account_id = sqlc.arg(account_id)
+    AND period_start < sqlc.arg(period_start)
+ORDER BY period_start DESC
+LIMIT 1;
+-- name: MarkInterestAccrualsPosted :one
+-- marks the unposted accruals of an account before period_end as paid by a posting, returns their sum
+WITH posted AS (
+    UPDATE interest_accruals
+    SET posting_id = sqlc.arg(posting_id)
+    WHERE account_id = sqlc.arg(account_id)
+        AND posting_id IS NULL
+        AND accrued_on < sqlc.arg(period_end)
+    RETURNING amount_micros
+)
+SELECT COALESCE(SUM(amount_micros), 0)::bigint AS sum
+FROM posted;
+-- name: UpdateInterestPosting :one
+UPDATE interest_postings
+SET accrued_micros = $2,
+    amount = $3,
+    carried_micros = $4,
+    transfer_id = $5
+WHERE id = $1
+RETURNING *;
+-- name: ListInterestPostings :many
+SELECT *
+FROM interest_postings
+WHERE account_id = $1
+ORDER BY period_start DESC
+LIMIT $2 OFFSET $3;
+-- name: GetUnpostedInterest :one
+-- the interest accrued & not posted yet, in millionths of a minor unit
+SELECT COALESCE(SUM(amount_micros), 0)::bigint AS sum
+FROM interest_accruals
+WHERE account_id = $1
+    AND posting_id IS NULL;
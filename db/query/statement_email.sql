@@ -0,0 +1,18 @@
+-- name: ListAccountsWithoutStatementEmail :many
+-- the accounts opened before period_end, not yet emailed their statement of the period, after the after_id cursor
+SELECT *
+FROM accounts
+WHERE accounts.id > sqlc.arg(after_id)
+    AND accounts.created_at < sqlc.arg(period_end)
+    AND NOT EXISTS (
+        SELECT 1
+        FROM statement_emails
+        WHERE statement_emails.account_id = accounts.id
+            AND statement_emails.period_start = sqlc.arg(period_start)
+    )
+ORDER BY accounts.id
+LIMIT sqlc.arg(max_accounts);
+-- name: CreateStatementEmail :exec
+-- note* a statement emailed twice, by runs racing each other, is recorded once
+INSERT INTO statement_emails (account_id, period_start, period_end)
+VALUES ($1, $2, $3) ON CONFLICT (account_id, period_start) DO NOTHING;
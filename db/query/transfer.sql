@@ -1,6 +1,20 @@
 -- name: CreateTransfer :one
-INSERT INTO transfers (from_account_id, to_account_id, amount)
-VALUES ($1, $2, $3)
+INSERT INTO transfers (
+        from_account_id,
+        to_account_id,
+        amount,
+        memo,
+        reference,
+        metadata
+    )
+VALUES (
+        $1,
+        $2,
+        $3,
+        sqlc.arg(memo),
+        sqlc.arg(reference),
+        COALESCE(sqlc.narg(metadata)::jsonb, '{}')
+    )
 RETURNING *;
 -- name: GetTransfer :one
 SELECT *
@@ -13,4 +27,24 @@ FROM transfers
 WHERE from_account_id = $1
     OR to_account_id = $2
 ORDER BY id
-LIMIT $3 OFFSET $4;
\ No newline at end of file
+LIMIT $3 OFFSET $4;
+-- name: GetTransferForUpdate :one
+SELECT *
+FROM transfers
+WHERE id = $1
+LIMIT 1 FOR NO KEY UPDATE;
+-- name: ListTransfersByReference :many
+-- the transfers with an external reference from or to an account of owner, with their currency
+SELECT sqlc.embed(transfers),
+    accounts.currency
+FROM transfers
+    JOIN accounts ON accounts.id = transfers.from_account_id
+WHERE transfers.reference = sqlc.arg(reference)
+    AND EXISTS (
+        SELECT 1
+        FROM accounts owned
+        WHERE owned.owner = sqlc.arg(owner)
+            AND owned.id IN (transfers.from_account_id, transfers.to_account_id)
+    )
+ORDER BY transfers.id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
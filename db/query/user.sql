@@ -1,12 +1,23 @@
 -- name: CreateUser :one
-INSERT INTO users (username, hashed_password, full_name, email)
-VALUES ($1, $2, $3, $4)
+INSERT INTO users (username, hashed_password, full_name, email, role)
+VALUES ($1, $2, $3, $4, COALESCE(sqlc.narg(role)::varchar, 'depositor')) -- role defaults to depositor, admins are created from the cli
 RETURNING *;
 -- name: GetUser :one
 SELECT *  
 FROM users
 WHERE username = $1
 LIMIT 1;
+-- name: GetUserForAuth :one
+-- same as GetUser, but never routed to a read replica - the role looked up to authorize admins
+SELECT *
+FROM users
+WHERE username = $1
+LIMIT 1;
+-- name: GetUserByEmail :one
+SELECT *
+FROM users
+WHERE email = $1
+LIMIT 1;
 -- name: GetCountForUsers :one
 SELECT COUNT(*) FROM users;
 -- name: UpdateUser :one
@@ -9,20 +9,59 @@ import (
 	"context"
 )
 
+const addAccountHeldAmount = `-- name: AddAccountHeldAmount :one
+UPDATE accounts
+SET held_amount = held_amount + $1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, held_amount, product
+`
+
+type AddAccountHeldAmountParams struct {
+	Amount int64 `json:"amount"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) AddAccountHeldAmount(ctx context.Context, arg AddAccountHeldAmountParams) (Account, error) {
+	row := q.db.QueryRow(ctx, addAccountHeldAmount, arg.Amount, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.HeldAmount,
+		&i.Product,
+	)
+	return i, err
+}
+
 const createAccount = `-- name: CreateAccount :one
-INSERT INTO accounts (owner, balance, currency)
-VALUES ($1, $2, $3)
-RETURNING id, owner, balance, currency, created_at
+INSERT INTO accounts (owner, balance, currency, product)
+VALUES (
+        $1,
+        $2,
+        $3,
+        COALESCE(NULLIF($4::varchar, ''), 'checking')
+    )
+RETURNING id, owner, balance, currency, created_at, held_amount, product
 `
 
 type CreateAccountParams struct {
 	Owner    string `json:"owner"`
 	Balance  int64  `json:"balance"`
 	Currency string `json:"currency"`
+	Product  string `json:"product"`
 }
 
+// note* an empty product is a checking account
 func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, createAccount, arg.Owner, arg.Balance, arg.Currency)
+	row := q.db.QueryRow(ctx, createAccount,
+		arg.Owner,
+		arg.Balance,
+		arg.Currency,
+		arg.Product,
+	)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -30,29 +69,48 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.HeldAmount,
+		&i.Product,
 	)
 	return i, err
 }
 
+const createInternalAccount = `-- name: CreateInternalAccount :exec
+INSERT INTO accounts (owner, balance, currency, product)
+VALUES ($1, 0, $2, $3) ON CONFLICT (owner, currency, product) DO NOTHING
+`
+
+type CreateInternalAccountParams struct {
+	Owner    string `json:"owner"`
+	Currency string `json:"currency"`
+	Product  string `json:"product"`
+}
+
+// note* one per currency & internal product, created the first time it's needed
+func (q *Queries) CreateInternalAccount(ctx context.Context, arg CreateInternalAccountParams) error {
+	_, err := q.db.Exec(ctx, createInternalAccount, arg.Owner, arg.Currency, arg.Product)
+	return err
+}
+
 const deleteAccount = `-- name: DeleteAccount :exec
 DELETE FROM accounts
 WHERE id = $1
 `
 
 func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteAccount, id)
+	_, err := q.db.Exec(ctx, deleteAccount, id)
 	return err
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at
+SELECT id, owner, balance, currency, created_at, held_amount, product
 FROM accounts
 WHERE id = $1
 LIMIT 1
 `
 
 func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
-	row := q.db.QueryRowContext(ctx, getAccount, id)
+	row := q.db.QueryRow(ctx, getAccount, id)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -60,12 +118,44 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.HeldAmount,
+		&i.Product,
+	)
+	return i, err
+}
+
+const getAccountByOwnerAndCurrency = `-- name: GetAccountByOwnerAndCurrency :one
+SELECT id, owner, balance, currency, created_at, held_amount, product
+FROM accounts
+WHERE owner = $1
+    AND currency = $2
+    AND product = 'checking'
+LIMIT 1
+`
+
+type GetAccountByOwnerAndCurrencyParams struct {
+	Owner    string `json:"owner"`
+	Currency string `json:"currency"`
+}
+
+// the checking account of an owner in a currency, at most one - see the (owner, currency, product) unique index
+func (q *Queries) GetAccountByOwnerAndCurrency(ctx context.Context, arg GetAccountByOwnerAndCurrencyParams) (Account, error) {
+	row := q.db.QueryRow(ctx, getAccountByOwnerAndCurrency, arg.Owner, arg.Currency)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.HeldAmount,
+		&i.Product,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at
+SELECT id, owner, balance, currency, created_at, held_amount, product
 FROM accounts
 WHERE id = $1
 LIMIT 1 FOR NO KEY
@@ -73,7 +163,7 @@ UPDATE
 `
 
 func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
-	row := q.db.QueryRowContext(ctx, getAccountForUpdate, id)
+	row := q.db.QueryRow(ctx, getAccountForUpdate, id)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -81,6 +171,8 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.HeldAmount,
+		&i.Product,
 	)
 	return i, err
 }
@@ -90,14 +182,44 @@ SELECT COUNT(*) FROM accounts
 `
 
 func (q *Queries) GetCountForAccounts(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, getCountForAccounts)
+	row := q.db.QueryRow(ctx, getCountForAccounts)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
+const getInternalAccount = `-- name: GetInternalAccount :one
+SELECT id, owner, balance, currency, created_at, held_amount, product
+FROM accounts
+WHERE owner = $1
+    AND currency = $2
+    AND product = $3
+LIMIT 1
+`
+
+type GetInternalAccountParams struct {
+	Owner    string `json:"owner"`
+	Currency string `json:"currency"`
+	Product  string `json:"product"`
+}
+
+func (q *Queries) GetInternalAccount(ctx context.Context, arg GetInternalAccountParams) (Account, error) {
+	row := q.db.QueryRow(ctx, getInternalAccount, arg.Owner, arg.Currency, arg.Product)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.HeldAmount,
+		&i.Product,
+	)
+	return i, err
+}
+
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at
+SELECT id, owner, balance, currency, created_at, held_amount, product
 FROM accounts
 WHERE owner = $1
 ORDER BY id
@@ -111,7 +233,7 @@ type ListAccountsParams struct {
 }
 
 func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
-	rows, err := q.db.QueryContext(ctx, listAccounts, arg.Owner, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listAccounts, arg.Owner, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -125,14 +247,13 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.HeldAmount,
+			&i.Product,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -143,7 +264,7 @@ const updateAccount = `-- name: UpdateAccount :one
 UPDATE accounts
 SET balance = $2
 WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, held_amount, product
 `
 
 type UpdateAccountParams struct {
@@ -152,7 +273,7 @@ type UpdateAccountParams struct {
 }
 
 func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, updateAccount, arg.ID, arg.Balance)
+	row := q.db.QueryRow(ctx, updateAccount, arg.ID, arg.Balance)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -160,6 +281,8 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.HeldAmount,
+		&i.Product,
 	)
 	return i, err
 }
@@ -168,7 +291,7 @@ const updateAccountBalance = `-- name: UpdateAccountBalance :one
 UPDATE accounts
 SET balance = balance + $1 -- sqlc.arg changes the generated arg name of UpdateAccountBalanceParams in account.sql.go from Balance int64 ` + "`" + `json:"balance"` + "`" + ` to Amount int64 ` + "`" + `json:"amount"` + "`" + `
 WHERE id = $2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, held_amount, product
 `
 
 type UpdateAccountBalanceParams struct {
@@ -182,7 +305,7 @@ type UpdateAccountBalanceParams struct {
 // WHERE id = $1
 // RETURNING *;
 func (q *Queries) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, updateAccountBalance, arg.Amount, arg.ID)
+	row := q.db.QueryRow(ctx, updateAccountBalance, arg.Amount, arg.ID)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -190,6 +313,8 @@ func (q *Queries) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBal
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.HeldAmount,
+		&i.Product,
 	)
 	return i, err
 }
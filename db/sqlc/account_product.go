@@ -0,0 +1,11 @@
+package db
+
+// account products - stored in account_products.code
+const (
+	ProductChecking        = "checking"
+	ProductSavings         = "savings"
+	ProductInterestExpense = "interest_expense" // internal, pays the interest of the other accounts
+)
+
+// SystemUsername owns the bank's internal accounts, nobody can log in as it - see migration 000012
+const SystemUsername = "simplebank"
@@ -0,0 +1,88 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.19.0
+// source: account_product.sql
+
+package db
+
+import (
+	"context"
+)
+
+const getAccountProduct = `-- name: GetAccountProduct :one
+SELECT code, display_name, annual_interest_rate_bps, internal, updated_at
+FROM account_products
+WHERE code = $1
+LIMIT 1
+`
+
+func (q *Queries) GetAccountProduct(ctx context.Context, code string) (AccountProduct, error) {
+	row := q.db.QueryRow(ctx, getAccountProduct, code)
+	var i AccountProduct
+	err := row.Scan(
+		&i.Code,
+		&i.DisplayName,
+		&i.AnnualInterestRateBps,
+		&i.Internal,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listAccountProducts = `-- name: ListAccountProducts :many
+SELECT code, display_name, annual_interest_rate_bps, internal, updated_at
+FROM account_products
+ORDER BY code
+`
+
+func (q *Queries) ListAccountProducts(ctx context.Context) ([]AccountProduct, error) {
+	rows, err := q.db.Query(ctx, listAccountProducts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AccountProduct{}
+	for rows.Next() {
+		var i AccountProduct
+		if err := rows.Scan(
+			&i.Code,
+			&i.DisplayName,
+			&i.AnnualInterestRateBps,
+			&i.Internal,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateAccountProductRate = `-- name: UpdateAccountProductRate :one
+UPDATE account_products
+SET annual_interest_rate_bps = $2,
+  updated_at = now()
+WHERE code = $1
+RETURNING code, display_name, annual_interest_rate_bps, internal, updated_at
+`
+
+type UpdateAccountProductRateParams struct {
+	Code                  string `json:"code"`
+	AnnualInterestRateBps int32  `json:"annual_interest_rate_bps"`
+}
+
+func (q *Queries) UpdateAccountProductRate(ctx context.Context, arg UpdateAccountProductRateParams) (AccountProduct, error) {
+	row := q.db.QueryRow(ctx, updateAccountProductRate, arg.Code, arg.AnnualInterestRateBps)
+	var i AccountProduct
+	err := row.Scan(
+		&i.Code,
+		&i.DisplayName,
+		&i.AnnualInterestRateBps,
+		&i.Internal,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
@@ -46,6 +45,26 @@ func TestGetAccount(t *testing.T) {
 	require.WithinDuration(t, account.CreatedAt, accountFromDB.CreatedAt, time.Nanosecond)
 }
 
+func TestGetAccountByOwnerAndCurrency(t *testing.T) {
+	account := createRandomAccount(t)
+	accountFromDB, err := testQueries.GetAccountByOwnerAndCurrency(context.Background(), GetAccountByOwnerAndCurrencyParams{
+		Owner:    account.Owner,
+		Currency: account.Currency,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.ID, accountFromDB.ID)
+
+	otherCurrency := util.USD
+	if account.Currency == util.USD {
+		otherCurrency = util.EUR
+	}
+	_, err = testQueries.GetAccountByOwnerAndCurrency(context.Background(), GetAccountByOwnerAndCurrencyParams{
+		Owner:    account.Owner,
+		Currency: otherCurrency,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
 func TestUpdateAccount(t *testing.T) {
 	account := createRandomAccount(t)
 	arg := UpdateAccountParams{
@@ -71,7 +90,7 @@ func TestDeleteAccount(t *testing.T) {
 	accountFromDB, err := testQueries.GetAccount(context.Background(), account.ID)
 
 	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.ErrorIs(t, err, ErrRecordNotFound)
 	require.Empty(t, accountFromDB)
 }
 
@@ -0,0 +1,215 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.19.0
+// source: audit_event.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createAuditEvent = `-- name: CreateAuditEvent :one
+INSERT INTO audit_events (action, actor, resource_type, resource_id, client_ip, user_agent, request_id, before, after, prev_hash, hash, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING id, action, actor, resource_type, resource_id, client_ip, user_agent, request_id, before, after, prev_hash, hash, created_at
+`
+
+type CreateAuditEventParams struct {
+	Action       string    `json:"action"`
+	Actor        string    `json:"actor"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	ClientIp     string    `json:"client_ip"`
+	UserAgent    string    `json:"user_agent"`
+	RequestID    string    `json:"request_id"`
+	Before       []byte    `json:"before"`
+	After        []byte    `json:"after"`
+	PrevHash     []byte    `json:"prev_hash"`
+	Hash         []byte    `json:"hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error) {
+	row := q.db.QueryRow(ctx, createAuditEvent,
+		arg.Action,
+		arg.Actor,
+		arg.ResourceType,
+		arg.ResourceID,
+		arg.ClientIp,
+		arg.UserAgent,
+		arg.RequestID,
+		arg.Before,
+		arg.After,
+		arg.PrevHash,
+		arg.Hash,
+		arg.CreatedAt,
+	)
+	var i AuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Action,
+		&i.Actor,
+		&i.ResourceType,
+		&i.ResourceID,
+		&i.ClientIp,
+		&i.UserAgent,
+		&i.RequestID,
+		&i.Before,
+		&i.After,
+		&i.PrevHash,
+		&i.Hash,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLastAuditEvent = `-- name: GetLastAuditEvent :one
+SELECT id, action, actor, resource_type, resource_id, client_ip, user_agent, request_id, before, after, prev_hash, hash, created_at
+FROM audit_events
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastAuditEvent(ctx context.Context) (AuditEvent, error) {
+	row := q.db.QueryRow(ctx, getLastAuditEvent)
+	var i AuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Action,
+		&i.Actor,
+		&i.ResourceType,
+		&i.ResourceID,
+		&i.ClientIp,
+		&i.UserAgent,
+		&i.RequestID,
+		&i.Before,
+		&i.After,
+		&i.PrevHash,
+		&i.Hash,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAuditEvents = `-- name: ListAuditEvents :many
+SELECT id, action, actor, resource_type, resource_id, client_ip, user_agent, request_id, before, after, prev_hash, hash, created_at
+FROM audit_events
+WHERE ($3::varchar IS NULL OR actor = $3)
+  AND ($4::varchar IS NULL OR action = $4)
+  AND ($5::timestamptz IS NULL OR created_at >= $5)
+  AND ($6::timestamptz IS NULL OR created_at < $6)
+ORDER BY id DESC
+LIMIT $1
+OFFSET $2
+`
+
+type ListAuditEventsParams struct {
+	Limit    int32              `json:"limit"`
+	Offset   int32              `json:"offset"`
+	Actor    pgtype.Text        `json:"actor"`
+	Action   pgtype.Text        `json:"action"`
+	FromTime pgtype.Timestamptz `json:"from_time"`
+	ToTime   pgtype.Timestamptz `json:"to_time"`
+}
+
+func (q *Queries) ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error) {
+	rows, err := q.db.Query(ctx, listAuditEvents,
+		arg.Limit,
+		arg.Offset,
+		arg.Actor,
+		arg.Action,
+		arg.FromTime,
+		arg.ToTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditEvent{}
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Action,
+			&i.Actor,
+			&i.ResourceType,
+			&i.ResourceID,
+			&i.ClientIp,
+			&i.UserAgent,
+			&i.RequestID,
+			&i.Before,
+			&i.After,
+			&i.PrevHash,
+			&i.Hash,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAuditEventsAfter = `-- name: ListAuditEventsAfter :many
+SELECT id, action, actor, resource_type, resource_id, client_ip, user_agent, request_id, before, after, prev_hash, hash, created_at
+FROM audit_events
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListAuditEventsAfterParams struct {
+	ID    int64 `json:"id"`
+	Limit int32 `json:"limit"`
+}
+
+func (q *Queries) ListAuditEventsAfter(ctx context.Context, arg ListAuditEventsAfterParams) ([]AuditEvent, error) {
+	rows, err := q.db.Query(ctx, listAuditEventsAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditEvent{}
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Action,
+			&i.Actor,
+			&i.ResourceType,
+			&i.ResourceID,
+			&i.ClientIp,
+			&i.UserAgent,
+			&i.RequestID,
+			&i.Before,
+			&i.After,
+			&i.PrevHash,
+			&i.Hash,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockAuditChain = `-- name: LockAuditChain :exec
+SELECT pg_advisory_xact_lock($1::bigint)
+`
+
+// serializes appends to the hash chain, released on commit/rollback
+func (q *Queries) LockAuditChain(ctx context.Context, lockKey int64) error {
+	_, err := q.db.Exec(ctx, lockAuditChain, lockKey)
+	return err
+}
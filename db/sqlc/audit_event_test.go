@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/util"
+)
+
+func randomAuditEventParams() CreateAuditEventTxParams {
+	return CreateAuditEventTxParams{
+		Action:       "user.updated",
+		Actor:        util.RandomUsername(),
+		ResourceType: "user",
+		ResourceID:   util.RandomUsername(),
+		ClientIp:     "127.0.0.1",
+		UserAgent:    "test",
+		RequestID:    util.RandomString(12),
+		Before:       json.RawMessage(`{"full_name":"old"}`),
+		After:        json.RawMessage(`{"full_name":"new"}`),
+	}
+}
+
+func createRandomAuditEvent(t *testing.T, store Store) AuditEvent {
+	arg := randomAuditEventParams()
+	event, err := store.CreateAuditEventTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, event.ID)
+	require.Equal(t, arg.Actor, event.Actor)
+	require.JSONEq(t, string(arg.Before), string(event.Before))
+	require.JSONEq(t, string(arg.After), string(event.After))
+	require.Equal(t, AuditEventHash(event), event.Hash)
+	return event
+}
+
+func TestCreateAuditEventTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	// concurrent appends must still form a single chain
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.CreateAuditEventTx(context.Background(), randomAuditEventParams())
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+	createRandomAuditEvent(t, store)
+
+	result, err := store.VerifyAuditChain(context.Background())
+	require.NoError(t, err)
+	require.True(t, result.Valid)
+	require.GreaterOrEqual(t, result.Checked, int64(n+1))
+}
+
+func TestVerifyAuditChainDetectsEdit(t *testing.T) {
+	store := NewStore(testDB)
+	createRandomAuditEvent(t, store)
+	event := createRandomAuditEvent(t, store)
+	createRandomAuditEvent(t, store)
+
+	// tamper with an event, then restore it
+	_, err := testDB.Exec(context.Background(), "UPDATE audit_events SET actor = $2 WHERE id = $1", event.ID, "mallory")
+	require.NoError(t, err)
+	result, err := store.VerifyAuditChain(context.Background())
+	_, restoreErr := testDB.Exec(context.Background(), "UPDATE audit_events SET actor = $2 WHERE id = $1", event.ID, event.Actor)
+	require.NoError(t, restoreErr)
+
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+	require.Equal(t, event.ID, result.BrokenAtID)
+}
+
+func TestAuditEventHash(t *testing.T) {
+	event := AuditEvent{
+		Action:       "user.login",
+		Actor:        util.RandomUsername(),
+		ResourceType: "session",
+		ResourceID:   util.RandomString(12),
+		After:        json.RawMessage(`{}`),
+		PrevHash:     make([]byte, sha256.Size),
+		CreatedAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+	hash := AuditEventHash(event)
+	require.Len(t, hash, sha256.Size)
+
+	// id & hash aren't hashed, every other column is
+	event.ID = 42
+	event.Hash = hash
+	require.Equal(t, hash, AuditEventHash(event))
+
+	edited := event
+	edited.Actor = "mallory"
+	require.NotEqual(t, hash, AuditEventHash(edited))
+
+	// shifting bytes between fields changes the hash
+	edited = event
+	edited.Action, edited.Actor = event.Action+event.Actor[:1], event.Actor[1:]
+	require.NotEqual(t, hash, AuditEventHash(edited))
+
+	// null & empty json differ
+	edited = event
+	edited.Before = json.RawMessage{}
+	require.NotEqual(t, hash, AuditEventHash(edited))
+}
+
+func TestTransferTxAudit(t *testing.T) {
+	store := NewStore(testDB)
+	payer := createRandomAccountIn(t, util.USD, 1000)
+	payee := createRandomAccountIn(t, util.USD, 0)
+	arg := randomAuditEventParams()
+	listEvents := func() []AuditEvent {
+		events, err := store.ListAuditEvents(context.Background(), ListAuditEventsParams{
+			Limit: 10,
+			Actor: pgtype.Text{String: arg.Actor, Valid: true},
+		})
+		require.NoError(t, err)
+		return events
+	}
+
+	// a failed audit fails the transfer - nothing moved
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: payer.ID,
+		ToAccountID:   payee.ID,
+		Amount:        10,
+		Audit: func(result TransferTxResult) (CreateAuditEventTxParams, error) {
+			return arg, errors.New("cannot marshal")
+		},
+	})
+	require.Error(t, err)
+	stored, err := store.GetAccount(context.Background(), payer.ID)
+	require.NoError(t, err)
+	require.Equal(t, payer.Balance, stored.Balance)
+	require.Empty(t, listEvents())
+
+	// committed with the transfer, built from its result
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: payer.ID,
+		ToAccountID:   payee.ID,
+		Amount:        10,
+		Audit: func(result TransferTxResult) (CreateAuditEventTxParams, error) {
+			event := arg
+			event.ResourceID = strconv.FormatInt(result.Transfer.ID, 10)
+			return event, nil
+		},
+	})
+	require.NoError(t, err)
+	events := listEvents()
+	require.Len(t, events, 1)
+	require.Equal(t, strconv.FormatInt(result.Transfer.ID, 10), events[0].ResourceID)
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+/*
+   Balance snapshots - the balance of every account at midnight UTC, written daily by the worker
+	- the balance of an account at any time is its latest snapshot before it plus the entries since,
+	  rather than the sum of all of its entries
+	- a snapshot is its previous one plus the entries of the day, so each day only reads that day's entries
+   Note* an entry is dated by the start of its tx but seen once committed - the worker snapshots a day
+        a while after midnight (see worker.balanceSnapshotsCronspec), past DB_TX_TIMEOUT of the txs still running
+*/
+
+// BalanceSnapshotDay returns the instant of the snapshot of the day of t - its midnight UTC
+func BalanceSnapshotDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// GetBalanceAt returns the balance of an account at at, the entries created at at included
+// note* an account without a snapshot by at, or before its first one, is summed up from its first entry
+func (store *SQLStore) GetBalanceAt(ctx context.Context, accountID int64, at time.Time) (int64, error) {
+	var from time.Time // year 1, before any entry
+	var balance int64
+	snapshot, err := store.GetLatestBalanceSnapshot(ctx, GetLatestBalanceSnapshotParams{
+		AccountID: accountID,
+		At:        at,
+	})
+	switch {
+	case err == nil:
+		from, balance = snapshot.SnapshotAt, snapshot.Balance
+	case !errors.Is(err, ErrRecordNotFound):
+		return 0, err
+	}
+
+	sum, err := store.GetEntriesSumBetween(ctx, GetEntriesSumBetweenParams{
+		AccountID: accountID,
+		FromTime:  from,
+		ToTime:    at,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return balance + sum, nil
+}
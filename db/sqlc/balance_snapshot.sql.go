@@ -0,0 +1,103 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.19.0
+// source: balance_snapshot.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createBalanceSnapshots = `-- name: CreateBalanceSnapshots :one
+WITH page AS (
+    SELECT accounts.id
+    FROM accounts
+    WHERE accounts.id > $1
+        AND accounts.created_at < $2
+    ORDER BY accounts.id
+    LIMIT $3
+), inserted AS (
+    INSERT INTO account_balance_snapshots (account_id, snapshot_at, balance)
+    SELECT page.id,
+        $2,
+        COALESCE(prev.balance, 0) + COALESCE(
+            (
+                SELECT SUM(entries.amount)
+                FROM entries
+                WHERE entries.account_id = page.id
+                    AND entries.created_at >= COALESCE(prev.snapshot_at, '-infinity')
+                    AND entries.created_at < $2
+            ),
+            0
+        )
+    FROM page
+        LEFT JOIN LATERAL (
+            SELECT snapshot_at,
+                balance
+            FROM account_balance_snapshots
+            WHERE account_id = page.id
+                AND snapshot_at < $2
+            ORDER BY snapshot_at DESC
+            LIMIT 1
+        ) prev ON true ON CONFLICT (account_id, snapshot_at) DO NOTHING
+    RETURNING account_id
+)
+SELECT COUNT(*) AS accounts,
+    COALESCE(MAX(page.id), 0)::bigint AS last_account_id,
+    (
+        SELECT COUNT(*)
+        FROM inserted
+    ) AS snapshots
+FROM page
+`
+
+type CreateBalanceSnapshotsParams struct {
+	AfterID     int64     `json:"after_id"`
+	SnapshotAt  time.Time `json:"snapshot_at"`
+	MaxAccounts int32     `json:"max_accounts"`
+}
+
+type CreateBalanceSnapshotsRow struct {
+	Accounts      int64 `json:"accounts"`
+	LastAccountID int64 `json:"last_account_id"`
+	Snapshots     int64 `json:"snapshots"`
+}
+
+// snapshots the balances at snapshot_at of the next max_accounts accounts after the after_id cursor
+// each one is its latest snapshot before snapshot_at plus the entries since, all of them if it has none
+// note* an existing snapshot is kept, a rerun of the same day only adds the missing ones
+func (q *Queries) CreateBalanceSnapshots(ctx context.Context, arg CreateBalanceSnapshotsParams) (CreateBalanceSnapshotsRow, error) {
+	row := q.db.QueryRow(ctx, createBalanceSnapshots, arg.AfterID, arg.SnapshotAt, arg.MaxAccounts)
+	var i CreateBalanceSnapshotsRow
+	err := row.Scan(&i.Accounts, &i.LastAccountID, &i.Snapshots)
+	return i, err
+}
+
+const getLatestBalanceSnapshot = `-- name: GetLatestBalanceSnapshot :one
+SELECT account_id, snapshot_at, balance, created_at
+FROM account_balance_snapshots
+WHERE account_id = $1
+    AND snapshot_at <= $2
+ORDER BY snapshot_at DESC
+LIMIT 1
+`
+
+type GetLatestBalanceSnapshotParams struct {
+	AccountID int64     `json:"account_id"`
+	At        time.Time `json:"at"`
+}
+
+// the latest snapshot of an account at or before at
+func (q *Queries) GetLatestBalanceSnapshot(ctx context.Context, arg GetLatestBalanceSnapshotParams) (AccountBalanceSnapshot, error) {
+	row := q.db.QueryRow(ctx, getLatestBalanceSnapshot, arg.AccountID, arg.At)
+	var i AccountBalanceSnapshot
+	err := row.Scan(
+		&i.AccountID,
+		&i.SnapshotAt,
+		&i.Balance,
+		&i.CreatedAt,
+	)
+	return i, err
+}
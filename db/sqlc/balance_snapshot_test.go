@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceSnapshots(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+	snapshot := func(at time.Time) CreateBalanceSnapshotsRow {
+		result, err := testQueries.CreateBalanceSnapshots(context.Background(), CreateBalanceSnapshotsParams{
+			AfterID:     account.ID - 1,
+			SnapshotAt:  at,
+			MaxAccounts: 1,
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), result.Accounts)
+		require.Equal(t, account.ID, result.LastAccountID)
+		return result
+	}
+
+	var first, second int64
+	for i := 0; i < 2; i++ {
+		first += createRandomEntry(t, account).Amount
+	}
+	firstAt := time.Now()
+	require.Equal(t, int64(1), snapshot(firstAt).Snapshots)
+	// a rerun keeps the snapshot already taken
+	require.Zero(t, snapshot(firstAt).Snapshots)
+
+	for i := 0; i < 3; i++ {
+		second += createRandomEntry(t, account).Amount
+	}
+	secondAt := time.Now()
+	require.Equal(t, int64(1), snapshot(secondAt).Snapshots)
+
+	latest, err := testQueries.GetLatestBalanceSnapshot(context.Background(), GetLatestBalanceSnapshotParams{
+		AccountID: account.ID,
+		At:        secondAt.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, first+second, latest.Balance)
+
+	// before, at & after the snapshots
+	third := createRandomEntry(t, account).Amount
+	balance, err := store.GetBalanceAt(context.Background(), account.ID, firstAt.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, balance)
+	balance, err = store.GetBalanceAt(context.Background(), account.ID, firstAt)
+	require.NoError(t, err)
+	require.Equal(t, first, balance)
+	balance, err = store.GetBalanceAt(context.Background(), account.ID, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, first+second+third, balance)
+}
+
+func TestBalanceSnapshotDay(t *testing.T) {
+	at := time.Date(2026, time.October, 18, 1, 30, 0, 0, time.FixedZone("IST", 5*3600+1800))
+	require.Equal(t, time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC), BalanceSnapshotDay(at))
+}
@@ -0,0 +1,88 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.19.0
+// source: currency.sql
+
+package db
+
+import (
+	"context"
+)
+
+const getCurrency = `-- name: GetCurrency :one
+SELECT code, minor_units, enabled, display_name, updated_at
+FROM currencies
+WHERE code = $1
+LIMIT 1
+`
+
+func (q *Queries) GetCurrency(ctx context.Context, code string) (Currency, error) {
+	row := q.db.QueryRow(ctx, getCurrency, code)
+	var i Currency
+	err := row.Scan(
+		&i.Code,
+		&i.MinorUnits,
+		&i.Enabled,
+		&i.DisplayName,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listCurrencies = `-- name: ListCurrencies :many
+SELECT code, minor_units, enabled, display_name, updated_at
+FROM currencies
+ORDER BY code
+`
+
+func (q *Queries) ListCurrencies(ctx context.Context) ([]Currency, error) {
+	rows, err := q.db.Query(ctx, listCurrencies)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Currency{}
+	for rows.Next() {
+		var i Currency
+		if err := rows.Scan(
+			&i.Code,
+			&i.MinorUnits,
+			&i.Enabled,
+			&i.DisplayName,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateCurrencyEnabled = `-- name: UpdateCurrencyEnabled :one
+UPDATE currencies
+SET enabled = $2,
+  updated_at = now()
+WHERE code = $1
+RETURNING code, minor_units, enabled, display_name, updated_at
+`
+
+type UpdateCurrencyEnabledParams struct {
+	Code    string `json:"code"`
+	Enabled bool   `json:"enabled"`
+}
+
+func (q *Queries) UpdateCurrencyEnabled(ctx context.Context, arg UpdateCurrencyEnabledParams) (Currency, error) {
+	row := q.db.QueryRow(ctx, updateCurrencyEnabled, arg.Code, arg.Enabled)
+	var i Currency
+	err := row.Scan(
+		&i.Code,
+		&i.MinorUnits,
+		&i.Enabled,
+		&i.DisplayName,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/util"
+)
+
+func TestListCurrencies(t *testing.T) {
+	currencies, err := testQueries.ListCurrencies(context.Background())
+	require.NoError(t, err)
+
+	// the currencies the migration creates, by code
+	codes := make([]string, 0, len(currencies))
+	for _, currency := range currencies {
+		codes = append(codes, currency.Code)
+		require.Equal(t, int32(2), currency.MinorUnits)
+		require.NotEmpty(t, currency.DisplayName)
+	}
+	require.Subset(t, codes, util.SupportedCurrencies())
+	for i := 1; i < len(codes); i++ {
+		require.Less(t, codes[i-1], codes[i])
+	}
+}
+
+func TestUpdateCurrencyEnabled(t *testing.T) {
+	before, err := testQueries.GetCurrency(context.Background(), util.EUR)
+	require.NoError(t, err)
+
+	updated, err := testQueries.UpdateCurrencyEnabled(context.Background(), UpdateCurrencyEnabledParams{
+		Code:    util.EUR,
+		Enabled: !before.Enabled,
+	})
+	require.NoError(t, err)
+	require.Equal(t, !before.Enabled, updated.Enabled)
+	require.Equal(t, before.MinorUnits, updated.MinorUnits)
+	require.True(t, updated.UpdatedAt.After(before.UpdatedAt))
+
+	// restored - other tests open EUR accounts
+	_, err = testQueries.UpdateCurrencyEnabled(context.Background(), UpdateCurrencyEnabledParams{
+		Code:    util.EUR,
+		Enabled: before.Enabled,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.GetCurrency(context.Background(), "XYZ")
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestCreateAccountUnknownCurrency(t *testing.T) {
+	user := createRandomUser(t)
+	_, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: "XYZ",
+	})
+	require.Equal(t, ForeignKeyViolation, ErrorCode(err))
+}
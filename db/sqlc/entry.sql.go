@@ -7,52 +7,111 @@ package db
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
-INSERT INTO entries (account_id, amount)
-VALUES ($1, $2)
-RETURNING id, account_id, amount, created_at
+INSERT INTO entries (account_id, amount, memo, reference, metadata)
+VALUES (
+        $1,
+        $2,
+        $3,
+        $4,
+        COALESCE($5::jsonb, '{}')
+    )
+RETURNING id, account_id, amount, created_at, memo, reference, metadata
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID int64           `json:"account_id"`
+	Amount    int64           `json:"amount"`
+	Memo      string          `json:"memo"`
+	Reference string          `json:"reference"`
+	Metadata  json.RawMessage `json:"metadata"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.Memo,
+		arg.Reference,
+		arg.Metadata,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Memo,
+		&i.Reference,
+		&i.Metadata,
 	)
 	return i, err
 }
 
+const getEntriesSum = `-- name: GetEntriesSum :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS sum
+FROM entries
+WHERE account_id = $1
+`
+
+// an account's balance must always equal the sum of its entries
+func (q *Queries) GetEntriesSum(ctx context.Context, accountID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, getEntriesSum, accountID)
+	var sum int64
+	err := row.Scan(&sum)
+	return sum, err
+}
+
+const getEntriesSumBetween = `-- name: GetEntriesSumBetween :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS sum
+FROM entries
+WHERE account_id = $1
+    AND created_at >= $2
+    AND created_at <= $3
+`
+
+type GetEntriesSumBetweenParams struct {
+	AccountID int64     `json:"account_id"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+}
+
+// the sum of the entries of an account from from_time, included, to to_time, included
+func (q *Queries) GetEntriesSumBetween(ctx context.Context, arg GetEntriesSumBetweenParams) (int64, error) {
+	row := q.db.QueryRow(ctx, getEntriesSumBetween, arg.AccountID, arg.FromTime, arg.ToTime)
+	var sum int64
+	err := row.Scan(&sum)
+	return sum, err
+}
+
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at
+SELECT id, account_id, amount, created_at, memo, reference, metadata
 FROM entries
 WHERE id = $1
 LIMIT 1
 `
 
 func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, getEntry, id)
+	row := q.db.QueryRow(ctx, getEntry, id)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Memo,
+		&i.Reference,
+		&i.Metadata,
 	)
 	return i, err
 }
 
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at
+SELECT id, account_id, amount, created_at, memo, reference, metadata
 FROM entries
 WHERE account_id = $1
 ORDER BY id
@@ -66,7 +125,7 @@ type ListEntriesParams struct {
 }
 
 func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
-	rows, err := q.db.QueryContext(ctx, listEntries, arg.AccountID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listEntries, arg.AccountID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -79,14 +138,66 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Memo,
+			&i.Reference,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesBetween = `-- name: ListEntriesBetween :many
+SELECT id, account_id, amount, created_at, memo, reference, metadata
+FROM entries
+WHERE account_id = $1
+    AND created_at >= $2
+    AND created_at < $3
+ORDER BY created_at,
+    id
+LIMIT $4
+`
+
+type ListEntriesBetweenParams struct {
+	AccountID  int64     `json:"account_id"`
+	FromTime   time.Time `json:"from_time"`
+	ToTime     time.Time `json:"to_time"`
+	MaxEntries int32     `json:"max_entries"`
+}
+
+// the entries of a statement, in the order they were made
+func (q *Queries) ListEntriesBetween(ctx context.Context, arg ListEntriesBetweenParams) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listEntriesBetween,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.MaxEntries,
+	)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Memo,
+			&i.Reference,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -45,6 +45,21 @@ func TestGetEntry(t *testing.T) {
 	require.WithinDuration(t, entry.CreatedAt, entryFromDB.CreatedAt, time.Nanosecond)
 }
 
+func TestGetEntriesSum(t *testing.T) {
+	account := createRandomAccount(t)
+	sum, err := testQueries.GetEntriesSum(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Zero(t, sum)
+
+	var expected int64
+	for i := 0; i < 5; i++ {
+		expected += createRandomEntry(t, account).Amount
+	}
+	sum, err = testQueries.GetEntriesSum(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, expected, sum)
+}
+
 func TestListEntries(t *testing.T) {
 	account := createRandomAccount(t)
 	for i := 0; i < 10; i++ {
@@ -65,3 +80,30 @@ func TestListEntries(t *testing.T) {
 		require.Equal(t, arg.AccountID, entry.AccountID)
 	}
 }
+
+func TestListEntriesBetween(t *testing.T) {
+	account := createRandomAccount(t)
+	entries := make([]Entry, 5)
+	for i := range entries {
+		entries[i] = createRandomEntry(t, account)
+	}
+
+	// from included, to excluded
+	arg := ListEntriesBetweenParams{
+		AccountID:  account.ID,
+		FromTime:   entries[1].CreatedAt,
+		ToTime:     entries[4].CreatedAt,
+		MaxEntries: 10,
+	}
+	between, err := testQueries.ListEntriesBetween(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, between, 3)
+	for i, entry := range between {
+		require.Equal(t, entries[i+1].ID, entry.ID)
+	}
+
+	arg.MaxEntries = 2
+	between, err = testQueries.ListEntriesBetween(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, between, 2)
+}
@@ -5,19 +5,27 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/web3dev6/simplebank/apperr"
 )
 
 const (
-	ForeignKeyViolation = "23503"
-	UniqueViolation     = "23505"
+	CheckViolation       = "23514"
+	ForeignKeyViolation  = "23503"
+	UniqueViolation      = "23505"
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
 )
 
+// AccountsBalanceCheck is the constraint keeping the balance of an account above its held amount - see migration 000013
+const AccountsBalanceCheck = "accounts_balance_check"
+
 var ErrRecordNotFound = pgx.ErrNoRows
 
 var ErrUniqueViolation = &pgconn.PgError{
 	Code: UniqueViolation,
 }
 
+// ErrorCode returns the postgres error code of err, empty if err doesn't come from postgres
 func ErrorCode(err error) string {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
@@ -25,3 +33,32 @@ func ErrorCode(err error) string {
 	}
 	return ""
 }
+
+// DomainError maps an error of the store to a domain error - resource names what was queried, e.g. "account"
+// no rows is not found, constraint violations are conflicts (an overdraft is insufficient funds), domain errors pass as-is & anything else is internal
+func DomainError(err error, resource string) error {
+	if err == nil {
+		return nil
+	}
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	if errors.Is(err, ErrRecordNotFound) {
+		return apperr.NotFound(resource + " not found").WithCause(err)
+	}
+	switch ErrorCode(err) {
+	case UniqueViolation:
+		return apperr.Conflict(resource + " already exists").WithCause(err)
+	case ForeignKeyViolation:
+		return apperr.Conflict(resource + " references a record that doesn't exist").WithCause(err)
+	case CheckViolation:
+		// note* the balance check is the last line against an overdraft the txs' own checks missed
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == AccountsBalanceCheck {
+			return apperr.InsufficientFunds("account available balance is lower than the amount").WithCause(err)
+		}
+		return apperr.Conflict(resource + " breaks a constraint").WithCause(err)
+	}
+	return apperr.Internal(err)
+}
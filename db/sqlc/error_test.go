@@ -0,0 +1,33 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/apperr"
+)
+
+func TestErrorCode(t *testing.T) {
+	require.Empty(t, ErrorCode(nil))
+	require.Empty(t, ErrorCode(errors.New("conn reset")))
+	require.Equal(t, UniqueViolation, ErrorCode(&pgconn.PgError{Code: UniqueViolation}))
+	// wrapped, e.g. by execTx on a failed rollback
+	require.Equal(t, DeadlockDetected, ErrorCode(fmt.Errorf("tx error: %w", &pgconn.PgError{Code: DeadlockDetected})))
+}
+
+func TestDomainError(t *testing.T) {
+	require.NoError(t, DomainError(nil, "account"))
+	require.True(t, apperr.Is(DomainError(pgx.ErrNoRows, "account"), apperr.CodeNotFound))
+	require.True(t, apperr.Is(DomainError(&pgconn.PgError{Code: UniqueViolation}, "account"), apperr.CodeConflict))
+	require.True(t, apperr.Is(DomainError(&pgconn.PgError{Code: ForeignKeyViolation}, "account"), apperr.CodeConflict))
+	require.True(t, apperr.Is(DomainError(&pgconn.PgError{Code: CheckViolation, ConstraintName: AccountsBalanceCheck}, "transfer"), apperr.CodeInsufficientFunds))
+	require.True(t, apperr.Is(DomainError(&pgconn.PgError{Code: CheckViolation, ConstraintName: "accounts_held_amount_check"}, "hold"), apperr.CodeConflict))
+	require.True(t, apperr.Is(DomainError(errors.New("conn reset"), "account"), apperr.CodeInternal))
+
+	insufficientFunds := apperr.InsufficientFunds("balance too low")
+	require.Equal(t, insufficientFunds, DomainError(insufficientFunds, "account"))
+}
@@ -0,0 +1,223 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.19.0
+// source: hold.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createHold = `-- name: CreateHold :one
+INSERT INTO holds (
+        account_id,
+        to_account_id,
+        amount,
+        expires_at,
+        memo,
+        reference,
+        metadata
+    )
+VALUES (
+        $1,
+        $2,
+        $3,
+        $4,
+        $5,
+        $6,
+        COALESCE($7::jsonb, '{}')
+    )
+RETURNING id, account_id, to_account_id, amount, status, captured_amount, transfer_id, expires_at, created_at, updated_at, memo, reference, metadata
+`
+
+type CreateHoldParams struct {
+	AccountID   int64           `json:"account_id"`
+	ToAccountID int64           `json:"to_account_id"`
+	Amount      int64           `json:"amount"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+	Memo        string          `json:"memo"`
+	Reference   string          `json:"reference"`
+	Metadata    json.RawMessage `json:"metadata"`
+}
+
+func (q *Queries) CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	row := q.db.QueryRow(ctx, createHold,
+		arg.AccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ExpiresAt,
+		arg.Memo,
+		arg.Reference,
+		arg.Metadata,
+	)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Status,
+		&i.CapturedAmount,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Memo,
+		&i.Reference,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const getHold = `-- name: GetHold :one
+SELECT id, account_id, to_account_id, amount, status, captured_amount, transfer_id, expires_at, created_at, updated_at, memo, reference, metadata
+FROM holds
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetHold(ctx context.Context, id int64) (Hold, error) {
+	row := q.db.QueryRow(ctx, getHold, id)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Status,
+		&i.CapturedAmount,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Memo,
+		&i.Reference,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const getHoldForUpdate = `-- name: GetHoldForUpdate :one
+SELECT id, account_id, to_account_id, amount, status, captured_amount, transfer_id, expires_at, created_at, updated_at, memo, reference, metadata
+FROM holds
+WHERE id = $1
+LIMIT 1 FOR NO KEY
+UPDATE
+`
+
+func (q *Queries) GetHoldForUpdate(ctx context.Context, id int64) (Hold, error) {
+	row := q.db.QueryRow(ctx, getHoldForUpdate, id)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Status,
+		&i.CapturedAmount,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Memo,
+		&i.Reference,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const listExpiredHolds = `-- name: ListExpiredHolds :many
+SELECT id, account_id, to_account_id, amount, status, captured_amount, transfer_id, expires_at, created_at, updated_at, memo, reference, metadata
+FROM holds
+WHERE status = 'authorized'
+    AND expires_at <= $1
+ORDER BY expires_at
+LIMIT $2
+`
+
+type ListExpiredHoldsParams struct {
+	Now      time.Time `json:"now"`
+	MaxHolds int32     `json:"max_holds"`
+}
+
+func (q *Queries) ListExpiredHolds(ctx context.Context, arg ListExpiredHoldsParams) ([]Hold, error) {
+	rows, err := q.db.Query(ctx, listExpiredHolds, arg.Now, arg.MaxHolds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Hold{}
+	for rows.Next() {
+		var i Hold
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Status,
+			&i.CapturedAmount,
+			&i.TransferID,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Memo,
+			&i.Reference,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateHoldStatus = `-- name: UpdateHoldStatus :one
+UPDATE holds
+SET status = $1,
+    captured_amount = $2,
+    transfer_id = $3,
+    updated_at = now()
+WHERE id = $4
+RETURNING id, account_id, to_account_id, amount, status, captured_amount, transfer_id, expires_at, created_at, updated_at, memo, reference, metadata
+`
+
+type UpdateHoldStatusParams struct {
+	Status         string      `json:"status"`
+	CapturedAmount int64       `json:"captured_amount"`
+	TransferID     pgtype.Int8 `json:"transfer_id"`
+	ID             int64       `json:"id"`
+}
+
+func (q *Queries) UpdateHoldStatus(ctx context.Context, arg UpdateHoldStatusParams) (Hold, error) {
+	row := q.db.QueryRow(ctx, updateHoldStatus,
+		arg.Status,
+		arg.CapturedAmount,
+		arg.TransferID,
+		arg.ID,
+	)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Status,
+		&i.CapturedAmount,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Memo,
+		&i.Reference,
+		&i.Metadata,
+	)
+	return i, err
+}
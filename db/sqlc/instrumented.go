@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/web3dev6/simplebank/logging"
+	"github.com/web3dev6/simplebank/metrics"
+	"github.com/web3dev6/simplebank/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentedDBTX wraps a DBTX (*pgxpool.Pool or pgx.Tx), records the latency of every sqlc query and traces it as a child span of the caller
+// note* queries within a tx nest under the tx span, execTx passes its span down the ctx to fn
+type instrumentedDBTX struct {
+	db DBTX
+}
+
+// instrument wraps db so all queries made through the returned DBTX are measured & traced
+func instrument(db DBTX) DBTX {
+	return &instrumentedDBTX{db: db}
+}
+
+func (i *instrumentedDBTX) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := i.startQuerySpan(ctx, query)
+	defer span.End()
+
+	startTime := time.Now()
+	tag, err := i.db.Exec(ctx, query, args...)
+	observeQuery(ctx, span, query, time.Since(startTime), err)
+	return tag, err
+}
+
+// note* the span covers the query round trip, not the scan of the returned rows
+func (i *instrumentedDBTX) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := i.startQuerySpan(ctx, query)
+	defer span.End()
+
+	startTime := time.Now()
+	rows, err := i.db.Query(ctx, query, args...)
+	observeQuery(ctx, span, query, time.Since(startTime), err)
+	return rows, err
+}
+
+// note* pgx runs the query of a row lazily on Scan, so the span & latency cover the round trip & the scan
+func (i *instrumentedDBTX) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	ctx, span := i.startQuerySpan(ctx, query)
+	return &instrumentedRow{
+		row:       i.db.QueryRow(ctx, query, args...),
+		ctx:       ctx,
+		span:      span,
+		query:     query,
+		startTime: time.Now(),
+	}
+}
+
+// instrumentedRow ends the span of a QueryRow once the row is scanned
+type instrumentedRow struct {
+	row       pgx.Row
+	ctx       context.Context
+	span      trace.Span
+	query     string
+	startTime time.Time
+}
+
+func (r *instrumentedRow) Scan(dest ...interface{}) error {
+	defer r.span.End()
+	err := r.row.Scan(dest...)
+	// note* no rows is an expected result, not a failed query
+	queryErr := err
+	if errors.Is(err, pgx.ErrNoRows) {
+		queryErr = nil
+	}
+	observeQuery(r.ctx, r.span, r.query, time.Since(r.startTime), queryErr)
+	return err
+}
+
+// startQuerySpan starts a client span named after the sqlc query
+// note* the statement is recorded without args - it never holds user data
+func (i *instrumentedDBTX) startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "db "+queryName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperation(queryName(query)),
+			semconv.DBStatement(query),
+		),
+	)
+}
+
+// observeQuery records a finished query - metrics, span status & a debug line in the request-scoped log
+func observeQuery(ctx context.Context, span trace.Span, query string, duration time.Duration, err error) {
+	metrics.ObserveQuery(queryName(query), duration, err)
+	tracing.RecordError(span, err)
+	logging.Logger(ctx).Debug().Err(err).
+		Str("query", queryName(query)).
+		Dur("duration", duration).
+		Msg("db query")
+}
+
+// queryName returns the sqlc query name from the "-- name: GetAccount :one" header of every generated query
+func queryName(query string) string {
+	const prefix = "-- name: "
+	if !strings.HasPrefix(query, prefix) {
+		return "unnamed"
+	}
+	fields := strings.Fields(query[len(prefix):])
+	if len(fields) == 0 {
+		return "unnamed"
+	}
+	return fields[0]
+}
@@ -0,0 +1,335 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.19.0
+// source: interest.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createInterestAccrual = `-- name: CreateInterestAccrual :exec
+INSERT INTO interest_accruals (
+        account_id,
+        accrued_on,
+        balance,
+        annual_interest_rate_bps,
+        amount_micros
+    )
+VALUES ($1, $2, $3, $4, $5) ON CONFLICT (account_id, accrued_on) DO NOTHING
+`
+
+type CreateInterestAccrualParams struct {
+	AccountID             int64     `json:"account_id"`
+	AccruedOn             time.Time `json:"accrued_on"`
+	Balance               int64     `json:"balance"`
+	AnnualInterestRateBps int32     `json:"annual_interest_rate_bps"`
+	AmountMicros          int64     `json:"amount_micros"`
+}
+
+// note* a day accrued twice, by runs racing each other, is accrued once
+func (q *Queries) CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) error {
+	_, err := q.db.Exec(ctx, createInterestAccrual,
+		arg.AccountID,
+		arg.AccruedOn,
+		arg.Balance,
+		arg.AnnualInterestRateBps,
+		arg.AmountMicros,
+	)
+	return err
+}
+
+const createInterestPosting = `-- name: CreateInterestPosting :one
+INSERT INTO interest_postings (account_id, period_start, period_end)
+VALUES ($1, $2, $3) ON CONFLICT (account_id, period_start) DO NOTHING
+RETURNING id, account_id, period_start, period_end, accrued_micros, amount, carried_micros, transfer_id, created_at
+`
+
+type CreateInterestPostingParams struct {
+	AccountID   int64     `json:"account_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+}
+
+// no rows if the period is already posted
+func (q *Queries) CreateInterestPosting(ctx context.Context, arg CreateInterestPostingParams) (InterestPosting, error) {
+	row := q.db.QueryRow(ctx, createInterestPosting, arg.AccountID, arg.PeriodStart, arg.PeriodEnd)
+	var i InterestPosting
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.AccruedMicros,
+		&i.Amount,
+		&i.CarriedMicros,
+		&i.TransferID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLastInterestPosting = `-- name: GetLastInterestPosting :one
+SELECT id, account_id, period_start, period_end, accrued_micros, amount, carried_micros, transfer_id, created_at
+FROM interest_postings
+WHERE account_id = $1
+    AND period_start < $2
+ORDER BY period_start DESC
+LIMIT 1
+`
+
+type GetLastInterestPostingParams struct {
+	AccountID   int64     `json:"account_id"`
+	PeriodStart time.Time `json:"period_start"`
+}
+
+func (q *Queries) GetLastInterestPosting(ctx context.Context, arg GetLastInterestPostingParams) (InterestPosting, error) {
+	row := q.db.QueryRow(ctx, getLastInterestPosting, arg.AccountID, arg.PeriodStart)
+	var i InterestPosting
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.AccruedMicros,
+		&i.Amount,
+		&i.CarriedMicros,
+		&i.TransferID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUnpostedInterest = `-- name: GetUnpostedInterest :one
+SELECT COALESCE(SUM(amount_micros), 0)::bigint AS sum
+FROM interest_accruals
+WHERE account_id = $1
+    AND posting_id IS NULL
+`
+
+// the interest accrued & not posted yet, in millionths of a minor unit
+func (q *Queries) GetUnpostedInterest(ctx context.Context, accountID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, getUnpostedInterest, accountID)
+	var sum int64
+	err := row.Scan(&sum)
+	return sum, err
+}
+
+const listAccountsForInterestAccrual = `-- name: ListAccountsForInterestAccrual :many
+SELECT accounts.id, accounts.owner, accounts.balance, accounts.currency, accounts.created_at, accounts.held_amount, accounts.product,
+    account_products.annual_interest_rate_bps
+FROM accounts
+    JOIN account_products ON account_products.code = accounts.product
+WHERE accounts.id > $1
+    AND accounts.created_at < $2
+    AND account_products.annual_interest_rate_bps > 0
+    AND NOT account_products.internal
+    AND NOT EXISTS (
+        SELECT 1
+        FROM interest_accruals
+        WHERE interest_accruals.account_id = accounts.id
+            AND interest_accruals.accrued_on = $3
+    )
+ORDER BY accounts.id
+LIMIT $4
+`
+
+type ListAccountsForInterestAccrualParams struct {
+	AfterID     int64     `json:"after_id"`
+	DayEnd      time.Time `json:"day_end"`
+	AccruedOn   time.Time `json:"accrued_on"`
+	MaxAccounts int32     `json:"max_accounts"`
+}
+
+type ListAccountsForInterestAccrualRow struct {
+	Account               Account `json:"account"`
+	AnnualInterestRateBps int32   `json:"annual_interest_rate_bps"`
+}
+
+// the accounts of products paying interest, opened by the end of the day & not accrued yet for it, after the after_id cursor
+func (q *Queries) ListAccountsForInterestAccrual(ctx context.Context, arg ListAccountsForInterestAccrualParams) ([]ListAccountsForInterestAccrualRow, error) {
+	rows, err := q.db.Query(ctx, listAccountsForInterestAccrual,
+		arg.AfterID,
+		arg.DayEnd,
+		arg.AccruedOn,
+		arg.MaxAccounts,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountsForInterestAccrualRow{}
+	for rows.Next() {
+		var i ListAccountsForInterestAccrualRow
+		if err := rows.Scan(
+			&i.Account.ID,
+			&i.Account.Owner,
+			&i.Account.Balance,
+			&i.Account.Currency,
+			&i.Account.CreatedAt,
+			&i.Account.HeldAmount,
+			&i.Account.Product,
+			&i.AnnualInterestRateBps,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountsWithUnpostedInterest = `-- name: ListAccountsWithUnpostedInterest :many
+SELECT DISTINCT account_id
+FROM interest_accruals
+WHERE posting_id IS NULL
+    AND accrued_on < $1
+    AND account_id > $2
+ORDER BY account_id
+LIMIT $3
+`
+
+type ListAccountsWithUnpostedInterestParams struct {
+	PeriodEnd   time.Time `json:"period_end"`
+	AfterID     int64     `json:"after_id"`
+	MaxAccounts int32     `json:"max_accounts"`
+}
+
+// the accounts with interest accrued before period_end & not posted yet, after the after_id cursor
+func (q *Queries) ListAccountsWithUnpostedInterest(ctx context.Context, arg ListAccountsWithUnpostedInterestParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listAccountsWithUnpostedInterest, arg.PeriodEnd, arg.AfterID, arg.MaxAccounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var account_id int64
+		if err := rows.Scan(&account_id); err != nil {
+			return nil, err
+		}
+		items = append(items, account_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInterestPostings = `-- name: ListInterestPostings :many
+SELECT id, account_id, period_start, period_end, accrued_micros, amount, carried_micros, transfer_id, created_at
+FROM interest_postings
+WHERE account_id = $1
+ORDER BY period_start DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListInterestPostingsParams struct {
+	AccountID int64 `json:"account_id"`
+	Limit     int32 `json:"limit"`
+	Offset    int32 `json:"offset"`
+}
+
+func (q *Queries) ListInterestPostings(ctx context.Context, arg ListInterestPostingsParams) ([]InterestPosting, error) {
+	rows, err := q.db.Query(ctx, listInterestPostings, arg.AccountID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []InterestPosting{}
+	for rows.Next() {
+		var i InterestPosting
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.PeriodStart,
+			&i.PeriodEnd,
+			&i.AccruedMicros,
+			&i.Amount,
+			&i.CarriedMicros,
+			&i.TransferID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markInterestAccrualsPosted = `-- name: MarkInterestAccrualsPosted :one
+WITH posted AS (
+    UPDATE interest_accruals
+    SET posting_id = $1
+    WHERE account_id = $2
+        AND posting_id IS NULL
+        AND accrued_on < $3
+    RETURNING amount_micros
+)
+SELECT COALESCE(SUM(amount_micros), 0)::bigint AS sum
+FROM posted
+`
+
+type MarkInterestAccrualsPostedParams struct {
+	PostingID pgtype.Int8 `json:"posting_id"`
+	AccountID int64       `json:"account_id"`
+	PeriodEnd time.Time   `json:"period_end"`
+}
+
+// marks the unposted accruals of an account before period_end as paid by a posting, returns their sum
+func (q *Queries) MarkInterestAccrualsPosted(ctx context.Context, arg MarkInterestAccrualsPostedParams) (int64, error) {
+	row := q.db.QueryRow(ctx, markInterestAccrualsPosted, arg.PostingID, arg.AccountID, arg.PeriodEnd)
+	var sum int64
+	err := row.Scan(&sum)
+	return sum, err
+}
+
+const updateInterestPosting = `-- name: UpdateInterestPosting :one
+UPDATE interest_postings
+SET accrued_micros = $2,
+    amount = $3,
+    carried_micros = $4,
+    transfer_id = $5
+WHERE id = $1
+RETURNING id, account_id, period_start, period_end, accrued_micros, amount, carried_micros, transfer_id, created_at
+`
+
+type UpdateInterestPostingParams struct {
+	ID            int64       `json:"id"`
+	AccruedMicros int64       `json:"accrued_micros"`
+	Amount        int64       `json:"amount"`
+	CarriedMicros int64       `json:"carried_micros"`
+	TransferID    pgtype.Int8 `json:"transfer_id"`
+}
+
+func (q *Queries) UpdateInterestPosting(ctx context.Context, arg UpdateInterestPostingParams) (InterestPosting, error) {
+	row := q.db.QueryRow(ctx, updateInterestPosting,
+		arg.ID,
+		arg.AccruedMicros,
+		arg.Amount,
+		arg.CarriedMicros,
+		arg.TransferID,
+	)
+	var i InterestPosting
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.AccruedMicros,
+		&i.Amount,
+		&i.CarriedMicros,
+		&i.TransferID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
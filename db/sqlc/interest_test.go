@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSavingsAccount(t *testing.T) Account {
+	user := createRandomUser(t)
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: "EUR",
+		Product:  ProductSavings,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ProductSavings, account.Product)
+	return account
+}
+
+func accrueInterest(t *testing.T, account Account, day time.Time, amountMicros int64) {
+	err := testQueries.CreateInterestAccrual(context.Background(), CreateInterestAccrualParams{
+		AccountID:             account.ID,
+		AccruedOn:             day,
+		Balance:               account.Balance,
+		AnnualInterestRateBps: 200,
+		AmountMicros:          amountMicros,
+	})
+	require.NoError(t, err)
+}
+
+func TestListAccountsForInterestAccrual(t *testing.T) {
+	account := createRandomSavingsAccount(t)
+	checking := createRandomAccount(t)
+	day := BalanceSnapshotDay(time.Now())
+	list := func() []int64 {
+		rows, err := testQueries.ListAccountsForInterestAccrual(context.Background(), ListAccountsForInterestAccrualParams{
+			AfterID:     account.ID - 1,
+			DayEnd:      day.AddDate(0, 0, 1),
+			AccruedOn:   day,
+			MaxAccounts: 1000,
+		})
+		require.NoError(t, err)
+		var ids []int64
+		for _, row := range rows {
+			require.Positive(t, row.AnnualInterestRateBps)
+			ids = append(ids, row.Account.ID)
+		}
+		return ids
+	}
+
+	// checking pays no interest
+	require.Contains(t, list(), account.ID)
+	require.NotContains(t, list(), checking.ID)
+
+	// accrued once a day - a second accrual of the day is ignored
+	accrueInterest(t, account, day, 5479452)
+	accrueInterest(t, account, day, 1)
+	require.NotContains(t, list(), account.ID)
+	unposted, err := testQueries.GetUnpostedInterest(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(5479452), unposted)
+}
+
+func TestPostInterestTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomSavingsAccount(t)
+	september := time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+	october := september.AddDate(0, 1, 0)
+	november := october.AddDate(0, 1, 0)
+
+	// 30 days of 0.4 cents - 12 cents paid in september, nothing carried
+	for day := september; day.Before(october); day = day.AddDate(0, 0, 1) {
+		accrueInterest(t, account, day, 400_000)
+	}
+	// accrued in october, not paid by the september posting
+	accrueInterest(t, account, october, 700_000)
+
+	result, err := store.PostInterestTx(context.Background(), PostInterestTxParams{AccountID: account.ID, PeriodStart: september, PeriodEnd: october})
+	require.NoError(t, err)
+	require.False(t, result.AlreadyPosted)
+	require.Equal(t, int64(12_000_000), result.Posting.AccruedMicros)
+	require.Equal(t, int64(12), result.Posting.Amount)
+	require.Zero(t, result.Posting.CarriedMicros)
+	require.Equal(t, result.Transfer.ID, result.Posting.TransferID.Int64)
+	require.Equal(t, account.ID, result.Transfer.ToAccountID)
+	require.Equal(t, "interest-2026-09", result.Transfer.Reference)
+	require.Equal(t, account.Balance+12, result.Account.Balance)
+
+	// paid from the interest expense account of the currency
+	expense, err := store.GetAccount(context.Background(), result.Transfer.FromAccountID)
+	require.NoError(t, err)
+	require.Equal(t, SystemUsername, expense.Owner)
+	require.Equal(t, ProductInterestExpense, expense.Product)
+	require.Equal(t, account.Currency, expense.Currency)
+
+	// posted once
+	again, err := store.PostInterestTx(context.Background(), PostInterestTxParams{AccountID: account.ID, PeriodStart: september, PeriodEnd: october})
+	require.NoError(t, err)
+	require.True(t, again.AlreadyPosted)
+	updated, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Account.Balance, updated.Balance)
+
+	// less than a cent in october - nothing paid, all of it carried to november
+	result, err = store.PostInterestTx(context.Background(), PostInterestTxParams{AccountID: account.ID, PeriodStart: october, PeriodEnd: november})
+	require.NoError(t, err)
+	require.Zero(t, result.Posting.Amount)
+	require.Equal(t, int64(700_000), result.Posting.CarriedMicros)
+	require.False(t, result.Posting.TransferID.Valid)
+
+	// carried fraction adds up to a cent
+	accrueInterest(t, account, november, 400_000)
+	result, err = store.PostInterestTx(context.Background(), PostInterestTxParams{AccountID: account.ID, PeriodStart: november, PeriodEnd: november.AddDate(0, 1, 0)})
+	require.NoError(t, err)
+	require.Equal(t, int64(1_100_000), result.Posting.AccruedMicros)
+	require.Equal(t, int64(1), result.Posting.Amount)
+	require.Equal(t, int64(100_000), result.Posting.CarriedMicros)
+
+	unposted, err := store.GetUnpostedInterest(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Zero(t, unposted)
+}
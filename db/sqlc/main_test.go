@@ -1,17 +1,17 @@
 package db
 
 import (
-	"database/sql"
+	"context"
 	"log"
 	"os"
 	"testing"
 
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/web3dev6/simplebank/util"
 )
 
 var testQueries *Queries
-var testDB *sql.DB
+var testDB *pgxpool.Pool
 
 func TestMain(m *testing.M) {
 	// load config from app.env
@@ -20,7 +20,7 @@ func TestMain(m *testing.M) {
 		log.Fatal("cannot load config:", err)
 	}
 
-	testDB, err = sql.Open(config.DbDriver, config.DbSourceTest)
+	testDB, err = NewPool(context.Background(), config.DbSourceTest, PoolConfig{})
 	if err != nil {
 		log.Fatal("cannot connect to db:", err)
 	}
@@ -5,18 +5,74 @@
 package db
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Account struct {
-	ID      int64  `json:"id"`
-	Owner   string `json:"owner"`
-	Balance int64  `json:"balance"`
-	// can use enum here later
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	// ledger balance, the available balance is balance - held_amount
+	Balance int64 `json:"balance"`
+	// new accounts only in enabled currencies
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// sum of the authorized holds on the account
+	HeldAmount int64 `json:"held_amount"`
+	// checking or savings, interest_expense for the internal accounts
+	Product string `json:"product"`
+}
+
+type AccountBalanceSnapshot struct {
+	AccountID int64 `json:"account_id"`
+	// midnight UTC of the day of the snapshot
+	SnapshotAt time.Time `json:"snapshot_at"`
+	// sum of the entries of the account created before snapshot_at
+	Balance   int64     `json:"balance"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AccountProduct struct {
+	Code        string `json:"code"`
+	DisplayName string `json:"display_name"`
+	// in basis points - 200 is 2.00% a year
+	AnnualInterestRateBps int32 `json:"annual_interest_rate_bps"`
+	// accounts of the bank itself, owned by the simplebank user
+	Internal  bool      `json:"internal"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AuditEvent struct {
+	ID     int64  `json:"id"`
+	Action string `json:"action"`
+	// username of the authenticated user, or the attempted one for failed logins
+	Actor        string `json:"actor"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+	ClientIp     string `json:"client_ip"`
+	UserAgent    string `json:"user_agent"`
+	RequestID    string `json:"request_id"`
+	// json, not jsonb - kept byte for byte, the hash covers it
+	Before   []byte `json:"before"`
+	After    []byte `json:"after"`
+	PrevHash []byte `json:"prev_hash"`
+	// sha256 of prev_hash and all the other columns - editing or deleting a row breaks the chain
+	Hash      []byte    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Currency struct {
+	// ISO 4217 code
+	Code string `json:"code"`
+	// ISO 4217 exponent - 2 means an amount of 1234 is 12.34
+	MinorUnits int32 `json:"minor_units"`
+	// disabled currencies take no new accounts nor transfers, existing balances stay
+	Enabled     bool      `json:"enabled"`
+	DisplayName string    `json:"display_name"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 type Entry struct {
@@ -25,6 +81,58 @@ type Entry struct {
 	// it can be positive or negative
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	Memo      string    `json:"memo"`
+	// memo, reference & metadata are copied from the entry's transfer
+	Reference string          `json:"reference"`
+	Metadata  json.RawMessage `json:"metadata"`
+}
+
+type Hold struct {
+	ID          int64 `json:"id"`
+	AccountID   int64 `json:"account_id"`
+	ToAccountID int64 `json:"to_account_id"`
+	Amount      int64 `json:"amount"`
+	// authorized, then captured, voided or expired
+	Status         string `json:"status"`
+	CapturedAmount int64  `json:"captured_amount"`
+	// the transfer of the captured amount
+	TransferID pgtype.Int8 `json:"transfer_id"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	Memo       string      `json:"memo"`
+	// memo, reference & metadata are copied to the transfer of the capture
+	Reference string          `json:"reference"`
+	Metadata  json.RawMessage `json:"metadata"`
+}
+
+type InterestAccrual struct {
+	AccountID int64 `json:"account_id"`
+	// midnight UTC of the day of the accrual
+	AccruedOn time.Time `json:"accrued_on"`
+	// balance at the end of the day
+	Balance               int64 `json:"balance"`
+	AnnualInterestRateBps int32 `json:"annual_interest_rate_bps"`
+	// interest of the day in millionths of a minor unit
+	AmountMicros int64 `json:"amount_micros"`
+	// the posting that paid it, null until then
+	PostingID pgtype.Int8 `json:"posting_id"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type InterestPosting struct {
+	ID          int64     `json:"id"`
+	AccountID   int64     `json:"account_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	// accruals paid by the posting plus the carry of the previous one
+	AccruedMicros int64 `json:"accrued_micros"`
+	// whole minor units paid, the rest of accrued_micros is carried
+	Amount        int64 `json:"amount"`
+	CarriedMicros int64 `json:"carried_micros"`
+	// from the interest_expense account, null when nothing is paid
+	TransferID pgtype.Int8 `json:"transfer_id"`
+	CreatedAt  time.Time   `json:"created_at"`
 }
 
 type Session struct {
@@ -38,6 +146,15 @@ type Session struct {
 	CreatedAt    time.Time `json:"created_at"`
 }
 
+// monthly statements emailed, so a retried run does not send them again
+type StatementEmail struct {
+	ID          int64     `json:"id"`
+	AccountID   int64     `json:"account_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	SentAt      time.Time `json:"sent_at"`
+}
+
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
@@ -45,6 +162,25 @@ type Transfer struct {
 	// it must be positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	Memo      string    `json:"memo"`
+	// external reference, e.g. an invoice number - empty for none
+	Reference string `json:"reference"`
+	// json object of at most 4KB, checked by the api
+	Metadata json.RawMessage `json:"metadata"`
+}
+
+type TransferReversal struct {
+	ID                 int64 `json:"id"`
+	OriginalTransferID int64 `json:"original_transfer_id"`
+	// the compensating transfer, from the original to account back to the original from account
+	ReversalTransferID int64 `json:"reversal_transfer_id"`
+	// partial refunds allowed, their sum never exceeds the original amount
+	Amount int64 `json:"amount"`
+	// duplicate, fraud, customer_request, processing_error or other
+	Reason string `json:"reason"`
+	// username of the admin who reversed it
+	ReversedBy string    `json:"reversed_by"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type User struct {
@@ -55,6 +191,8 @@ type User struct {
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 	IsEmailVerified   bool      `json:"is_email_verified"`
+	// depositor, admin or system
+	Role string `json:"role"`
 }
 
 type VerifyEmail struct {
@@ -11,24 +11,85 @@ import (
 )
 
 type Querier interface {
+	AddAccountHeldAmount(ctx context.Context, arg AddAccountHeldAmountParams) (Account, error)
+	// note* an empty product is a checking account
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
+	// snapshots the balances at snapshot_at of the next max_accounts accounts after the after_id cursor
+	// each one is its latest snapshot before snapshot_at plus the entries since, all of them if it has none
+	// note* an existing snapshot is kept, a rerun of the same day only adds the missing ones
+	CreateBalanceSnapshots(ctx context.Context, arg CreateBalanceSnapshotsParams) (CreateBalanceSnapshotsRow, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error)
+	// note* a day accrued twice, by runs racing each other, is accrued once
+	CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) error
+	// no rows if the period is already posted
+	CreateInterestPosting(ctx context.Context, arg CreateInterestPostingParams) (InterestPosting, error)
+	// note* one per currency & internal product, created the first time it's needed
+	CreateInternalAccount(ctx context.Context, arg CreateInternalAccountParams) error
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	// note* a statement emailed twice, by runs racing each other, is recorded once
+	CreateStatementEmail(ctx context.Context, arg CreateStatementEmailParams) error
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateTransferReversal(ctx context.Context, arg CreateTransferReversalParams) (TransferReversal, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
 	DeleteAccount(ctx context.Context, id int64) error
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	// the checking account of an owner in a currency, at most one - see the (owner, currency, product) unique index
+	GetAccountByOwnerAndCurrency(ctx context.Context, arg GetAccountByOwnerAndCurrencyParams) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountProduct(ctx context.Context, code string) (AccountProduct, error)
 	GetCountForAccounts(ctx context.Context) (int64, error)
 	GetCountForUsers(ctx context.Context) (int64, error)
+	GetCurrency(ctx context.Context, code string) (Currency, error)
+	// an account's balance must always equal the sum of its entries
+	GetEntriesSum(ctx context.Context, accountID int64) (int64, error)
+	// the sum of the entries of an account from from_time, included, to to_time, included
+	GetEntriesSumBetween(ctx context.Context, arg GetEntriesSumBetweenParams) (int64, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetHold(ctx context.Context, id int64) (Hold, error)
+	GetHoldForUpdate(ctx context.Context, id int64) (Hold, error)
+	GetInternalAccount(ctx context.Context, arg GetInternalAccountParams) (Account, error)
+	GetLastAuditEvent(ctx context.Context) (AuditEvent, error)
+	GetLastInterestPosting(ctx context.Context, arg GetLastInterestPostingParams) (InterestPosting, error)
+	// the latest snapshot of an account at or before at
+	GetLatestBalanceSnapshot(ctx context.Context, arg GetLatestBalanceSnapshotParams) (AccountBalanceSnapshot, error)
+	GetReversedAmount(ctx context.Context, originalTransferID int64) (int64, error)
 	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
+	// the interest accrued & not posted yet, in millionths of a minor unit
+	GetUnpostedInterest(ctx context.Context, accountID int64) (int64, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	// same as GetUser, but never routed to a read replica - the role looked up to authorize admins
+	GetUserForAuth(ctx context.Context, username string) (User, error)
+	IsReversalTransfer(ctx context.Context, reversalTransferID int64) (bool, error)
+	ListAccountProducts(ctx context.Context) ([]AccountProduct, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	// the accounts of products paying interest, opened by the end of the day & not accrued yet for it, after the after_id cursor
+	ListAccountsForInterestAccrual(ctx context.Context, arg ListAccountsForInterestAccrualParams) ([]ListAccountsForInterestAccrualRow, error)
+	// the accounts with interest accrued before period_end & not posted yet, after the after_id cursor
+	ListAccountsWithUnpostedInterest(ctx context.Context, arg ListAccountsWithUnpostedInterestParams) ([]int64, error)
+	// the accounts opened before period_end, not yet emailed their statement of the period, after the after_id cursor
+	ListAccountsWithoutStatementEmail(ctx context.Context, arg ListAccountsWithoutStatementEmailParams) ([]Account, error)
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error)
+	ListAuditEventsAfter(ctx context.Context, arg ListAuditEventsAfterParams) ([]AuditEvent, error)
+	ListCurrencies(ctx context.Context) ([]Currency, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	// the entries of a statement, in the order they were made
+	ListEntriesBetween(ctx context.Context, arg ListEntriesBetweenParams) ([]Entry, error)
+	ListExpiredHolds(ctx context.Context, arg ListExpiredHoldsParams) ([]Hold, error)
+	ListInterestPostings(ctx context.Context, arg ListInterestPostingsParams) ([]InterestPosting, error)
+	ListTransferReversals(ctx context.Context, originalTransferID int64) ([]TransferReversal, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	// the transfers with an external reference from or to an account of owner, with their currency
+	ListTransfersByReference(ctx context.Context, arg ListTransfersByReferenceParams) ([]ListTransfersByReferenceRow, error)
+	// serializes appends to the hash chain, released on commit/rollback
+	LockAuditChain(ctx context.Context, lockKey int64) error
+	// marks the unposted accruals of an account before period_end as paid by a posting, returns their sum
+	MarkInterestAccrualsPosted(ctx context.Context, arg MarkInterestAccrualsPostedParams) (int64, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
 	// -- name: UpdateAccountBalance :one
 	// UPDATE accounts
@@ -36,6 +97,10 @@ type Querier interface {
 	// WHERE id = $1
 	// RETURNING *;
 	UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error)
+	UpdateAccountProductRate(ctx context.Context, arg UpdateAccountProductRateParams) (AccountProduct, error)
+	UpdateCurrencyEnabled(ctx context.Context, arg UpdateCurrencyEnabledParams) (Currency, error)
+	UpdateHoldStatus(ctx context.Context, arg UpdateHoldStatusParams) (Hold, error)
+	UpdateInterestPosting(ctx context.Context, arg UpdateInterestPostingParams) (InterestPosting, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
 	UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (VerifyEmail, error)
 }
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+/*
+   Read replica routing
+	- GetAccount, GetUser, ListAccounts, ListTransfers & ListEntries read from the replica while its lag is within MaxLag
+	- everything else - execTx, ...ForUpdate queries & all writes - always goes to the primary
+	- so does GetUserForAuth - it's the role looked up to authorize admins, a revoked admin must lose access right away
+   Note* the replica lag is checked every CheckInterval in the background of the reads, a replica lagging behind, not streaming,
+        unreachable or failing a read is skipped for the primary until its next check
+   Note* a row not found on the replica is read again from the primary - it may just not be replicated yet
+*/
+
+// lagQuery returns whether a replica is streaming from the primary & its replay lag in seconds
+// the lag is 0 when it has replayed all it received (e.g. an idle primary) - which a replica cut off from the primary has too,
+// so it's only believed while the wal receiver is streaming
+// note* a primary isn't in recovery, it's streaming with a lag of 0
+// note* the status is null for roles without pg_read_all_stats, the wal receiver running is all that's known then
+const lagQuery = `SELECT
+	NOT pg_is_in_recovery() OR EXISTS (
+		SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming' OR status IS NULL
+	),
+	COALESCE(
+	CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END, 0)::float8`
+
+// errReplicaNotStreaming - the replica isn't receiving wal from the primary, however small its lag looks
+var errReplicaNotStreaming = errors.New("db replica not streaming from the primary")
+
+// ReplicaConfig configures the routing of reads to a replica
+type ReplicaConfig struct {
+	MaxLag        time.Duration // replicas lagging more are skipped
+	CheckInterval time.Duration // how often the lag is checked
+}
+
+// replica is a read replica & its last known state
+type replica struct {
+	queries  *Queries
+	connPool *pgxpool.Pool
+	config   ReplicaConfig
+
+	mutex     sync.Mutex
+	usable    bool
+	checkedAt time.Time
+	checking  bool
+}
+
+// NewReplicatedStore creates a store on connPool, routing the reads it can to replicaPool
+func NewReplicatedStore(connPool *pgxpool.Pool, replicaPool *pgxpool.Pool, replicaConfig ReplicaConfig, txConfig TxConfig) Store {
+	store := NewStore(connPool, txConfig).(*SQLStore)
+	store.replica = &replica{
+		queries:  New(instrument(replicaPool)),
+		connPool: replicaPool,
+		config:   replicaConfig,
+	}
+	return store
+}
+
+// reader returns the replica's queries if it's usable - a check is started in the background when the last one is too old
+// note* unusable until checked once, the first reads go to the primary
+func (r *replica) reader() (*Queries, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.checking && time.Since(r.checkedAt) >= r.config.CheckInterval {
+		r.checking = true
+		go r.check()
+	}
+	return r.queries, r.usable
+}
+
+// check measures the lag of the replica
+func (r *replica) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.CheckInterval)
+	defer cancel()
+
+	var streaming bool
+	var lagSeconds float64
+	err := r.connPool.QueryRow(ctx, lagQuery).Scan(&streaming, &lagSeconds)
+	if err == nil && !streaming {
+		err = errReplicaNotStreaming
+	}
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	usable := err == nil && lag <= r.config.MaxLag
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if usable != r.usable {
+		if usable {
+			log.Info().Dur("lag", lag).Msg("db replica usable")
+		} else {
+			log.Warn().Err(err).Dur("lag", lag).Dur("max_lag", r.config.MaxLag).Msg("db replica unusable, reading from the primary")
+		}
+	}
+	r.usable = usable
+	r.checkedAt = time.Now()
+	r.checking = false
+}
+
+// failed marks the replica unusable after a failed read, until its next check
+func (r *replica) failed(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.usable {
+		log.Warn().Err(err).Msg("db replica read failed, reading from the primary")
+	}
+	r.usable = false
+	r.checkedAt = time.Now()
+}
+
+// readReplica runs read on the replica if there's a usable one, else on the primary
+// a failed read, or a row not found, on the replica is run again on the primary
+func readReplica[T any](ctx context.Context, store *SQLStore, read func(q *Queries) (T, error)) (T, error) {
+	if store.replica != nil {
+		if q, ok := store.replica.reader(); ok {
+			result, err := read(q)
+			if err == nil {
+				return result, nil
+			}
+			if !errors.Is(err, ErrRecordNotFound) && ctx.Err() == nil {
+				store.replica.failed(err)
+			}
+		}
+	}
+	return read(store.Queries)
+}
+
+func (store *SQLStore) GetAccount(ctx context.Context, id int64) (Account, error) {
+	return readReplica(ctx, store, func(q *Queries) (Account, error) {
+		return q.GetAccount(ctx, id)
+	})
+}
+
+func (store *SQLStore) GetUser(ctx context.Context, username string) (User, error) {
+	return readReplica(ctx, store, func(q *Queries) (User, error) {
+		return q.GetUser(ctx, username)
+	})
+}
+
+func (store *SQLStore) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	return readReplica(ctx, store, func(q *Queries) ([]Account, error) {
+		return q.ListAccounts(ctx, arg)
+	})
+}
+
+func (store *SQLStore) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	return readReplica(ctx, store, func(q *Queries) ([]Transfer, error) {
+		return q.ListTransfers(ctx, arg)
+	})
+}
+
+func (store *SQLStore) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	return readReplica(ctx, store, func(q *Queries) ([]Entry, error) {
+		return q.ListEntries(ctx, arg)
+	})
+}
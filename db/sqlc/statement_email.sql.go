@@ -0,0 +1,84 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.19.0
+// source: statement_email.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createStatementEmail = `-- name: CreateStatementEmail :exec
+INSERT INTO statement_emails (account_id, period_start, period_end)
+VALUES ($1, $2, $3) ON CONFLICT (account_id, period_start) DO NOTHING
+`
+
+type CreateStatementEmailParams struct {
+	AccountID   int64     `json:"account_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+}
+
+// note* a statement emailed twice, by runs racing each other, is recorded once
+func (q *Queries) CreateStatementEmail(ctx context.Context, arg CreateStatementEmailParams) error {
+	_, err := q.db.Exec(ctx, createStatementEmail, arg.AccountID, arg.PeriodStart, arg.PeriodEnd)
+	return err
+}
+
+const listAccountsWithoutStatementEmail = `-- name: ListAccountsWithoutStatementEmail :many
+SELECT id, owner, balance, currency, created_at, held_amount, product
+FROM accounts
+WHERE accounts.id > $1
+    AND accounts.created_at < $2
+    AND NOT EXISTS (
+        SELECT 1
+        FROM statement_emails
+        WHERE statement_emails.account_id = accounts.id
+            AND statement_emails.period_start = $3
+    )
+ORDER BY accounts.id
+LIMIT $4
+`
+
+type ListAccountsWithoutStatementEmailParams struct {
+	AfterID     int64     `json:"after_id"`
+	PeriodEnd   time.Time `json:"period_end"`
+	PeriodStart time.Time `json:"period_start"`
+	MaxAccounts int32     `json:"max_accounts"`
+}
+
+// the accounts opened before period_end, not yet emailed their statement of the period, after the after_id cursor
+func (q *Queries) ListAccountsWithoutStatementEmail(ctx context.Context, arg ListAccountsWithoutStatementEmailParams) ([]Account, error) {
+	rows, err := q.db.Query(ctx, listAccountsWithoutStatementEmail,
+		arg.AfterID,
+		arg.PeriodEnd,
+		arg.PeriodStart,
+		arg.MaxAccounts,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.HeldAmount,
+			&i.Product,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAccountsWithoutStatementEmail(t *testing.T) {
+	account := createRandomAccount(t)
+	periodStart := time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Now().Add(time.Hour)
+
+	arg := ListAccountsWithoutStatementEmailParams{
+		AfterID:     account.ID - 1,
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		MaxAccounts: 1,
+	}
+	accounts, err := testQueries.ListAccountsWithoutStatementEmail(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, account.ID, accounts[0].ID)
+
+	// recorded twice, only once
+	for i := 0; i < 2; i++ {
+		err = testQueries.CreateStatementEmail(context.Background(), CreateStatementEmailParams{
+			AccountID:   account.ID,
+			PeriodStart: periodStart,
+			PeriodEnd:   periodEnd,
+		})
+		require.NoError(t, err)
+	}
+	accounts, err = testQueries.ListAccountsWithoutStatementEmail(context.Background(), arg)
+	require.NoError(t, err)
+	for _, a := range accounts {
+		require.NotEqual(t, account.ID, a.ID)
+	}
+
+	// opened after the period
+	arg.PeriodStart = periodStart.AddDate(0, 1, 0)
+	arg.PeriodEnd = account.CreatedAt
+	accounts, err = testQueries.ListAccountsWithoutStatementEmail(context.Background(), arg)
+	require.NoError(t, err)
+	for _, a := range accounts {
+		require.NotEqual(t, account.ID, a.ID)
+	}
+}
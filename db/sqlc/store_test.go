@@ -2,17 +2,25 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/util"
 )
 
 func TestTransferTx(t *testing.T) {
 	store := NewStore(testDB)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountIn(t, util.USD, 1000)
+	account2 := createRandomAccountIn(t, util.USD, 1000)
 	// log.Println(">> before:", account1.Balance, account2.Balance)
 
 	// run n concurrent transfer transactions - robust testing
@@ -112,8 +120,8 @@ func TestTransferTx(t *testing.T) {
 func TestTransferTxDeadlock(t *testing.T) {
 	store := NewStore(testDB)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountIn(t, util.USD, 1000)
+	account2 := createRandomAccountIn(t, util.USD, 1000)
 	// log.Println(">> before:", account1.Balance, account2.Balance)
 
 	// run n concurrent transfer transactions
@@ -158,3 +166,710 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestFundAccountTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+	amount := int64(100)
+
+	result, err := store.FundAccountTx(context.Background(), FundAccountTxParams{
+		AccountID: account.ID,
+		Amount:    amount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.ID, result.Account.ID)
+	require.Equal(t, account.Balance+amount, result.Account.Balance)
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, amount, result.Entry.Amount)
+
+	_, err = store.GetEntry(context.Background(), result.Entry.ID)
+	require.NoError(t, err)
+}
+
+// createRandomAccountIn creates a random account in currency with balance
+func createRandomAccountIn(t *testing.T, currency string, balance int64) Account {
+	user := createRandomUser(t)
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  balance,
+		Currency: currency,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestBatchTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	// payroll - one account paying three, one of them twice
+	payer := createRandomAccountIn(t, util.USD, 1000)
+	payees := []Account{
+		createRandomAccountIn(t, util.USD, 0),
+		createRandomAccountIn(t, util.USD, 0),
+		createRandomAccountIn(t, util.USD, 0),
+	}
+	legs := []BatchTransferLeg{
+		{FromAccountID: payer.ID, ToAccountID: payees[2].ID, Amount: 300},
+		{FromAccountID: payer.ID, ToAccountID: payees[0].ID, Amount: 100},
+		{FromAccountID: payer.ID, ToAccountID: payees[1].ID, Amount: 200},
+		{FromAccountID: payer.ID, ToAccountID: payees[0].ID, Amount: 50},
+	}
+
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{Currency: util.USD, Legs: legs})
+	require.NoError(t, err)
+
+	require.Len(t, result.Transfers, len(legs))
+	require.Len(t, result.Entries, 2*len(legs))
+	for i, leg := range legs {
+		transfer := result.Transfers[i]
+		require.NotZero(t, transfer.ID)
+		require.Equal(t, leg.FromAccountID, transfer.FromAccountID)
+		require.Equal(t, leg.ToAccountID, transfer.ToAccountID)
+		require.Equal(t, leg.Amount, transfer.Amount)
+
+		fromEntry, toEntry := result.Entries[2*i], result.Entries[2*i+1]
+		require.Equal(t, leg.FromAccountID, fromEntry.AccountID)
+		require.Equal(t, -leg.Amount, fromEntry.Amount)
+		require.Equal(t, leg.ToAccountID, toEntry.AccountID)
+		require.Equal(t, leg.Amount, toEntry.Amount)
+	}
+
+	// every account once, by id
+	expected := map[int64]int64{payer.ID: 350, payees[0].ID: 150, payees[1].ID: 200, payees[2].ID: 300}
+	require.Len(t, result.Accounts, len(expected))
+	for i, account := range result.Accounts {
+		if i > 0 {
+			require.Less(t, result.Accounts[i-1].ID, account.ID)
+		}
+		require.Equal(t, expected[account.ID], account.Balance)
+
+		stored, err := testQueries.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, expected[account.ID], stored.Balance)
+	}
+}
+
+func TestBatchTransferTxAllOrNothing(t *testing.T) {
+	store := NewStore(testDB)
+
+	payer := createRandomAccountIn(t, util.USD, 100)
+	payee1 := createRandomAccountIn(t, util.USD, 0)
+	payee2 := createRandomAccountIn(t, util.USD, 0)
+	eurPayee := createRandomAccountIn(t, util.EUR, 0)
+	expense, err := internalAccount(context.Background(), testQueries, util.USD, ProductInterestExpense)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		legs []BatchTransferLeg
+		code apperr.Code
+	}{
+		{
+			// each leg is covered on its own, not their total
+			name: "InsufficientFunds",
+			legs: []BatchTransferLeg{
+				{FromAccountID: payer.ID, ToAccountID: payee1.ID, Amount: 60},
+				{FromAccountID: payer.ID, ToAccountID: payee2.ID, Amount: 60},
+			},
+			code: apperr.CodeInsufficientFunds,
+		},
+		{
+			name: "CurrencyMismatch",
+			legs: []BatchTransferLeg{
+				{FromAccountID: payer.ID, ToAccountID: payee1.ID, Amount: 10},
+				{FromAccountID: payer.ID, ToAccountID: eurPayee.ID, Amount: 10},
+			},
+			code: apperr.CodeValidation,
+		},
+		{
+			name: "AccountNotFound",
+			legs: []BatchTransferLeg{
+				{FromAccountID: payer.ID, ToAccountID: payee1.ID, Amount: 10},
+				{FromAccountID: payer.ID, ToAccountID: math.MaxInt64, Amount: 10},
+			},
+			code: apperr.CodeNotFound,
+		},
+		{
+			// the bank's own accounts are unknown to a batch
+			name: "InternalAccount",
+			legs: []BatchTransferLeg{
+				{FromAccountID: payer.ID, ToAccountID: payee1.ID, Amount: 10},
+				{FromAccountID: payer.ID, ToAccountID: expense.ID, Amount: 10},
+			},
+			code: apperr.CodeNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{Currency: util.USD, Legs: tc.legs})
+			require.True(t, apperr.Is(err, tc.code), err)
+
+			// nothing moved, no entries written
+			for _, account := range []Account{payer, payee1, payee2, eurPayee} {
+				stored, err := testQueries.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+				require.Equal(t, account.Balance, stored.Balance)
+
+				sum, err := testQueries.GetEntriesSum(context.Background(), account.ID)
+				require.NoError(t, err)
+				require.Zero(t, sum)
+			}
+		})
+	}
+}
+
+func TestBatchTransferTxDeadlock(t *testing.T) {
+	store := NewStore(testDB)
+
+	accounts := []Account{
+		createRandomAccountIn(t, util.USD, 1000),
+		createRandomAccountIn(t, util.USD, 1000),
+		createRandomAccountIn(t, util.USD, 1000),
+	}
+
+	// half the batches go round the accounts one way, half the other way, with plain transfers in between
+	n := 10
+	amount := int64(10)
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		a, b, c := accounts[0].ID, accounts[1].ID, accounts[2].ID
+		if i%2 == 1 {
+			a, c = c, a
+		}
+		go func() {
+			_, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+				Currency: util.USD,
+				Legs: []BatchTransferLeg{
+					{FromAccountID: a, ToAccountID: b, Amount: amount},
+					{FromAccountID: b, ToAccountID: c, Amount: amount},
+					{FromAccountID: c, ToAccountID: a, Amount: amount},
+				},
+			})
+			errs <- err
+		}()
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{FromAccountID: c, ToAccountID: a, Amount: amount})
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < 2*n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	// every batch nets to zero, half the transfers go each way
+	for _, account := range accounts {
+		updated, err := testQueries.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updated.Balance)
+	}
+}
+
+func TestBatchNetAmounts(t *testing.T) {
+	nets, err := batchNetAmounts(BatchTransferTxParams{
+		Currency: util.USD,
+		Legs: []BatchTransferLeg{
+			{FromAccountID: 1, ToAccountID: 2, Amount: 100},
+			{FromAccountID: 1, ToAccountID: 3, Amount: 50},
+			{FromAccountID: 3, ToAccountID: 1, Amount: 20},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[int64]int64{1: -130, 2: 100, 3: 30}, nets)
+
+	testCases := []struct {
+		name  string
+		legs  []BatchTransferLeg
+		field string
+	}{
+		{name: "NoLegs", legs: nil, field: "legs"},
+		{name: "TooManyLegs", legs: make([]BatchTransferLeg, MaxBatchLegs+1), field: "legs"},
+		{name: "SameAccount", legs: []BatchTransferLeg{{FromAccountID: 1, ToAccountID: 1, Amount: 1}}, field: "legs[0]"},
+		{name: "InvalidAccount", legs: []BatchTransferLeg{{FromAccountID: 0, ToAccountID: 1, Amount: 1}}, field: "legs[0]"},
+		{name: "NotPositive", legs: []BatchTransferLeg{
+			{FromAccountID: 1, ToAccountID: 2, Amount: 1},
+			{FromAccountID: 1, ToAccountID: 2, Amount: 0},
+		}, field: "legs[1].amount"},
+		{name: "Overflow", legs: []BatchTransferLeg{
+			{FromAccountID: 1, ToAccountID: 2, Amount: math.MaxInt64},
+			{FromAccountID: 3, ToAccountID: 2, Amount: 1},
+		}, field: "legs"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := batchNetAmounts(BatchTransferTxParams{Currency: util.USD, Legs: tc.legs})
+			var appErr *apperr.Error
+			require.ErrorAs(t, err, &appErr)
+			require.Equal(t, apperr.CodeValidation, appErr.Code)
+			require.Equal(t, tc.field, appErr.Violations[0].Field)
+		})
+	}
+}
+
+func TestTransferTxDetails(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createRandomAccountIn(t, util.USD, 1000)
+	account2 := createRandomAccountIn(t, util.USD, 0)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		TransferDetails: TransferDetails{
+			Memo:      "lunch",
+			Reference: util.RandomString(12),
+			Metadata:  json.RawMessage(`{"split": 3}`),
+		},
+	})
+	require.NoError(t, err)
+	for _, details := range []TransferDetails{
+		{Memo: result.Transfer.Memo, Reference: result.Transfer.Reference, Metadata: result.Transfer.Metadata},
+		{Memo: result.FromEntry.Memo, Reference: result.FromEntry.Reference, Metadata: result.FromEntry.Metadata},
+		{Memo: result.ToEntry.Memo, Reference: result.ToEntry.Reference, Metadata: result.ToEntry.Metadata},
+	} {
+		require.Equal(t, "lunch", details.Memo)
+		require.Equal(t, result.Transfer.Reference, details.Reference)
+		require.JSONEq(t, `{"split": 3}`, string(details.Metadata))
+	}
+
+	// no details - an empty metadata object
+	result, err = store.TransferTx(context.Background(), TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10})
+	require.NoError(t, err)
+	require.Empty(t, result.Transfer.Memo)
+	require.JSONEq(t, `{}`, string(result.Transfer.Metadata))
+	require.JSONEq(t, `{}`, string(result.ToEntry.Metadata))
+}
+
+func TestTransferDetailsValidate(t *testing.T) {
+	require.Empty(t, TransferDetails{}.Validate())
+	require.Empty(t, TransferDetails{Metadata: json.RawMessage("null")}.Validate())
+	require.Empty(t, TransferDetails{
+		Memo:      strings.Repeat("€", MaxMemoLength), // characters, not bytes
+		Reference: strings.Repeat("r", MaxReferenceLength),
+		Metadata:  json.RawMessage(`{"a": [1, 2]}`),
+	}.Validate())
+
+	violations := TransferDetails{
+		Memo:      strings.Repeat("m", MaxMemoLength+1),
+		Reference: strings.Repeat("r", MaxReferenceLength+1),
+		Metadata:  json.RawMessage(`[1, 2]`),
+	}.Validate()
+	require.Len(t, violations, 3)
+	require.Equal(t, "memo", violations[0].Field)
+	require.Equal(t, "reference", violations[1].Field)
+	require.Equal(t, "metadata", violations[2].Field)
+
+	bigMetadata := json.RawMessage(`{"a": "` + strings.Repeat("x", MaxMetadataSize) + `"}`)
+	violations = TransferDetails{Metadata: bigMetadata}.Validate()
+	require.Len(t, violations, 1)
+	require.Contains(t, violations[0].Description, "bytes")
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createRandomUser(t)
+
+	payer := createRandomAccountIn(t, util.USD, 1000)
+	payee := createRandomAccountIn(t, util.USD, 0)
+	transferred, err := store.TransferTx(context.Background(), TransferTxParams{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: 500})
+	require.NoError(t, err)
+	original := transferred.Transfer
+
+	// partial refund
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.ID,
+		Amount:     200,
+		Reason:     ReversalReasonCustomerRequest,
+		ReversedBy: admin.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, payee.ID, result.Transfer.FromAccountID)
+	require.Equal(t, payer.ID, result.Transfer.ToAccountID)
+	require.Equal(t, int64(200), result.Transfer.Amount)
+	require.Equal(t, int64(-200), result.FromEntry.Amount)
+	require.Equal(t, int64(200), result.ToEntry.Amount)
+	require.Equal(t, int64(300), result.FromAccount.Balance)
+	require.Equal(t, int64(700), result.ToAccount.Balance)
+	require.Equal(t, original.ID, result.Reversal.OriginalTransferID)
+	require.Equal(t, result.Transfer.ID, result.Reversal.ReversalTransferID)
+	require.Equal(t, admin.Username, result.Reversal.ReversedBy)
+	require.Equal(t, int64(300), result.RemainingAmount)
+
+	// more than what's left
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.ID,
+		Amount:     301,
+		Reason:     ReversalReasonCustomerRequest,
+		ReversedBy: admin.Username,
+	})
+	require.True(t, apperr.Is(err, apperr.CodeValidation), err)
+
+	// a reversal can't be reversed
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: result.Transfer.ID,
+		Reason:     ReversalReasonOther,
+		ReversedBy: admin.Username,
+	})
+	require.True(t, apperr.Is(err, apperr.CodeValidation), err)
+
+	// the rest, no amount
+	result, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.ID,
+		Reason:     ReversalReasonDuplicate,
+		ReversedBy: admin.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(300), result.Transfer.Amount)
+	require.Zero(t, result.RemainingAmount)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.ID,
+		Reason:     ReversalReasonDuplicate,
+		ReversedBy: admin.Username,
+	})
+	require.True(t, apperr.Is(err, apperr.CodeConflict), err)
+
+	reversals, err := testQueries.ListTransferReversals(context.Background(), original.ID)
+	require.NoError(t, err)
+	require.Len(t, reversals, 2)
+	for _, account := range []Account{payer, payee} {
+		updated, err := testQueries.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updated.Balance)
+	}
+}
+
+func TestReverseTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createRandomUser(t)
+
+	payer := createRandomAccountIn(t, util.USD, 1000)
+	payee := createRandomAccountIn(t, util.USD, 0)
+	transferred, err := store.TransferTx(context.Background(), TransferTxParams{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: 500})
+	require.NoError(t, err)
+
+	// concurrent full reversals of one transfer - only one of them goes through
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+				TransferID: transferred.Transfer.ID,
+				Reason:     ReversalReasonDuplicate,
+				ReversedBy: admin.Username,
+			})
+			errs <- err
+		}()
+	}
+
+	reversed := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			reversed++
+			continue
+		}
+		require.True(t, apperr.Is(err, apperr.CodeConflict), err)
+	}
+	require.Equal(t, 1, reversed)
+
+	updated, err := testQueries.GetAccount(context.Background(), payer.ID)
+	require.NoError(t, err)
+	require.Equal(t, payer.Balance, updated.Balance)
+}
+
+func TestReverseTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createRandomUser(t)
+
+	payer := createRandomAccountIn(t, util.USD, 1000)
+	payee := createRandomAccountIn(t, util.USD, 0)
+	other := createRandomAccountIn(t, util.USD, 0)
+	transferred, err := store.TransferTx(context.Background(), TransferTxParams{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: 500})
+	require.NoError(t, err)
+	// the payee spent the money already
+	_, err = store.TransferTx(context.Background(), TransferTxParams{FromAccountID: payee.ID, ToAccountID: other.ID, Amount: 400})
+	require.NoError(t, err)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transferred.Transfer.ID,
+		Reason:     ReversalReasonFraud,
+		ReversedBy: admin.Username,
+	})
+	require.True(t, apperr.Is(err, apperr.CodeInsufficientFunds), err)
+
+	reversed, err := testQueries.GetReversedAmount(context.Background(), transferred.Transfer.ID)
+	require.NoError(t, err)
+	require.Zero(t, reversed)
+}
+
+func TestReverseTransferTxInvalidParams(t *testing.T) {
+	store := &SQLStore{} // never reaches the db
+
+	_, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: 1, Reason: "changed_my_mind"})
+	require.True(t, apperr.Is(err, apperr.CodeValidation), err)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: 1, Amount: -1, Reason: ReversalReasonOther})
+	require.True(t, apperr.Is(err, apperr.CodeValidation), err)
+}
+
+func TestHoldTx(t *testing.T) {
+	store := NewStore(testDB)
+	payer := createRandomAccountIn(t, util.USD, 1000)
+	payee := createRandomAccountIn(t, util.USD, 0)
+	expiresAt := time.Now().Add(time.Hour)
+
+	authorized, err := store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountID: payer.ID,
+		ToAccountID:   payee.ID,
+		Amount:        600,
+		ExpiresAt:     expiresAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusAuthorized, authorized.Hold.Status)
+	// the ledger balance stays, the available balance drops
+	require.Equal(t, int64(1000), authorized.FromAccount.Balance)
+	require.Equal(t, int64(400), authorized.FromAccount.AvailableBalance())
+
+	// a transfer can't spend the held money
+	_, err = store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountID: payer.ID,
+		ToAccountID:   payee.ID,
+		Amount:        401,
+		ExpiresAt:     expiresAt,
+	})
+	require.True(t, apperr.Is(err, apperr.CodeInsufficientFunds), err)
+
+	// capture part of it, the rest is released
+	captured, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: authorized.Hold.ID, Amount: 250})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusCaptured, captured.Hold.Status)
+	require.Equal(t, int64(250), captured.Hold.CapturedAmount)
+	require.Equal(t, captured.Transfer.ID, captured.Hold.TransferID.Int64)
+	require.Equal(t, int64(750), captured.FromAccount.Balance)
+	require.Zero(t, captured.FromAccount.HeldAmount)
+	require.Equal(t, int64(250), captured.ToAccount.Balance)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: authorized.Hold.ID})
+	require.True(t, apperr.Is(err, apperr.CodeConflict), err)
+
+	// void releases everything
+	authorized, err = store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountID: payer.ID,
+		ToAccountID:   payee.ID,
+		Amount:        100,
+		ExpiresAt:     expiresAt,
+	})
+	require.NoError(t, err)
+	_, err = store.VoidHoldTx(context.Background(), VoidHoldTxParams{HoldID: authorized.Hold.ID, Expired: true})
+	require.True(t, apperr.Is(err, apperr.CodeConflict), err)
+	voided, err := store.VoidHoldTx(context.Background(), VoidHoldTxParams{HoldID: authorized.Hold.ID})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusVoided, voided.Hold.Status)
+	require.Equal(t, int64(750), voided.FromAccount.Balance)
+	require.Zero(t, voided.FromAccount.HeldAmount)
+}
+
+func TestHoldTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	payer := createRandomAccountIn(t, util.USD, 1000)
+	payee := createRandomAccountIn(t, util.USD, 0)
+
+	authorized, err := store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountID: payer.ID,
+		ToAccountID:   payee.ID,
+		Amount:        300,
+		ExpiresAt:     time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: authorized.Hold.ID})
+	require.True(t, apperr.Is(err, apperr.CodeConflict), err)
+
+	expired, err := store.ListExpiredHolds(context.Background(), ListExpiredHoldsParams{Now: time.Now(), MaxHolds: 1000})
+	require.NoError(t, err)
+	require.Contains(t, expired, authorized.Hold)
+
+	voided, err := store.VoidHoldTx(context.Background(), VoidHoldTxParams{HoldID: authorized.Hold.ID, Expired: true})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusExpired, voided.Hold.Status)
+	require.Equal(t, int64(1000), voided.FromAccount.AvailableBalance())
+}
+
+func TestTransferTxHeldFunds(t *testing.T) {
+	store := NewStore(testDB)
+	payer := createRandomAccountIn(t, util.USD, 1000)
+	payee := createRandomAccountIn(t, util.USD, 0)
+
+	authorized, err := store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		FromAccountID: payer.ID,
+		ToAccountID:   payee.ID,
+		Amount:        600,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// neither a transfer nor a withdrawal can spend the held money
+	_, err = store.TransferTx(context.Background(), TransferTxParams{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: 401})
+	require.True(t, apperr.Is(err, apperr.CodeInsufficientFunds), err)
+	_, err = store.FundAccountTx(context.Background(), FundAccountTxParams{AccountID: payer.ID, Amount: -401})
+	require.True(t, apperr.Is(err, apperr.CodeInsufficientFunds), err)
+
+	// the rest is spent, the hold is still captured in full
+	_, err = store.TransferTx(context.Background(), TransferTxParams{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: 400})
+	require.NoError(t, err)
+	captured, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: authorized.Hold.ID})
+	require.NoError(t, err)
+	require.Zero(t, captured.FromAccount.Balance)
+	require.Zero(t, captured.FromAccount.HeldAmount)
+	require.Equal(t, int64(1000), captured.ToAccount.Balance)
+
+	// the db refuses a balance below the held amount too
+	_, err = testQueries.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{ID: payer.ID, Amount: -1})
+	require.Equal(t, CheckViolation, ErrorCode(err))
+}
+
+func TestTransferTxSerializable(t *testing.T) {
+	// hot accounts under serializable fail with serialization failures - retried until they all commit
+	store := NewStore(testDB, TxConfig{
+		MaxRetries:        50,
+		RetryDelay:        5 * time.Millisecond,
+		TransferIsolation: pgx.Serializable,
+	})
+
+	account1 := createRandomAccountIn(t, util.USD, 1000)
+	account2 := createRandomAccountIn(t, util.USD, 1000)
+
+	n := 10
+	amount := int64(10)
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		fromAccountID, toAccountID := account1.ID, account2.ID
+		if i%2 == 1 {
+			fromAccountID, toAccountID = account2.ID, account1.ID
+		}
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: fromAccountID,
+				ToAccountID:   toAccountID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestExecTxRetry(t *testing.T) {
+	store := NewStore(testDB, TxConfig{MaxRetries: 2, RetryDelay: time.Millisecond}).(*SQLStore)
+	ctx := context.Background()
+
+	// fails twice, commits on the last retry
+	attempts := 0
+	err := store.execTx(ctx, TxOptions{}, func(ctx context.Context, q *Queries) error {
+		attempts++
+		if attempts < 3 {
+			return &pgconn.PgError{Code: SerializationFailure}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+
+	// retries run out
+	attempts = 0
+	err = store.execTx(ctx, TxOptions{}, func(ctx context.Context, q *Queries) error {
+		attempts++
+		return &pgconn.PgError{Code: DeadlockDetected}
+	})
+	require.Equal(t, DeadlockDetected, ErrorCode(err))
+	require.Equal(t, 3, attempts)
+
+	// other errors aren't retried
+	attempts = 0
+	err = store.execTx(ctx, TxOptions{}, func(ctx context.Context, q *Queries) error {
+		attempts++
+		return &pgconn.PgError{Code: UniqueViolation}
+	})
+	require.Equal(t, UniqueViolation, ErrorCode(err))
+	require.Equal(t, 1, attempts)
+
+	// nor a tx with side effects
+	attempts = 0
+	err = store.execTx(ctx, TxOptions{NoRetry: true}, func(ctx context.Context, q *Queries) error {
+		attempts++
+		return &pgconn.PgError{Code: SerializationFailure}
+	})
+	require.Equal(t, SerializationFailure, ErrorCode(err))
+	require.Equal(t, 1, attempts)
+}
+
+func TestExecTxOptions(t *testing.T) {
+	store := NewStore(testDB).(*SQLStore)
+
+	err := store.execTx(context.Background(), TxOptions{Isolation: pgx.Serializable, ReadOnly: true}, func(ctx context.Context, q *Queries) error {
+		var isolation string
+		err := q.db.QueryRow(ctx, "SHOW transaction_isolation").Scan(&isolation)
+		require.NoError(t, err)
+		require.Equal(t, "serializable", isolation)
+
+		_, err = q.CreateAccount(ctx, CreateAccountParams{Owner: createRandomUser(t).Username, Currency: "USD"})
+		return err
+	})
+	require.Equal(t, "25006", ErrorCode(err)) // read_only_sql_transaction
+}
+
+func TestExecTxTimeout(t *testing.T) {
+	store := NewStore(testDB, TxConfig{Timeout: 100 * time.Millisecond}).(*SQLStore)
+
+	start := time.Now()
+	err := store.execTx(context.Background(), TxOptions{}, func(ctx context.Context, q *Queries) error {
+		_, err := q.db.Exec(ctx, "SELECT pg_sleep(5)")
+		return err
+	})
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRetryDelay(t *testing.T) {
+	require.Zero(t, retryDelay(0, 1))
+
+	base := 10 * time.Millisecond
+	for attempt := 1; attempt <= 30; attempt++ {
+		ceiling := maxRetryDelay
+		if attempt <= 7 {
+			ceiling = base << (attempt - 1)
+		}
+		for i := 0; i < 100; i++ {
+			delay := retryDelay(base, attempt)
+			require.GreaterOrEqual(t, delay, time.Duration(0))
+			require.LessOrEqual(t, delay, ceiling)
+		}
+	}
+}
+
+func TestParseIsolationLevel(t *testing.T) {
+	level, err := ParseIsolationLevel("serializable")
+	require.NoError(t, err)
+	require.Equal(t, pgx.Serializable, level)
+
+	level, err = ParseIsolationLevel("")
+	require.NoError(t, err)
+	require.Empty(t, level)
+
+	_, err = ParseIsolationLevel("snapshot")
+	require.Error(t, err)
+}
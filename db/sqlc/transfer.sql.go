@@ -7,22 +7,47 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
-INSERT INTO transfers (from_account_id, to_account_id, amount)
-VALUES ($1, $2, $3)
-RETURNING id, from_account_id, to_account_id, amount, created_at
+INSERT INTO transfers (
+        from_account_id,
+        to_account_id,
+        amount,
+        memo,
+        reference,
+        metadata
+    )
+VALUES (
+        $1,
+        $2,
+        $3,
+        $4,
+        $5,
+        COALESCE($6::jsonb, '{}')
+    )
+RETURNING id, from_account_id, to_account_id, amount, created_at, memo, reference, metadata
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64           `json:"from_account_id"`
+	ToAccountID   int64           `json:"to_account_id"`
+	Amount        int64           `json:"amount"`
+	Memo          string          `json:"memo"`
+	Reference     string          `json:"reference"`
+	Metadata      json.RawMessage `json:"metadata"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Memo,
+		arg.Reference,
+		arg.Metadata,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -30,19 +55,22 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Memo,
+		&i.Reference,
+		&i.Metadata,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at
+SELECT id, from_account_id, to_account_id, amount, created_at, memo, reference, metadata
 FROM transfers
 WHERE id = $1
 LIMIT 1
 `
 
 func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, getTransfer, id)
+	row := q.db.QueryRow(ctx, getTransfer, id)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -50,12 +78,38 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Memo,
+		&i.Reference,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, memo, reference, metadata
+FROM transfers
+WHERE id = $1
+LIMIT 1 FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRow(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Memo,
+		&i.Reference,
+		&i.Metadata,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at
+SELECT id, from_account_id, to_account_id, amount, created_at, memo, reference, metadata
 FROM transfers
 WHERE from_account_id = $1
     OR to_account_id = $2
@@ -71,7 +125,7 @@ type ListTransfersParams struct {
 }
 
 func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
-	rows, err := q.db.QueryContext(ctx, listTransfers,
+	rows, err := q.db.Query(ctx, listTransfers,
 		arg.FromAccountID,
 		arg.ToAccountID,
 		arg.Limit,
@@ -90,14 +144,78 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Memo,
+			&i.Reference,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return items, nil
+}
+
+const listTransfersByReference = `-- name: ListTransfersByReference :many
+SELECT transfers.id, transfers.from_account_id, transfers.to_account_id, transfers.amount, transfers.created_at, transfers.memo, transfers.reference, transfers.metadata,
+    accounts.currency
+FROM transfers
+    JOIN accounts ON accounts.id = transfers.from_account_id
+WHERE transfers.reference = $1
+    AND EXISTS (
+        SELECT 1
+        FROM accounts owned
+        WHERE owned.owner = $2
+            AND owned.id IN (transfers.from_account_id, transfers.to_account_id)
+    )
+ORDER BY transfers.id
+LIMIT $4 OFFSET $3
+`
+
+type ListTransfersByReferenceParams struct {
+	Reference string `json:"reference"`
+	Owner     string `json:"owner"`
+	Offset    int32  `json:"offset"`
+	Limit     int32  `json:"limit"`
+}
+
+type ListTransfersByReferenceRow struct {
+	Transfer Transfer `json:"transfer"`
+	Currency string   `json:"currency"`
+}
+
+// the transfers with an external reference from or to an account of owner, with their currency
+func (q *Queries) ListTransfersByReference(ctx context.Context, arg ListTransfersByReferenceParams) ([]ListTransfersByReferenceRow, error) {
+	rows, err := q.db.Query(ctx, listTransfersByReference,
+		arg.Reference,
+		arg.Owner,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTransfersByReferenceRow{}
+	for rows.Next() {
+		var i ListTransfersByReferenceRow
+		if err := rows.Scan(
+			&i.Transfer.ID,
+			&i.Transfer.FromAccountID,
+			&i.Transfer.ToAccountID,
+			&i.Transfer.Amount,
+			&i.Transfer.CreatedAt,
+			&i.Transfer.Memo,
+			&i.Transfer.Reference,
+			&i.Transfer.Metadata,
+			&i.Currency,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,114 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.19.0
+// source: transfer_reversal.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createTransferReversal = `-- name: CreateTransferReversal :one
+INSERT INTO transfer_reversals (
+        original_transfer_id,
+        reversal_transfer_id,
+        amount,
+        reason,
+        reversed_by
+    )
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, original_transfer_id, reversal_transfer_id, amount, reason, reversed_by, created_at
+`
+
+type CreateTransferReversalParams struct {
+	OriginalTransferID int64  `json:"original_transfer_id"`
+	ReversalTransferID int64  `json:"reversal_transfer_id"`
+	Amount             int64  `json:"amount"`
+	Reason             string `json:"reason"`
+	ReversedBy         string `json:"reversed_by"`
+}
+
+func (q *Queries) CreateTransferReversal(ctx context.Context, arg CreateTransferReversalParams) (TransferReversal, error) {
+	row := q.db.QueryRow(ctx, createTransferReversal,
+		arg.OriginalTransferID,
+		arg.ReversalTransferID,
+		arg.Amount,
+		arg.Reason,
+		arg.ReversedBy,
+	)
+	var i TransferReversal
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalTransferID,
+		&i.ReversalTransferID,
+		&i.Amount,
+		&i.Reason,
+		&i.ReversedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getReversedAmount = `-- name: GetReversedAmount :one
+SELECT COALESCE(SUM(amount), 0)::bigint
+FROM transfer_reversals
+WHERE original_transfer_id = $1
+`
+
+func (q *Queries) GetReversedAmount(ctx context.Context, originalTransferID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, getReversedAmount, originalTransferID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const isReversalTransfer = `-- name: IsReversalTransfer :one
+SELECT EXISTS (
+        SELECT 1
+        FROM transfer_reversals
+        WHERE reversal_transfer_id = $1
+    )
+`
+
+func (q *Queries) IsReversalTransfer(ctx context.Context, reversalTransferID int64) (bool, error) {
+	row := q.db.QueryRow(ctx, isReversalTransfer, reversalTransferID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listTransferReversals = `-- name: ListTransferReversals :many
+SELECT id, original_transfer_id, reversal_transfer_id, amount, reason, reversed_by, created_at
+FROM transfer_reversals
+WHERE original_transfer_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListTransferReversals(ctx context.Context, originalTransferID int64) ([]TransferReversal, error) {
+	rows, err := q.db.Query(ctx, listTransferReversals, originalTransferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TransferReversal{}
+	for rows.Next() {
+		var i TransferReversal
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalTransferID,
+			&i.ReversalTransferID,
+			&i.Amount,
+			&i.Reason,
+			&i.ReversedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -74,3 +74,30 @@ func TestListTransfers(t *testing.T) {
 		require.True(t, transfer.FromAccountID == accountFrom.ID || transfer.ToAccountID == accountTo.ID)
 	}
 }
+
+func TestListTransfersByReference(t *testing.T) {
+	owner := createRandomAccount(t)
+	other := createRandomAccount(t)
+	stranger := createRandomAccount(t)
+	reference := util.RandomString(16)
+
+	arg := CreateTransferParams{FromAccountID: other.ID, ToAccountID: owner.ID, Amount: 10, Reference: reference}
+	paid, err := testQueries.CreateTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	// same reference, no account of owner
+	arg = CreateTransferParams{FromAccountID: other.ID, ToAccountID: stranger.ID, Amount: 10, Reference: reference}
+	_, err = testQueries.CreateTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	createRandomTransfer(t, owner, other)
+
+	rows, err := testQueries.ListTransfersByReference(context.Background(), ListTransfersByReferenceParams{
+		Reference: reference,
+		Owner:     owner.Owner,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, paid.ID, rows[0].Transfer.ID)
+	require.Equal(t, other.Currency, rows[0].Currency)
+}
@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/metrics"
+	"github.com/web3dev6/simplebank/money"
+)
+
+/*
+   Batch transfer - N legs committed all-or-nothing, e.g. one payroll account paying many employees
+	1. the legs are validated & every account's net amount is summed, with overflow checks
+	2. every account of the batch is locked in ascending id order, the addAmountInOrder idea for N accounts,
+	   so two batches (or a batch & a transfer) sharing accounts never wait on each other in a cycle
+	3. currencies & balances are checked on the locked rows - no concurrent transfer can overdraw an account in between
+	4. a transfer & its two entries are created per leg, then each balance is updated once by its net amount
+*/
+
+// MaxBatchLegs is the most legs a batch transfer can have
+const MaxBatchLegs = 100
+
+// BatchTransferLeg is one transfer of a batch
+type BatchTransferLeg struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+
+	TransferDetails
+}
+
+// BatchTransferTxParams contains the input parameters of the batch transfer transaction
+type BatchTransferTxParams struct {
+	Currency string             `json:"currency"` // of every account in the batch
+	Legs     []BatchTransferLeg `json:"legs"`
+
+	// appends the audit event of the tx inside it, nil for none
+	Audit AuditFunc[BatchTransferTxResult] `json:"-"`
+}
+
+// BatchTransferTxResult contains the result of the batch transfer transaction
+type BatchTransferTxResult struct {
+	Transfers []Transfer `json:"transfers"` // one per leg, in the order of the legs
+	Entries   []Entry    `json:"entries"`   // from & to entry of each leg, in the order of the legs
+	Accounts  []Account  `json:"accounts"`  // every account of the batch after it, by id
+}
+
+// BatchTransferTx performs the legs of arg as one transfer - either all of them or none
+// Note* validation errors, unknown (or internal) accounts, currency mismatches & insufficient funds are returned as apperr errors
+func (store *SQLStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+
+	nets, err := batchNetAmounts(arg)
+	if err != nil {
+		return result, err
+	}
+	accountIDs := make([]int64, 0, len(nets))
+	for id := range nets {
+		accountIDs = append(accountIDs, id)
+	}
+	sort.Slice(accountIDs, func(i, j int) bool { return accountIDs[i] < accountIDs[j] })
+
+	err = store.execTx(ctx, TxOptions{Isolation: store.txConfig.TransferIsolation}, func(ctx context.Context, q *Queries) error {
+		// note* reset, the fn reruns when the tx is retried
+		result = BatchTransferTxResult{
+			Transfers: make([]Transfer, 0, len(arg.Legs)),
+			Entries:   make([]Entry, 0, 2*len(arg.Legs)),
+			Accounts:  make([]Account, 0, len(accountIDs)),
+		}
+
+		// lock every account in a consistent order (lower account id first) - avoid deadlock
+		for _, id := range accountIDs {
+			account, err := q.GetAccountForUpdate(ctx, id)
+			if err != nil {
+				if errors.Is(err, ErrRecordNotFound) {
+					return apperr.NotFound(fmt.Sprintf("account [%d] not found", id)).WithCause(err)
+				}
+				return err
+			}
+			// note* the bank's internal accounts (e.g. interest expense) are only moved by the bank itself
+			if account.Owner == SystemUsername {
+				return apperr.NotFound(fmt.Sprintf("account [%d] not found", id))
+			}
+			if account.Currency != arg.Currency {
+				return apperr.Validation(
+					fmt.Sprintf("account [%d] currency mismatch, account currency:%s, transfer currency:%s", id, account.Currency, arg.Currency),
+					apperr.FieldViolation{Field: "currency", Description: "must match the currency of every account"},
+				)
+			}
+			if net := nets[id]; net < 0 && account.AvailableBalance()+net < 0 {
+				return apperr.InsufficientFunds(fmt.Sprintf("account [%d] available balance is lower than its total debit in the batch", id))
+			}
+		}
+
+		for _, leg := range arg.Legs {
+			transfer, fromEntry, toEntry, err := createTransferWithEntries(ctx, q, leg.FromAccountID, leg.ToAccountID, leg.Amount, leg.TransferDetails)
+			if err != nil {
+				return err
+			}
+			result.Transfers = append(result.Transfers, transfer)
+			result.Entries = append(result.Entries, fromEntry, toEntry)
+		}
+
+		// each balance updated once, in the same order the accounts were locked
+		for _, id := range accountIDs {
+			account, err := q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{ID: id, Amount: nets[id]})
+			if err != nil {
+				return err
+			}
+			result.Accounts = append(result.Accounts, account)
+		}
+		return auditTx(ctx, q, arg.Audit, result)
+	})
+	if err == nil {
+		// count only committed transfers
+		for _, leg := range arg.Legs {
+			metrics.ObserveTransfer(arg.Currency, leg.Amount)
+		}
+	}
+
+	return result, err
+}
+
+// batchNetAmounts validates the legs of arg & returns the net amount of every account in them - negative for a net debit
+func batchNetAmounts(arg BatchTransferTxParams) (map[int64]int64, error) {
+	if len(arg.Legs) == 0 || len(arg.Legs) > MaxBatchLegs {
+		return nil, apperr.Validation("invalid batch", apperr.FieldViolation{
+			Field:       "legs",
+			Description: fmt.Sprintf("must have 1 to %d legs", MaxBatchLegs),
+		})
+	}
+
+	var violations []apperr.FieldViolation
+	nets := make(map[int64]money.Money)
+	add := func(id int64, amount money.Money) error {
+		net, ok := nets[id]
+		if !ok {
+			net = money.Money{Currency: arg.Currency}
+		}
+		net, err := net.Add(amount)
+		nets[id] = net
+		return err
+	}
+	for i, leg := range arg.Legs {
+		switch {
+		case leg.FromAccountID < 1 || leg.ToAccountID < 1:
+			violations = append(violations, apperr.FieldViolation{Field: fmt.Sprintf("legs[%d]", i), Description: "account ids must be positive"})
+			continue
+		case leg.FromAccountID == leg.ToAccountID:
+			violations = append(violations, apperr.FieldViolation{Field: fmt.Sprintf("legs[%d]", i), Description: "must be between two different accounts"})
+			continue
+		case leg.Amount <= 0:
+			violations = append(violations, apperr.FieldViolation{Field: fmt.Sprintf("legs[%d].amount", i), Description: "must be positive"})
+			continue
+		}
+
+		amount := money.Money{Amount: leg.Amount, Currency: arg.Currency}
+		debit, _ := amount.Neg() // never overflows, the amount is positive
+		if err := add(leg.FromAccountID, debit); err != nil {
+			return nil, apperr.Validation("invalid batch", apperr.FieldViolation{Field: "legs", Description: "total amount is out of range"}).WithCause(err)
+		}
+		if err := add(leg.ToAccountID, amount); err != nil {
+			return nil, apperr.Validation("invalid batch", apperr.FieldViolation{Field: "legs", Description: "total amount is out of range"}).WithCause(err)
+		}
+	}
+	if len(violations) > 0 {
+		return nil, apperr.Validation("invalid batch", violations...)
+	}
+
+	amounts := make(map[int64]int64, len(nets))
+	for id, net := range nets {
+		amounts[id] = net.Amount
+	}
+	return amounts, nil
+}
@@ -18,7 +18,8 @@ type CreateUserTxResult struct {
 func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
 	var result CreateUserTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	// note* not retried - AfterCreate enqueues the verify email task, a retry would enqueue it again
+	err := store.execTx(ctx, TxOptions{NoRetry: true}, func(ctx context.Context, q *Queries) error {
 		var err error
 
 		// call store:create_user and create user in db
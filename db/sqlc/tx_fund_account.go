@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/web3dev6/simplebank/apperr"
+)
+
+// FundAccountTxParams contains the input parameters of the fund account transaction
+type FundAccountTxParams struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"` // positive to deposit, negative to withdraw
+
+	// appends the audit event of the tx inside it, nil for none
+	Audit AuditFunc[FundAccountTxResult] `json:"-"`
+}
+
+// FundAccountTxResult contains the result of the fund account transaction
+type FundAccountTxResult struct {
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+}
+
+// FundAccountTx deposits money into an account from outside the bank (or withdraws it, for a negative amount)
+// Note* unlike a transfer there's no counterpart account - the entry alone records the money entering the ledger
+func (store *SQLStore) FundAccountTx(ctx context.Context, arg FundAccountTxParams) (FundAccountTxResult, error) {
+	var result FundAccountTxResult
+
+	err := store.execTx(ctx, TxOptions{}, func(ctx context.Context, q *Queries) error {
+		var err error
+
+		// a withdrawal can't spend held funds, checked on the locked row
+		if arg.Amount < 0 {
+			account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+			if err != nil {
+				if errors.Is(err, ErrRecordNotFound) {
+					return apperr.NotFound(fmt.Sprintf("account [%d] not found", arg.AccountID)).WithCause(err)
+				}
+				return err
+			}
+			if account.AvailableBalance() < -arg.Amount {
+				return apperr.InsufficientFunds(fmt.Sprintf("account [%d] available balance is lower than the withdrawal amount", account.ID))
+			}
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
+			ID:     arg.AccountID,
+			Amount: arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+		return auditTx(ctx, q, arg.Audit, result)
+	})
+
+	return result, err
+}
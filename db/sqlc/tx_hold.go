@@ -0,0 +1,263 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/metrics"
+)
+
+/*
+   Holds - card-style transfers, the funds are reserved first & moved later
+	1. AuthorizeTransferTx places a hold on the from account - its held_amount goes up, so its available balance
+	   (balance - held_amount) goes down, the ledger balance stays as it is
+	2. CaptureHoldTx moves the captured amount (all of it or less) with a transfer & releases the whole hold
+	3. VoidHoldTx releases the hold without moving anything - by a user, or the worker once the hold expired
+   Note* the hold row is always locked before its accounts, & accounts in ascending id order - no deadlock with transfers
+*/
+
+// hold statuses, the holds_status_check constraint has the same list
+const (
+	HoldStatusAuthorized = "authorized"
+	HoldStatusCaptured   = "captured"
+	HoldStatusVoided     = "voided"
+	HoldStatusExpired    = "expired"
+)
+
+// AvailableBalance returns the balance of the account not held by authorized transfers - what it can spend
+func (account Account) AvailableBalance() int64 {
+	return account.Balance - account.HeldAmount
+}
+
+// AuthorizeTransferTxParams contains the input parameters of the authorize transfer transaction
+type AuthorizeTransferTxParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	ExpiresAt     time.Time `json:"expires_at"`
+
+	// the memo, reference & metadata of the transfer once captured
+	TransferDetails
+
+	// appends the audit event of the tx inside it, nil for none
+	Audit AuditFunc[AuthorizeTransferTxResult] `json:"-"`
+}
+
+// AuthorizeTransferTxResult contains the result of the authorize transfer transaction
+type AuthorizeTransferTxResult struct {
+	Hold        Hold    `json:"hold"`
+	FromAccount Account `json:"from_account"`
+}
+
+// AuthorizeTransferTx holds arg.Amount of the from account until the hold is captured, voided or expires
+func (store *SQLStore) AuthorizeTransferTx(ctx context.Context, arg AuthorizeTransferTxParams) (AuthorizeTransferTxResult, error) {
+	var result AuthorizeTransferTxResult
+
+	if arg.Amount <= 0 {
+		return result, apperr.Validation("invalid hold", apperr.FieldViolation{Field: "amount", Description: "must be positive"})
+	}
+	if arg.FromAccountID == arg.ToAccountID {
+		return result, apperr.Validation("invalid hold", apperr.FieldViolation{Field: "to_account_id", Description: "must differ from the from account"})
+	}
+
+	err := store.execTx(ctx, TxOptions{Isolation: store.txConfig.TransferIsolation}, func(ctx context.Context, q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.FromAccountID)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return apperr.NotFound(fmt.Sprintf("account [%d] not found", arg.FromAccountID)).WithCause(err)
+			}
+			return err
+		}
+		if account.AvailableBalance() < arg.Amount {
+			return apperr.InsufficientFunds(fmt.Sprintf("account [%d] available balance is lower than the hold amount", account.ID))
+		}
+
+		result.Hold, err = q.CreateHold(ctx, CreateHoldParams{
+			AccountID:   arg.FromAccountID,
+			ToAccountID: arg.ToAccountID,
+			Amount:      arg.Amount,
+			ExpiresAt:   arg.ExpiresAt,
+			Memo:        arg.Memo,
+			Reference:   arg.Reference,
+			Metadata:    arg.metadata(),
+		})
+		if err != nil {
+			return err
+		}
+		result.FromAccount, err = q.AddAccountHeldAmount(ctx, AddAccountHeldAmountParams{ID: arg.FromAccountID, Amount: arg.Amount})
+		if err != nil {
+			return err
+		}
+		return auditTx(ctx, q, arg.Audit, result)
+	})
+
+	return result, err
+}
+
+// CaptureHoldTxParams contains the input parameters of the capture hold transaction
+type CaptureHoldTxParams struct {
+	HoldID int64 `json:"hold_id"`
+	Amount int64 `json:"amount"` // 0 to capture the whole hold
+
+	// appends the audit event of the tx inside it, nil for none
+	Audit AuditFunc[CaptureHoldTxResult] `json:"-"`
+}
+
+// CaptureHoldTxResult contains the result of the capture hold transaction
+type CaptureHoldTxResult struct {
+	Hold        Hold     `json:"hold"`
+	Transfer    Transfer `json:"transfer"`
+	FromAccount Account  `json:"from_account"`
+	ToAccount   Account  `json:"to_account"`
+	FromEntry   Entry    `json:"from_entry"`
+	ToEntry     Entry    `json:"to_entry"`
+}
+
+// CaptureHoldTx transfers arg.Amount of an authorized hold to its to account & releases the rest of it
+// Note* a hold is captured once - capturing less than its amount releases the rest
+func (store *SQLStore) CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	var result CaptureHoldTxResult
+
+	if arg.Amount < 0 {
+		return result, apperr.Validation("invalid capture", apperr.FieldViolation{Field: "amount", Description: "must be positive"})
+	}
+
+	err := store.execTx(ctx, TxOptions{Isolation: store.txConfig.TransferIsolation}, func(ctx context.Context, q *Queries) error {
+		// note* reset, the fn reruns when the tx is retried
+		result = CaptureHoldTxResult{}
+
+		hold, err := lockAuthorizedHold(ctx, q, arg.HoldID)
+		if err != nil {
+			return err
+		}
+		if !hold.ExpiresAt.After(time.Now()) {
+			return apperr.Conflict(fmt.Sprintf("hold [%d] expired", hold.ID))
+		}
+		amount := arg.Amount
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		if amount > hold.Amount {
+			return apperr.Validation("invalid capture", apperr.FieldViolation{
+				Field:       "amount",
+				Description: fmt.Sprintf("must be at most %d, the held amount", hold.Amount),
+			})
+		}
+
+		// lock both accounts in a consistent order (lower account id first) - avoid deadlock
+		lowerID, higherID := hold.AccountID, hold.ToAccountID
+		if lowerID > higherID {
+			lowerID, higherID = higherID, lowerID
+		}
+		for _, id := range []int64{lowerID, higherID} {
+			account, err := q.GetAccountForUpdate(ctx, id)
+			if err != nil {
+				return err
+			}
+			// note* the hold is released first, so what's left available plus the held amount pays for the capture
+			if id == hold.AccountID && account.AvailableBalance()+hold.Amount < amount {
+				return apperr.InsufficientFunds(fmt.Sprintf("account [%d] available balance is lower than the capture amount", id))
+			}
+		}
+
+		if _, err := q.AddAccountHeldAmount(ctx, AddAccountHeldAmountParams{ID: hold.AccountID, Amount: -hold.Amount}); err != nil {
+			return err
+		}
+		// the details of the authorization are the transfer's
+		details := TransferDetails{Memo: hold.Memo, Reference: hold.Reference, Metadata: hold.Metadata}
+		result.Transfer, result.FromEntry, result.ToEntry, err = createTransferWithEntries(ctx, q, hold.AccountID, hold.ToAccountID, amount, details)
+		if err != nil {
+			return err
+		}
+		if hold.AccountID < hold.ToAccountID {
+			result.FromAccount, result.ToAccount, err = addAmountInOrder(ctx, q, hold.AccountID, -amount, hold.ToAccountID, amount)
+		} else {
+			result.ToAccount, result.FromAccount, err = addAmountInOrder(ctx, q, hold.ToAccountID, amount, hold.AccountID, -amount)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.UpdateHoldStatus(ctx, UpdateHoldStatusParams{
+			ID:             hold.ID,
+			Status:         HoldStatusCaptured,
+			CapturedAmount: amount,
+			TransferID:     pgtype.Int8{Int64: result.Transfer.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		return auditTx(ctx, q, arg.Audit, result)
+	})
+	if err == nil {
+		// count only committed transfers
+		metrics.ObserveTransfer(result.FromAccount.Currency, result.Transfer.Amount)
+	}
+
+	return result, err
+}
+
+// VoidHoldTxParams contains the input parameters of the void hold transaction
+type VoidHoldTxParams struct {
+	HoldID  int64 `json:"hold_id"`
+	Expired bool  `json:"expired"` // voided by the expiry worker - the hold must have expired, its status becomes expired
+
+	// appends the audit event of the tx inside it, nil for none
+	Audit AuditFunc[VoidHoldTxResult] `json:"-"`
+}
+
+// VoidHoldTxResult contains the result of the void hold transaction
+type VoidHoldTxResult struct {
+	Hold        Hold    `json:"hold"`
+	FromAccount Account `json:"from_account"`
+}
+
+// VoidHoldTx releases an authorized hold, nothing is transferred
+func (store *SQLStore) VoidHoldTx(ctx context.Context, arg VoidHoldTxParams) (VoidHoldTxResult, error) {
+	var result VoidHoldTxResult
+
+	err := store.execTx(ctx, TxOptions{}, func(ctx context.Context, q *Queries) error {
+		hold, err := lockAuthorizedHold(ctx, q, arg.HoldID)
+		if err != nil {
+			return err
+		}
+		status := HoldStatusVoided
+		if arg.Expired {
+			if hold.ExpiresAt.After(time.Now()) {
+				return apperr.Conflict(fmt.Sprintf("hold [%d] hasn't expired", hold.ID))
+			}
+			status = HoldStatusExpired
+		}
+
+		result.FromAccount, err = q.AddAccountHeldAmount(ctx, AddAccountHeldAmountParams{ID: hold.AccountID, Amount: -hold.Amount})
+		if err != nil {
+			return err
+		}
+		result.Hold, err = q.UpdateHoldStatus(ctx, UpdateHoldStatusParams{ID: hold.ID, Status: status})
+		if err != nil {
+			return err
+		}
+		return auditTx(ctx, q, arg.Audit, result)
+	})
+
+	return result, err
+}
+
+// lockAuthorizedHold locks the hold of id - a conflict if it's no longer authorized
+func lockAuthorizedHold(ctx context.Context, q *Queries, id int64) (Hold, error) {
+	hold, err := q.GetHoldForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return hold, apperr.NotFound(fmt.Sprintf("hold [%d] not found", id)).WithCause(err)
+		}
+		return hold, err
+	}
+	if hold.Status != HoldStatusAuthorized {
+		return hold, apperr.Conflict(fmt.Sprintf("hold [%d] is already %s", hold.ID, hold.Status))
+	}
+	return hold, nil
+}
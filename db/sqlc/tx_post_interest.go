@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/web3dev6/simplebank/interest"
+	"github.com/web3dev6/simplebank/metrics"
+)
+
+// PostInterestTxParams contains the input parameters of the post interest transaction
+type PostInterestTxParams struct {
+	AccountID   int64     `json:"account_id"`
+	PeriodStart time.Time `json:"period_start"` // the first day of the month
+	PeriodEnd   time.Time `json:"period_end"`   // the first day of the next month
+}
+
+// PostInterestTxResult contains the result of the post interest transaction
+type PostInterestTxResult struct {
+	Posting       InterestPosting `json:"posting"`
+	Transfer      Transfer        `json:"transfer"` // zero when nothing was paid
+	Account       Account         `json:"account"`
+	AlreadyPosted bool            `json:"already_posted"`
+}
+
+// PostInterestTx pays the interest accrued on an account before the end of a period, once per period
+// the accruals not posted yet & the fraction carried by the previous posting add up to the accrued micros,
+// its whole minor units are transferred from the interest_expense account of the currency, the rest is carried
+// Note* an accrual made late, after its period was posted, is paid by the next posting
+// Note* a period already posted is left as is - AlreadyPosted, no error
+func (store *SQLStore) PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error) {
+	var result PostInterestTxResult
+
+	err := store.execTx(ctx, TxOptions{}, func(ctx context.Context, q *Queries) error {
+		// reset on retry
+		result = PostInterestTxResult{}
+
+		// note* taken first - a concurrent posting of the period waits on its unique index, then finds it posted
+		posting, err := q.CreateInterestPosting(ctx, CreateInterestPostingParams{
+			AccountID:   arg.AccountID,
+			PeriodStart: arg.PeriodStart,
+			PeriodEnd:   arg.PeriodEnd,
+		})
+		if errors.Is(err, ErrRecordNotFound) {
+			result.AlreadyPosted = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var carried int64
+		last, err := q.GetLastInterestPosting(ctx, GetLastInterestPostingParams{
+			AccountID:   arg.AccountID,
+			PeriodStart: arg.PeriodStart,
+		})
+		switch {
+		case err == nil:
+			carried = last.CarriedMicros
+		case !errors.Is(err, ErrRecordNotFound):
+			return err
+		}
+		accrued, err := q.MarkInterestAccrualsPosted(ctx, MarkInterestAccrualsPostedParams{
+			PostingID: pgtype.Int8{Int64: posting.ID, Valid: true},
+			AccountID: arg.AccountID,
+			PeriodEnd: arg.PeriodEnd,
+		})
+		if err != nil {
+			return err
+		}
+		accrued += carried
+
+		amount, carry := interest.Post(accrued)
+		update := UpdateInterestPostingParams{
+			ID:            posting.ID,
+			AccruedMicros: accrued,
+			Amount:        amount,
+			CarriedMicros: carry,
+		}
+		if amount > 0 {
+			result.Account, result.Transfer, err = payInterest(ctx, q, arg, amount)
+			if err != nil {
+				return err
+			}
+			update.TransferID = pgtype.Int8{Int64: result.Transfer.ID, Valid: true}
+		}
+
+		result.Posting, err = q.UpdateInterestPosting(ctx, update)
+		return err
+	})
+	if err == nil && result.Transfer.ID != 0 {
+		// count only committed transfers
+		metrics.ObserveTransfer(result.Account.Currency, result.Transfer.Amount)
+	}
+
+	return result, err
+}
+
+// payInterest transfers amount to the account from the interest_expense account of its currency
+func payInterest(ctx context.Context, q *Queries, arg PostInterestTxParams, amount int64) (account Account, transfer Transfer, err error) {
+	account, err = q.GetAccount(ctx, arg.AccountID)
+	if err != nil {
+		return
+	}
+	expense, err := internalAccount(ctx, q, account.Currency, ProductInterestExpense)
+	if err != nil {
+		return
+	}
+
+	month := arg.PeriodStart.UTC()
+	transfer, _, _, err = createTransferWithEntries(ctx, q, expense.ID, account.ID, amount, TransferDetails{
+		Memo:      fmt.Sprintf("Interest for %s", month.Format("January 2006")),
+		Reference: fmt.Sprintf("interest-%s", month.Format("2006-01")),
+	})
+	if err != nil {
+		return
+	}
+
+	// update accounts' balance in a consistent order (lower account id first) - avoid deadlock
+	if expense.ID < account.ID {
+		_, account, err = addAmountInOrder(ctx, q, expense.ID, -amount, account.ID, amount)
+	} else {
+		account, _, err = addAmountInOrder(ctx, q, account.ID, amount, expense.ID, -amount)
+	}
+	return
+}
+
+// internalAccount returns the internal account of product in currency, creating it the first time
+// note* its balance goes negative, it's the bank's side of what it paid
+func internalAccount(ctx context.Context, q *Queries, currency string, product string) (Account, error) {
+	arg := CreateInternalAccountParams{Owner: SystemUsername, Currency: currency, Product: product}
+	if err := q.CreateInternalAccount(ctx, arg); err != nil {
+		return Account{}, err
+	}
+	return q.GetInternalAccount(ctx, GetInternalAccountParams(arg))
+}
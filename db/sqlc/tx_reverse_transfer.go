@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/metrics"
+)
+
+/*
+   Transfer reversal - undoes a committed transfer, in full or in part (a refund), with a compensating transfer
+	- the compensating transfer goes from the original to account back to the original from account, with its own entries
+	  & a transfer_reversals row linking it to the original transfer, with the reason & the admin who reversed it
+	- the original transfer row is locked first, so two reversals of one transfer run one after the other
+	  & the sum of its reversals never exceeds its amount - no double reversal
+	- a reversal can't itself be reversed, make a new transfer instead
+*/
+
+// reversal reason codes, the transfer_reversals_reason_check constraint has the same list
+const (
+	ReversalReasonDuplicate       = "duplicate"
+	ReversalReasonFraud           = "fraud"
+	ReversalReasonCustomerRequest = "customer_request"
+	ReversalReasonProcessingError = "processing_error"
+	ReversalReasonOther           = "other"
+)
+
+// ReversalReasons returns every reversal reason code
+func ReversalReasons() []string {
+	return []string{
+		ReversalReasonDuplicate,
+		ReversalReasonFraud,
+		ReversalReasonCustomerRequest,
+		ReversalReasonProcessingError,
+		ReversalReasonOther,
+	}
+}
+
+// IsReversalReason returns true if reason is a reversal reason code
+func IsReversalReason(reason string) bool {
+	for _, code := range ReversalReasons() {
+		if reason == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ReverseTransferTxParams contains the input parameters of the reverse transfer transaction
+type ReverseTransferTxParams struct {
+	TransferID int64  `json:"transfer_id"`
+	Amount     int64  `json:"amount"` // 0 to reverse all that's not reversed yet
+	Reason     string `json:"reason"`
+	ReversedBy string `json:"reversed_by"`
+
+	// appends the audit event of the tx inside it, nil for none
+	Audit AuditFunc[ReverseTransferTxResult] `json:"-"`
+}
+
+// ReverseTransferTxResult contains the result of the reverse transfer transaction
+type ReverseTransferTxResult struct {
+	Reversal        TransferReversal `json:"reversal"`
+	Transfer        Transfer         `json:"transfer"`     // the compensating transfer
+	FromAccount     Account          `json:"from_account"` // the original to account
+	ToAccount       Account          `json:"to_account"`   // the original from account
+	FromEntry       Entry            `json:"from_entry"`
+	ToEntry         Entry            `json:"to_entry"`
+	RemainingAmount int64            `json:"remaining_amount"` // of the original transfer that can still be reversed
+}
+
+// ReverseTransferTx moves arg.Amount of a transfer back to where it came from
+// Note* an unknown transfer, over-reversal & insufficient funds in the original to account are returned as apperr errors
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	if arg.Amount < 0 {
+		return result, apperr.Validation("invalid reversal", apperr.FieldViolation{Field: "amount", Description: "must be positive"})
+	}
+	if !IsReversalReason(arg.Reason) {
+		return result, apperr.Validation("invalid reversal", apperr.FieldViolation{Field: "reason", Description: "must be a reversal reason code"})
+	}
+
+	err := store.execTx(ctx, TxOptions{Isolation: store.txConfig.TransferIsolation}, func(ctx context.Context, q *Queries) error {
+		// note* reset, the fn reruns when the tx is retried
+		result = ReverseTransferTxResult{}
+
+		// lock the original transfer - concurrent reversals of it wait here
+		original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return apperr.NotFound(fmt.Sprintf("transfer [%d] not found", arg.TransferID)).WithCause(err)
+			}
+			return err
+		}
+		isReversal, err := q.IsReversalTransfer(ctx, original.ID)
+		if err != nil {
+			return err
+		}
+		if isReversal {
+			return apperr.Validation("invalid reversal", apperr.FieldViolation{Field: "transfer_id", Description: "is a reversal, it can't be reversed"})
+		}
+
+		reversed, err := q.GetReversedAmount(ctx, original.ID)
+		if err != nil {
+			return err
+		}
+		remaining := original.Amount - reversed
+		if remaining <= 0 {
+			return apperr.Conflict(fmt.Sprintf("transfer [%d] is already fully reversed", original.ID))
+		}
+		amount := arg.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount > remaining {
+			return apperr.Validation("invalid reversal", apperr.FieldViolation{
+				Field:       "amount",
+				Description: fmt.Sprintf("must be at most %d, the amount not reversed yet", remaining),
+			})
+		}
+
+		// lock both accounts in a consistent order (lower account id first) - avoid deadlock
+		fromAccountID, toAccountID := original.ToAccountID, original.FromAccountID
+		lowerID, higherID := fromAccountID, toAccountID
+		if lowerID > higherID {
+			lowerID, higherID = higherID, lowerID
+		}
+		for _, id := range []int64{lowerID, higherID} {
+			account, err := q.GetAccountForUpdate(ctx, id)
+			if err != nil {
+				return err
+			}
+			if id == fromAccountID && account.AvailableBalance() < amount {
+				return apperr.InsufficientFunds(fmt.Sprintf("account [%d] available balance is lower than the reversal amount", id))
+			}
+		}
+
+		// note* the original's reference, a search by it finds its refunds too
+		details := TransferDetails{Reference: original.Reference}
+		result.Transfer, result.FromEntry, result.ToEntry, err = createTransferWithEntries(ctx, q, fromAccountID, toAccountID, amount, details)
+		if err != nil {
+			return err
+		}
+		if fromAccountID < toAccountID {
+			result.FromAccount, result.ToAccount, err = addAmountInOrder(ctx, q, fromAccountID, -amount, toAccountID, amount)
+		} else {
+			result.ToAccount, result.FromAccount, err = addAmountInOrder(ctx, q, toAccountID, amount, fromAccountID, -amount)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.Reversal, err = q.CreateTransferReversal(ctx, CreateTransferReversalParams{
+			OriginalTransferID: original.ID,
+			ReversalTransferID: result.Transfer.ID,
+			Amount:             amount,
+			Reason:             arg.Reason,
+			ReversedBy:         arg.ReversedBy,
+		})
+		if err != nil {
+			return err
+		}
+		result.RemainingAmount = remaining - amount
+		return auditTx(ctx, q, arg.Audit, result)
+	})
+	if err == nil {
+		metrics.ObserveTransfer(result.FromAccount.Currency, result.Transfer.Amount)
+	}
+
+	return result, err
+}
@@ -1,12 +1,83 @@
 package db
 
-import "context"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/metrics"
+)
+
+// limits of the transfer details, the memo & reference lengths are checked by the db too
+const (
+	MaxMemoLength      = 140  // characters
+	MaxReferenceLength = 64   // characters
+	MaxMetadataSize    = 4096 // bytes of json
+)
+
+// TransferDetails are the optional memo, external reference & json metadata of a transfer, copied onto its entries
+type TransferDetails struct {
+	Memo      string          `json:"memo"`
+	Reference string          `json:"reference"`
+	Metadata  json.RawMessage `json:"metadata"` // a json object, empty or null for none
+}
+
+// Validate returns the violations of the limits of details, by field name
+func (details TransferDetails) Validate() (violations []apperr.FieldViolation) {
+	if utf8.RuneCountInString(details.Memo) > MaxMemoLength {
+		violations = append(violations, apperr.FieldViolation{
+			Field:       "memo",
+			Description: fmt.Sprintf("must be at most %d characters", MaxMemoLength),
+		})
+	}
+	if utf8.RuneCountInString(details.Reference) > MaxReferenceLength {
+		violations = append(violations, apperr.FieldViolation{
+			Field:       "reference",
+			Description: fmt.Sprintf("must be at most %d characters", MaxReferenceLength),
+		})
+	}
+	if len(details.Metadata) > MaxMetadataSize {
+		violations = append(violations, apperr.FieldViolation{
+			Field:       "metadata",
+			Description: fmt.Sprintf("must be at most %d bytes", MaxMetadataSize),
+		})
+	} else if !details.noMetadata() {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(details.Metadata, &object); err != nil {
+			violations = append(violations, apperr.FieldViolation{Field: "metadata", Description: "must be a json object"})
+		}
+	}
+	return violations
+}
+
+func (details TransferDetails) noMetadata() bool {
+	metadata := bytes.TrimSpace(details.Metadata)
+	return len(metadata) == 0 || bytes.Equal(metadata, []byte("null"))
+}
+
+// metadata is the json object stored for details - nil for none, stored as {}
+// note* a json null would be stored as is, failing the db's object check
+func (details TransferDetails) metadata() json.RawMessage {
+	if details.noMetadata() {
+		return nil
+	}
+	return details.Metadata
+}
 
 // TransferTxParams contains the input parameters of the transfer transaction
 type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+
+	TransferDetails
+
+	// appends the audit event of the tx inside it, nil for none
+	Audit AuditFunc[TransferTxResult] `json:"-"`
 }
 
 // TransferTxResult contains the result of the transfer transaction
@@ -26,35 +97,34 @@ var txKey = struct{}{}
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	err := store.execTx(ctx, TxOptions{Isolation: store.txConfig.TransferIsolation}, func(ctx context.Context, q *Queries) error {
 		var err error
 
 		// get tx name from ctx
 		// txName := ctx.Value(txKey)
 
-		// transfer
-		// log.Println(txName, "create Transfer")
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg))
-		if err != nil {
-			return err
+		// lock both accounts in a consistent order (lower account id first) - avoid deadlock
+		// note* the balance is checked on the locked row, a concurrent transfer can't overdraw the account in between
+		lowerID, higherID := arg.FromAccountID, arg.ToAccountID
+		if lowerID > higherID {
+			lowerID, higherID = higherID, lowerID
 		}
-
-		// from entry
-		// log.Println(txName, "create FromEntry")
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
-		if err != nil {
-			return err
+		for _, id := range []int64{lowerID, higherID} {
+			account, err := q.GetAccountForUpdate(ctx, id)
+			if err != nil {
+				if errors.Is(err, ErrRecordNotFound) {
+					return apperr.NotFound(fmt.Sprintf("account [%d] not found", id)).WithCause(err)
+				}
+				return err
+			}
+			if id == arg.FromAccountID && account.AvailableBalance() < arg.Amount {
+				return apperr.InsufficientFunds(fmt.Sprintf("account [%d] available balance is lower than the transfer amount", id))
+			}
 		}
 
-		// to entry
-		// log.Println(txName, "create ToEntry")
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+		// transfer, from entry & to entry
+		// log.Println(txName, "create Transfer")
+		result.Transfer, result.FromEntry, result.ToEntry, err = createTransferWithEntries(ctx, q, arg.FromAccountID, arg.ToAccountID, arg.Amount, arg.TransferDetails)
 		if err != nil {
 			return err
 		}
@@ -103,8 +173,12 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 				return err
 			}
 		}
-		return nil
+		return auditTx(ctx, q, arg.Audit, result)
 	})
+	if err == nil {
+		// count only committed transfers
+		metrics.ObserveTransfer(result.FromAccount.Currency, arg.Amount)
+	}
 
 	return result, err
 }
@@ -126,3 +200,36 @@ func addAmountInOrder(ctx context.Context, q *Queries, AccountID1 int64, amount1
 	})
 	return
 }
+
+// createTransferWithEntries creates a transfer of amount & its from & to entries, all with details
+func createTransferWithEntries(ctx context.Context, q *Queries, fromAccountID int64, toAccountID int64, amount int64, details TransferDetails) (transfer Transfer, fromEntry Entry, toEntry Entry, err error) {
+	transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        amount,
+		Memo:          details.Memo,
+		Reference:     details.Reference,
+		Metadata:      details.metadata(),
+	})
+	if err != nil {
+		return
+	}
+	fromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: fromAccountID,
+		Amount:    -amount,
+		Memo:      details.Memo,
+		Reference: details.Reference,
+		Metadata:  details.metadata(),
+	})
+	if err != nil {
+		return
+	}
+	toEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: toAccountID,
+		Amount:    amount,
+		Memo:      details.Memo,
+		Reference: details.Reference,
+		Metadata:  details.metadata(),
+	})
+	return
+}
@@ -2,7 +2,8 @@ package db
 
 import (
 	"context"
-	"database/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // VerifyEmailTxParams contains the input parameters of the VerifyEmail transaction
@@ -25,7 +26,7 @@ type VerifyEmailTxResult struct {
 func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
 	var result VerifyEmailTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	err := store.execTx(ctx, TxOptions{}, func(ctx context.Context, q *Queries) error {
 		var err error
 
 		// call store:update_verify_email and update verify_email in db - set is_used to true for record with given id & secret_code
@@ -39,7 +40,7 @@ func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParam
 		// call store:update_user and update user in db - set is_email_verified to true for record with given username
 		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
 			Username: result.VerifyEmail.Username,
-			IsEmailVerified: sql.NullBool{
+			IsEmailVerified: pgtype.Bool{
 				Bool:  true,
 				Valid: true,
 			},
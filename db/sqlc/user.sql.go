@@ -7,28 +7,31 @@ package db
 
 import (
 	"context"
-	"database/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (username, hashed_password, full_name, email)
-VALUES ($1, $2, $3, $4)
-RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified
+INSERT INTO users (username, hashed_password, full_name, email, role)
+VALUES ($1, $2, $3, $4, COALESCE($5::varchar, 'depositor')) -- role defaults to depositor, admins are created from the cli
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, role
 `
 
 type CreateUserParams struct {
-	Username       string `json:"username"`
-	HashedPassword string `json:"hashed_password"`
-	FullName       string `json:"full_name"`
-	Email          string `json:"email"`
+	Username       string      `json:"username"`
+	HashedPassword string      `json:"hashed_password"`
+	FullName       string      `json:"full_name"`
+	Email          string      `json:"email"`
+	Role           pgtype.Text `json:"role"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser,
+	row := q.db.QueryRow(ctx, createUser,
 		arg.Username,
 		arg.HashedPassword,
 		arg.FullName,
 		arg.Email,
+		arg.Role,
 	)
 	var i User
 	err := row.Scan(
@@ -39,6 +42,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
 		&i.IsEmailVerified,
+		&i.Role,
 	)
 	return i, err
 }
@@ -48,21 +52,68 @@ SELECT COUNT(*) FROM users
 `
 
 func (q *Queries) GetCountForUsers(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, getCountForUsers)
+	row := q.db.QueryRow(ctx, getCountForUsers)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified  
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, role  
 FROM users
 WHERE username = $1
 LIMIT 1
 `
 
 func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUser, username)
+	row := q.db.QueryRow(ctx, getUser, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, role
+FROM users
+WHERE email = $1
+LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.IsEmailVerified,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUserForAuth = `-- name: GetUserForAuth :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, role
+FROM users
+WHERE username = $1
+LIMIT 1
+`
+
+// same as GetUser, but never routed to a read replica - the role looked up to authorize admins
+func (q *Queries) GetUserForAuth(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserForAuth, username)
 	var i User
 	err := row.Scan(
 		&i.Username,
@@ -72,6 +123,7 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
 		&i.IsEmailVerified,
+		&i.Role,
 	)
 	return i, err
 }
@@ -84,20 +136,20 @@ SET hashed_password = COALESCE($1, hashed_password),
     password_changed_at = COALESCE($4, password_changed_at),
     is_email_verified = COALESCE($5, is_email_verified)
 WHERE username = $6
-RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, is_email_verified, role
 `
 
 type UpdateUserParams struct {
-	HashedPassword    sql.NullString `json:"hashed_password"`
-	FullName          sql.NullString `json:"full_name"`
-	Email             sql.NullString `json:"email"`
-	PasswordChangedAt sql.NullTime   `json:"password_changed_at"`
-	IsEmailVerified   sql.NullBool   `json:"is_email_verified"`
-	Username          string         `json:"username"`
+	HashedPassword    pgtype.Text        `json:"hashed_password"`
+	FullName          pgtype.Text        `json:"full_name"`
+	Email             pgtype.Text        `json:"email"`
+	PasswordChangedAt pgtype.Timestamptz `json:"password_changed_at"`
+	IsEmailVerified   pgtype.Bool        `json:"is_email_verified"`
+	Username          string             `json:"username"`
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser,
+	row := q.db.QueryRow(ctx, updateUser,
 		arg.HashedPassword,
 		arg.FullName,
 		arg.Email,
@@ -114,6 +166,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
 		&i.IsEmailVerified,
+		&i.Role,
 	)
 	return i, err
 }
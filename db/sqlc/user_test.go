@@ -2,11 +2,12 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/apperr"
 	"github.com/web3dev6/simplebank/util"
 )
 
@@ -39,6 +40,19 @@ func TestCreateUser(t *testing.T) {
 	createRandomUser(t)
 }
 
+func TestCreateUserDuplicate(t *testing.T) {
+	user := createRandomUser(t)
+
+	_, err := testQueries.CreateUser(context.Background(), CreateUserParams{
+		Username:       user.Username,
+		HashedPassword: user.HashedPassword,
+		FullName:       user.FullName,
+		Email:          util.RandomEmail(),
+	})
+	require.Equal(t, UniqueViolation, ErrorCode(err))
+	require.True(t, apperr.Is(DomainError(err, "user"), apperr.CodeConflict))
+}
+
 func TestGetUser(t *testing.T) {
 	user := createRandomUser(t)
 	userFromDB, err := testQueries.GetUser(context.Background(), user.Username)
@@ -54,12 +68,23 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user.PasswordChangedAt, userFromDB.PasswordChangedAt, time.Nanosecond)
 }
 
+func TestGetUserByEmail(t *testing.T) {
+	user := createRandomUser(t)
+	userFromDB, err := testQueries.GetUserByEmail(context.Background(), user.Email)
+
+	require.NoError(t, err)
+	require.Equal(t, user.Username, userFromDB.Username)
+
+	_, err = testQueries.GetUserByEmail(context.Background(), util.RandomEmail())
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
 func TestUpdateUserOnlyFullName(t *testing.T) {
 	user := createRandomUser(t)
 	newFullName := util.RandomOwner()
 	updatedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
 		Username: user.Username,
-		FullName: sql.NullString{
+		FullName: pgtype.Text{
 			String: newFullName,
 			Valid:  true,
 		},
@@ -80,7 +105,7 @@ func TestUpdateUserOnlyEmail(t *testing.T) {
 	newEmail := util.RandomEmail()
 	updatedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
 		Username: user.Username,
-		Email: sql.NullString{
+		Email: pgtype.Text{
 			String: newEmail,
 			Valid:  true,
 		},
@@ -105,11 +130,11 @@ func TestUpdateUserOnlyPassword(t *testing.T) {
 
 	updatedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
 		Username: user.Username,
-		HashedPassword: sql.NullString{
+		HashedPassword: pgtype.Text{
 			String: newHashedPassword,
 			Valid:  true,
 		},
-		PasswordChangedAt: sql.NullTime{
+		PasswordChangedAt: pgtype.Timestamptz{
 			Time:  time.Now(),
 			Valid: true,
 		},
@@ -138,19 +163,19 @@ func TestUpdateUserAllFields(t *testing.T) {
 
 	updatedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
 		Username: user.Username,
-		FullName: sql.NullString{
+		FullName: pgtype.Text{
 			String: newFullName,
 			Valid:  true,
 		},
-		Email: sql.NullString{
+		Email: pgtype.Text{
 			String: newEmail,
 			Valid:  true,
 		},
-		HashedPassword: sql.NullString{
+		HashedPassword: pgtype.Text{
 			String: newHashedPassword,
 			Valid:  true,
 		},
-		PasswordChangedAt: sql.NullTime{
+		PasswordChangedAt: pgtype.Timestamptz{
 			Time:  time.Now(),
 			Valid: true,
 		},
@@ -9,7 +9,8 @@ CREATE TABLE "users" (
   "email" varchar UNIQUE NOT NULL,
   "is_email_verified" bool NOT NULL DEFAULT false,
   "password_changed_at" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z',
-  "created_at" timestamptz NOT NULL DEFAULT (now())
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "role" varchar NOT NULL DEFAULT 'depositor'
 );
 
 CREATE TABLE "verify_emails" (
@@ -22,19 +23,40 @@ CREATE TABLE "verify_emails" (
   "expires_at" timestamptz NOT NULL DEFAULT (now() + interval '15 minutes')
 );
 
+CREATE TABLE "currencies" (
+  "code" varchar PRIMARY KEY,
+  "minor_units" int NOT NULL,
+  "enabled" bool NOT NULL DEFAULT true,
+  "display_name" varchar NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
 CREATE TABLE "accounts" (
   "id" BIGSERIAL PRIMARY KEY,
   "owner" varchar NOT NULL,
   "balance" bigint NOT NULL,
   "currency" varchar NOT NULL,
-  "created_at" timestamptz NOT NULL DEFAULT (now())
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "held_amount" bigint NOT NULL DEFAULT 0,
+  "product" varchar NOT NULL DEFAULT 'checking'
+);
+
+CREATE TABLE "account_products" (
+  "code" varchar PRIMARY KEY,
+  "display_name" varchar NOT NULL,
+  "annual_interest_rate_bps" int NOT NULL DEFAULT 0,
+  "internal" bool NOT NULL DEFAULT false,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
 );
 
 CREATE TABLE "entries" (
   "id" BIGSERIAL PRIMARY KEY,
   "account_id" bigint NOT NULL,
   "amount" bigint NOT NULL,
-  "created_at" timestamptz NOT NULL DEFAULT (now())
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "memo" varchar NOT NULL DEFAULT '',
+  "reference" varchar NOT NULL DEFAULT '',
+  "metadata" jsonb NOT NULL DEFAULT '{}'
 );
 
 CREATE TABLE "transfers" (
@@ -42,6 +64,74 @@ CREATE TABLE "transfers" (
   "from_account_id" bigint NOT NULL,
   "to_account_id" bigint NOT NULL,
   "amount" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "memo" varchar NOT NULL DEFAULT '',
+  "reference" varchar NOT NULL DEFAULT '',
+  "metadata" jsonb NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE "transfer_reversals" (
+  "id" bigserial PRIMARY KEY,
+  "original_transfer_id" bigint NOT NULL,
+  "reversal_transfer_id" bigint UNIQUE NOT NULL,
+  "amount" bigint NOT NULL,
+  "reason" varchar NOT NULL,
+  "reversed_by" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "holds" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'authorized',
+  "captured_amount" bigint NOT NULL DEFAULT 0,
+  "transfer_id" bigint,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "memo" varchar NOT NULL DEFAULT '',
+  "reference" varchar NOT NULL DEFAULT '',
+  "metadata" jsonb NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE "statement_emails" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "period_start" timestamptz NOT NULL,
+  "period_end" timestamptz NOT NULL,
+  "sent_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "account_balance_snapshots" (
+  "account_id" bigint NOT NULL,
+  "snapshot_at" timestamptz NOT NULL,
+  "balance" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "snapshot_at")
+);
+
+CREATE TABLE "interest_accruals" (
+  "account_id" bigint NOT NULL,
+  "accrued_on" timestamptz NOT NULL,
+  "balance" bigint NOT NULL,
+  "annual_interest_rate_bps" int NOT NULL,
+  "amount_micros" bigint NOT NULL,
+  "posting_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "accrued_on")
+);
+
+CREATE TABLE "interest_postings" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "period_start" timestamptz NOT NULL,
+  "period_end" timestamptz NOT NULL,
+  "accrued_micros" bigint NOT NULL DEFAULT 0,
+  "amount" bigint NOT NULL DEFAULT 0,
+  "carried_micros" bigint NOT NULL DEFAULT 0,
+  "transfer_id" bigint,
   "created_at" timestamptz NOT NULL DEFAULT (now())
 );
 
@@ -56,34 +146,166 @@ CREATE TABLE "sessions" (
   "created_at" timestamptz NOT NULL DEFAULT (now())
 );
 
+CREATE TABLE "audit_events" (
+  "id" bigserial PRIMARY KEY,
+  "action" varchar NOT NULL,
+  "actor" varchar NOT NULL,
+  "resource_type" varchar NOT NULL,
+  "resource_id" varchar NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "user_agent" varchar NOT NULL,
+  "request_id" varchar NOT NULL,
+  "before" json,
+  "after" json,
+  "prev_hash" bytea NOT NULL,
+  "hash" bytea UNIQUE NOT NULL,
+  "created_at" timestamptz NOT NULL
+);
+
 CREATE INDEX ON "accounts" ("owner");
 
-CREATE UNIQUE INDEX ON "accounts" ("owner", "currency");
+CREATE UNIQUE INDEX ON "accounts" ("owner", "currency", "product");
 
 CREATE INDEX ON "entries" ("account_id");
 
+CREATE INDEX ON "entries" ("reference");
+
+CREATE INDEX ON "entries" ("account_id", "created_at");
+
 CREATE INDEX ON "transfers" ("from_account_id");
 
 CREATE INDEX ON "transfers" ("to_account_id");
 
 CREATE INDEX ON "transfers" ("from_account_id", "to_account_id");
 
+CREATE INDEX ON "transfers" ("reference");
+
+CREATE INDEX ON "transfer_reversals" ("original_transfer_id");
+
+CREATE INDEX ON "holds" ("account_id");
+
+CREATE INDEX ON "holds" ("expires_at");
+
+CREATE UNIQUE INDEX ON "statement_emails" ("account_id", "period_start");
+
+CREATE UNIQUE INDEX ON "interest_postings" ("account_id", "period_start");
+
 CREATE INDEX ON "sessions" ("username");
 
-COMMENT ON COLUMN "accounts"."currency" IS 'can use enum here later';
+CREATE INDEX ON "audit_events" ("actor");
+
+CREATE INDEX ON "audit_events" ("action");
+
+CREATE INDEX ON "audit_events" ("created_at");
+
+COMMENT ON COLUMN "users"."role" IS 'depositor, admin or system - the simplebank system user owns the internal accounts';
+
+COMMENT ON COLUMN "currencies"."code" IS 'ISO 4217 code';
+
+COMMENT ON COLUMN "currencies"."minor_units" IS 'ISO 4217 exponent - 2 means an amount of 1234 is 12.34';
+
+COMMENT ON COLUMN "currencies"."enabled" IS 'disabled currencies take no new accounts nor transfers, existing balances stay';
+
+COMMENT ON COLUMN "accounts"."balance" IS 'ledger balance, the available balance is balance - held_amount - never below held_amount, but for interest_expense accounts';
+
+COMMENT ON COLUMN "accounts"."currency" IS 'new accounts only in enabled currencies';
+
+COMMENT ON COLUMN "accounts"."held_amount" IS 'sum of the authorized holds on the account';
+
+COMMENT ON COLUMN "accounts"."product" IS 'checking or savings, interest_expense for the internal accounts';
+
+COMMENT ON COLUMN "account_products"."annual_interest_rate_bps" IS 'in basis points - 200 is 2.00% a year';
+
+COMMENT ON COLUMN "account_products"."internal" IS 'accounts of the bank itself, owned by the simplebank user';
 
 COMMENT ON COLUMN "entries"."amount" IS 'it can be positive or negative';
 
+COMMENT ON COLUMN "entries"."reference" IS 'memo, reference & metadata are copied from the entry''s transfer';
+
 COMMENT ON COLUMN "transfers"."amount" IS 'it must be positive';
 
+COMMENT ON COLUMN "transfers"."reference" IS 'external reference, e.g. an invoice number - empty for none';
+
+COMMENT ON COLUMN "transfers"."metadata" IS 'json object of at most 4KB, checked by the api';
+
+COMMENT ON COLUMN "transfer_reversals"."reversal_transfer_id" IS 'the compensating transfer, from the original to account back to the original from account';
+
+COMMENT ON COLUMN "transfer_reversals"."amount" IS 'partial refunds allowed, their sum never exceeds the original amount';
+
+COMMENT ON COLUMN "transfer_reversals"."reason" IS 'duplicate, fraud, customer_request, processing_error or other';
+
+COMMENT ON COLUMN "transfer_reversals"."reversed_by" IS 'username of the admin who reversed it';
+
+COMMENT ON COLUMN "holds"."status" IS 'authorized, then captured, voided or expired';
+
+COMMENT ON COLUMN "holds"."transfer_id" IS 'the transfer of the captured amount';
+
+COMMENT ON COLUMN "holds"."reference" IS 'memo, reference & metadata are copied to the transfer of the capture';
+
+COMMENT ON TABLE "statement_emails" IS 'monthly statements emailed, so a retried run does not send them again';
+
+COMMENT ON COLUMN "account_balance_snapshots"."snapshot_at" IS 'midnight UTC of the day of the snapshot';
+
+COMMENT ON COLUMN "account_balance_snapshots"."balance" IS 'sum of the entries of the account created before snapshot_at';
+
+COMMENT ON COLUMN "interest_accruals"."accrued_on" IS 'midnight UTC of the day of the accrual';
+
+COMMENT ON COLUMN "interest_accruals"."balance" IS 'balance at the end of the day';
+
+COMMENT ON COLUMN "interest_accruals"."amount_micros" IS 'interest of the day in millionths of a minor unit';
+
+COMMENT ON COLUMN "interest_accruals"."posting_id" IS 'the posting that paid it, null until then';
+
+COMMENT ON TABLE "interest_postings" IS 'monthly interest paid to an account, once per period';
+
+COMMENT ON COLUMN "interest_postings"."accrued_micros" IS 'accruals paid by the posting plus the carry of the previous one';
+
+COMMENT ON COLUMN "interest_postings"."amount" IS 'whole minor units paid, the rest of accrued_micros is carried';
+
+COMMENT ON COLUMN "interest_postings"."transfer_id" IS 'from the interest_expense account, null when nothing is paid';
+
+COMMENT ON COLUMN "audit_events"."actor" IS 'username of the authenticated user, or the attempted one for failed logins';
+
+COMMENT ON COLUMN "audit_events"."before" IS 'json, not jsonb - kept byte for byte, the hash covers it';
+
+COMMENT ON COLUMN "audit_events"."hash" IS 'sha256 of prev_hash and all the other columns - editing or deleting a row breaks the chain';
+
 ALTER TABLE "verify_emails" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
 
 ALTER TABLE "accounts" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
 
+ALTER TABLE "accounts" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
 ALTER TABLE "entries" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
 
 ALTER TABLE "transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
 
 ALTER TABLE "transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
 
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("original_transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("reversal_transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("reversed_by") REFERENCES "users" ("username");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "statement_emails" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "account_balance_snapshots" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("product") REFERENCES "account_products" ("code");
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("posting_id") REFERENCES "interest_postings" ("id");
+
+ALTER TABLE "interest_postings" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "interest_postings" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
 ALTER TABLE "sessions" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
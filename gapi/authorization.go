@@ -3,15 +3,18 @@ package gapi
 import (
 	"context"
 	"fmt"
-	"strings"
 
+	"github.com/web3dev6/simplebank/apperr"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/middleware"
 	"github.com/web3dev6/simplebank/token"
+	"github.com/web3dev6/simplebank/util"
 	"google.golang.org/grpc/metadata"
 )
 
 const (
-	authorizationHeader = "authorization"
-	authorizationBearer = "bearer"
+	authorizationHeader = middleware.AuthorizationHeaderKey
+	authorizationBearer = middleware.AuthorizationTypeBearer
 )
 
 // authorizeUser
@@ -25,28 +28,28 @@ func (server *Server) authorizeUser(ctx context.Context) (*token.Payload, error)
 	}
 
 	// get value stored in authHeader
-	values := md.Get(authorizationHeader)
-	if len(values) == 0 {
-		return nil, fmt.Errorf("missing authorization header")
+	var authHeader string
+	if values := md.Get(authorizationHeader); len(values) > 0 {
+		authHeader = values[0]
 	}
-	authHeader := values[0]
 
-	// get <authorization-type> <authorization-data::access-token>
-	fields := strings.Fields(authHeader)
-	if len(fields) < 2 {
-		return nil, fmt.Errorf("invalid authorization header format")
-	}
-	authorizationType := strings.ToLower(fields[0])
-	if authorizationType != authorizationBearer {
-		return nil, fmt.Errorf("unsupported authorization type: %s", authorizationType)
-	}
-	accessToken := fields[1]
-
-	// get payload
-	payload, err := server.tokenMaker.VerifyToken(accessToken)
+	// verify "bearer <access-token>" the same way the Gin authMiddleware does
+	return middleware.Authenticate(server.tokenMaker, authHeader)
+}
 
+// authorizeAdmin authorizes the user like authorizeUser & checks they're an admin - the grpc equivalent of the Gin adminMiddleware
+func (server *Server) authorizeAdmin(ctx context.Context) (*token.Payload, error) {
+	authPayload, err := server.authorizeUser(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("invalid access token %s: %w", accessToken, err)
+		return nil, unauthenticatedError(err)
+	}
+	// note* GetUserForAuth reads from the primary, a revoked admin loses access right away
+	user, err := server.store.GetUserForAuth(ctx, authPayload.Username)
+	if err != nil {
+		return nil, db.DomainError(err, "user")
+	}
+	if user.Role != util.AdminRole {
+		return nil, apperr.Forbidden("only admins can access this resource")
 	}
-	return payload, nil
+	return authPayload, nil
 }
@@ -1,8 +1,13 @@
 package gapi
 
 import (
+	"encoding/json"
+
 	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
 	"github.com/web3dev6/simplebank/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -15,3 +20,71 @@ func convertUser(user db.User) *pb.User {
 		CreatedAt:         timestamppb.New(user.CreatedAt),
 	}
 }
+
+func convertMoney(amount int64, currency string) *pb.Money {
+	return &pb.Money{
+		Amount:   money.Money{Amount: amount, Currency: currency}.Decimal(),
+		Currency: currency,
+	}
+}
+
+func convertAccount(account db.Account) *pb.Account {
+	return &pb.Account{
+		Id:               account.ID,
+		Owner:            account.Owner,
+		Balance:          convertMoney(account.Balance, account.Currency),
+		CreatedAt:        timestamppb.New(account.CreatedAt),
+		AvailableBalance: convertMoney(account.AvailableBalance(), account.Currency),
+		Product:          account.Product,
+	}
+}
+
+func convertTransfer(transfer db.Transfer, currency string) *pb.Transfer {
+	return &pb.Transfer{
+		Id:            transfer.ID,
+		FromAccountId: transfer.FromAccountID,
+		ToAccountId:   transfer.ToAccountID,
+		Amount:        convertMoney(transfer.Amount, currency),
+		CreatedAt:     timestamppb.New(transfer.CreatedAt),
+		Memo:          transfer.Memo,
+		Reference:     transfer.Reference,
+		Metadata:      convertMetadata(transfer.Metadata),
+	}
+}
+
+func convertEntry(entry db.Entry, currency string) *pb.Entry {
+	return &pb.Entry{
+		Id:        entry.ID,
+		AccountId: entry.AccountID,
+		Amount:    convertMoney(entry.Amount, currency),
+		CreatedAt: timestamppb.New(entry.CreatedAt),
+		Memo:      entry.Memo,
+		Reference: entry.Reference,
+		Metadata:  convertMetadata(entry.Metadata),
+	}
+}
+
+// convertMetadata converts the json object metadata of a transfer or entry, nil if there's none
+func convertMetadata(metadata json.RawMessage) *structpb.Struct {
+	if len(metadata) == 0 {
+		return nil
+	}
+	var object structpb.Struct
+	if err := protojson.Unmarshal(metadata, &object); err != nil {
+		// note* the db only takes json objects
+		return nil
+	}
+	return &object
+}
+
+func convertTransferReversal(reversal db.TransferReversal, currency string) *pb.TransferReversal {
+	return &pb.TransferReversal{
+		Id:                 reversal.ID,
+		OriginalTransferId: reversal.OriginalTransferID,
+		ReversalTransferId: reversal.ReversalTransferID,
+		Amount:             convertMoney(reversal.Amount, currency),
+		Reason:             reversal.Reason,
+		ReversedBy:         reversal.ReversedBy,
+		CreatedAt:          timestamppb.New(reversal.CreatedAt),
+	}
+}
@@ -1,28 +1,20 @@
 package gapi
 
 import (
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/web3dev6/simplebank/apperr"
 )
 
-func fieldViolation(field string, err error) *errdetails.BadRequest_FieldViolation {
-	return &errdetails.BadRequest_FieldViolation{
+func fieldViolation(field string, err error) apperr.FieldViolation {
+	return apperr.FieldViolation{
 		Field:       field,
 		Description: err.Error(),
 	}
 }
 
-func invalidArgumentError(violations []*errdetails.BadRequest_FieldViolation) error {
-	badRequest := &errdetails.BadRequest{FieldViolations: violations}
-	statusInvalid := status.New(codes.InvalidArgument, "invalid parameters")
-	statusInvalidWithDetails, err := statusInvalid.WithDetails(badRequest)
-	if err != nil {
-		return statusInvalid.Err()
-	}
-	return statusInvalidWithDetails.Err()
+func invalidArgumentError(violations []apperr.FieldViolation) error {
+	return apperr.Validation("invalid parameters", violations...)
 }
 
 func unauthenticatedError(err error) error {
-	return status.Errorf(codes.Unauthenticated, "unauthorized: %s", err)
+	return apperr.Unauthenticated("unauthorized: " + err.Error())
 }
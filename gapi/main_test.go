@@ -3,11 +3,13 @@ package gapi
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/currency"
 	db "github.com/web3dev6/simplebank/db/sqlc"
 	"github.com/web3dev6/simplebank/token"
 	"github.com/web3dev6/simplebank/util"
@@ -22,12 +24,21 @@ func newTestServer(t *testing.T, store db.Store, taskDistributor worker.TaskDist
 		TokenMakerType:      "PASETO",
 	}
 
-	server, err := NewServer(config, store, taskDistributor)
+	server, err := NewServer(config, store, testCurrencies(), taskDistributor)
 	require.NoError(t, err)
 
 	return server
 }
 
+// testCurrencies returns a registry of the currencies the migrations create, all enabled
+func testCurrencies() *currency.Registry {
+	return currency.NewStaticRegistry(
+		db.Currency{Code: util.USD, MinorUnits: 2, Enabled: true, DisplayName: "US Dollar"},
+		db.Currency{Code: util.EUR, MinorUnits: 2, Enabled: true, DisplayName: "Euro"},
+		db.Currency{Code: util.INR, MinorUnits: 2, Enabled: true, DisplayName: "Indian Rupee"},
+	)
+}
+
 func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username string, duration time.Duration) context.Context {
 	accessToken, _, err := tokenMaker.CreateToken(username, duration)
 	require.NoError(t, err)
@@ -39,3 +50,39 @@ func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username st
 	}
 	return metadata.NewIncomingContext(context.Background(), md)
 }
+
+type eqAuditedTxParamsMatcher struct {
+	arg    interface{}
+	action string
+}
+
+// passes iff -> the params of a money tx equal arg but for their Audit func -> which must be set, auditing action
+// note* Audit is called with a zero result, the mocked tx never calls it
+func (e eqAuditedTxParamsMatcher) Matches(x interface{}) bool {
+	value := reflect.ValueOf(x)
+	if value.Type() != reflect.TypeOf(e.arg) {
+		return false
+	}
+	audit := value.FieldByName("Audit")
+	if audit.IsNil() {
+		return false
+	}
+	out := audit.Call([]reflect.Value{reflect.Zero(audit.Type().In(0))})
+	if !out[1].IsNil() || out[0].Interface().(db.CreateAuditEventTxParams).Action != e.action {
+		return false
+	}
+
+	// the rest of the params as is
+	params := reflect.New(value.Type()).Elem()
+	params.Set(value)
+	params.FieldByName("Audit").Set(reflect.Zero(audit.Type()))
+	return reflect.DeepEqual(e.arg, params.Interface())
+}
+
+func (e eqAuditedTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v audited as %s", e.arg, e.action)
+}
+
+func EqAuditedTxParams(arg interface{}, action string) gomock.Matcher {
+	return eqAuditedTxParamsMatcher{arg, action}
+}
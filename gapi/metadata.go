@@ -3,13 +3,14 @@ package gapi
 import (
 	"context"
 
+	"github.com/web3dev6/simplebank/audit"
+	"github.com/web3dev6/simplebank/middleware"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 )
 
 const (
 	grpcGatewayUserAgentHeader = "grpcgateway-user-agent"
-	xForwardedForHeader        = "x-forwarded-for"
 	userAgentHeader            = "user-agent"
 )
 
@@ -32,15 +33,27 @@ func (server *Server) ExtractMetadata(ctx context.Context) *Metadata {
 		if userAgents := md.Get(userAgentHeader); len(userAgents) > 0 {
 			meta.UserAgent = userAgents[0]
 		}
-		// for requests coming from http clients
-		if clientIPs := md.Get(xForwardedForHeader); len(clientIPs) > 0 {
-			meta.ClientIP = clientIPs[0]
-		}
 	}
-	// for requests coming from grpc-cli clients like evans
-	if peer, ok := peer.FromContext(ctx); ok {
-		// log.Printf("peer: %+v\n", peer)
-		meta.ClientIP = peer.Addr.String()
+	// note* resolved by the pipeline for both grpc & gateway requests, x-forwarded-for is only believed from trusted proxies
+	meta.ClientIP = middleware.ClientIP(ctx)
+	if meta.ClientIP == "" {
+		// for requests not through the pipeline
+		if peer, ok := peer.FromContext(ctx); ok {
+			meta.ClientIP = peer.Addr.String()
+		}
 	}
 	return meta
 }
+
+// audit records event in the audit log, with the client ip & user agent from the request metadata
+func (server *Server) audit(ctx context.Context, event audit.Event) {
+	audit.Record(ctx, server.store, server.auditEvent(ctx, event))
+}
+
+// auditEvent returns event with the client ip & user agent from the request metadata - for the events of money txs, see audit.InTx
+func (server *Server) auditEvent(ctx context.Context, event audit.Event) audit.Event {
+	meta := server.ExtractMetadata(ctx)
+	event.ClientIP = meta.ClientIP
+	event.UserAgent = meta.UserAgent
+	return event
+}
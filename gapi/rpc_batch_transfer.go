@@ -0,0 +1,105 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/pb"
+)
+
+// BatchTransfer moves money along every leg of the request, all-or-nothing - the POST /transfers/batch of the http api
+func (server *Server) BatchTransfer(ctx context.Context, req *pb.BatchTransferRequest) (*pb.BatchTransferResponse, error) {
+	authPayload, err := server.authorizeUser(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	// validate request & parse the amounts into the store's legs
+	arg, violations := server.validateBatchTransferRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	// the user must own the from account of every leg
+	fromAccounts := make(map[int64]db.Account)
+	for _, leg := range arg.Legs {
+		if _, ok := fromAccounts[leg.FromAccountID]; ok {
+			continue
+		}
+		fromAccount, err := server.store.GetAccount(ctx, leg.FromAccountID)
+		if err != nil {
+			return nil, db.DomainError(err, fmt.Sprintf("account [%d]", leg.FromAccountID))
+		}
+		if fromAccount.Owner != authPayload.Username {
+			return nil, apperr.Forbidden("account doesn't belong to the authenticated user")
+		}
+		fromAccounts[leg.FromAccountID] = fromAccount
+	}
+
+	// currencies & balances are checked by the store on the locked accounts
+	arg.Audit = audit.InTx(ctx, func(result db.BatchTransferTxResult) audit.Event {
+		return server.auditEvent(ctx, audit.Event{
+			Action:       audit.ActionTransferBatchCreated,
+			Actor:        authPayload.Username,
+			ResourceType: audit.ResourceTransfer,
+			ResourceID:   audit.BatchResourceID(result.Transfers),
+			Before:       map[string]interface{}{"from_accounts": fromAccounts},
+			After:        result,
+		})
+	})
+	result, err := server.store.BatchTransferTx(ctx, arg)
+	if err != nil {
+		return nil, db.DomainError(err, "transfer")
+	}
+
+	resp := &pb.BatchTransferResponse{}
+	for _, transfer := range result.Transfers {
+		resp.Transfers = append(resp.Transfers, convertTransfer(transfer, req.GetCurrency()))
+	}
+	for _, entry := range result.Entries {
+		resp.Entries = append(resp.Entries, convertEntry(entry, req.GetCurrency()))
+	}
+	// note* only the user's own accounts, the balances of the accounts paid into are not theirs to see
+	for _, account := range result.Accounts {
+		if account.Owner == authPayload.Username {
+			resp.Accounts = append(resp.Accounts, convertAccount(account))
+		}
+	}
+	return resp, nil
+}
+
+func (server *Server) validateBatchTransferRequest(req *pb.BatchTransferRequest) (arg db.BatchTransferTxParams, violations []apperr.FieldViolation) {
+	if err := ValidateCurrency(server.currencies, req.GetCurrency()); err != nil {
+		// note* the amounts can't be parsed without a known currency
+		return arg, append(violations, fieldViolation("currency", err))
+	}
+	if len(req.GetLegs()) == 0 || len(req.GetLegs()) > db.MaxBatchLegs {
+		return arg, append(violations, fieldViolation("legs", fmt.Errorf("must have 1 to %d legs", db.MaxBatchLegs)))
+	}
+
+	arg.Currency = req.GetCurrency()
+	for i, leg := range req.GetLegs() {
+		if err := ValidateAccountId(leg.GetFromAccountId()); err != nil {
+			violations = append(violations, fieldViolation(fmt.Sprintf("legs[%d].from_account_id", i), err))
+		}
+		if err := ValidateAccountId(leg.GetToAccountId()); err != nil {
+			violations = append(violations, fieldViolation(fmt.Sprintf("legs[%d].to_account_id", i), err))
+		}
+		amount, err := ValidateAmount(leg.GetAmount(), req.GetCurrency())
+		if err != nil {
+			violations = append(violations, fieldViolation(fmt.Sprintf("legs[%d].amount", i), err))
+		}
+		details, detailViolations := ValidateTransferDetails(fmt.Sprintf("legs[%d].", i), leg.GetMemo(), leg.GetReference(), leg.GetMetadata())
+		violations = append(violations, detailViolations...)
+		arg.Legs = append(arg.Legs, db.BatchTransferLeg{
+			FromAccountID:   leg.GetFromAccountId(),
+			ToAccountID:     leg.GetToAccountId(),
+			Amount:          amount.Amount,
+			TransferDetails: details,
+		})
+	}
+	return arg, violations
+}
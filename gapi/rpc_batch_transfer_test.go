@@ -0,0 +1,258 @@
+package gapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/pb"
+	"github.com/web3dev6/simplebank/token"
+	"github.com/web3dev6/simplebank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestBatchTransferGAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	payer := db.Account{ID: util.RandomInt(1, 1000), Owner: user.Username, Balance: 10000, Currency: util.USD}
+	payee1 := db.Account{ID: payer.ID + 1, Owner: "payee1", Currency: util.USD}
+	payee2 := db.Account{ID: payer.ID + 2, Owner: "payee2", Currency: util.USD}
+	otherPayer := db.Account{ID: payer.ID + 3, Owner: "other", Balance: 10000, Currency: util.USD}
+
+	legs := []*pb.TransferLeg{
+		{FromAccountId: payer.ID, ToAccountId: payee1.ID, Amount: "10.50"},
+		{FromAccountId: payer.ID, ToAccountId: payee2.ID, Amount: "20"},
+	}
+
+	metadata, err := structpb.NewStruct(map[string]interface{}{"employee_id": 42})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		req           *pb.BatchTransferRequest
+		buildStubs    func(store *mockdb.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.BatchTransferResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.BatchTransferRequest{Currency: util.USD, Legs: legs},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(payer.ID)).
+					Times(1).
+					Return(payer, nil)
+				arg := db.BatchTransferTxParams{
+					Currency: util.USD,
+					Legs: []db.BatchTransferLeg{
+						{FromAccountID: payer.ID, ToAccountID: payee1.ID, Amount: 1050},
+						{FromAccountID: payer.ID, ToAccountID: payee2.ID, Amount: 2000},
+					},
+				}
+				paidPayer := payer
+				paidPayer.Balance -= 3050
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), EqAuditedTxParams(arg, audit.ActionTransferBatchCreated)).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.BatchTransferTxParams) (db.BatchTransferTxResult, error) {
+						result := db.BatchTransferTxResult{
+							Transfers: []db.Transfer{
+								{ID: 1, FromAccountID: payer.ID, ToAccountID: payee1.ID, Amount: 1050},
+								{ID: 2, FromAccountID: payer.ID, ToAccountID: payee2.ID, Amount: 2000},
+							},
+							Entries: []db.Entry{
+								{ID: 1, AccountID: payer.ID, Amount: -1050},
+								{ID: 2, AccountID: payee1.ID, Amount: 1050},
+								{ID: 3, AccountID: payer.ID, Amount: -2000},
+								{ID: 4, AccountID: payee2.ID, Amount: 2000},
+							},
+							Accounts: []db.Account{paidPayer, payee1, payee2},
+						}
+						// the audit event is appended by the tx, from its result
+						event, err := arg.Audit(result)
+						require.NoError(t, err)
+						require.Equal(t, "1,2", event.ResourceID)
+						return result, nil
+					})
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.BatchTransferResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetTransfers(), 2)
+				require.Equal(t, "10.50", res.GetTransfers()[0].GetAmount().GetAmount())
+				require.Len(t, res.GetEntries(), 4)
+				require.Equal(t, "-20.00", res.GetEntries()[2].GetAmount().GetAmount())
+				// only the user's own account
+				require.Len(t, res.GetAccounts(), 1)
+				require.Equal(t, payer.ID, res.GetAccounts()[0].GetId())
+			},
+		},
+		{
+			name: "UnauthorizedLeg",
+			req: &pb.BatchTransferRequest{Currency: util.USD, Legs: append(legs,
+				&pb.TransferLeg{FromAccountId: otherPayer.ID, ToAccountId: payee1.ID, Amount: "1"})},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(payer.ID)).
+					Times(1).
+					Return(payer, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(otherPayer.ID)).
+					Times(1).
+					Return(otherPayer, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.BatchTransferResponse, err error) {
+				status, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, status.Code())
+			},
+		},
+		{
+			name: "InvalidLegs",
+			req: &pb.BatchTransferRequest{Currency: util.USD, Legs: []*pb.TransferLeg{
+				{FromAccountId: payer.ID, ToAccountId: 0, Amount: "1"},
+				{FromAccountId: payer.ID, ToAccountId: payee1.ID, Amount: "0.001"},
+			}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.BatchTransferResponse, err error) {
+				status, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, status.Code())
+			},
+		},
+		{
+			name: "LegDetails",
+			req: &pb.BatchTransferRequest{Currency: util.USD, Legs: []*pb.TransferLeg{{
+				FromAccountId: payer.ID,
+				ToAccountId:   payee1.ID,
+				Amount:        "1",
+				Memo:          "march salary",
+				Reference:     "PAYROLL-2024-03",
+				Metadata:      metadata,
+			}}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(payer.ID)).
+					Times(1).
+					Return(payer, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.BatchTransferTxParams) (db.BatchTransferTxResult, error) {
+						details := arg.Legs[0].TransferDetails
+						require.Equal(t, "march salary", details.Memo)
+						require.Equal(t, "PAYROLL-2024-03", details.Reference)
+						require.JSONEq(t, `{"employee_id":42}`, string(details.Metadata))
+						transfer := db.Transfer{ID: 1, FromAccountID: payer.ID, ToAccountID: payee1.ID, Amount: 100,
+							Memo: details.Memo, Reference: details.Reference, Metadata: details.Metadata}
+						return db.BatchTransferTxResult{Transfers: []db.Transfer{transfer}, Accounts: []db.Account{payer}}, nil
+					})
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.BatchTransferResponse, err error) {
+				require.NoError(t, err)
+				transfer := res.GetTransfers()[0]
+				require.Equal(t, "PAYROLL-2024-03", transfer.GetReference())
+				require.Equal(t, float64(42), transfer.GetMetadata().GetFields()["employee_id"].GetNumberValue())
+			},
+		},
+		{
+			name: "LegDetailsTooLong",
+			req: &pb.BatchTransferRequest{Currency: util.USD, Legs: []*pb.TransferLeg{
+				{FromAccountId: payer.ID, ToAccountId: payee1.ID, Amount: "1", Memo: strings.Repeat("m", db.MaxMemoLength+1)},
+				{FromAccountId: payer.ID, ToAccountId: payee2.ID, Amount: "1", Reference: strings.Repeat("r", db.MaxReferenceLength+1)},
+			}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.BatchTransferResponse, err error) {
+				status, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, status.Code())
+				require.Contains(t, status.Message(), "invalid parameters")
+			},
+		},
+		{
+			name: "UnsupportedCurrency",
+			req:  &pb.BatchTransferRequest{Currency: "JPY", Legs: legs},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.BatchTransferResponse, err error) {
+				status, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, status.Code())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			req:  &pb.BatchTransferRequest{Currency: util.USD, Legs: legs},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.BatchTransferResponse, err error) {
+				status, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, status.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrlStore := gomock.NewController(t)
+			defer ctrlStore.Finish()
+			store := mockdb.NewMockStore(ctrlStore)
+
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil) // taskDistributor not used by batch_transfer
+
+			ctx := tc.buildContext(t, server.tokenMaker)
+			res, err := server.BatchTransfer(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
@@ -2,16 +2,15 @@ package gapi
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/hibiken/asynq"
+	"github.com/web3dev6/simplebank/apperr"
 	db "github.com/web3dev6/simplebank/db/sqlc"
 	"github.com/web3dev6/simplebank/pb"
 	"github.com/web3dev6/simplebank/util"
 	"github.com/web3dev6/simplebank/worker"
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
@@ -24,7 +23,7 @@ func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 	// hash password
 	hashedPassword, err := util.HashPassword(req.GetPassword())
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
+		return nil, apperr.Internal(fmt.Errorf("failed to hash password: %w", err))
 	}
 
 	// using SQL DB Tx with CreateUserTx - as user shouldn't be created if taskDistributor fails - rollback
@@ -49,7 +48,7 @@ func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 			}
 			err = server.taskDistributor.DistributeTaskSendVerifyEmail(ctx, taskPayload, opts...)
 			if err != nil {
-				return status.Errorf(codes.Internal, "failed to distribute task TaskSendVerifyEmail: %s", err)
+				return fmt.Errorf("failed to distribute task TaskSendVerifyEmail: %w", err)
 			}
 			return nil
 		},
@@ -58,11 +57,8 @@ func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 	// now call CreateUserTx, instead of CreateUser directly
 	txResult, err := server.store.CreateUserTx(ctx, arg)
 	if err != nil {
-		// username and email must be unique (UNIQUE)
-		if db.ErrorCode(err) == db.UniqueViolation {
-			return nil, status.Errorf(codes.AlreadyExists, "username or email already exists: %s", err)
-		}
-		return nil, status.Errorf(codes.Internal, "failed to create user: %s", err)
+		// username and email must be unique (UNIQUE) - a conflict
+		return nil, db.DomainError(err, "username or email")
 	}
 
 	// return resp
@@ -72,7 +68,7 @@ func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 	return resp, nil
 }
 
-func validateCreateUserRequest(req *pb.CreateUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+func validateCreateUserRequest(req *pb.CreateUserRequest) (violations []apperr.FieldViolation) {
 	if err := ValidateUsername(req.GetUsername()); err != nil {
 		violations = append(violations, fieldViolation("username", err))
 	}
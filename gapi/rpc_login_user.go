@@ -2,15 +2,13 @@ package gapi
 
 import (
 	"context"
-	"database/sql"
-	"errors"
+	"fmt"
 
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
 	db "github.com/web3dev6/simplebank/db/sqlc"
 	"github.com/web3dev6/simplebank/pb"
 	"github.com/web3dev6/simplebank/util"
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -24,24 +22,26 @@ func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (
 	// get user from db
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
-		if errors.Is(err, db.ErrRecordNotFound) || errors.Is(err, sql.ErrNoRows) {
-			return nil, status.Errorf(codes.NotFound, "user not found: %s", err)
+		err = db.DomainError(err, "user")
+		if apperr.Is(err, apperr.CodeNotFound) {
+			server.audit(ctx, audit.Event{Action: audit.ActionUserLoginFailed, Actor: req.Username, ResourceType: audit.ResourceUser, ResourceID: req.Username})
 		}
-		return nil, status.Errorf(codes.Internal, "failed to fetch user: %s", err)
+		return nil, err
 	}
 
 	// check password and create tokens if all ok, or error out
 	err = util.CheckPassword(req.Password, user.HashedPassword)
 	if err != nil {
-		return nil, status.Errorf(codes.Unauthenticated, "incorrect user password: %s", err)
+		server.audit(ctx, audit.Event{Action: audit.ActionUserLoginFailed, Actor: user.Username, ResourceType: audit.ResourceUser, ResourceID: user.Username})
+		return nil, apperr.Unauthenticated("incorrect user password").WithCause(err)
 	}
 	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create access token: %s", err)
+		return nil, apperr.Internal(fmt.Errorf("failed to create access token: %w", err))
 	}
 	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.RefreshTokenDuration)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create refresh token: %s", err)
+		return nil, apperr.Internal(fmt.Errorf("failed to create refresh token: %w", err))
 	}
 
 	// extract metadata from ctx
@@ -58,8 +58,9 @@ func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (
 		ExpiresAt:    refreshPayload.ExpiresAt,
 	})
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create user session: %s", err)
+		return nil, db.DomainError(err, "session")
 	}
+	server.audit(ctx, audit.Event{Action: audit.ActionUserLogin, Actor: user.Username, ResourceType: audit.ResourceSession, ResourceID: session.ID.String()})
 
 	// return resp
 	return &pb.LoginUserResponse{
@@ -72,7 +73,7 @@ func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (
 	}, nil
 }
 
-func validateLoginUserRequest(req *pb.LoginUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+func validateLoginUserRequest(req *pb.LoginUserRequest) (violations []apperr.FieldViolation) {
 	if err := ValidateUsername(req.GetUsername()); err != nil {
 		violations = append(violations, fieldViolation("username", err))
 	}
@@ -0,0 +1,82 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
+	"github.com/web3dev6/simplebank/pb"
+)
+
+// ReverseTransfer moves a transfer's money back, in full or in part - admins only, the POST /admin/transfers/:id/reverse of the http api
+func (server *Server) ReverseTransfer(ctx context.Context, req *pb.ReverseTransferRequest) (*pb.ReverseTransferResponse, error) {
+	authPayload, err := server.authorizeAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := validateReverseTransferRequest(req)
+	if violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	// the currency of the transfer, to parse the amount in
+	transfer, err := server.store.GetTransfer(ctx, req.GetTransferId())
+	if err != nil {
+		return nil, db.DomainError(err, "transfer")
+	}
+	fromAccount, err := server.store.GetAccount(ctx, transfer.FromAccountID)
+	if err != nil {
+		return nil, db.DomainError(err, fmt.Sprintf("account [%d]", transfer.FromAccountID))
+	}
+	var amount money.Money
+	if req.Amount != nil {
+		amount, err = ValidateAmount(req.GetAmount(), fromAccount.Currency)
+		if err != nil {
+			return nil, invalidArgumentError([]apperr.FieldViolation{fieldViolation("amount", err)})
+		}
+	}
+
+	result, err := server.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{
+		TransferID: transfer.ID,
+		Amount:     amount.Amount,
+		Reason:     req.GetReason(),
+		ReversedBy: authPayload.Username,
+		Audit: audit.InTx(ctx, func(result db.ReverseTransferTxResult) audit.Event {
+			return server.auditEvent(ctx, audit.Event{
+				Action:       audit.ActionTransferReversed,
+				Actor:        authPayload.Username,
+				ResourceType: audit.ResourceTransfer,
+				ResourceID:   strconv.FormatInt(transfer.ID, 10),
+				Before:       transfer,
+				After:        result,
+			})
+		}),
+	})
+	if err != nil {
+		return nil, db.DomainError(err, "transfer")
+	}
+
+	resp := &pb.ReverseTransferResponse{
+		Reversal:        convertTransferReversal(result.Reversal, fromAccount.Currency),
+		Transfer:        convertTransfer(result.Transfer, fromAccount.Currency),
+		FromEntry:       convertEntry(result.FromEntry, fromAccount.Currency),
+		ToEntry:         convertEntry(result.ToEntry, fromAccount.Currency),
+		RemainingAmount: convertMoney(result.RemainingAmount, fromAccount.Currency),
+	}
+	return resp, nil
+}
+
+func validateReverseTransferRequest(req *pb.ReverseTransferRequest) (violations []apperr.FieldViolation) {
+	if err := ValidateTransferId(req.GetTransferId()); err != nil {
+		violations = append(violations, fieldViolation("transfer_id", err))
+	}
+	if err := ValidateReversalReason(req.GetReason()); err != nil {
+		violations = append(violations, fieldViolation("reason", err))
+	}
+	return violations
+}
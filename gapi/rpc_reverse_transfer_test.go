@@ -0,0 +1,160 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/pb"
+	"github.com/web3dev6/simplebank/token"
+	"github.com/web3dev6/simplebank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReverseTransferGAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	depositor, _ := randomUser(t)
+	depositor.Role = util.DepositorRole
+
+	fromAccount := db.Account{ID: util.RandomInt(1, 1000), Owner: "payer", Currency: util.EUR}
+	toAccount := db.Account{ID: fromAccount.ID + 1, Owner: "payee", Currency: util.EUR}
+	transfer := db.Transfer{ID: 7, FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 5000}
+	amount := "12.50"
+
+	testCases := []struct {
+		name          string
+		req           *pb.ReverseTransferRequest
+		buildStubs    func(store *mockdb.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.ReverseTransferResponse, err error)
+	}{
+		{
+			name: "PartialRefund",
+			req:  &pb.ReverseTransferRequest{TransferId: transfer.ID, Amount: &amount, Reason: db.ReversalReasonCustomerRequest},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				arg := db.ReverseTransferTxParams{
+					TransferID: transfer.ID,
+					Amount:     1250,
+					Reason:     db.ReversalReasonCustomerRequest,
+					ReversedBy: admin.Username,
+				}
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), EqAuditedTxParams(arg, audit.ActionTransferReversed)).
+					Times(1).
+					Return(db.ReverseTransferTxResult{
+						Reversal:        db.TransferReversal{ID: 1, OriginalTransferID: transfer.ID, ReversalTransferID: 8, Amount: 1250},
+						Transfer:        db.Transfer{ID: 8, FromAccountID: toAccount.ID, ToAccountID: fromAccount.ID, Amount: 1250},
+						RemainingAmount: 3750,
+					}, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, admin.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ReverseTransferResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, transfer.ID, res.GetReversal().GetOriginalTransferId())
+				require.Equal(t, "12.50", res.GetTransfer().GetAmount().GetAmount())
+				require.Equal(t, "37.50", res.GetRemainingAmount().GetAmount())
+				require.Equal(t, util.EUR, res.GetRemainingAmount().GetCurrency())
+			},
+		},
+		{
+			name: "AlreadyReversed",
+			req:  &pb.ReverseTransferRequest{TransferId: transfer.ID, Reason: db.ReversalReasonDuplicate},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ReverseTransferTxResult{}, apperr.Conflict("transfer [7] is already fully reversed"))
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, admin.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ReverseTransferResponse, err error) {
+				status, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.AlreadyExists, status.Code())
+			},
+		},
+		{
+			name: "InvalidReason",
+			req:  &pb.ReverseTransferRequest{TransferId: transfer.ID, Reason: "changed_my_mind"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, admin.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ReverseTransferResponse, err error) {
+				status, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, status.Code())
+			},
+		},
+		{
+			name: "NotAdmin",
+			req:  &pb.ReverseTransferRequest{TransferId: transfer.ID, Reason: db.ReversalReasonFraud},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Eq(depositor.Username)).Times(1).Return(depositor, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, depositor.Username, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.ReverseTransferResponse, err error) {
+				status, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.PermissionDenied, status.Code())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			req:  &pb.ReverseTransferRequest{TransferId: transfer.ID, Reason: db.ReversalReasonFraud},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserForAuth(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.ReverseTransferResponse, err error) {
+				status, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.Unauthenticated, status.Code())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrlStore := gomock.NewController(t)
+			defer ctrlStore.Finish()
+			store := mockdb.NewMockStore(ctrlStore)
+
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil) // taskDistributor not used by reverse_transfer
+
+			ctx := tc.buildContext(t, server.tokenMaker)
+			res, err := server.ReverseTransfer(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
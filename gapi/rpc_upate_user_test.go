@@ -2,12 +2,13 @@ package gapi
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/audit"
 	db "github.com/web3dev6/simplebank/db/sqlc"
 	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
 	"github.com/web3dev6/simplebank/pb"
@@ -41,11 +42,11 @@ func TestUpdateUserGAPI(t *testing.T) {
 			buildStubs: func(store *mockdb.MockStore) {
 				arg := db.UpdateUserParams{
 					Username: user.Username,
-					FullName: sql.NullString{
+					FullName: pgtype.Text{
 						String: newFullName,
 						Valid:  true,
 					},
-					Email: sql.NullString{
+					Email: pgtype.Text{
 						String: newEmail,
 						Valid:  true,
 					},
@@ -59,10 +60,25 @@ func TestUpdateUserGAPI(t *testing.T) {
 					CreatedAt:         user.CreatedAt,
 					IsEmailVerified:   user.IsEmailVerified,
 				}
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
 				store.EXPECT().
 					UpdateUser(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
 					Return(updatedUser, nil)
+				store.EXPECT().
+					CreateAuditEventTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateAuditEventTxParams) (db.AuditEvent, error) {
+						require.Equal(t, audit.ActionUserUpdated, arg.Action)
+						require.Equal(t, user.Username, arg.Actor)
+						require.Contains(t, string(arg.Before), user.Email)
+						require.Contains(t, string(arg.After), newEmail)
+						require.NotContains(t, string(arg.After), user.HashedPassword)
+						return db.AuditEvent{}, nil
+					})
 			},
 			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
 				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
@@ -87,9 +103,12 @@ func TestUpdateUserGAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					UpdateUser(gomock.Any(), gomock.Any()).
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
-					Return(db.User{}, sql.ErrNoRows)
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(0)
 			},
 			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
 				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
@@ -2,15 +2,15 @@ package gapi
 
 import (
 	"context"
-	"database/sql"
+	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/audit"
 	db "github.com/web3dev6/simplebank/db/sqlc"
 	"github.com/web3dev6/simplebank/pb"
 	"github.com/web3dev6/simplebank/util"
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UpdateUserResponse, error) {
@@ -21,7 +21,7 @@ func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 		return nil, unauthenticatedError(err)
 	}
 	if req.Username != authPayload.Username {
-		return nil, status.Errorf(codes.PermissionDenied, "mismatch in username from authToken and update_request payload: %s", err)
+		return nil, apperr.Forbidden("mismatch in username from authToken and update_request payload")
 	}
 
 	// validate update_request & err handling
@@ -30,14 +30,20 @@ func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 		return nil, invalidArgumentError(violations)
 	}
 
+	// user before the update - for the audit log
+	oldUser, err := server.store.GetUser(ctx, req.GetUsername())
+	if err != nil {
+		return nil, db.DomainError(err, "user")
+	}
+
 	// make update_user params
 	arg := db.UpdateUserParams{
 		Username: req.GetUsername(),
-		FullName: sql.NullString{
+		FullName: pgtype.Text{
 			String: req.GetFullName(),
 			Valid:  req.FullName != nil,
 		},
-		Email: sql.NullString{
+		Email: pgtype.Text{
 			String: req.GetEmail(),
 			Valid:  req.Email != nil,
 		},
@@ -47,14 +53,14 @@ func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 		// hash password
 		hashedPassword, err := util.HashPassword(req.GetPassword())
 		if err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
+			return nil, apperr.Internal(fmt.Errorf("failed to hash password: %w", err))
 		}
 		// set hash_password
-		arg.HashedPassword = sql.NullString{
+		arg.HashedPassword = pgtype.Text{
 			String: hashedPassword,
 			Valid:  true,
 		}
-		arg.PasswordChangedAt = sql.NullTime{
+		arg.PasswordChangedAt = pgtype.Timestamptz{
 			Time:  time.Now(),
 			Valid: true,
 		}
@@ -63,11 +69,16 @@ func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 	// call UpdateUser for db
 	user, err := server.store.UpdateUser(ctx, arg)
 	if err != nil {
-		if db.ErrorCode(err) == db.ErrRecordNotFound.Error() || err.Error() == sql.ErrNoRows.Error() {
-			return nil, status.Errorf(codes.NotFound, "user with username not found: %s", err)
-		}
-		return nil, status.Errorf(codes.Internal, "failed to update user: %s", err)
+		return nil, db.DomainError(err, "user")
 	}
+	server.audit(ctx, audit.Event{
+		Action:       audit.ActionUserUpdated,
+		Actor:        authPayload.Username,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   user.Username,
+		Before:       audit.UserSnapshot(oldUser),
+		After:        audit.UserSnapshot(user),
+	})
 
 	// return resp
 	resp := &pb.UpdateUserResponse{
@@ -76,7 +87,7 @@ func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 	return resp, nil
 }
 
-func validateUpdateUserRequest(req *pb.UpdateUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+func validateUpdateUserRequest(req *pb.UpdateUserRequest) (violations []apperr.FieldViolation) {
 	// required
 	if err := ValidateUsername(req.GetUsername()); err != nil {
 		violations = append(violations, fieldViolation("username", err))
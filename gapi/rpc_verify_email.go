@@ -3,11 +3,9 @@ package gapi
 import (
 	"context"
 
+	"github.com/web3dev6/simplebank/apperr"
 	db "github.com/web3dev6/simplebank/db/sqlc"
 	"github.com/web3dev6/simplebank/pb"
-	"google.golang.org/genproto/googleapis/rpc/errdetails"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func (server *Server) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.VerifyEmailResponse, error) {
@@ -24,7 +22,8 @@ func (server *Server) VerifyEmail(ctx context.Context, req *pb.VerifyEmailReques
 		SecretCode: req.GetSecretCode(),
 	})
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to verify email: %s", err)
+		// note* no row for a wrong secret_code, an already used or an expired one
+		return nil, db.DomainError(err, "verify email")
 	}
 
 	// return resp
@@ -34,7 +33,7 @@ func (server *Server) VerifyEmail(ctx context.Context, req *pb.VerifyEmailReques
 	return resp, nil
 }
 
-func validateVerifyEmailRequest(req *pb.VerifyEmailRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+func validateVerifyEmailRequest(req *pb.VerifyEmailRequest) (violations []apperr.FieldViolation) {
 	if err := ValidateEmailId(req.GetEmailId()); err != nil {
 		violations = append(violations, fieldViolation("email_id", err))
 	}
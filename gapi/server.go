@@ -3,6 +3,7 @@ package gapi
 import (
 	"fmt"
 
+	"github.com/web3dev6/simplebank/currency"
 	db "github.com/web3dev6/simplebank/db/sqlc"
 	"github.com/web3dev6/simplebank/pb"
 	token "github.com/web3dev6/simplebank/token"
@@ -13,6 +14,7 @@ import (
 // Server serves gRPC requests for our banking service
 type Server struct {
 	store                            db.Store               // do the transfer_tx
+	currencies                       *currency.Registry     // currencies requests can be made in
 	tokenMaker                       token.Maker            // manage tokens for users
 	config                           util.Config            // store config used to start the server
 	pb.UnimplementedSimpleBankServer                        // gRPCs work right away without impl- forward compatibility
@@ -20,7 +22,7 @@ type Server struct {
 }
 
 // NewServer creates a new HTTP server and setup routing for service
-func NewServer(config util.Config, store db.Store, taskDistributor worker.TaskDistributor) (*Server, error) {
+func NewServer(config util.Config, store db.Store, currencies *currency.Registry, taskDistributor worker.TaskDistributor) (*Server, error) {
 	// token maker for auth handling from config
 	var tokenMaker token.Maker
 	var err error
@@ -29,6 +31,8 @@ func NewServer(config util.Config, store db.Store, taskDistributor worker.TaskDi
 		tokenMaker, err = token.NewJWTMaker(config.TokenSymmetricKey)
 	case "PASETO":
 		tokenMaker, err = token.NewPasetoMaker(config.TokenSymmetricKey)
+	default:
+		err = fmt.Errorf("unknown token maker type %q", config.TokenMakerType)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
@@ -37,6 +41,7 @@ func NewServer(config util.Config, store db.Store, taskDistributor worker.TaskDi
 	// server instance with store, tokenMaker & config
 	server := &Server{
 		store:           store,
+		currencies:      currencies,
 		tokenMaker:      tokenMaker,
 		config:          config,
 		taskDistributor: taskDistributor,
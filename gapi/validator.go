@@ -4,10 +4,18 @@ import (
 	"fmt"
 	"net/mail"
 	"regexp"
+	"strings"
+
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/currency"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
+	"github.com/web3dev6/simplebank/util"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 var (
-	isValidUsername = regexp.MustCompile(`^[a-z0-9_]+$`).MatchString
 	isValidFullname = regexp.MustCompile(`^[a-zA-Z\s]+$`).MatchString
 )
 
@@ -23,7 +31,7 @@ func ValidateUsername(value string) error {
 	if err := ValidateString(value, 3, 100); err != nil {
 		return err
 	}
-	if !isValidUsername(value) {
+	if !util.IsValidUsername(value) {
 		return fmt.Errorf("must contain only lowercase letters, digits or underscore")
 	}
 	return nil
@@ -66,3 +74,62 @@ func ValidateEmailId(value int64) error {
 func ValidateSecretCode(value string) error {
 	return ValidateString(value, 32, 128)
 }
+
+// ValidateCurrency checks value is a currency enabled in currencies - the same check as the http api's "currency" tag
+func ValidateCurrency(currencies *currency.Registry, value string) error {
+	if !currencies.IsEnabled(value) {
+		return fmt.Errorf("must be an enabled currency")
+	}
+	return nil
+}
+
+func ValidateAccountId(value int64) error {
+	if value <= 0 {
+		return fmt.Errorf("must be a positive integer")
+	}
+	return nil
+}
+
+// ValidateAmount parses value, a decimal string in major units of currency, to a positive amount
+func ValidateAmount(value string, currency string) (money.Money, error) {
+	amount, err := money.Parse(value, currency)
+	if err != nil {
+		return amount, err
+	}
+	if !amount.IsPositive() {
+		return amount, fmt.Errorf("must be positive")
+	}
+	return amount, nil
+}
+
+func ValidateReversalReason(value string) error {
+	if !db.IsReversalReason(value) {
+		return fmt.Errorf("must be one of %s", strings.Join(db.ReversalReasons(), ", "))
+	}
+	return nil
+}
+
+func ValidateTransferId(value int64) error {
+	if value <= 0 {
+		return fmt.Errorf("must be a positive integer")
+	}
+	return nil
+}
+
+// ValidateTransferDetails converts the optional memo, reference & metadata of a transfer to the store's,
+// with the violations of their limits named with prefix
+func ValidateTransferDetails(prefix string, memo string, reference string, metadata *structpb.Struct) (details db.TransferDetails, violations []apperr.FieldViolation) {
+	details = db.TransferDetails{Memo: memo, Reference: reference}
+	if metadata != nil {
+		var err error
+		details.Metadata, err = protojson.Marshal(metadata)
+		if err != nil {
+			return details, append(violations, fieldViolation(prefix+"metadata", err))
+		}
+	}
+	for _, violation := range details.Validate() {
+		violation.Field = prefix + violation.Field
+		violations = append(violations, violation)
+	}
+	return details, violations
+}
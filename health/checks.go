@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// PingCheck checks a dependency exposing Ping - like *pgxpool.Pool for postgres
+func PingCheck(pinger interface {
+	Ping(ctx context.Context) error
+}) Check {
+	return pinger.Ping
+}
+
+// RedisCheck checks the redis server used by asynq
+func RedisCheck(client redis.UniversalClient) Check {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// MigrationCheck checks the db schema is clean & at the latest migration version found at migrationURL
+// Note* reads the schema_migrations table kept by golang-migrate through the app's own conn pool
+// Note* the latest version is read once here, it only changes with a new release
+func MigrationCheck(connPool *pgxpool.Pool, migrationURL string) (Check, error) {
+	latest, err := latestMigrationVersion(migrationURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		var version int64
+		var dirty bool
+		err := connPool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("no migration applied, latest is %d", latest)
+			}
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if uint(version) != latest {
+			return fmt.Errorf("db at migration %d, latest is %d", version, latest)
+		}
+		return nil
+	}, nil
+}
+
+// latestMigrationVersion walks the migration source to its last version
+func latestMigrationVersion(migrationURL string) (uint, error) {
+	src, err := source.Open(migrationURL)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open migration source: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("cannot read first migration: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("cannot read migration after %d: %w", version, err)
+		}
+		version = next
+	}
+}
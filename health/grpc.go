@@ -0,0 +1,46 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// GrpcCheckInterval - how often readiness is re-checked for the grpc.health.v1.Health service
+const GrpcCheckInterval = 5 * time.Second
+
+// ServeGrpc mirrors readiness into healthServer (grpc.health.v1.Health) for the overall server ("") & given services
+// It blocks, re-checking every interval until ctx is done or the checker shuts down, then marks everything NOT_SERVING
+func (checker *Checker) ServeGrpc(ctx context.Context, healthServer *health.Server, interval time.Duration, services ...string) {
+	services = append([]string{""}, services...)
+
+	update := func() {
+		servingStatus := healthpb.HealthCheckResponse_SERVING
+		if checker.Check(ctx).Status != StatusUp {
+			servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		for _, service := range services {
+			healthServer.SetServingStatus(service, servingStatus)
+		}
+	}
+
+	update()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// Shutdown sets all services to NOT_SERVING & ignores later updates
+			healthServer.Shutdown()
+			return
+		case <-checker.shutdown:
+			// not ready right away, not at the next tick - the server itself may keep serving a while (SHUTDOWN_DRAIN_DELAY)
+			healthServer.Shutdown()
+			return
+		case <-ticker.C:
+			update()
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+   Liveness  (/healthz) - the process is up and serving, no dependency is checked
+   Readiness (/readyz)  - every registered dependency check passed, safe to route traffic here
+   Note* readiness fails as soon as graceful shutdown starts, so the orchestrator drains traffic before the servers stop (SHUTDOWN_DRAIN_DELAY later)
+*/
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	defaultCheckTimeout = 2 * time.Second
+)
+
+// Check verifies one dependency of the service, returns nil if healthy
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of one Check
+type CheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Report is the outcome of all readiness checks
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the registered readiness checks
+type Checker struct {
+	timeout      time.Duration
+	checks       []namedCheck
+	shuttingDown int32 // set atomically, 1 once graceful shutdown started
+	shutdown     chan struct{} // closed once graceful shutdown started
+	shutdownOnce sync.Once
+}
+
+// NewChecker creates a Checker, each check gets at most timeout to complete
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	return &Checker{
+		timeout:  timeout,
+		shutdown: make(chan struct{}),
+	}
+}
+
+// Register adds a named readiness check - call before serving any probe
+func (checker *Checker) Register(name string, check Check) {
+	checker.checks = append(checker.checks, namedCheck{name: name, check: check})
+}
+
+// Shutdown marks the service as not ready - called when graceful shutdown starts
+func (checker *Checker) Shutdown() {
+	atomic.StoreInt32(&checker.shuttingDown, 1)
+	checker.shutdownOnce.Do(func() { close(checker.shutdown) })
+}
+
+// Check runs every registered check concurrently and reports each one individually with its latency
+func (checker *Checker) Check(ctx context.Context) Report {
+	report := Report{
+		Status: StatusUp,
+		Checks: make([]CheckResult, len(checker.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range checker.checks {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checker.timeout)
+			defer cancel()
+
+			startTime := time.Now()
+			err := c.check(checkCtx)
+			result := CheckResult{
+				Name:    c.name,
+				Status:  StatusUp,
+				Latency: time.Since(startTime).String(),
+			}
+			if err != nil {
+				result.Status = StatusDown
+				result.Error = err.Error()
+			}
+			// note* each goroutine writes its own index, no lock needed
+			report.Checks[i] = result
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	if atomic.LoadInt32(&checker.shuttingDown) == 1 {
+		report.Status = StatusDown
+	}
+	return report
+}
+
+// Handler serves the liveness & readiness probes and forwards every other request to next
+// Note: probes are answered before the request pipeline, so they are never rate-limited and don't flood the request logs
+func (checker *Checker) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case LivenessPath:
+			writeJSON(res, http.StatusOK, Report{Status: StatusUp, Checks: []CheckResult{}})
+		case ReadinessPath:
+			report := checker.Check(req.Context())
+			statusCode := http.StatusOK
+			if report.Status != StatusUp {
+				statusCode = http.StatusServiceUnavailable
+			}
+			writeJSON(res, statusCode, report)
+		default:
+			next.ServeHTTP(res, req)
+		}
+	})
+}
+
+func writeJSON(res http.ResponseWriter, statusCode int, body interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(statusCode)
+	json.NewEncoder(res).Encode(body)
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func newTestChecker(redisErr error) *Checker {
+	checker := NewChecker(0)
+	checker.Register("postgres", func(ctx context.Context) error { return nil })
+	checker.Register("redis", func(ctx context.Context) error { return redisErr })
+	return checker
+}
+
+func TestCheck(t *testing.T) {
+	report := newTestChecker(nil).Check(context.Background())
+	require.Equal(t, StatusUp, report.Status)
+	require.Len(t, report.Checks, 2)
+	for _, result := range report.Checks {
+		require.Equal(t, StatusUp, result.Status)
+		require.NotEmpty(t, result.Latency)
+		require.Empty(t, result.Error)
+	}
+
+	// a single failing check marks the report down, every check is still reported individually
+	report = newTestChecker(errors.New("connection refused")).Check(context.Background())
+	require.Equal(t, StatusDown, report.Status)
+	require.Equal(t, "postgres", report.Checks[0].Name)
+	require.Equal(t, StatusUp, report.Checks[0].Status)
+	require.Equal(t, "redis", report.Checks[1].Name)
+	require.Equal(t, StatusDown, report.Checks[1].Status)
+	require.Equal(t, "connection refused", report.Checks[1].Error)
+}
+
+func TestHandler(t *testing.T) {
+	testCases := []struct {
+		name         string
+		path         string
+		redisErr     error
+		shutdown     bool
+		expectedCode int
+	}{
+		{name: "Liveness", path: LivenessPath, redisErr: errors.New("down"), expectedCode: http.StatusOK},
+		{name: "Ready", path: ReadinessPath, expectedCode: http.StatusOK},
+		{name: "NotReady", path: ReadinessPath, redisErr: errors.New("down"), expectedCode: http.StatusServiceUnavailable},
+		{name: "ShuttingDown", path: ReadinessPath, shutdown: true, expectedCode: http.StatusServiceUnavailable},
+		{name: "Forwarded", path: "/accounts", expectedCode: http.StatusTeapot},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			checker := newTestChecker(tc.redisErr)
+			if tc.shutdown {
+				checker.Shutdown()
+			}
+			handler := checker.Handler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				res.WriteHeader(http.StatusTeapot)
+			}))
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			require.Equal(t, tc.expectedCode, recorder.Code)
+
+			if tc.path == ReadinessPath {
+				var report Report
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+				require.Len(t, report.Checks, 2)
+			}
+		})
+	}
+}
+
+func TestServeGrpcShutdown(t *testing.T) {
+	checker := newTestChecker(nil)
+	healthServer := grpchealth.NewServer()
+	served := make(chan struct{})
+	go func() {
+		// an interval no tick reaches - only the shutdown can end it
+		checker.ServeGrpc(context.Background(), healthServer, time.Hour, "simple_bank")
+		close(served)
+	}()
+
+	request := &healthpb.HealthCheckRequest{Service: "simple_bank"}
+	require.Eventually(t, func() bool {
+		response, err := healthServer.Check(context.Background(), request)
+		return err == nil && response.Status == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 10*time.Millisecond)
+
+	checker.Shutdown()
+	checker.Shutdown() // idempotent
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("ServeGrpc still running after the checker shut down")
+	}
+	response, err := healthServer.Check(context.Background(), request)
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, response.Status)
+}
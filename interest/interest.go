@@ -0,0 +1,51 @@
+package interest
+
+import (
+	"fmt"
+	"math/big"
+)
+
+/*
+   Interest - accrued daily on the end of day balance, posted monthly
+	- a day's interest is balance * annual rate / 365 (actual/365 fixed), kept in millionths of a minor unit,
+	  so the interest of a small balance isn't rounded away day after day
+	- a posting pays the whole minor units of what was accrued, the fraction left is carried to the next one
+   Note* rates are in basis points, 200 is 2.00% a year
+*/
+
+const (
+	// MicrosPerMinorUnit - accruals are in millionths of a minor unit, e.g. of a cent
+	MicrosPerMinorUnit = 1_000_000
+	// DaysPerYear - a year of daily accruals, leap or not
+	DaysPerYear = 365
+	// BpsPerUnit - basis points in a rate of 1, i.e. 100%
+	BpsPerUnit = 10_000
+)
+
+// DailyAccrual returns the interest of a day on balance at annualRateBps, in millionths of a minor unit
+// rounded half up - never a tie, 365 is odd so the exact interest is never halfway between two micros
+// note* no interest on a balance of 0 or less
+func DailyAccrual(balance int64, annualRateBps int32) (int64, error) {
+	if balance <= 0 || annualRateBps <= 0 {
+		return 0, nil
+	}
+	// balance * rate/BpsPerUnit / DaysPerYear * MicrosPerMinorUnit, as balance * rate * 100 / 365 - in big ints, it overflows int64
+	numerator := new(big.Int).Mul(big.NewInt(balance), big.NewInt(int64(annualRateBps)*MicrosPerMinorUnit/BpsPerUnit))
+	quotient, remainder := new(big.Int).QuoRem(numerator, big.NewInt(DaysPerYear), new(big.Int))
+	if remainder.Int64()*2 > DaysPerYear {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if !quotient.IsInt64() {
+		return 0, fmt.Errorf("interest of balance %d at %d bps overflows", balance, annualRateBps)
+	}
+	return quotient.Int64(), nil
+}
+
+// Post splits accruedMicros in the whole minor units paid & the micros carried to the next posting
+// note* rounded down, it never pays interest not accrued yet - the fraction is paid once it adds up to a minor unit
+func Post(accruedMicros int64) (amount int64, carriedMicros int64) {
+	if accruedMicros <= 0 {
+		return 0, accruedMicros
+	}
+	return accruedMicros / MicrosPerMinorUnit, accruedMicros % MicrosPerMinorUnit
+}
@@ -0,0 +1,64 @@
+package interest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDailyAccrual(t *testing.T) {
+	testCases := []struct {
+		name     string
+		balance  int64
+		rateBps  int32
+		expected int64
+	}{
+		// 1000.00 at 2% - 20.00 a year, 5.479452054... cents a day
+		{name: "Savings", balance: 100000, rateBps: 200, expected: 5479452},
+		// 1 cent at 2% - 0.0000547945... cents a day, rounded up
+		{name: "OneCent", balance: 1, rateBps: 200, expected: 55},
+		// 0.0000273972... cents, rounded down
+		{name: "OneCentAtOnePercent", balance: 1, rateBps: 100, expected: 27},
+		// a year of 365 days pays the annual rate
+		{name: "FullRate", balance: 365, rateBps: 10000, expected: 1_000_000},
+		{name: "ZeroBalance", balance: 0, rateBps: 200, expected: 0},
+		{name: "NegativeBalance", balance: -100000, rateBps: 200, expected: 0},
+		{name: "ZeroRate", balance: 100000, rateBps: 0, expected: 0},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			amount, err := DailyAccrual(tc.balance, tc.rateBps)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, amount)
+		})
+	}
+
+	_, err := DailyAccrual(math.MaxInt64, 10000)
+	require.Error(t, err)
+}
+
+func TestAccrueAndPost(t *testing.T) {
+	// 30 days of 1000.00 at 2% - 164.38356... cents, 164 paid & the rest carried
+	var accrued int64
+	for day := 0; day < 30; day++ {
+		micros, err := DailyAccrual(100000, 200)
+		require.NoError(t, err)
+		accrued += micros
+	}
+	amount, carried := Post(accrued)
+	require.Equal(t, int64(164), amount)
+	require.Equal(t, int64(383560), carried)
+	require.Equal(t, accrued, amount*MicrosPerMinorUnit+carried)
+
+	// the carry is paid once it adds up to a cent
+	amount, carried = Post(carried + 700000)
+	require.Equal(t, int64(1), amount)
+	require.Equal(t, int64(83560), carried)
+
+	amount, carried = Post(0)
+	require.Zero(t, amount)
+	require.Zero(t, carried)
+}
@@ -0,0 +1,256 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
+	"github.com/web3dev6/simplebank/util"
+	"golang.org/x/sync/errgroup"
+)
+
+/*
+   Built-in load generator for transfers
+	1. Setup creates the users of a run, each with one account funded through the store
+	2. Run has Concurrency workers transfer small amounts between them through a Target - a few hot accounts
+	   take part in most transfers (like merchants), the rest are cold, so hot accounts see lock contention
+	3. Verify checks the ledger afterwards - the total balance is conserved & every balance equals the sum of its entries
+   Note* setup & verification always go through the store, even when the transfers run against a server
+*/
+
+const (
+	// usernameSpace - lowercase alphanumeric usernames are valid for both the http & grpc apis
+	usernameSpace = "0123456789abcdefghijklmnopqrstuvwxyz"
+	runIDLength   = 6
+)
+
+// Options configure a load test run
+type Options struct {
+	Users       int           // users, each with one account
+	HotAccounts int           // accounts taking part in most transfers
+	HotRatio    float64       // chance of each side of a transfer being a hot account
+	Concurrency int           // concurrent workers
+	Transfers   int           // transfers to attempt, 0 runs for Duration instead
+	Duration    time.Duration // how long to run when Transfers is 0
+	MaxAmount   int64         // transfers are of 1..MaxAmount
+	Funding     int64         // initial balance of every account
+	Currency    string
+	Password    string // password of every user, to log in against a server
+	Seed        int64  // seed of the transfer sequence, each worker derives its own
+}
+
+// Validate returns an error for options a run can't be made with
+func (options Options) Validate() error {
+	switch {
+	case options.Users < 2:
+		return errors.New("at least 2 users are needed")
+	case options.HotAccounts < 0 || options.HotAccounts > options.Users:
+		return fmt.Errorf("hot accounts must be between 0 and %d users", options.Users)
+	case options.HotRatio < 0 || options.HotRatio > 1:
+		return errors.New("hot ratio must be between 0 and 1")
+	case options.Concurrency < 1:
+		return errors.New("concurrency must be at least 1")
+	case options.Transfers < 0 || (options.Transfers == 0 && options.Duration <= 0):
+		return errors.New("either a number of transfers or a duration is needed")
+	case options.MaxAmount < 1 || options.Funding < 1:
+		return errors.New("max amount & funding must be positive")
+	case !money.IsKnownCurrency(options.Currency):
+		// note* the db rejects a currency missing from the currencies table
+		return fmt.Errorf("unknown currency %s", options.Currency)
+	}
+	return nil
+}
+
+// Setup creates options.Users users with a funded account each, under a new run id
+func Setup(ctx context.Context, store db.Store, options Options) ([]Account, error) {
+	hashedPassword, err := util.HashPassword(options.Password)
+	if err != nil {
+		return nil, err
+	}
+	runID := util.RandomString(runIDLength, usernameSpace)
+
+	accounts := make([]Account, options.Users)
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(options.Concurrency)
+	for i := range accounts {
+		i := i
+		group.Go(func() error {
+			username := fmt.Sprintf("lt%s%d", runID, i)
+			_, err := store.CreateUser(ctx, db.CreateUserParams{
+				Username:       username,
+				HashedPassword: hashedPassword,
+				FullName:       "Load Test",
+				Email:          username + "@loadtest.example.com",
+			})
+			if err != nil {
+				return fmt.Errorf("cannot create user %s: %w", username, err)
+			}
+			account, err := store.CreateAccount(ctx, db.CreateAccountParams{
+				Owner:    username,
+				Currency: options.Currency,
+				Balance:  0,
+			})
+			if err != nil {
+				return fmt.Errorf("cannot create account of %s: %w", username, err)
+			}
+			// note* funded with an entry, so the balance equals the sum of the entries from the start
+			_, err = store.FundAccountTx(ctx, db.FundAccountTxParams{AccountID: account.ID, Amount: options.Funding})
+			if err != nil {
+				return fmt.Errorf("cannot fund account of %s: %w", username, err)
+			}
+			accounts[i] = Account{ID: account.ID, Owner: username, Password: options.Password, Currency: account.Currency}
+			return nil
+		})
+	}
+	if err = group.Wait(); err != nil {
+		return nil, err
+	}
+	log.Info().Str("run_id", runID).Int("accounts", len(accounts)).Msg("load test accounts ready")
+	return accounts, nil
+}
+
+// picker picks the accounts of a transfer, hot ones with the chance of hotRatio
+type picker struct {
+	random   *util.Random
+	accounts []Account
+	hot      int
+	hotRatio float64
+}
+
+// pick returns the index of an account, hot ones are the first p.hot accounts
+func (p *picker) pick() int {
+	cold := len(p.accounts) - p.hot
+	if p.hot > 0 && (cold == 0 || p.random.Float64() < p.hotRatio) {
+		return p.random.Intn(p.hot)
+	}
+	return p.hot + p.random.Intn(cold)
+}
+
+// transfer returns a transfer of 1..maxAmount between two different accounts
+func (p *picker) transfer(maxAmount int64) Transfer {
+	from := p.pick()
+	to := p.pick()
+	for to == from {
+		to = p.pick()
+	}
+	return Transfer{
+		From:   p.accounts[from],
+		To:     p.accounts[to],
+		Amount: p.random.RandomInt(1, maxAmount),
+	}
+}
+
+// Run attempts transfers between accounts through target until options.Transfers are done, options.Duration is over or ctx is done
+func Run(ctx context.Context, target Target, accounts []Account, options Options) Report {
+	if options.Transfers == 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Duration)
+		defer cancel()
+	}
+
+	var (
+		mutex     sync.Mutex
+		latencies []time.Duration
+		outcomes  = make(map[Outcome]int)
+		attempted int64
+		wg        sync.WaitGroup
+	)
+	// note* retries counted before the run (e.g. by Setup) are left out
+	retryCounting, countsRetries := target.(retryCountingTarget)
+	var retriesBefore map[Outcome]int
+	if countsRetries {
+		retriesBefore = retryCounting.RetriesCounted()
+	}
+	start := time.Now()
+	for w := 0; w < options.Concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			p := &picker{
+				random:   util.NewRandom(options.Seed + int64(w)),
+				accounts: accounts,
+				hot:      options.HotAccounts,
+				hotRatio: options.HotRatio,
+			}
+			// each worker collects its own results, merged at the end
+			workerLatencies := make([]time.Duration, 0, 1024)
+			workerOutcomes := make(map[Outcome]int)
+			for ctx.Err() == nil {
+				if options.Transfers > 0 && atomic.AddInt64(&attempted, 1) > int64(options.Transfers) {
+					break
+				}
+				transfer := p.transfer(options.MaxAmount)
+				transferStart := time.Now()
+				outcome, err := target.Transfer(ctx, transfer)
+				if ctx.Err() != nil {
+					// cut off by the end of the run, neither a success nor a failure
+					break
+				}
+				workerLatencies = append(workerLatencies, time.Since(transferStart))
+				workerOutcomes[outcome]++
+				if outcome == OutcomeError {
+					log.Debug().Err(err).Int64("from", transfer.From.ID).Int64("to", transfer.To.ID).Msg("load test transfer failed")
+				}
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			latencies = append(latencies, workerLatencies...)
+			for outcome, n := range workerOutcomes {
+				outcomes[outcome] += n
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+
+	var retries map[Outcome]int
+	if countsRetries {
+		retries = retryCounting.RetriesCounted()
+		if retries != nil {
+			for outcome, n := range retriesBefore {
+				retries[outcome] -= n
+			}
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return Report{
+		Elapsed:  elapsed,
+		Attempts: len(latencies),
+		Outcomes: outcomes,
+		Retries:  retries,
+		P50:      percentile(latencies, 0.50),
+		P99:      percentile(latencies, 0.99),
+		Max:      percentile(latencies, 1),
+	}
+}
+
+// Verify checks the ledger of accounts after a run - their total balance must still be len(accounts)*funding
+// & every balance must equal the sum of the account's entries
+// note* store must read from the primary db - balances read from a lagging replica wouldn't match their entries
+func Verify(ctx context.Context, store db.Store, accounts []Account, funding int64) (Conservation, error) {
+	conservation := Conservation{Expected: int64(len(accounts)) * funding}
+	for _, account := range accounts {
+		dbAccount, err := store.GetAccount(ctx, account.ID)
+		if err != nil {
+			return conservation, fmt.Errorf("cannot get account %d: %w", account.ID, err)
+		}
+		entriesSum, err := store.GetEntriesSum(ctx, account.ID)
+		if err != nil {
+			return conservation, fmt.Errorf("cannot sum the entries of account %d: %w", account.ID, err)
+		}
+		conservation.Actual += dbAccount.Balance
+		if dbAccount.Balance != entriesSum {
+			conservation.Mismatched = append(conservation.Mismatched, account.ID)
+		}
+	}
+	return conservation, nil
+}
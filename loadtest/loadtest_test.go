@@ -0,0 +1,280 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/util"
+)
+
+func testOptions() Options {
+	return Options{
+		Users:       20,
+		HotAccounts: 2,
+		HotRatio:    0.8,
+		Concurrency: 4,
+		Transfers:   500,
+		MaxAmount:   10,
+		Funding:     1000,
+		Currency:    util.USD,
+		Password:    "secret",
+		Seed:        1,
+	}
+}
+
+// ledger is an in-memory store of balances & entry sums behind a mocked store
+type ledger struct {
+	mutex    sync.Mutex
+	balances map[int64]int64
+	entries  map[int64]int64
+}
+
+func newLedgerStore(t *testing.T, ledger *ledger, transferErr func(n int64) error) *mockdb.MockStore {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	var nextID, transfers int64
+	store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.User, error) {
+			require.NoError(t, util.CheckPassword("secret", arg.HashedPassword))
+			return db.User{Username: arg.Username}, nil
+		})
+	store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			return db.Account{ID: atomic.AddInt64(&nextID, 1), Owner: arg.Owner, Currency: arg.Currency}, nil
+		})
+	store.EXPECT().FundAccountTx(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.FundAccountTxParams) (db.FundAccountTxResult, error) {
+			ledger.mutex.Lock()
+			defer ledger.mutex.Unlock()
+			ledger.balances[arg.AccountID] += arg.Amount
+			ledger.entries[arg.AccountID] += arg.Amount
+			return db.FundAccountTxResult{}, nil
+		})
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+			if err := transferErr(atomic.AddInt64(&transfers, 1)); err != nil {
+				return db.TransferTxResult{}, err
+			}
+			ledger.mutex.Lock()
+			defer ledger.mutex.Unlock()
+			ledger.balances[arg.FromAccountID] -= arg.Amount
+			ledger.entries[arg.FromAccountID] -= arg.Amount
+			ledger.balances[arg.ToAccountID] += arg.Amount
+			ledger.entries[arg.ToAccountID] += arg.Amount
+			return db.TransferTxResult{}, nil
+		})
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, id int64) (db.Account, error) {
+			ledger.mutex.Lock()
+			defer ledger.mutex.Unlock()
+			return db.Account{ID: id, Balance: ledger.balances[id]}, nil
+		})
+	store.EXPECT().GetEntriesSum(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, id int64) (int64, error) {
+			ledger.mutex.Lock()
+			defer ledger.mutex.Unlock()
+			return ledger.entries[id], nil
+		})
+	return store
+}
+
+func TestRunStoreTarget(t *testing.T) {
+	options := testOptions()
+	ledger := &ledger{balances: make(map[int64]int64), entries: make(map[int64]int64)}
+	// every 10th transfer deadlocks, every 25th fails serialization
+	store := newLedgerStore(t, ledger, func(n int64) error {
+		switch {
+		case n%10 == 0:
+			return &pgconn.PgError{Code: db.DeadlockDetected}
+		case n%25 == 0:
+			return &pgconn.PgError{Code: db.SerializationFailure}
+		}
+		return nil
+	})
+
+	ctx := context.Background()
+	accounts, err := Setup(ctx, store, options)
+	require.NoError(t, err)
+	require.Len(t, accounts, options.Users)
+	owners := make(map[string]bool)
+	for _, account := range accounts {
+		require.NotZero(t, account.ID)
+		require.Equal(t, options.Currency, account.Currency)
+		require.False(t, owners[account.Owner])
+		owners[account.Owner] = true
+	}
+
+	report := Run(ctx, StoreTarget{Store: store}, accounts, options)
+	require.Equal(t, options.Transfers, report.Attempts)
+	require.Equal(t, 50, report.Outcomes[OutcomeDeadlock])
+	require.Equal(t, 10, report.Outcomes[OutcomeSerializationFailure]) // 25, 75, ... - not multiples of 10
+	require.Equal(t, options.Transfers-60, report.Outcomes[OutcomeOK])
+	require.LessOrEqual(t, report.P50, report.P99)
+	require.LessOrEqual(t, report.P99, report.Max)
+
+	conservation, err := Verify(ctx, store, accounts, options.Funding)
+	require.NoError(t, err)
+	require.True(t, conservation.OK())
+	require.Equal(t, int64(options.Users)*options.Funding, conservation.Actual)
+
+	// money out of thin air is caught
+	ledger.balances[accounts[0].ID] += 1
+	conservation, err = Verify(ctx, store, accounts, options.Funding)
+	require.NoError(t, err)
+	require.False(t, conservation.OK())
+	require.Equal(t, []int64{accounts[0].ID}, conservation.Mismatched)
+}
+
+func TestRunCountsRetries(t *testing.T) {
+	options := testOptions()
+	ledger := &ledger{balances: make(map[int64]int64), entries: make(map[int64]int64)}
+	retries := &RetryCounter{}
+	// every 10th transfer is retried once after a deadlock, every 25th after a serialization failure - all succeed in the end,
+	// as if the store's TxConfig.OnRetry was retries.OnRetry
+	store := newLedgerStore(t, ledger, func(n int64) error {
+		switch {
+		case n%10 == 0:
+			retries.OnRetry(db.DeadlockDetected)
+		case n%25 == 0:
+			retries.OnRetry(db.SerializationFailure)
+		}
+		return nil
+	})
+
+	ctx := context.Background()
+	accounts, err := Setup(ctx, store, options)
+	require.NoError(t, err)
+	// counted before the run, left out of its report
+	retries.OnRetry(db.DeadlockDetected)
+
+	report := Run(ctx, StoreTarget{Store: store, Retries: retries}, accounts, options)
+	require.Equal(t, options.Transfers, report.Outcomes[OutcomeOK])
+	require.Zero(t, report.Outcomes[OutcomeDeadlock])
+	require.Equal(t, 50, report.Retries[OutcomeDeadlock])
+	require.Equal(t, 10, report.Retries[OutcomeSerializationFailure])
+
+	// not counted
+	report = Run(ctx, StoreTarget{Store: store}, accounts, options)
+	require.Nil(t, report.Retries)
+}
+
+func TestRunDuration(t *testing.T) {
+	options := testOptions()
+	options.Transfers = 0
+	options.Duration = 50 * time.Millisecond
+	ledger := &ledger{balances: make(map[int64]int64), entries: make(map[int64]int64)}
+	store := newLedgerStore(t, ledger, func(int64) error { return nil })
+
+	accounts, err := Setup(context.Background(), store, options)
+	require.NoError(t, err)
+	report := Run(context.Background(), StoreTarget{Store: store}, accounts, options)
+	require.Positive(t, report.Attempts)
+	require.Equal(t, report.Attempts, report.Outcomes[OutcomeOK])
+	require.GreaterOrEqual(t, report.Elapsed, options.Duration)
+	require.Positive(t, report.Throughput())
+}
+
+func TestPicker(t *testing.T) {
+	accounts := make([]Account, 100)
+	for i := range accounts {
+		accounts[i] = Account{ID: int64(i + 1)}
+	}
+	p := &picker{random: util.NewRandom(1), accounts: accounts, hot: 5, hotRatio: 0.8}
+
+	n := 10000
+	var hot int
+	for i := 0; i < n; i++ {
+		transfer := p.transfer(10)
+		require.NotEqual(t, transfer.From.ID, transfer.To.ID)
+		require.GreaterOrEqual(t, transfer.Amount, int64(1))
+		require.LessOrEqual(t, transfer.Amount, int64(10))
+		if transfer.To.ID <= 5 {
+			hot++
+		}
+	}
+	// ~80% of the receivers are the 5 hot accounts
+	require.InDelta(t, 0.8, float64(hot)/float64(n), 0.03)
+
+	// no hot accounts - everything is cold
+	p = &picker{random: util.NewRandom(1), accounts: accounts, hot: 0, hotRatio: 0.8}
+	for i := 0; i < 100; i++ {
+		require.Less(t, p.pick(), len(accounts))
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	require.Zero(t, percentile(nil, 0.5))
+
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	require.Equal(t, 50*time.Millisecond, percentile(sorted, 0.50))
+	require.Equal(t, 99*time.Millisecond, percentile(sorted, 0.99))
+	require.Equal(t, 100*time.Millisecond, percentile(sorted, 1))
+	require.Equal(t, time.Millisecond, percentile(sorted[:1], 0.99))
+}
+
+func TestStoreOutcome(t *testing.T) {
+	require.Equal(t, OutcomeOK, storeOutcome(nil))
+	require.Equal(t, OutcomeDeadlock, storeOutcome(&pgconn.PgError{Code: db.DeadlockDetected}))
+	require.Equal(t, OutcomeSerializationFailure, storeOutcome(&pgconn.PgError{Code: db.SerializationFailure}))
+	require.Equal(t, OutcomeError, storeOutcome(errors.New("conn reset")))
+}
+
+func TestHttpTarget(t *testing.T) {
+	var logins int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		switch req.URL.Path {
+		case "/users/login":
+			atomic.AddInt64(&logins, 1)
+			require.Equal(t, "secret", body["password"])
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "token-" + body["username"].(string)})
+		case "/transfers":
+			require.Equal(t, "Bearer token-alice", req.Header.Get("Authorization"))
+			// minor units are sent in major units
+			switch body["amount"].(string) {
+			case "0.01":
+				w.WriteHeader(http.StatusOK)
+			case "0.02":
+				w.WriteHeader(http.StatusUnprocessableEntity)
+			case "0.03":
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+	}))
+	defer server.Close()
+
+	target := NewHttpTarget(server.URL+"/", time.Second)
+	from := Account{ID: 1, Owner: "alice", Password: "secret", Currency: util.USD}
+	to := Account{ID: 2, Owner: "bob", Password: "secret", Currency: util.USD}
+	for amount, expected := range map[int64]Outcome{
+		1: OutcomeOK,
+		2: OutcomeInsufficientFunds,
+		3: OutcomeRateLimited,
+		4: OutcomeError,
+	} {
+		outcome, err := target.Transfer(context.Background(), Transfer{From: from, To: to, Amount: amount})
+		require.Equal(t, expected, outcome)
+		require.Equal(t, expected != OutcomeOK, err != nil)
+	}
+	// logged in once, the token is reused
+	require.Equal(t, int64(1), logins)
+}
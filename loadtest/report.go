@@ -0,0 +1,75 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Report summarizes a run
+type Report struct {
+	Elapsed  time.Duration
+	Attempts int             // transfers that completed, successfully or not
+	Outcomes map[Outcome]int // attempts by outcome
+	Retries  map[Outcome]int // retries of the transfers' txs by the outcome of the retried attempt, nil if the target can't see them
+	P50      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+// Throughput returns the successful transfers per second
+func (report Report) Throughput() float64 {
+	if report.Elapsed <= 0 {
+		return 0
+	}
+	return float64(report.Outcomes[OutcomeOK]) / report.Elapsed.Seconds()
+}
+
+// Conservation is the result of verifying the ledger after a run
+type Conservation struct {
+	Expected   int64   // total balance the accounts were funded with
+	Actual     int64   // total balance after the run
+	Mismatched []int64 // accounts whose balance differs from the sum of their entries
+}
+
+// OK reports whether no money was created or lost
+func (conservation Conservation) OK() bool {
+	return conservation.Expected == conservation.Actual && len(conservation.Mismatched) == 0
+}
+
+// percentile returns the p-th percentile (0..1] of sorted latencies, by nearest rank
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Print writes report & conservation in a human readable form
+func Print(w io.Writer, report Report, conservation Conservation) {
+	fmt.Fprintf(w, "elapsed:     %s\n", report.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "attempts:    %d\n", report.Attempts)
+	fmt.Fprintf(w, "throughput:  %.1f transfers/s\n", report.Throughput())
+	fmt.Fprintf(w, "latency:     p50 %s  p99 %s  max %s\n",
+		report.P50.Round(time.Microsecond), report.P99.Round(time.Microsecond), report.Max.Round(time.Microsecond))
+	for _, outcome := range Outcomes {
+		fmt.Fprintf(w, "  %-22s %d\n", outcome, report.Outcomes[outcome])
+	}
+	if report.Retries == nil {
+		fmt.Fprintf(w, "retries:     not seen by the target\n")
+	} else {
+		fmt.Fprintf(w, "retries:     %d deadlocks, %d serialization failures\n",
+			report.Retries[OutcomeDeadlock], report.Retries[OutcomeSerializationFailure])
+	}
+	status := "ok"
+	if !conservation.OK() {
+		status = "VIOLATED"
+	}
+	fmt.Fprintf(w, "conservation: %s (expected %d, actual %d, mismatched accounts %v)\n",
+		status, conservation.Expected, conservation.Actual, conservation.Mismatched)
+}
@@ -0,0 +1,258 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
+)
+
+// Outcome is how a transfer attempt ended
+type Outcome string
+
+const (
+	OutcomeOK                   Outcome = "ok"
+	OutcomeDeadlock             Outcome = "deadlock"
+	OutcomeSerializationFailure Outcome = "serialization_failure"
+	OutcomeInsufficientFunds    Outcome = "insufficient_funds"
+	OutcomeConflict             Outcome = "conflict"
+	OutcomeRateLimited          Outcome = "rate_limited"
+	OutcomeError                Outcome = "error"
+)
+
+// Outcomes lists every outcome, in report order
+var Outcomes = []Outcome{
+	OutcomeOK,
+	OutcomeDeadlock,
+	OutcomeSerializationFailure,
+	OutcomeInsufficientFunds,
+	OutcomeConflict,
+	OutcomeRateLimited,
+	OutcomeError,
+}
+
+// Account is a load test account & its owner's credentials
+type Account struct {
+	ID       int64
+	Owner    string
+	Password string
+	Currency string
+}
+
+// Transfer is a transfer the load generator attempts
+type Transfer struct {
+	From   Account
+	To     Account
+	Amount int64
+}
+
+// Target executes transfers - in-process through the store, or against a running server
+type Target interface {
+	Transfer(ctx context.Context, transfer Transfer) (Outcome, error)
+}
+
+// retryCountingTarget is a Target which sees the retries of its transfers, e.g. a StoreTarget
+type retryCountingTarget interface {
+	RetriesCounted() map[Outcome]int
+}
+
+// StoreTarget runs transfers in-process with Store.TransferTx, the same tx the api servers run
+// Note* the store retries deadlocks & serialization failures up to its TxConfig.MaxRetries, only the ones left show in the outcomes -
+// Retries counts all of them when it's the OnRetry of the store's TxConfig
+type StoreTarget struct {
+	Store   db.Store
+	Retries *RetryCounter // nil if not counted
+}
+
+// RetriesCounted returns the retries counted so far, nil if not counted
+func (target StoreTarget) RetriesCounted() map[Outcome]int {
+	if target.Retries == nil {
+		return nil
+	}
+	return target.Retries.Counts()
+}
+
+// RetryCounter counts the retried txs of a store by outcome - set its OnRetry as the store's TxConfig.OnRetry
+type RetryCounter struct {
+	deadlocks             int64
+	serializationFailures int64
+}
+
+// OnRetry counts one retry of a tx which failed with the postgres error code
+func (counter *RetryCounter) OnRetry(code string) {
+	switch code {
+	case db.DeadlockDetected:
+		atomic.AddInt64(&counter.deadlocks, 1)
+	case db.SerializationFailure:
+		atomic.AddInt64(&counter.serializationFailures, 1)
+	}
+}
+
+// Counts returns the retries so far by outcome
+func (counter *RetryCounter) Counts() map[Outcome]int {
+	return map[Outcome]int{
+		OutcomeDeadlock:             int(atomic.LoadInt64(&counter.deadlocks)),
+		OutcomeSerializationFailure: int(atomic.LoadInt64(&counter.serializationFailures)),
+	}
+}
+
+func (target StoreTarget) Transfer(ctx context.Context, transfer Transfer) (Outcome, error) {
+	_, err := target.Store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: transfer.From.ID,
+		ToAccountID:   transfer.To.ID,
+		Amount:        transfer.Amount,
+	})
+	return storeOutcome(err), err
+}
+
+// storeOutcome classifies an error of the store by its postgres error code
+func storeOutcome(err error) Outcome {
+	if err == nil {
+		return OutcomeOK
+	}
+	switch db.ErrorCode(err) {
+	case db.DeadlockDetected:
+		return OutcomeDeadlock
+	case db.SerializationFailure:
+		return OutcomeSerializationFailure
+	}
+	return OutcomeError
+}
+
+// HttpTarget runs transfers against the Gin http api at BaseURL (e.g. http://localhost:8080)
+// every owner logs in once, its access token is reused until the server rejects it
+// Note* the server maps db errors to problem codes, so deadlocks & serialization failures only show as errors here -
+// & the ones it retried don't show at all, they're in its simple_bank_db_tx_retries_total metric
+type HttpTarget struct {
+	BaseURL string
+	Client  *http.Client
+
+	mutex  sync.Mutex
+	tokens map[string]string // access token by username
+}
+
+// NewHttpTarget creates a HttpTarget for the server at baseURL
+func NewHttpTarget(baseURL string, timeout time.Duration) *HttpTarget {
+	return &HttpTarget{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  &http.Client{Timeout: timeout},
+		tokens:  make(map[string]string),
+	}
+}
+
+func (target *HttpTarget) Transfer(ctx context.Context, transfer Transfer) (Outcome, error) {
+	token, err := target.accessToken(ctx, transfer.From)
+	if err != nil {
+		return OutcomeError, err
+	}
+
+	// note* amounts are minor units here, the api takes them in major units
+	amount := money.Money{Amount: transfer.Amount, Currency: transfer.From.Currency}
+	statusCode, err := target.post(ctx, "/transfers", token, jsonBody{
+		"from_account_id": transfer.From.ID,
+		"to_account_id":   transfer.To.ID,
+		"amount":          amount.Decimal(),
+		"currency":        transfer.From.Currency,
+	}, nil)
+	if err != nil {
+		return OutcomeError, err
+	}
+	if statusCode == http.StatusUnauthorized {
+		// expired token - log in again on the next transfer
+		target.mutex.Lock()
+		delete(target.tokens, transfer.From.Owner)
+		target.mutex.Unlock()
+	}
+	outcome := httpOutcome(statusCode)
+	if outcome != OutcomeOK {
+		return outcome, fmt.Errorf("transfer failed with status %d", statusCode)
+	}
+	return outcome, nil
+}
+
+// httpOutcome classifies a response of the http api by its status
+func httpOutcome(statusCode int) Outcome {
+	switch statusCode {
+	case http.StatusOK:
+		return OutcomeOK
+	case http.StatusUnprocessableEntity:
+		return OutcomeInsufficientFunds
+	case http.StatusConflict:
+		return OutcomeConflict
+	case http.StatusTooManyRequests:
+		return OutcomeRateLimited
+	}
+	return OutcomeError
+}
+
+// jsonBody is a json object request body
+type jsonBody map[string]interface{}
+
+// accessToken returns the cached access token of account's owner, logging in if there's none
+func (target *HttpTarget) accessToken(ctx context.Context, account Account) (string, error) {
+	target.mutex.Lock()
+	token, ok := target.tokens[account.Owner]
+	target.mutex.Unlock()
+	if ok {
+		return token, nil
+	}
+
+	var rsp struct {
+		AccessToken string `json:"access_token"`
+	}
+	statusCode, err := target.post(ctx, "/users/login", "", jsonBody{
+		"username": account.Owner,
+		"password": account.Password,
+	}, &rsp)
+	if err != nil {
+		return "", err
+	}
+	if statusCode != http.StatusOK {
+		return "", fmt.Errorf("cannot log in %s, status %d", account.Owner, statusCode)
+	}
+
+	target.mutex.Lock()
+	target.tokens[account.Owner] = rsp.AccessToken
+	target.mutex.Unlock()
+	return rsp.AccessToken, nil
+}
+
+// post sends body as json to path & decodes a 200 response into rsp, if not nil
+func (target *HttpTarget) post(ctx context.Context, path string, token string, body jsonBody, rsp interface{}) (int, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.BaseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := target.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusOK && rsp != nil {
+		if err = json.NewDecoder(res.Body).Decode(rsp); err != nil {
+			return res.StatusCode, fmt.Errorf("cannot decode %s response: %w", path, err)
+		}
+		return res.StatusCode, nil
+	}
+	// note* drained so the connection is reused
+	io.Copy(io.Discard, res.Body)
+	return res.StatusCode, nil
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"context"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+/*
+   Request IDs & request-scoped logging
+   Note* every entry point (Gin, gRPC, gateway, asynq tasks) puts a request id in the context,
+        along with a logger tagged with it - db, worker & mail log through Logger(ctx) so all their lines correlate
+*/
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	// client supplied ids are echoed in headers & logs - only short printable ones are accepted
+	maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+type loggerKey struct{}
+
+// RequestIDOrNew returns id if it's a valid request id sent by a client, else a freshly generated one
+func RequestIDOrNew(id string) string {
+	if validRequestID(id) {
+		return id
+	}
+	return uuid.NewString()
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) || unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestID returns ctx carrying id and a logger tagged with it - plus the trace id, if ctx is in a trace
+func WithRequestID(ctx context.Context, id string) context.Context {
+	logContext := log.Logger.With().Str("request_id", id)
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		logContext = logContext.Str("trace_id", spanContext.TraceID().String())
+	}
+	logger := logContext.Logger()
+
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return context.WithValue(ctx, loggerKey{}, &logger)
+}
+
+// RequestID returns the request id carried by ctx, empty outside of a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Logger returns the request-scoped logger carried by ctx, or the global logger outside of a request
+func Logger(ctx context.Context) *zerolog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zerolog.Logger); ok {
+		return logger
+	}
+	return &log.Logger
+}
@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDOrNew(t *testing.T) {
+	require.Equal(t, "client-id-1", RequestIDOrNew("client-id-1"))
+
+	// invalid client ids are replaced
+	for _, id := range []string{"", "with space", "new\nline", "ünicode", strings.Repeat("a", maxRequestIDLength+1)} {
+		generated := RequestIDOrNew(id)
+		require.NotEqual(t, id, generated)
+		require.Len(t, generated, 36)
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	globalLogger := log.Logger
+	log.Logger = log.Output(&buf)
+	defer func() { log.Logger = globalLogger }()
+
+	ctx := context.Background()
+	require.Empty(t, RequestID(ctx))
+	require.Equal(t, &log.Logger, Logger(ctx))
+
+	ctx = WithRequestID(ctx, "req-1")
+	require.Equal(t, "req-1", RequestID(ctx))
+
+	Logger(ctx).Info().Msg("hello")
+	require.Contains(t, buf.String(), `"request_id":"req-1"`)
+}
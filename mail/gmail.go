@@ -1,10 +1,12 @@
 package mail
 
 import (
+	"context"
 	"fmt"
 	"net/smtp"
 
 	"github.com/jordan-wright/email"
+	"github.com/web3dev6/simplebank/logging"
 )
 
 const (
@@ -27,6 +29,7 @@ func NewGmailSender(name string, fromEmailAddress string, fromEmailPassword stri
 }
 
 func (sender *GmailSender) SendEmail(
+	ctx context.Context,
 	subject string,
 	content string,
 	to []string,
@@ -53,5 +56,14 @@ func (sender *GmailSender) SendEmail(
 	smtpAuth := smtp.PlainAuth("", sender.fromEmailAddress, sender.fromEmailPassword, smtpGmailAuthAddress)
 
 	// send email
-	return e.Send(smtpGmailServerAddress, smtpAuth)
+	err := e.Send(smtpGmailServerAddress, smtpAuth)
+	if err != nil {
+		return err
+	}
+	logging.Logger(ctx).Info().
+		Str("subject", subject).
+		Int("recipients", len(to)+len(cc)+len(bcc)).
+		Int("attachments", len(attachFiles)).
+		Msg("sent email")
+	return nil
 }
@@ -1,6 +1,7 @@
 package mail
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -29,6 +30,6 @@ func TestSendEmailWithGamil(t *testing.T) {
 	attachFiles := []string{"../simple-bank.pdf"}
 
 	// send email
-	err = sender.SendEmail(subject, content, to, nil, nil, attachFiles)
+	err = sender.SendEmail(context.Background(), subject, content, to, nil, nil, attachFiles)
 	require.NoError(t, err)
 }
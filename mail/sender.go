@@ -1,7 +1,10 @@
 package mail
 
+import "context"
+
 type EmailSender interface {
 	SendEmail(
+		ctx context.Context,
 		subject string,
 		content string,
 		to []string,
@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+/*
+   Prometheus metrics for every layer of the service - scraped from /metrics
+   Note* all collectors live in the default registry, which also exports the go runtime & process metrics
+*/
+
+const (
+	Path      = "/metrics"
+	namespace = "simplebank"
+
+	// UnmatchedRoute labels requests for unknown paths - keeps label cardinality bounded
+	UnmatchedRoute = "unmatched"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Number of served requests by entry point, route and status.",
+	}, []string{"protocol", "method", "route", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Latency of served requests by entry point and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"protocol", "method", "route"})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of sqlc queries by query name and outcome.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"query", "status"})
+
+	dbTxRetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_tx_retries_total",
+		Help:      "Number of db transactions retried by the postgres error code they failed with.",
+	}, []string{"code"})
+
+	tasksEnqueuedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_enqueued_total",
+		Help:      "Number of tasks enqueued to redis by task type and queue.",
+	}, []string{"task_type", "queue"})
+
+	tasksProcessedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_processed_total",
+		Help:      "Number of processed tasks by task type and outcome (success/failure).",
+	}, []string{"task_type", "status"})
+
+	tasksRetriedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_retried_total",
+		Help:      "Number of task runs that were a retry of an earlier failed run, by task type.",
+	}, []string{"task_type"})
+
+	taskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_duration_seconds",
+		Help:      "Processing latency of tasks by task type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"task_type"})
+
+	transfersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Number of committed transfers by currency.",
+	}, []string{"currency"})
+
+	transferVolumeTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_volume_total",
+		Help:      "Sum of committed transfer amounts by currency.",
+	}, []string{"currency"})
+)
+
+// ObserveRequest records one served request - statusCode is an http status, or a grpc code for the grpc protocol
+func ObserveRequest(protocol string, method string, route string, statusCode int, duration time.Duration) {
+	requestsTotal.WithLabelValues(protocol, method, route, strconv.Itoa(statusCode)).Inc()
+	requestDuration.WithLabelValues(protocol, method, route).Observe(duration.Seconds())
+}
+
+// ObserveQuery records one db query
+func ObserveQuery(query string, duration time.Duration, err error) {
+	dbQueryDuration.WithLabelValues(query, outcome(err)).Observe(duration.Seconds())
+}
+
+// ObserveTxRetry records one retry of a db tx which failed with the postgres error code
+func ObserveTxRetry(code string) {
+	dbTxRetriesTotal.WithLabelValues(code).Inc()
+}
+
+// ObserveTaskEnqueued records one task put in a redis queue
+func ObserveTaskEnqueued(taskType string, queue string) {
+	tasksEnqueuedTotal.WithLabelValues(taskType, queue).Inc()
+}
+
+// ObserveTask records one processed task - retried is true if this run is a retry
+func ObserveTask(taskType string, duration time.Duration, retried bool, err error) {
+	tasksProcessedTotal.WithLabelValues(taskType, outcome(err)).Inc()
+	taskDuration.WithLabelValues(taskType).Observe(duration.Seconds())
+	if retried {
+		tasksRetriedTotal.WithLabelValues(taskType).Inc()
+	}
+}
+
+// ObserveTransfer records one committed transfer
+func ObserveTransfer(currency string, amount int64) {
+	transfersTotal.WithLabelValues(currency).Inc()
+	transferVolumeTotal.WithLabelValues(currency).Add(float64(amount))
+}
+
+// RegisterDBStats exports the pgxpool.Pool.Stat() conn pool metrics of connPool, labelled with dbName
+func RegisterDBStats(connPool *pgxpool.Pool, dbName string) error {
+	return prometheus.Register(&poolStatsCollector{connPool: connPool, dbName: dbName})
+}
+
+var (
+	poolMaxConnsDesc = prometheus.NewDesc(namespace+"_db_pool_max_conns",
+		"Maximum size of the conn pool.", []string{"db_name"}, nil)
+	poolTotalConnsDesc = prometheus.NewDesc(namespace+"_db_pool_total_conns",
+		"Conns in the pool - idle, acquired & being constructed.", []string{"db_name"}, nil)
+	poolIdleConnsDesc = prometheus.NewDesc(namespace+"_db_pool_idle_conns",
+		"Idle conns in the pool.", []string{"db_name"}, nil)
+	poolAcquiredConnsDesc = prometheus.NewDesc(namespace+"_db_pool_acquired_conns",
+		"Conns currently acquired from the pool.", []string{"db_name"}, nil)
+	poolAcquireCountDesc = prometheus.NewDesc(namespace+"_db_pool_acquires_total",
+		"Number of successful conn acquires from the pool.", []string{"db_name"}, nil)
+	poolEmptyAcquireCountDesc = prometheus.NewDesc(namespace+"_db_pool_empty_acquires_total",
+		"Number of acquires that waited for a conn because the pool was empty.", []string{"db_name"}, nil)
+	poolCanceledAcquireCountDesc = prometheus.NewDesc(namespace+"_db_pool_canceled_acquires_total",
+		"Number of acquires cancelled by their ctx.", []string{"db_name"}, nil)
+	poolAcquireDurationDesc = prometheus.NewDesc(namespace+"_db_pool_acquire_duration_seconds_total",
+		"Total time spent acquiring conns from the pool.", []string{"db_name"}, nil)
+)
+
+// poolStatsCollector reads the stats of a conn pool on every scrape
+type poolStatsCollector struct {
+	connPool *pgxpool.Pool
+	dbName   string
+}
+
+func (c *poolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.connPool.Stat()
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, c.dbName)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, c.dbName)
+	}
+	gauge(poolMaxConnsDesc, float64(stat.MaxConns()))
+	gauge(poolTotalConnsDesc, float64(stat.TotalConns()))
+	gauge(poolIdleConnsDesc, float64(stat.IdleConns()))
+	gauge(poolAcquiredConnsDesc, float64(stat.AcquiredConns()))
+	counter(poolAcquireCountDesc, float64(stat.AcquireCount()))
+	counter(poolEmptyAcquireCountDesc, float64(stat.EmptyAcquireCount()))
+	counter(poolCanceledAcquireCountDesc, float64(stat.CanceledAcquireCount()))
+	counter(poolAcquireDurationDesc, stat.AcquireDuration().Seconds())
+}
+
+// Handler serves the metrics at /metrics and forwards every other request to next
+// Note: like health probes, scrapes are answered before the request pipeline
+func Handler(next http.Handler) http.Handler {
+	metricsHandler := promhttp.Handler()
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == Path {
+			metricsHandler.ServeHTTP(res, req)
+			return
+		}
+		next.ServeHTTP(res, req)
+	})
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
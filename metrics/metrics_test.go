@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusTeapot)
+	})
+	handler := Handler(next)
+
+	ObserveRequest("http", http.MethodGet, "/accounts/:id", http.StatusOK, time.Millisecond)
+	ObserveTask("task:send_verify_email", time.Millisecond, true, errors.New("smtp down"))
+	ObserveTransfer("USD", 10)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `simplebank_requests_total{method="GET",protocol="http",route="/accounts/:id",status="200"} 1`)
+	require.Contains(t, string(body), `simplebank_tasks_processed_total{status="failure",task_type="task:send_verify_email"} 1`)
+	require.Contains(t, string(body), `simplebank_tasks_retried_total{task_type="task:send_verify_email"} 1`)
+	require.Contains(t, string(body), `simplebank_transfer_volume_total{currency="USD"} 10`)
+
+	// every other path goes to next
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/accounts", nil))
+	require.Equal(t, http.StatusTeapot, recorder.Code)
+}
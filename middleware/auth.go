@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/web3dev6/simplebank/token"
+)
+
+const (
+	AuthorizationHeaderKey  = "authorization"
+	AuthorizationTypeBearer = "bearer"
+)
+
+var ErrMissingAuthHeader = errors.New("missing authorization header")
+var ErrInvalidAuthHeaderFormat = errors.New("invalid authorization header format")
+var ErrUnsupportedAuthType = errors.New("unsupported authorization type in authorization header")
+
+// Authenticate verifies an authorization header of the form "bearer <access-token>" and returns the token payload
+// Note: shared by the Gin authMiddleware and gapi's authorizeUser, so every entry point authenticates the same way
+func Authenticate(tokenMaker token.Maker, authorizationHeader string) (*token.Payload, error) {
+	// check auth header
+	if len(authorizationHeader) == 0 {
+		return nil, ErrMissingAuthHeader
+	}
+
+	// check auth header format - <authorization-type> <authorization-data::access-token>
+	fields := strings.Fields(authorizationHeader)
+	if len(fields) < 2 {
+		return nil, ErrInvalidAuthHeaderFormat
+	}
+
+	// check auth header type if bearer or not
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != AuthorizationTypeBearer {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAuthType, authorizationType)
+	}
+
+	// check bearer token
+	payload, err := tokenMaker.VerifyToken(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token: %w", err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// logRequest logs a finished request in JSON format - same fields for every entry point
+func logRequest(req *Request) {
+	// default looger type is info
+	logger := log.Info()
+	// change logger type to err if received error
+	if req.Failed() {
+		logger = log.Error()
+		// Note* gateway error bodies are already json, embed them as-is
+		if json.Valid([]byte(req.Error)) {
+			logger = logger.RawJSON("error", []byte(req.Error))
+		} else {
+			logger = logger.Str("error", req.Error)
+		}
+	}
+
+	// log here
+	logger.
+		Str("protocol", req.Protocol).
+		Str("request_id", req.RequestID).
+		Str("method", req.Method).
+		Str("path", req.Path).
+		Str("client_ip", req.ClientIP).
+		Dur("duration", req.Duration).
+		Int("status_code", req.StatusCode).
+		Str("status_text", req.StatusText).
+		Msgf("%s request -> %s server", req.Protocol, req.Protocol)
+}
+
+// ResponseRecorder - To capture the response details of our http requests
+type ResponseRecorder struct {
+	http.ResponseWriter
+	StatusCode int
+	Body       []byte
+}
+
+// override WriteHeader to save response status, and then call the original ResponseWriter.WriteHeader func
+func (rec *ResponseRecorder) WriteHeader(statusCode int) {
+	rec.StatusCode = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+// override Write to save response body details, and then call the original ResponseWriter.Write func
+func (rec *ResponseRecorder) Write(body []byte) (int, error) {
+	rec.Body = body
+	return rec.ResponseWriter.Write(body)
+}
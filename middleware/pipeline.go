@@ -0,0 +1,338 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/web3dev6/simplebank/apperr"
+	"github.com/web3dev6/simplebank/logging"
+	"github.com/web3dev6/simplebank/metrics"
+	"github.com/web3dev6/simplebank/util"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ProtocolHttp    = "http"         // Gin http server
+	ProtocolGateway = "http-gateway" // gRPC http-gateway server
+	ProtocolGrpc    = "grpc"         // gRPC server
+
+	xForwardedForHeader  = "x-forwarded-for"
+	requestIDMetadataKey = "x-request-id" // grpc metadata keys are lowercase
+)
+
+// errRateLimited is returned to clients who exceeded their request budget
+var errRateLimited = apperr.RateLimited("rate limit exceeded, try again later")
+
+// Request describes one served request, independent of the entry point it came through
+type Request struct {
+	Protocol   string
+	Method     string
+	Path       string
+	Route      string // route template (Gin), matched path (gateway) or full method (grpc) - a low cardinality Path for metrics
+	RequestID  string
+	ClientIP   string
+	StatusCode int // http status code for http & http-gateway, grpc code for grpc
+	StatusText string
+	Duration   time.Duration
+	Error      string
+}
+
+// Failed reports whether the request ended with an error
+func (req *Request) Failed() bool {
+	if req.Protocol == ProtocolGrpc {
+		return req.StatusCode != int(codes.OK)
+	}
+	return req.StatusCode != http.StatusOK
+}
+
+// Pipeline holds the cross-cutting concerns (rate-limit, logging, metrics, tracing) every entry point runs through before reaching a handler
+// Note* one Pipeline is shared by the Gin, gRPC & gRPC-gateway servers - a client has a single request budget across all of them
+type Pipeline struct {
+	limiter        *RateLimiter
+	trustedProxies []*net.IPNet // whose x-forwarded-for is believed
+}
+
+// NewPipeline creates a Pipeline from config
+// note* TRUSTED_PROXIES is checked by config.Validate, an invalid one here means no trusted proxies
+func NewPipeline(config util.Config) *Pipeline {
+	trustedProxies, _ := util.ParseTrustedProxies(config.TrustedProxyList())
+	return &Pipeline{
+		limiter:        NewRateLimiter(config.RateLimitRps, config.RateLimitBurst),
+		trustedProxies: trustedProxies,
+	}
+}
+
+// observe is called once per finished request, ending its span
+func (pipeline *Pipeline) observe(span trace.Span, req *Request) {
+	endSpan(span, req)
+	logRequest(req)
+	metrics.ObserveRequest(req.Protocol, req.Method, req.Route, req.StatusCode, req.Duration)
+}
+
+// Gin returns the pipeline as a Gin middleware - for the Gin http server
+func (pipeline *Pipeline) Gin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		startTime := time.Now()
+		path := ctx.Request.URL.Path
+		if raw := ctx.Request.URL.RawQuery; raw != "" {
+			path = path + "?" + raw
+		}
+
+		// note* Gin matched the route before running any middleware
+		route := ctx.FullPath()
+		if route == "" {
+			route = metrics.UnmatchedRoute
+		}
+		// the span & request id travel down to the handlers (and sql queries) within the request context
+		requestID := logging.RequestIDOrNew(ctx.GetHeader(logging.RequestIDHeader))
+		ctx.Header(logging.RequestIDHeader, requestID)
+		spanCtx, span := startSpan(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header), ProtocolHttp, ctx.Request.Method+" "+route)
+		// note* the router is set up with the same trusted proxies, see api.Server.setupRouter
+		clientIP := ctx.ClientIP()
+		ctx.Request = ctx.Request.WithContext(WithClientIP(logging.WithRequestID(spanCtx, requestID), clientIP))
+
+		if pipeline.limiter.Allow(clientIP) {
+			// Process request
+			ctx.Next()
+		} else {
+			ctx.Header("Content-Type", apperr.ProblemContentType)
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, apperr.NewProblem(errRateLimited, ctx.Request.URL.Path, requestID))
+		}
+
+		statusCode := ctx.Writer.Status()
+		errorMessage := ctx.Errors.ByType(gin.ErrorTypeAny).String()
+		if statusCode == http.StatusTooManyRequests && errorMessage == "" {
+			errorMessage = errRateLimited.Message
+		}
+		pipeline.observe(span, &Request{
+			Protocol:   ProtocolHttp,
+			Method:     ctx.Request.Method,
+			Path:       path,
+			Route:      route,
+			RequestID:  requestID,
+			ClientIP:   clientIP,
+			StatusCode: statusCode,
+			StatusText: http.StatusText(statusCode),
+			Duration:   time.Since(startTime),
+			Error:      errorMessage,
+		})
+	}
+}
+
+// Http wraps handler with the pipeline - for the gRPC http-gateway server
+// Note: the gateway translates HTTP to gRPC in-process (calling gRPC handler funcs directly, skipping grpc interceptors),
+// so the pipeline runs here instead - once per request, exactly as it does for native gRPC requests
+func (pipeline *Pipeline) Http(handler http.Handler) http.Handler {
+	// doing a type conversion from an anonymous func to http.HandlerFunc - which implemets the ServeHTTP func required by http.Handler interface
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		startTime := time.Now()
+		clientIP := pipeline.clientIP(req.RemoteAddr, req.Header.Values(xForwardedForHeader))
+
+		// use ResponseRecorder instance when serving the request
+		rec := &ResponseRecorder{
+			ResponseWriter: res,
+			StatusCode:     http.StatusOK, // default StatusCode is ok, will be set to correct value when over-ridden WriteHeader func is called
+		}
+		requestID := logging.RequestIDOrNew(req.Header.Get(logging.RequestIDHeader))
+		rec.Header().Set(logging.RequestIDHeader, requestID)
+		// note* the route is only known once the mux handled the request, the span is renamed then
+		spanCtx, span := startSpan(req.Context(), propagation.HeaderCarrier(req.Header), ProtocolGateway, req.Method)
+		// note* gateway routes have no path params, so the path itself is the route - unless nothing matched it
+		route := metrics.UnmatchedRoute
+		if pipeline.limiter.Allow(clientIP) {
+			// call the hadnler func (with rec instead of res) - forward the request to the handler to be processed
+			handler.ServeHTTP(rec, req.WithContext(WithClientIP(logging.WithRequestID(spanCtx, requestID), clientIP)))
+			if rec.StatusCode != http.StatusNotFound {
+				route = req.URL.Path
+			}
+		} else {
+			apperr.WriteProblem(rec, apperr.NewProblem(errRateLimited, req.URL.Path, requestID))
+		}
+
+		var errorMessage string
+		if rec.StatusCode != http.StatusOK {
+			errorMessage = string(rec.Body)
+		}
+		span.SetName(req.Method + " " + route)
+		pipeline.observe(span, &Request{
+			Protocol:   ProtocolGateway,
+			Method:     req.Method,
+			Path:       req.RequestURI,
+			Route:      route,
+			RequestID:  requestID,
+			ClientIP:   clientIP,
+			StatusCode: rec.StatusCode,
+			StatusText: http.StatusText(rec.StatusCode),
+			Duration:   time.Since(startTime),
+			Error:      errorMessage,
+		})
+	})
+}
+
+// UnaryServerInterceptor returns the pipeline as a grpc interceptor - for the gRPC server
+func (pipeline *Pipeline) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		startTime := time.Now()
+		clientIP := pipeline.grpcClientIP(ctx)
+
+		var result interface{}
+		var err error
+		requestID := logging.RequestIDOrNew(grpcRequestID(ctx))
+		// note* fails only outside of a real grpc stream (unit tests), the id is then just not echoed
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+		ctx, span := startSpan(ctx, grpcCarrier(ctx), ProtocolGrpc, info.FullMethod)
+		ctx = WithClientIP(logging.WithRequestID(ctx, requestID), clientIP)
+		if pipeline.limiter.Allow(clientIP) {
+			// call the hadnler func - forward the request to the handler to be processed
+			result, err = handler(ctx, req)
+		} else {
+			err = errRateLimited
+		}
+
+		// note* logged with its cause, the client only gets the status message
+		var errorMessage string
+		if err != nil {
+			errorMessage = err.Error()
+			// domain errors map themselves to a status, anything else is internal - never leak a raw error
+			if _, ok := status.FromError(err); !ok {
+				err = apperr.Internal(err)
+			}
+			err = withRequestInfo(err, requestID)
+		}
+		statusCode := status.Code(err)
+		pipeline.observe(span, &Request{
+			Protocol:   ProtocolGrpc,
+			Method:     info.FullMethod,
+			Path:       info.FullMethod,
+			Route:      info.FullMethod,
+			RequestID:  requestID,
+			ClientIP:   clientIP,
+			StatusCode: int(statusCode),
+			StatusText: statusCode.String(),
+			Duration:   time.Since(startTime),
+			Error:      errorMessage,
+		})
+
+		return result, err
+	}
+}
+
+// GatewayErrorHandler writes gateway errors as problem+json with the request id - the same body the Gin server returns
+// note* the gateway calls the grpc handlers in-process, so their errors skip the grpc interceptor
+func GatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, res http.ResponseWriter, req *http.Request, err error) {
+	// the request log only gets the body, log the hidden cause of internal errors here
+	if apperr.From(err).Code == apperr.CodeInternal {
+		logging.Logger(req.Context()).Error().Err(err).Msg("internal error")
+	}
+	apperr.WriteProblem(res, apperr.NewProblem(err, req.URL.Path, logging.RequestID(req.Context())))
+}
+
+// withRequestInfo adds requestID to the details of a grpc status error, so clients can quote it
+func withRequestInfo(err error, requestID string) error {
+	if err == nil || requestID == "" {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	stWithDetails, detailsErr := st.WithDetails(&errdetails.RequestInfo{RequestId: requestID})
+	if detailsErr != nil {
+		return err
+	}
+	return stWithDetails.Err()
+}
+
+// grpcRequestID returns the request id sent in the incoming metadata, if any
+func grpcRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// grpcClientIP returns the client ip of a grpc request, from its peer address & x-forwarded-for metadata
+func (pipeline *Pipeline) grpcClientIP(ctx context.Context) string {
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	var forwarded []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		forwarded = md.Get(xForwardedForHeader)
+	}
+	return pipeline.clientIP(remoteAddr, forwarded)
+}
+
+// clientIP returns the ip of the client - the remote address, unless it's a trusted proxy
+// then the x-forwarded-for addresses are walked from the right (the ones added last, by the proxies nearest to us),
+// the first one not of a trusted proxy is the client - the addresses left of it could be made up by the client
+func (pipeline *Pipeline) clientIP(remoteAddr string, forwarded []string) string {
+	clientIP := hostOnly(remoteAddr)
+	if !pipeline.trusted(clientIP) {
+		return clientIP
+	}
+	addresses := strings.Split(strings.Join(forwarded, ","), ",")
+	for i := len(addresses) - 1; i >= 0; i-- {
+		address := strings.TrimSpace(addresses[i])
+		if net.ParseIP(address) == nil {
+			// garbage - the hop before it isn't known
+			break
+		}
+		clientIP = address
+		if !pipeline.trusted(address) {
+			break
+		}
+	}
+	return clientIP
+}
+
+// trusted reports whether ip is of a trusted proxy
+func (pipeline *Pipeline) trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range pipeline.trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns ctx with the client ip of its request - set by the pipeline
+func WithClientIP(ctx context.Context, clientIP string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, clientIP)
+}
+
+// ClientIP returns the client ip the pipeline resolved for the request of ctx, empty outside of one
+func ClientIP(ctx context.Context) string {
+	clientIP, _ := ctx.Value(clientIPKey{}).(string)
+	return clientIP
+}
+
+// hostOnly strips the port, so all conns from one client share a rate limit bucket
+func hostOnly(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/logging"
+	"github.com/web3dev6/simplebank/tracing"
+	"github.com/web3dev6/simplebank/util"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestPipeline() *Pipeline {
+	// a single request allowed per client, refilled only after ~1000s
+	return NewPipeline(util.Config{
+		RateLimitRps:   0.001,
+		RateLimitBurst: 1,
+	})
+}
+
+func TestPipelineGin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(newTestPipeline().Gin())
+	router.GET("/ping", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{})
+	})
+
+	// first request passes through to the handler
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// second request from the same client is rate limited
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+}
+
+func TestPipelineClientIP(t *testing.T) {
+	pipeline := NewPipeline(util.Config{TrustedProxies: "10.0.0.0/8, 127.0.0.1"})
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		clientIP   string
+	}{
+		{
+			name:       "NoProxy",
+			remoteAddr: "203.0.113.7:51234",
+			clientIP:   "203.0.113.7",
+		},
+		{
+			// only a trusted proxy's x-forwarded-for is believed
+			name:       "UntrustedForwarded",
+			remoteAddr: "203.0.113.7:51234",
+			forwarded:  []string{"198.51.100.1"},
+			clientIP:   "203.0.113.7",
+		},
+		{
+			name:       "TrustedProxy",
+			remoteAddr: "10.0.0.5:443",
+			forwarded:  []string{"198.51.100.1"},
+			clientIP:   "198.51.100.1",
+		},
+		{
+			// the client made up the left-most address, the proxy appended the one it saw
+			name:       "SpoofedForwarded",
+			remoteAddr: "10.0.0.5:443",
+			forwarded:  []string{"1.2.3.4, 198.51.100.1"},
+			clientIP:   "198.51.100.1",
+		},
+		{
+			name:       "ProxyChain",
+			remoteAddr: "127.0.0.1:443",
+			forwarded:  []string{"198.51.100.1, 10.0.0.9", "10.0.0.5"},
+			clientIP:   "198.51.100.1",
+		},
+		{
+			name:       "InvalidForwarded",
+			remoteAddr: "10.0.0.5:443",
+			forwarded:  []string{"not-an-ip"},
+			clientIP:   "10.0.0.5",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.clientIP, pipeline.clientIP(tc.remoteAddr, tc.forwarded))
+		})
+	}
+
+	// without trusted proxies the header is never believed
+	require.Equal(t, "10.0.0.5", newTestPipeline().clientIP("10.0.0.5:443", []string{"198.51.100.1"}))
+}
+
+func TestPipelineHttp(t *testing.T) {
+	handler := newTestPipeline().Http(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	}))
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/v1/ping", nil)
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+}
+
+func TestPipelineUnaryServerInterceptor(t *testing.T) {
+	interceptor := newTestPipeline().UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/Ping"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "pong", nil
+	}
+
+	res, err := interceptor(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, "pong", res)
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	require.Error(t, err)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestPipelineTracing(t *testing.T) {
+	_, err := tracing.Init(context.Background(), util.Config{})
+	require.NoError(t, err)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	// client sent a w3c trace context
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01"))
+
+	interceptor := newTestPipeline().UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/Ping"}
+	_, err = interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		// handler runs within the server span
+		require.True(t, trace.SpanContextFromContext(ctx).IsValid())
+		return "pong", nil
+	})
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "/pb.SimpleBank/Ping", spans[0].Name())
+	require.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	require.Equal(t, traceID, spans[0].SpanContext().TraceID().String())
+}
+
+func TestPipelineRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(newTestPipeline().Gin())
+	router.GET("/ping", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"request_id": logging.RequestID(ctx.Request.Context())})
+	})
+
+	// client id is accepted & handed down to the handler
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	request.Header.Set(logging.RequestIDHeader, "client-id-1")
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "client-id-1", recorder.Header().Get(logging.RequestIDHeader))
+	require.JSONEq(t, `{"request_id":"client-id-1"}`, recorder.Body.String())
+
+	// rate limited, a new id is generated & echoed in the error body
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	requestID := recorder.Header().Get(logging.RequestIDHeader)
+	require.NotEmpty(t, requestID)
+	require.Contains(t, recorder.Body.String(), requestID)
+
+	// grpc errors carry the id in their details
+	interceptor := newTestPipeline().UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/Ping"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "client-id-2"))
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		require.Equal(t, "client-id-2", logging.RequestID(ctx))
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.NotFound, st.Code())
+	require.Len(t, st.Details(), 1)
+	require.Equal(t, "client-id-2", st.Details()[0].(*errdetails.RequestInfo).RequestId)
+}
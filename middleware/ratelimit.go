@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// clientsIdleTTL - a client's bucket is dropped after being idle this long, keeps the clients map bounded
+const clientsIdleTTL = 10 * time.Minute
+
+// RateLimiter keeps a token bucket per client key (client ip), so one noisy client can't starve the others
+// Note* a nil *RateLimiter allows every request - rate limiting disabled
+type RateLimiter struct {
+	mu          sync.Mutex
+	limit       rate.Limit
+	burst       int
+	clients     map[string]*rateLimitedClient
+	lastCleanup time.Time
+}
+
+type rateLimitedClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a RateLimiter allowing rps requests per second with bursts of up to burst requests per client
+// returns nil (disabled) if rps is not positive
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		limit:       rate.Limit(rps),
+		burst:       burst,
+		clients:     make(map[string]*rateLimitedClient),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Allow reports whether a request from the client with given key may proceed now
+func (rl *RateLimiter) Allow(key string) bool {
+	if rl == nil {
+		return true
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	// lazily evict idle clients, no background goroutine to stop on shutdown
+	if now.Sub(rl.lastCleanup) > clientsIdleTTL {
+		for k, c := range rl.clients {
+			if now.Sub(c.lastSeen) > clientsIdleTTL {
+				delete(rl.clients, k)
+			}
+		}
+		rl.lastCleanup = now
+	}
+
+	c, ok := rl.clients[key]
+	if !ok {
+		c = &rateLimitedClient{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.clients[key] = c
+	}
+	c.lastSeen = now
+	return c.limiter.AllowN(now, 1)
+}
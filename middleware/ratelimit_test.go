@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	burst := 3
+	limiter := NewRateLimiter(0.001, burst)
+	require.NotNil(t, limiter)
+
+	// a client can spend its whole burst right away, then gets limited
+	for i := 0; i < burst; i++ {
+		require.True(t, limiter.Allow("client-1"))
+	}
+	require.False(t, limiter.Allow("client-1"))
+
+	// other clients have their own bucket
+	require.True(t, limiter.Allow("client-2"))
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	// non-positive rps disables rate limiting - nil limiter allows everything
+	limiter := NewRateLimiter(0, 10)
+	require.Nil(t, limiter)
+	for i := 0; i < 100; i++ {
+		require.True(t, limiter.Allow("client"))
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/web3dev6/simplebank/tracing"
+)
+
+// startSpan starts the server span of a request, as a child of the trace context sent by the client in carrier (if any)
+func startSpan(ctx context.Context, carrier propagation.TextMapCarrier, protocol string, name string) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	return tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("protocol", protocol)),
+	)
+}
+
+// endSpan records the outcome of req on span and ends it
+func endSpan(span trace.Span, req *Request) {
+	span.SetAttributes(semconv.ClientAddress(req.ClientIP))
+	if req.Protocol == ProtocolGrpc {
+		span.SetAttributes(semconv.RPCSystemGRPC, semconv.RPCGRPCStatusCodeKey.Int(req.StatusCode))
+	} else {
+		span.SetAttributes(
+			semconv.HTTPMethod(req.Method),
+			semconv.HTTPRoute(req.Route),
+			semconv.HTTPStatusCode(req.StatusCode),
+		)
+	}
+	// note* 4xx are client errors, only server side failures mark the span as failed
+	if req.Failed() {
+		span.SetAttributes(attribute.String("error.message", req.Error))
+		if serverFailure(req) {
+			span.SetStatus(otelcodes.Error, req.StatusText)
+		}
+	}
+	span.End()
+}
+
+// serverFailure reports whether req failed because of the server - 5xx or the grpc equivalents
+func serverFailure(req *Request) bool {
+	if req.Protocol == ProtocolGrpc {
+		switch codes.Code(req.StatusCode) {
+		case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+			return true
+		}
+		return false
+	}
+	return req.StatusCode >= http.StatusInternalServerError
+}
+
+// metadataCarrier adapts incoming grpc metadata to the otel propagator
+type metadataCarrier metadata.MD
+
+func (carrier metadataCarrier) Get(key string) string {
+	values := metadata.MD(carrier).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (carrier metadataCarrier) Set(key string, value string) {
+	metadata.MD(carrier).Set(key, value)
+}
+
+func (carrier metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(carrier))
+	for key := range carrier {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// grpcCarrier returns the incoming metadata of ctx as a propagator carrier
+func grpcCarrier(ctx context.Context) propagation.TextMapCarrier {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return metadataCarrier(md)
+}
@@ -0,0 +1,27 @@
+package money
+
+// exponents are the ISO 4217 minor unit exponents - 2 means an amount of 1234 is 12.34
+// note* only a subset, a currency not listed here can't be used for money at all
+var exponents = map[string]int{
+	// no minor units
+	"CLP": 0, "ISK": 0, "JPY": 0, "KRW": 0, "PYG": 0, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+	// cents
+	"AED": 2, "AUD": 2, "BDT": 2, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2, "DKK": 2,
+	"EGP": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "KES": 2,
+	"LKR": 2, "MXN": 2, "MYR": 2, "NGN": 2, "NOK": 2, "NPR": 2, "NZD": 2, "PHP": 2, "PKR": 2,
+	"PLN": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TRY": 2, "TWD": 2, "USD": 2, "ZAR": 2,
+	// mills
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// Exponent returns the number of minor unit digits of currency, false if it's unknown
+func Exponent(currency string) (int, bool) {
+	exponent, ok := exponents[currency]
+	return exponent, ok
+}
+
+// IsKnownCurrency returns true if currency is an ISO 4217 code with a known exponent
+func IsKnownCurrency(currency string) bool {
+	_, ok := exponents[currency]
+	return ok
+}
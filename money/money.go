@@ -0,0 +1,217 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+   Money is an amount in the minor units of its currency - cents of USD, paise of INR, yen of JPY
+	- the db & the store keep raw int64 minor units, money adds the currency & its ISO 4217 exponent to them
+	- clients see decimal strings in major units - {"amount":"12.34","currency":"USD"} is 1234 cents
+   Note* arithmetic never wraps around, an overflow is an error
+   Note* parsing never rounds, an amount more precise than its currency is an error
+*/
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrTooPrecise       = errors.New("amount is more precise than its currency")
+	ErrOverflow         = errors.New("amount out of range")
+)
+
+// Money is an amount of a currency
+type Money struct {
+	Amount   int64  // in minor units
+	Currency string // ISO 4217 code
+}
+
+// New returns amount minor units of currency
+func New(amount int64, currency string) (Money, error) {
+	if !IsKnownCurrency(currency) {
+		return Money{}, fmt.Errorf("%w %q", ErrUnknownCurrency, currency)
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// Parse parses a decimal amount in major units of currency, e.g. "12.34" USD is 1234 cents
+// an optional leading '-' is the only sign allowed, no exponents, spaces or thousands separators
+func Parse(value string, currency string) (Money, error) {
+	exponent, ok := Exponent(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w %q", ErrUnknownCurrency, currency)
+	}
+
+	digits := value
+	negative := strings.HasPrefix(digits, "-")
+	if negative {
+		digits = digits[1:]
+	}
+	whole, fraction, hasPoint := strings.Cut(digits, ".")
+	if whole == "" || (hasPoint && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, value)
+	}
+	if len(fraction) > exponent {
+		return Money{}, fmt.Errorf("%w: %q, %s has %d decimals", ErrTooPrecise, value, currency, exponent)
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	var amount int64
+	for _, r := range whole + fraction {
+		digit := int64(r - '0')
+		if amount > (math.MaxInt64-digit)/10 {
+			return Money{}, fmt.Errorf("%w: %q", ErrOverflow, value)
+		}
+		amount = amount*10 + digit
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Exponent returns the number of minor unit digits of m's currency, 0 if it's unknown
+func (m Money) Exponent() int {
+	exponent, _ := Exponent(m.Currency)
+	return exponent
+}
+
+// Decimal formats m in major units, with exactly as many decimals as its currency has, e.g. "12.30"
+func (m Money) Decimal() string {
+	sign := ""
+	abs := uint64(m.Amount)
+	if m.Amount < 0 {
+		// note* negated as unsigned, so math.MinInt64 doesn't overflow
+		sign = "-"
+		abs = -abs
+	}
+	digits := strconv.FormatUint(abs, 10)
+	exponent := m.Exponent()
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	point := len(digits) - exponent
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// String formats m with its currency, e.g. "12.30 USD"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) sameCurrency(other Money) error {
+	if m.Currency != other.Currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return nil
+}
+
+// Cmp returns -1, 0 or +1 as m is less than, equal to or greater than other
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Add returns m + other
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrOverflow, m, other)
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m - other
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	if (other.Amount < 0 && m.Amount > math.MaxInt64+other.Amount) ||
+		(other.Amount > 0 && m.Amount < math.MinInt64+other.Amount) {
+		return Money{}, fmt.Errorf("%w: %s - %s", ErrOverflow, m, other)
+	}
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}, nil
+}
+
+// Mul returns m * n
+func (m Money) Mul(n int64) (Money, error) {
+	if m.Amount == 0 || n == 0 {
+		return Money{Amount: 0, Currency: m.Currency}, nil
+	}
+	product := m.Amount * n
+	if product/n != m.Amount || (m.Amount == -1 && n == math.MinInt64) || (n == -1 && m.Amount == math.MinInt64) {
+		return Money{}, fmt.Errorf("%w: %s * %d", ErrOverflow, m, n)
+	}
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+// Neg returns -m
+func (m Money) Neg() (Money, error) {
+	if m.Amount == math.MinInt64 {
+		return Money{}, fmt.Errorf("%w: -(%s)", ErrOverflow, m)
+	}
+	return Money{Amount: -m.Amount, Currency: m.Currency}, nil
+}
+
+// moneyJSON is the json form of money, the amount in major units
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var value moneyJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := Parse(value.Amount, value.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
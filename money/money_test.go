@@ -0,0 +1,145 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		value    string
+		currency string
+		amount   int64
+		err      error
+	}{
+		{"12.34", "USD", 1234, nil},
+		{"12.3", "USD", 1230, nil},
+		{"12", "USD", 1200, nil},
+		{"0.01", "INR", 1, nil},
+		{"-5.50", "EUR", -550, nil},
+		{"1000", "JPY", 1000, nil},
+		{"1.234", "KWD", 1234, nil},
+		{"92233720368547758.07", "USD", math.MaxInt64, nil},
+		{"92233720368547758.08", "USD", 0, ErrOverflow},
+		{"12.345", "USD", 0, ErrTooPrecise},
+		{"1.5", "JPY", 0, ErrTooPrecise},
+		{"12", "XYZ", 0, ErrUnknownCurrency},
+		{"", "USD", 0, ErrInvalidAmount},
+		{".5", "USD", 0, ErrInvalidAmount},
+		{"5.", "USD", 0, ErrInvalidAmount},
+		{"+5", "USD", 0, ErrInvalidAmount},
+		{"1,000", "USD", 0, ErrInvalidAmount},
+		{"1e3", "USD", 0, ErrInvalidAmount},
+		{" 5", "USD", 0, ErrInvalidAmount},
+	}
+
+	for _, tc := range testCases {
+		m, err := Parse(tc.value, tc.currency)
+		if tc.err != nil {
+			require.ErrorIs(t, err, tc.err, tc.value)
+			continue
+		}
+		require.NoError(t, err, tc.value)
+		require.Equal(t, Money{Amount: tc.amount, Currency: tc.currency}, m)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	testCases := []struct {
+		money    Money
+		expected string
+	}{
+		{Money{1234, "USD"}, "12.34"},
+		{Money{1230, "USD"}, "12.30"},
+		{Money{5, "INR"}, "0.05"},
+		{Money{0, "EUR"}, "0.00"},
+		{Money{-550, "EUR"}, "-5.50"},
+		{Money{-5, "USD"}, "-0.05"},
+		{Money{1000, "JPY"}, "1000"},
+		{Money{1, "KWD"}, "0.001"},
+		{Money{math.MinInt64, "USD"}, "-92233720368547758.08"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, tc.money.Decimal())
+		if tc.money.Amount != math.MinInt64 {
+			// formatting & parsing round trip
+			m, err := Parse(tc.money.Decimal(), tc.money.Currency)
+			require.NoError(t, err)
+			require.Equal(t, tc.money, m)
+		}
+	}
+	require.Equal(t, "12.34 USD", Money{1234, "USD"}.String())
+}
+
+func TestArithmetic(t *testing.T) {
+	usd := func(amount int64) Money { return Money{Amount: amount, Currency: "USD"} }
+
+	sum, err := usd(150).Add(usd(-50))
+	require.NoError(t, err)
+	require.Equal(t, usd(100), sum)
+	difference, err := usd(150).Sub(usd(200))
+	require.NoError(t, err)
+	require.Equal(t, usd(-50), difference)
+	product, err := usd(-25).Mul(4)
+	require.NoError(t, err)
+	require.Equal(t, usd(-100), product)
+	negated, err := usd(25).Neg()
+	require.NoError(t, err)
+	require.Equal(t, usd(-25), negated)
+
+	for _, cmp := range []struct {
+		a, b     int64
+		expected int
+	}{{1, 2, -1}, {2, 2, 0}, {3, 2, 1}} {
+		result, err := usd(cmp.a).Cmp(usd(cmp.b))
+		require.NoError(t, err)
+		require.Equal(t, cmp.expected, result)
+	}
+
+	// overflows are errors, never wrap around
+	_, err = usd(math.MaxInt64).Add(usd(1))
+	require.ErrorIs(t, err, ErrOverflow)
+	_, err = usd(math.MinInt64).Add(usd(-1))
+	require.ErrorIs(t, err, ErrOverflow)
+	_, err = usd(math.MinInt64).Sub(usd(1))
+	require.ErrorIs(t, err, ErrOverflow)
+	_, err = usd(math.MaxInt64).Sub(usd(-1))
+	require.ErrorIs(t, err, ErrOverflow)
+	_, err = usd(math.MaxInt64 / 2).Mul(3)
+	require.ErrorIs(t, err, ErrOverflow)
+	_, err = usd(math.MinInt64).Mul(-1)
+	require.ErrorIs(t, err, ErrOverflow)
+	_, err = usd(math.MinInt64).Neg()
+	require.ErrorIs(t, err, ErrOverflow)
+
+	// no arithmetic across currencies
+	_, err = usd(1).Add(Money{Amount: 1, Currency: "EUR"})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = usd(1).Cmp(Money{Amount: 1, Currency: "EUR"})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(Money{Amount: 1234, Currency: "INR"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"amount":"12.34","currency":"INR"}`, string(data))
+
+	var m Money
+	require.NoError(t, json.Unmarshal(data, &m))
+	require.Equal(t, Money{Amount: 1234, Currency: "INR"}, m)
+
+	require.ErrorIs(t, json.Unmarshal([]byte(`{"amount":"1.234","currency":"INR"}`), &m), ErrTooPrecise)
+	require.Error(t, json.Unmarshal([]byte(`{"amount":12.34,"currency":"INR"}`), &m))
+}
+
+func TestNew(t *testing.T) {
+	m, err := New(100, "JPY")
+	require.NoError(t, err)
+	require.Equal(t, 0, m.Exponent())
+	_, err = New(100, "usd")
+	require.ErrorIs(t, err, ErrUnknownCurrency)
+}
@@ -0,0 +1,244 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/util"
+)
+
+/*
+   Synthetic data for load tests & demos
+	1. NewPlan generates users, their funding & a transfer graph - the same plan for the same seed
+	2. Apply writes the plan through the store - accounts are funded with FundAccountTx & transfers go through TransferTx
+   Note* the transfer graph is skewed like a real one - a few popular users (merchants) receive most of the transfers,
+        amounts are mostly small with a long tail, and a transfer never overdraws its account
+*/
+
+const (
+	minFunding = 1_000
+	maxFunding = 100_000
+	// popularitySkew - receivers are picked with weight 1/rank^popularitySkew, a zipf-like distribution
+	popularitySkew = 1.1
+	// meanTransferShare - transfer amounts are exponentially distributed with a mean of this share of the sender's balance
+	meanTransferShare = 0.1
+	// maxSenderAttempts - senders picked for a transfer before giving up on a (near) empty currency
+	maxSenderAttempts = 20
+	// listAccountsLimit - an owner has at most one account per currency
+	listAccountsLimit = 100
+)
+
+var firstNames = []string{
+	"Aarav", "Amelia", "Arjun", "Chloe", "Diego", "Elena", "Farah", "Hiro", "Isla", "Jonas",
+	"Kavya", "Liam", "Maya", "Noah", "Olga", "Priya", "Rohan", "Sofia", "Tomas", "Zara",
+}
+
+var lastNames = []string{
+	"Bauer", "Costa", "Dubois", "Evans", "Fischer", "Gupta", "Hansen", "Ito", "Joshi", "Kowalski",
+	"Lopez", "Moreau", "Nakamura", "Okafor", "Patel", "Rossi", "Silva", "Tanaka", "Varga", "Weber",
+}
+
+// Funding is the money deposited into a user's account in Currency
+type Funding struct {
+	Currency string
+	Amount   int64
+}
+
+// UserPlan is a user to create, with one account funded per supported currency
+type UserPlan struct {
+	Username string
+	FullName string
+	Email    string
+	Fundings []Funding
+}
+
+// TransferPlan is a transfer between the accounts in Currency of two users, by their index in Plan.Users
+type TransferPlan struct {
+	From     int
+	To       int
+	Currency string
+	Amount   int64
+}
+
+// Plan is the synthetic data to seed
+type Plan struct {
+	Users     []UserPlan
+	Transfers []TransferPlan
+}
+
+// NewPlan generates numUsers users & up to numTransfers transfers between them, deterministically from seed
+// note* fewer transfers are planned if the accounts run (nearly) dry
+func NewPlan(seed int64, numUsers, numTransfers int) Plan {
+	random := util.NewRandom(seed)
+	currencies := util.SupportedCurrencies()
+
+	plan := Plan{Users: make([]UserPlan, numUsers)}
+	// balances tracks the planned balance of each user's account, per currency
+	balances := make([]map[string]int64, numUsers)
+	for i := range plan.Users {
+		firstName := firstNames[random.Intn(len(firstNames))]
+		lastName := lastNames[random.Intn(len(lastNames))]
+		// note* the index keeps usernames unique, lowercase alphanumeric is valid for both the http & grpc apis
+		username := fmt.Sprintf("%s%s%d", strings.ToLower(firstName), strings.ToLower(lastName), i+1)
+
+		user := UserPlan{
+			Username: username,
+			FullName: firstName + " " + lastName,
+			Email:    username + "@example.com",
+		}
+		balances[i] = make(map[string]int64, len(currencies))
+		for _, currency := range currencies {
+			amount := random.RandomInt(minFunding, maxFunding)
+			user.Fundings = append(user.Fundings, Funding{Currency: currency, Amount: amount})
+			balances[i][currency] = amount
+		}
+		plan.Users[i] = user
+	}
+	if numUsers < 2 {
+		return plan
+	}
+
+	// popularity of each user as a receiver, by a random rank
+	weights := make([]float64, numUsers)
+	var totalWeight float64
+	for i, rank := range random.Perm(numUsers) {
+		weights[i] = 1 / math.Pow(float64(rank+1), popularitySkew)
+		totalWeight += weights[i]
+	}
+	pickReceiver := func(from int) int {
+		for {
+			target := random.Float64() * totalWeight
+			for i, weight := range weights {
+				target -= weight
+				if target < 0 {
+					if i == from {
+						break
+					}
+					return i
+				}
+			}
+		}
+	}
+
+	for len(plan.Transfers) < numTransfers {
+		currency := currencies[random.Intn(len(currencies))]
+
+		from := -1
+		for attempt := 0; attempt < maxSenderAttempts; attempt++ {
+			if candidate := random.Intn(numUsers); balances[candidate][currency] >= 2 {
+				from = candidate
+				break
+			}
+		}
+		if from < 0 {
+			break
+		}
+		to := pickReceiver(from)
+
+		// mostly small amounts with a long tail, never more than half of the balance
+		balance := balances[from][currency]
+		amount := 1 + int64(random.ExpFloat64()*meanTransferShare*float64(balance))
+		if amount > balance/2 {
+			amount = balance / 2
+		}
+
+		balances[from][currency] -= amount
+		balances[to][currency] += amount
+		plan.Transfers = append(plan.Transfers, TransferPlan{From: from, To: to, Currency: currency, Amount: amount})
+	}
+	return plan
+}
+
+// Result counts what Apply wrote
+type Result struct {
+	UsersCreated    int
+	UsersExisting   int
+	AccountsCreated int
+	Transfers       int
+}
+
+// Apply writes plan through store, every user gets hashedPassword
+// Note* users & accounts already there (e.g. from a previous run with the same seed) are reused - their accounts are funded again
+// Note* store must read from the primary db - an account of a previous run not yet on a lagging replica would be created again (a unique violation)
+func Apply(ctx context.Context, store db.Store, plan Plan, hashedPassword string) (result Result, err error) {
+	// accountIDs of each planned user, per currency
+	accountIDs := make([]map[string]int64, len(plan.Users))
+
+	for i, userPlan := range plan.Users {
+		_, err = store.GetUser(ctx, userPlan.Username)
+		switch {
+		case err == nil:
+			result.UsersExisting++
+		case errors.Is(err, db.ErrRecordNotFound):
+			_, err = store.CreateUser(ctx, db.CreateUserParams{
+				Username:       userPlan.Username,
+				HashedPassword: hashedPassword,
+				FullName:       userPlan.FullName,
+				Email:          userPlan.Email,
+			})
+			if err != nil {
+				return result, fmt.Errorf("cannot create user %s: %w", userPlan.Username, err)
+			}
+			result.UsersCreated++
+		default:
+			return result, fmt.Errorf("cannot get user %s: %w", userPlan.Username, err)
+		}
+
+		accounts, err := store.ListAccounts(ctx, db.ListAccountsParams{
+			Owner: userPlan.Username,
+			Limit: listAccountsLimit,
+		})
+		if err != nil {
+			return result, fmt.Errorf("cannot list accounts of %s: %w", userPlan.Username, err)
+		}
+		accountIDs[i] = make(map[string]int64, len(userPlan.Fundings))
+		for _, account := range accounts {
+			accountIDs[i][account.Currency] = account.ID
+		}
+
+		for _, funding := range userPlan.Fundings {
+			if _, ok := accountIDs[i][funding.Currency]; !ok {
+				account, err := store.CreateAccount(ctx, db.CreateAccountParams{
+					Owner:    userPlan.Username,
+					Currency: funding.Currency,
+					Balance:  0,
+				})
+				if err != nil {
+					return result, fmt.Errorf("cannot create %s account of %s: %w", funding.Currency, userPlan.Username, err)
+				}
+				accountIDs[i][funding.Currency] = account.ID
+				result.AccountsCreated++
+			}
+
+			_, err = store.FundAccountTx(ctx, db.FundAccountTxParams{
+				AccountID: accountIDs[i][funding.Currency],
+				Amount:    funding.Amount,
+			})
+			if err != nil {
+				return result, fmt.Errorf("cannot fund %s account of %s: %w", funding.Currency, userPlan.Username, err)
+			}
+		}
+	}
+	log.Info().Int("created", result.UsersCreated).Int("existing", result.UsersExisting).Msg("seeded users & accounts")
+
+	for _, transfer := range plan.Transfers {
+		_, err = store.TransferTx(ctx, db.TransferTxParams{
+			FromAccountID: accountIDs[transfer.From][transfer.Currency],
+			ToAccountID:   accountIDs[transfer.To][transfer.Currency],
+			Amount:        transfer.Amount,
+		})
+		if err != nil {
+			return result, fmt.Errorf("cannot transfer: %w", err)
+		}
+		result.Transfers++
+		if result.Transfers%1000 == 0 {
+			log.Info().Int("transfers", result.Transfers).Int("total", len(plan.Transfers)).Msg("seeding transfers")
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,104 @@
+package seed
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/gapi"
+	"github.com/web3dev6/simplebank/util"
+)
+
+func TestNewPlanDeterministic(t *testing.T) {
+	plan := NewPlan(42, 20, 500)
+	require.Equal(t, plan, NewPlan(42, 20, 500))
+	require.NotEqual(t, plan, NewPlan(43, 20, 500))
+}
+
+func TestNewPlan(t *testing.T) {
+	numUsers := 30
+	plan := NewPlan(7, numUsers, 2000)
+	require.Len(t, plan.Users, numUsers)
+	require.Len(t, plan.Transfers, 2000)
+
+	balances := make([]map[string]int64, numUsers)
+	usernames := make(map[string]bool)
+	for i, user := range plan.Users {
+		require.NoError(t, gapi.ValidateUsername(user.Username))
+		require.NoError(t, gapi.ValidateFullname(user.FullName))
+		require.NoError(t, gapi.ValidateEmail(user.Email))
+		require.False(t, usernames[user.Username])
+		usernames[user.Username] = true
+
+		require.Len(t, user.Fundings, len(util.SupportedCurrencies()))
+		balances[i] = make(map[string]int64)
+		for _, funding := range user.Fundings {
+			require.GreaterOrEqual(t, funding.Amount, int64(minFunding))
+			balances[i][funding.Currency] = funding.Amount
+		}
+	}
+
+	received := make([]int, numUsers)
+	for _, transfer := range plan.Transfers {
+		require.NotEqual(t, transfer.From, transfer.To)
+		require.Positive(t, transfer.Amount)
+		// a planned transfer never overdraws its account
+		balances[transfer.From][transfer.Currency] -= transfer.Amount
+		require.GreaterOrEqual(t, balances[transfer.From][transfer.Currency], int64(0))
+		balances[transfer.To][transfer.Currency] += transfer.Amount
+		received[transfer.To]++
+	}
+
+	// skewed graph - the most popular receiver gets well over its even share
+	var maxReceived int
+	for _, n := range received {
+		if n > maxReceived {
+			maxReceived = n
+		}
+	}
+	require.Greater(t, maxReceived, 3*len(plan.Transfers)/numUsers)
+}
+
+func TestApply(t *testing.T) {
+	plan := NewPlan(1, 3, 10)
+	hashedPassword := "hashed"
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	// fresh db - every user & account is created
+	accounts := make(map[int64]db.Account)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(len(plan.Users)).Return(db.User{}, db.ErrRecordNotFound)
+	store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(len(plan.Users)).
+		DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.User, error) {
+			require.Equal(t, hashedPassword, arg.HashedPassword)
+			return db.User{Username: arg.Username}, nil
+		})
+	store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(len(plan.Users)).Return([]db.Account{}, nil)
+	numAccounts := len(plan.Users) * len(util.SupportedCurrencies())
+	store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(numAccounts).
+		DoAndReturn(func(_ context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			account := db.Account{ID: int64(len(accounts) + 1), Owner: arg.Owner, Currency: arg.Currency}
+			accounts[account.ID] = account
+			return account, nil
+		})
+	store.EXPECT().FundAccountTx(gomock.Any(), gomock.Any()).Times(numAccounts).Return(db.FundAccountTxResult{}, nil)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(len(plan.Transfers)).
+		DoAndReturn(func(_ context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+			from, to := accounts[arg.FromAccountID], accounts[arg.ToAccountID]
+			require.Equal(t, from.Currency, to.Currency)
+			require.NotEqual(t, from.Owner, to.Owner)
+			return db.TransferTxResult{}, nil
+		})
+
+	result, err := Apply(context.Background(), store, plan, hashedPassword)
+	require.NoError(t, err)
+	require.Equal(t, Result{
+		UsersCreated:    len(plan.Users),
+		AccountsCreated: numAccounts,
+		Transfers:       len(plan.Transfers),
+	}, result)
+}
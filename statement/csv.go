@@ -0,0 +1,41 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteCSV writes the statement as one row per entry, between an opening & a closing balance row
+// note* amounts in major units without the currency, e.g. -12.30 - it's the same for all of them
+func (statement Statement) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	rows := [][]string{
+		{"date", "entry_id", "memo", "reference", "amount", "balance"},
+		{statement.From.Format(DateFormat), "", "opening balance", "", "", statement.amount(statement.OpeningBalance)},
+	}
+	for _, line := range statement.Lines {
+		rows = append(rows, []string{
+			line.Entry.CreatedAt.UTC().Format(time.RFC3339),
+			strconv.FormatInt(line.Entry.ID, 10),
+			textCell(line.Entry.Memo),
+			textCell(line.Entry.Reference),
+			statement.amount(line.Entry.Amount),
+			statement.amount(line.Balance),
+		})
+	}
+	rows = append(rows, []string{statement.LastDay().Format(DateFormat), "", "closing balance", "", "", statement.amount(statement.ClosingBalance)})
+
+	// WriteAll flushes & returns the first write error
+	return writer.WriteAll(rows)
+}
+
+// textCell keeps a spreadsheet from running a memo or reference typed by a user as a formula, e.g. =HYPERLINK(...)
+func textCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
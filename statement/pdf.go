@@ -0,0 +1,158 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/*
+   PDF - a plain text document, written by hand rather than pulling in a pdf library
+	- A4 pages of monospaced lines in Courier, one of the standard fonts every pdf reader has
+	- a table of the entries, split in pages of pdfLinesPerPage lines
+   Note* the standard fonts only cover latin characters, the others are written as ?
+*/
+
+const (
+	pdfPageWidth  = 595 // A4 in points
+	pdfPageHeight = 842
+	pdfMargin     = 40
+	pdfFontSize   = 9
+	pdfLineHeight = 12
+	// leaving room for the footer
+	pdfLinesPerPage = (pdfPageHeight-2*pdfMargin)/pdfLineHeight - 2
+	// characters, Courier is 0.6 of the font size wide
+	pdfLineWidth = 94
+)
+
+// WritePDF writes the statement as a pdf table of its entries, between the opening & closing balance
+func (statement Statement) WritePDF(w io.Writer) error {
+	var pages [][]string
+	var page []string
+	for _, line := range statement.textLines() {
+		if len(page) == pdfLinesPerPage {
+			pages = append(pages, page)
+			page = nil
+		}
+		page = append(page, line)
+	}
+	pages = append(pages, page)
+
+	for i := range pages {
+		footer := fmt.Sprintf("page %d of %d", i+1, len(pages))
+		pages[i] = append(pages[i], "", fmt.Sprintf("%*s", pdfLineWidth, footer))
+	}
+	return writePDF(w, pages)
+}
+
+// textLines lays the statement out in lines of at most pdfLineWidth characters
+func (statement Statement) textLines() []string {
+	const row = "%-20s %10s  %-28s %15s %15s"
+	account := statement.Account
+	lines := []string{
+		"Simple Bank - Account Statement",
+		"",
+		fmt.Sprintf("Account:  %d (%s)", account.ID, account.Currency),
+		fmt.Sprintf("Owner:    %s", account.Owner),
+		fmt.Sprintf("Period:   %s to %s", statement.From.Format(DateFormat), statement.LastDay().Format(DateFormat)),
+		"",
+		fmt.Sprintf(row, "Date", "Entry", "Memo", "Amount", "Balance"),
+		strings.Repeat("-", pdfLineWidth),
+		fmt.Sprintf(row, statement.From.Format(DateFormat), "", "Opening balance", "", statement.amount(statement.OpeningBalance)),
+	}
+	for _, line := range statement.Lines {
+		memo := line.Entry.Memo
+		if memo == "" {
+			memo = line.Entry.Reference
+		}
+		lines = append(lines, fmt.Sprintf(row,
+			line.Entry.CreatedAt.UTC().Format("2006-01-02 15:04 MST"),
+			fmt.Sprint(line.Entry.ID),
+			truncate(memo, 28),
+			statement.amount(line.Entry.Amount),
+			statement.amount(line.Balance),
+		))
+	}
+	lines = append(lines,
+		strings.Repeat("-", pdfLineWidth),
+		fmt.Sprintf(row, statement.LastDay().Format(DateFormat), "", "Closing balance", "", statement.amount(statement.ClosingBalance)),
+		"",
+		fmt.Sprintf("Generated %s", time.Now().UTC().Format(time.RFC1123)),
+	)
+	return lines
+}
+
+// truncate cuts s to at most n characters, marking the cut with ...
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// writePDF writes a pdf of pages of text lines
+// note* objects 1 & 2 are the catalog & page tree, 3 the font, then a page & its content stream per page
+func writePDF(w io.Writer, pages [][]string) error {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, lines := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+2*i))
+
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range lines {
+			fmt.Fprintf(&content, "(%s) '\n", pdfString(line))
+		}
+		content.WriteString("ET")
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// pdfString escapes s for a pdf literal string, characters outside of latin-1 are written as ?
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			b.WriteByte(' ')
+		case r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			// WinAnsi matches latin-1 here, written as octal so the content stays ascii
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package statement
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/web3dev6/simplebank/apperr"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/money"
+)
+
+/*
+   Account statements - what happened on an account over a period, computed from its entries
+	- the opening balance is the balance of the account when the period started, from its balance snapshots (see db.SQLStore.GetBalanceAt)
+	- every entry of the period follows with the running balance after it, up to the closing balance
+	- written as CSV or PDF, downloaded from the api & emailed monthly by the worker
+   Note* ledger balances - holds don't move money until they're captured, so they're not in a statement
+*/
+
+// MaxEntries is the most entries in a statement, a busier period has to be split in shorter ones
+const MaxEntries = 10000
+
+// DateFormat is how the days of a statement are written, and read from the api
+const DateFormat = "2006-01-02"
+
+// Line is an entry of a statement with the balance of the account after it
+type Line struct {
+	Entry   db.Entry
+	Balance int64
+}
+
+// Statement of an account from From, inclusive, to To, exclusive
+type Statement struct {
+	Account        db.Account
+	From           time.Time
+	To             time.Time
+	OpeningBalance int64
+	Lines          []Line
+	ClosingBalance int64
+}
+
+// Build computes the statement of account from from to to, to excluded
+func Build(ctx context.Context, store db.Store, account db.Account, from time.Time, to time.Time) (Statement, error) {
+	if !from.Before(to) {
+		return Statement{}, apperr.Validation("statement period must not be empty",
+			apperr.FieldViolation{Field: "to", Description: "must be after from"})
+	}
+
+	// note* the entries before from, timestamps are in microseconds - the ones at from are the statement's first
+	opening, err := store.GetBalanceAt(ctx, account.ID, from.Add(-time.Microsecond))
+	if err != nil {
+		return Statement{}, fmt.Errorf("failed to get opening balance of account [%d]: %w", account.ID, err)
+	}
+	// note* one more than the max, to tell a period with too many entries
+	entries, err := store.ListEntriesBetween(ctx, db.ListEntriesBetweenParams{
+		AccountID:  account.ID,
+		FromTime:   from,
+		ToTime:     to,
+		MaxEntries: MaxEntries + 1,
+	})
+	if err != nil {
+		return Statement{}, fmt.Errorf("failed to list entries of account [%d]: %w", account.ID, err)
+	}
+	if len(entries) > MaxEntries {
+		return Statement{}, apperr.Validation(fmt.Sprintf("statement has more than %d entries", MaxEntries),
+			apperr.FieldViolation{Field: "to", Description: "pick a shorter period"})
+	}
+
+	statement := Statement{
+		Account:        account,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		Lines:          make([]Line, len(entries)),
+		ClosingBalance: opening,
+	}
+	for i, entry := range entries {
+		statement.ClosingBalance += entry.Amount
+		statement.Lines[i] = Line{Entry: entry, Balance: statement.ClosingBalance}
+	}
+	return statement, nil
+}
+
+// LastDay is the last day of the statement, To is excluded
+func (statement Statement) LastDay() time.Time {
+	return statement.To.Add(-time.Nanosecond)
+}
+
+// Filename names the statement's file, e.g. statement-12-2026-09-01-2026-09-30.csv
+func (statement Statement) Filename(extension string) string {
+	return fmt.Sprintf("statement-%d-%s-%s.%s", statement.Account.ID,
+		statement.From.Format(DateFormat), statement.LastDay().Format(DateFormat), extension)
+}
+
+// amount formats an amount in major units of the account's currency
+func (statement Statement) amount(amount int64) string {
+	return money.Money{Amount: amount, Currency: statement.Account.Currency}.Decimal()
+}
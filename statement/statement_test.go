@@ -0,0 +1,220 @@
+package statement
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/apperr"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	"github.com/web3dev6/simplebank/util"
+)
+
+var (
+	from    = time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+	to      = time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	account = db.Account{ID: 12, Owner: "alice", Currency: util.USD}
+)
+
+func randomEntries(n int) []db.Entry {
+	entries := make([]db.Entry, n)
+	for i := range entries {
+		entries[i] = db.Entry{
+			ID:        int64(i + 1),
+			AccountID: account.ID,
+			Amount:    util.RandomInt(-1000, 1000),
+			CreatedAt: from.Add(time.Duration(i) * time.Hour),
+		}
+	}
+	return entries
+}
+
+func TestBuild(t *testing.T) {
+	entries := []db.Entry{
+		{ID: 7, AccountID: account.ID, Amount: 1000, Memo: "salary", CreatedAt: from.Add(time.Hour)},
+		{ID: 9, AccountID: account.ID, Amount: -250, CreatedAt: from.Add(2 * time.Hour)},
+		{ID: 11, AccountID: account.ID, Amount: -1500, CreatedAt: from.Add(3 * time.Hour)},
+	}
+
+	testCases := []struct {
+		name          string
+		from          time.Time
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, statement Statement, err error)
+	}{
+		{
+			name: "OK",
+			from: from,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Eq(account.ID), gomock.Eq(from.Add(-time.Microsecond))).
+					Times(1).Return(int64(5000), nil)
+				arg := db.ListEntriesBetweenParams{AccountID: account.ID, FromTime: from, ToTime: to, MaxEntries: MaxEntries + 1}
+				store.EXPECT().ListEntriesBetween(gomock.Any(), gomock.Eq(arg)).Times(1).Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, statement Statement, err error) {
+				require.NoError(t, err)
+				require.Equal(t, int64(5000), statement.OpeningBalance)
+				require.Len(t, statement.Lines, 3)
+				require.Equal(t, []int64{6000, 5750, 4250}, []int64{
+					statement.Lines[0].Balance, statement.Lines[1].Balance, statement.Lines[2].Balance,
+				})
+				require.Equal(t, int64(4250), statement.ClosingBalance)
+			},
+		},
+		{
+			name: "NoEntries",
+			from: from,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(int64(5000), nil)
+				store.EXPECT().ListEntriesBetween(gomock.Any(), gomock.Any()).Times(1).Return([]db.Entry{}, nil)
+			},
+			checkResponse: func(t *testing.T, statement Statement, err error) {
+				require.NoError(t, err)
+				require.Empty(t, statement.Lines)
+				require.Equal(t, statement.OpeningBalance, statement.ClosingBalance)
+			},
+		},
+		{
+			name: "TooManyEntries",
+			from: from,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+				store.EXPECT().ListEntriesBetween(gomock.Any(), gomock.Any()).Times(1).Return(randomEntries(MaxEntries+1), nil)
+			},
+			checkResponse: func(t *testing.T, statement Statement, err error) {
+				require.True(t, apperr.Is(err, apperr.CodeValidation))
+			},
+		},
+		{
+			name: "EmptyPeriod",
+			from: to,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, statement Statement, err error) {
+				require.True(t, apperr.Is(err, apperr.CodeValidation))
+			},
+		},
+		{
+			name: "InternalError",
+			from: from,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(int64(0), errors.New("conn reset"))
+				store.EXPECT().ListEntriesBetween(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, statement Statement, err error) {
+				require.Error(t, err)
+				require.False(t, apperr.Is(err, apperr.CodeValidation))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			statement, err := Build(context.Background(), store, account, tc.from, to)
+			tc.checkResponse(t, statement, err)
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	statement := Statement{
+		Account:        account,
+		From:           from,
+		To:             to,
+		OpeningBalance: 5000,
+		Lines: []Line{
+			{Entry: db.Entry{ID: 7, Amount: 1000, Memo: "salary, september", CreatedAt: from.Add(time.Hour)}, Balance: 6000},
+			{Entry: db.Entry{ID: 9, Amount: -250, Memo: "=HYPERLINK(\"x\")", Reference: "inv-1", CreatedAt: from.Add(2 * time.Hour)}, Balance: 5750},
+		},
+		ClosingBalance: 5750,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, statement.WriteCSV(&buf))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{
+		{"date", "entry_id", "memo", "reference", "amount", "balance"},
+		{"2026-09-01", "", "opening balance", "", "", "50.00"},
+		{"2026-09-01T01:00:00Z", "7", "salary, september", "", "10.00", "60.00"},
+		{"2026-09-01T02:00:00Z", "9", "'=HYPERLINK(\"x\")", "inv-1", "-2.50", "57.50"},
+		{"2026-09-30", "", "closing balance", "", "", "57.50"},
+	}, rows)
+	require.Equal(t, "statement-12-2026-09-01-2026-09-30.csv", statement.Filename("csv"))
+}
+
+func TestWritePDF(t *testing.T) {
+	testCases := []struct {
+		name    string
+		entries int
+		pages   int
+	}{
+		{name: "NoEntries", entries: 0, pages: 1},
+		{name: "OnePage", entries: 40, pages: 1},
+		{name: "ManyPages", entries: 200, pages: 4},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			statement := Statement{Account: account, From: from, To: to}
+			for _, entry := range randomEntries(tc.entries) {
+				statement.ClosingBalance += entry.Amount
+				statement.Lines = append(statement.Lines, Line{Entry: entry, Balance: statement.ClosingBalance})
+			}
+
+			var buf bytes.Buffer
+			require.NoError(t, statement.WritePDF(&buf))
+			pdf := buf.String()
+
+			require.True(t, strings.HasPrefix(pdf, "%PDF-1.4\n"))
+			require.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+			require.Equal(t, tc.pages, strings.Count(pdf, "/Type /Page "))
+			require.Contains(t, pdf, fmt.Sprintf("/Count %d", tc.pages))
+			require.Contains(t, pdf, fmt.Sprintf("page %d of %d", tc.pages, tc.pages))
+			require.Contains(t, pdf, statement.amount(statement.ClosingBalance))
+
+			// startxref points at the xref table, whose entries point at their objects
+			match := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(pdf)
+			require.NotNil(t, match)
+			xref, err := strconv.Atoi(match[1])
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(pdf[xref:], "xref\n"))
+			offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(pdf[xref:], -1)
+			require.Len(t, offsets, 3+2*tc.pages)
+			for i, offset := range offsets {
+				at, err := strconv.Atoi(offset[1])
+				require.NoError(t, err)
+				require.True(t, strings.HasPrefix(pdf[at:], fmt.Sprintf("%d 0 obj\n", i+1)))
+			}
+		})
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	require.Equal(t, `memo \(draft\) \\ x`, pdfString(`memo (draft) \ x`))
+	require.Equal(t, `caf\351 ?`, pdfString("café €"))
+	require.Equal(t, "a b", pdfString("a\nb"))
+}
+
+func TestTruncate(t *testing.T) {
+	require.Equal(t, "short", truncate("short", 10))
+	require.Equal(t, "a long...", truncate("a long memo", 9))
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/web3dev6/simplebank/util"
+)
+
+/*
+   OpenTelemetry tracing - one trace follows a request through http/grpc, sql queries & the asynq tasks it enqueued
+   Note* spans are always created against the global tracer provider,
+        with no exporter configured it stays the otel no-op provider and tracing costs ~nothing
+*/
+
+const (
+	ExporterNone   = ""       // tracing disabled
+	ExporterStdout = "stdout" // pretty printed spans on stdout - for development
+	ExporterOtlp   = "otlp"   // otlp over grpc to OTLP_ENDPOINT - jaeger, tempo, otel-collector...
+
+	serviceName = "simplebank"
+	tracerName  = "github.com/web3dev6/simplebank"
+)
+
+// Init sets up the global tracer provider & the w3c trace-context propagator from config
+// the returned shutdown func flushes the buffered spans - call it once every component stopped
+func Init(ctx context.Context, config util.Config) (func(context.Context) error, error) {
+	// propagator is set even with tracing disabled, so an upstream trace context is still passed on to the tasks
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.TracingExporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterOtlp:
+		// note* insecure - the collector is expected to run as a sidecar / in the same private network
+		exporter, err = otlptracegrpc.New(ctx,
+			otlptracegrpc.WithEndpoint(config.OtlpEndpoint),
+			otlptracegrpc.WithInsecure(),
+		)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter: %s", config.TracingExporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s trace exporter: %w", config.TracingExporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.DeploymentEnvironment(config.Environment),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// follow the caller's sampling decision, sample everything that starts here
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer used for all the spans of the service
+// Note* looked up on every call, so spans follow the provider set by Init (or a test) at any time
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Inject returns the trace context of ctx as a string map - to be carried inside a task payload
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract returns ctx joined to the trace context carried in a task payload by Inject
+func Extract(ctx context.Context, traceContext map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(traceContext))
+}
+
+// RecordError marks span as failed with err, a nil err is a no-op
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
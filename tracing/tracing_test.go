@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/util"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInitExporter(t *testing.T) {
+	shutdown, err := Init(context.Background(), util.Config{TracingExporter: ExporterNone})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	_, err = Init(context.Background(), util.Config{TracingExporter: "zipkin"})
+	require.Error(t, err)
+}
+
+func TestInjectExtract(t *testing.T) {
+	_, err := Init(context.Background(), util.Config{})
+	require.NoError(t, err)
+
+	// no span, nothing to carry
+	require.Nil(t, Inject(context.Background()))
+
+	provider := sdktrace.NewTracerProvider()
+	ctx, span := provider.Tracer("test").Start(context.Background(), "enqueue")
+	defer span.End()
+
+	traceContext := Inject(ctx)
+	require.NotEmpty(t, traceContext["traceparent"])
+
+	// the worker side continues the same trace
+	spanContext := trace.SpanContextFromContext(Extract(context.Background(), traceContext))
+	require.True(t, spanContext.IsRemote())
+	require.Equal(t, span.SpanContext().TraceID(), spanContext.TraceID())
+	require.Equal(t, span.SpanContext().SpanID(), spanContext.SpanID())
+}
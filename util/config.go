@@ -1,49 +1,347 @@
 package util
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
+/*
+   Configuration layers, each one overriding the previous
+	1. defaults (configDefaults)
+	2. the base config file - app.env, app.yaml, app.toml or app.json in the config path
+	3. the environment's config file - app.<ENVIRONMENT>.env/yaml/toml/json, e.g. app.production.yaml
+	4. env-vars, e.g. TOKEN_MAKER_TYPE=JWT
+	5. secrets read from files - KEY_FILE=/run/secrets/key sets KEY to the file's content (as env-var or config file key)
+   Note* keys are the same in every layer - the mapstructure tags below, case-insensitive in yaml/toml/json files
+*/
+
 // This config struct stores all configuration of the application
-// The values are read by viper from a config file or environment variables
+// The values are read by viper from config files or environment variables
 type Config struct {
 	Environment          string        `mapstructure:"ENVIRONMENT"`
-	DbDriver             string        `mapstructure:"DB_DRIVER"`
-	DbSourceMain         string        `mapstructure:"DB_SOURCE_MAIN"`
-	DbSourceTest         string        `mapstructure:"DB_SOURCE_TEST"`
+	DbSourceMain         string        `mapstructure:"DB_SOURCE_MAIN" secret:"url"`
+	DbSourceTest         string        `mapstructure:"DB_SOURCE_TEST" secret:"url"`
+	DbSourceReplica      string        `mapstructure:"DB_SOURCE_REPLICA" secret:"url"`
+	DbReplicaMaxLag      time.Duration `mapstructure:"DB_REPLICA_MAX_LAG"`
+	DbReplicaInterval    time.Duration `mapstructure:"DB_REPLICA_INTERVAL"`
 	MigrationUrl         string        `mapstructure:"MIGRATION_URL"`
+	DbMaxConns           int32         `mapstructure:"DB_MAX_CONNS"`
+	DbMinConns           int32         `mapstructure:"DB_MIN_CONNS"`
+	DbMaxConnLifetime    time.Duration `mapstructure:"DB_MAX_CONN_LIFETIME"`
+	DbMaxConnIdleTime    time.Duration `mapstructure:"DB_MAX_CONN_IDLE_TIME"`
+	DbTxTimeout          time.Duration `mapstructure:"DB_TX_TIMEOUT"`
+	DbTxMaxRetries       int           `mapstructure:"DB_TX_MAX_RETRIES"`
+	DbTxRetryDelay       time.Duration `mapstructure:"DB_TX_RETRY_DELAY"`
+	DbTransferIsolation  string        `mapstructure:"DB_TRANSFER_ISOLATION"`
+	CurrencyCacheTTL     time.Duration `mapstructure:"CURRENCY_CACHE_TTL"`
+	HoldDuration         time.Duration `mapstructure:"HOLD_DURATION"`
+	HoldSweepInterval    time.Duration `mapstructure:"HOLD_SWEEP_INTERVAL"`
 	HttpServerAddress    string        `mapstructure:"HTTP_SERVER_ADDRESS"`
 	GrpcServerAddress    string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	GatewayServerAddress string        `mapstructure:"GATEWAY_SERVER_ADDRESS"`
+	MetricsServerAddress string        `mapstructure:"METRICS_SERVER_ADDRESS"`
 	ServerType           string        `mapstructure:"SERVER_TYPE"`
 	TokenMakerType       string        `mapstructure:"TOKEN_MAKER_TYPE"`
-	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY" secret:"true"`
 	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
 	RedisAddress         string        `mapstructure:"REDIS_ADDRESS"`
 	EmailSenderName      string        `mapstructure:"EMAIL_SENDER_NAME"`
 	EmailSenderAddress   string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
-	EmailSenderPassword  string        `mapstructure:"EMAIL_SENDER_PASSWORD"`
+	EmailSenderPassword  string        `mapstructure:"EMAIL_SENDER_PASSWORD" secret:"true"`
+	ShutdownTimeout      time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	ShutdownDrainDelay   time.Duration `mapstructure:"SHUTDOWN_DRAIN_DELAY"`
+	RateLimitRps         float64       `mapstructure:"RATE_LIMIT_RPS"`
+	RateLimitBurst       int           `mapstructure:"RATE_LIMIT_BURST"`
+	TrustedProxies       string        `mapstructure:"TRUSTED_PROXIES"`
+	TracingExporter      string        `mapstructure:"TRACING_EXPORTER"`
+	OtlpEndpoint         string        `mapstructure:"OTLP_ENDPOINT"`
+
+	// Files the config was read from, in the order they were merged - not a config key
+	Files []string `mapstructure:"-"`
 }
 
-// LoadConfig reads configuration from file if path exists or set/override configuration with env-vars if provided
+const (
+	configName = "app" // app.env, app.yaml...
+
+	// secretFileSuffix - KEY_FILE holds the path of a file with the value of KEY
+	secretFileSuffix = "_FILE"
+	// redacted replaces secrets in a printed config
+	redacted = "[REDACTED]"
+)
+
+// supported values of the enum keys
+var (
+	Environments    = []string{"development", "production"}
+	ServerTypes     = []string{"HTTP", "GRPC", "GRPC_GATEWAY", "ALL"}
+	TokenMakerTypes = []string{"JWT", "PASETO"}
+	// note* names of the isolation levels parsed by db.ParseIsolationLevel, empty is the db's default
+	IsolationLevels = []string{"", "read_committed", "repeatable_read", "serializable"}
+	// note* names of the exporters in the tracing package, which imports util
+	TracingExporters = []string{"", "stdout", "otlp"}
+)
+
+// token keys must be exactly 32 bytes for PASETO (chacha20poly1305 key size) and at least 32 for JWT
+const tokenSymmetricKeySize = 32
+
+// configDefaults - used for keys set in no config file nor env-var
+var configDefaults = map[string]interface{}{
+	"ENVIRONMENT":            "development",
+	"MIGRATION_URL":          "file://db/migration",
+	"DB_REPLICA_MAX_LAG":     2 * time.Second,
+	"DB_REPLICA_INTERVAL":    time.Second,
+	"DB_MAX_CONNS":           20,
+	"DB_MAX_CONN_LIFETIME":   time.Hour,
+	"DB_MAX_CONN_IDLE_TIME":  30 * time.Minute,
+	"DB_TX_TIMEOUT":          5 * time.Second,
+	"DB_TX_MAX_RETRIES":      3,
+	"DB_TX_RETRY_DELAY":      20 * time.Millisecond,
+	"DB_TRANSFER_ISOLATION":  "read_committed",
+	"CURRENCY_CACHE_TTL":     30 * time.Second,
+	"HOLD_DURATION":          7 * 24 * time.Hour,
+	"HOLD_SWEEP_INTERVAL":    time.Minute,
+	"HTTP_SERVER_ADDRESS":    "0.0.0.0:8080",
+	"GRPC_SERVER_ADDRESS":    "0.0.0.0:9090",
+	"SERVER_TYPE":            "HTTP",
+	"TOKEN_MAKER_TYPE":       "PASETO",
+	"ACCESS_TOKEN_DURATION":  15 * time.Minute,
+	"REFRESH_TOKEN_DURATION": 24 * time.Hour,
+	"REDIS_ADDRESS":          "0.0.0.0:6379",
+	"EMAIL_SENDER_NAME":      "Simple Bank",
+	"SHUTDOWN_TIMEOUT":       10 * time.Second,
+}
+
+// LoadConfig reads configuration from the config files in path (if any), overridden by env-vars & secret files
+// Note: the config isn't validated here, callers needing a complete config call Validate
 func LoadConfig(path string) (config Config, err error) {
-	// read config from file, if exists
-	viper.AddConfigPath(path)
-	viper.SetConfigName("app") // from app.env
-	viper.SetConfigType("env") // from app.env, could also be json, xml, yaml
+	v := viper.New()
+	v.AddConfigPath(path)
+	v.AutomaticEnv()
 
-	// read/override config with env-vars, if exist
-	viper.AutomaticEnv()
+	// every key is known to viper up front - env-vars are read even for keys set in no config file
+	for _, key := range configKeys() {
+		if value, ok := configDefaults[key]; ok {
+			v.SetDefault(key, value)
+		}
+		if err = v.BindEnv(key); err != nil {
+			return
+		}
+	}
+
+	// base config file - optional, the config may come from env-vars only (e.g. in a container)
+	v.SetConfigName(configName)
+	err = v.ReadInConfig()
+	if err == nil {
+		config.Files = append(config.Files, v.ConfigFileUsed())
+	} else if !errors.As(err, &viper.ConfigFileNotFoundError{}) {
+		return config, fmt.Errorf("cannot read config file: %w", err)
+	}
 
-	// read config now for both cases
-	err = viper.ReadInConfig()
-	if err != nil {
-		return
+	// environment's config file, merged over the base one
+	if environment := v.GetString("ENVIRONMENT"); environment != "" {
+		v.SetConfigName(configName + "." + environment)
+		err = v.MergeInConfig()
+		if err == nil {
+			config.Files = append(config.Files, v.ConfigFileUsed())
+		} else if !errors.As(err, &viper.ConfigFileNotFoundError{}) {
+			return config, fmt.Errorf("cannot read %s config file: %w", environment, err)
+		}
+	}
+
+	// secrets from files override everything else
+	for _, key := range configKeys() {
+		secretFile := v.GetString(key + secretFileSuffix)
+		if secretFile == "" {
+			continue
+		}
+		secret, err := os.ReadFile(secretFile)
+		if err != nil {
+			return config, fmt.Errorf("cannot read %s%s: %w", key, secretFileSuffix, err)
+		}
+		v.Set(key, strings.TrimRight(string(secret), "\r\n"))
 	}
 
 	// unmarshal the config values to target config object
-	err = viper.Unmarshal(&config)
+	err = v.Unmarshal(&config)
 	return
 }
+
+// Validate checks required keys, enums & values the servers can't start without - reporting all problems at once
+func (config Config) Validate() error {
+	var problems []string
+	required := func(key, value string) {
+		if value == "" {
+			problems = append(problems, fmt.Sprintf("%s is required", key))
+		}
+	}
+	oneOf := func(key, value string, allowed []string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		problems = append(problems, fmt.Sprintf("%s %q must be one of %s", key, value, strings.Join(allowed, "/")))
+	}
+	positive := func(key string, value time.Duration) {
+		if value <= 0 {
+			problems = append(problems, fmt.Sprintf("%s must be positive", key))
+		}
+	}
+
+	oneOf("ENVIRONMENT", config.Environment, Environments)
+	required("DB_SOURCE_MAIN", config.DbSourceMain)
+	required("MIGRATION_URL", config.MigrationUrl)
+	if config.DbMaxConns < 0 || config.DbMinConns < 0 || (config.DbMaxConns > 0 && config.DbMinConns > config.DbMaxConns) {
+		problems = append(problems, "DB_MIN_CONNS & DB_MAX_CONNS must not be negative, nor DB_MIN_CONNS over DB_MAX_CONNS")
+	}
+	if config.DbSourceReplica != "" {
+		positive("DB_REPLICA_MAX_LAG", config.DbReplicaMaxLag)
+		positive("DB_REPLICA_INTERVAL", config.DbReplicaInterval)
+	}
+	oneOf("DB_TRANSFER_ISOLATION", config.DbTransferIsolation, IsolationLevels)
+	if config.DbTxTimeout < 0 || config.DbTxRetryDelay < 0 || config.DbTxMaxRetries < 0 {
+		problems = append(problems, "DB_TX_TIMEOUT, DB_TX_RETRY_DELAY & DB_TX_MAX_RETRIES must not be negative")
+	}
+	positive("CURRENCY_CACHE_TTL", config.CurrencyCacheTTL)
+	positive("HOLD_DURATION", config.HoldDuration)
+	positive("HOLD_SWEEP_INTERVAL", config.HoldSweepInterval)
+	required("REDIS_ADDRESS", config.RedisAddress)
+
+	oneOf("SERVER_TYPE", config.ServerType, ServerTypes)
+	switch config.ServerType {
+	case "HTTP":
+		required("HTTP_SERVER_ADDRESS", config.HttpServerAddress)
+	case "GRPC":
+		required("GRPC_SERVER_ADDRESS", config.GrpcServerAddress)
+	case "GRPC_GATEWAY":
+		required("HTTP_SERVER_ADDRESS", config.HttpServerAddress)
+		required("GRPC_SERVER_ADDRESS", config.GrpcServerAddress)
+	case "ALL":
+		required("HTTP_SERVER_ADDRESS", config.HttpServerAddress)
+		required("GRPC_SERVER_ADDRESS", config.GrpcServerAddress)
+		if config.GatewayServerAddress == "" || config.GatewayServerAddress == config.HttpServerAddress {
+			problems = append(problems, "GATEWAY_SERVER_ADDRESS must be set and differ from HTTP_SERVER_ADDRESS for SERVER_TYPE=ALL")
+		}
+	}
+
+	oneOf("TOKEN_MAKER_TYPE", config.TokenMakerType, TokenMakerTypes)
+	switch {
+	case config.TokenMakerType == "PASETO" && len(config.TokenSymmetricKey) != tokenSymmetricKeySize:
+		problems = append(problems, fmt.Sprintf("TOKEN_SYMMETRIC_KEY must be exactly %d characters for PASETO", tokenSymmetricKeySize))
+	case config.TokenMakerType == "JWT" && len(config.TokenSymmetricKey) < tokenSymmetricKeySize:
+		problems = append(problems, fmt.Sprintf("TOKEN_SYMMETRIC_KEY must be at least %d characters for JWT", tokenSymmetricKeySize))
+	}
+	positive("ACCESS_TOKEN_DURATION", config.AccessTokenDuration)
+	positive("REFRESH_TOKEN_DURATION", config.RefreshTokenDuration)
+	positive("SHUTDOWN_TIMEOUT", config.ShutdownTimeout)
+	if config.ShutdownDrainDelay < 0 {
+		problems = append(problems, "SHUTDOWN_DRAIN_DELAY must not be negative")
+	}
+
+	if _, err := ParseTrustedProxies(config.TrustedProxyList()); err != nil {
+		problems = append(problems, fmt.Sprintf("TRUSTED_PROXIES %s", err))
+	}
+
+	oneOf("TRACING_EXPORTER", config.TracingExporter, TracingExporters)
+	if config.TracingExporter == "otlp" {
+		required("OTLP_ENDPOINT", config.OtlpEndpoint)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// TrustedProxyList returns the ips & cidrs of TRUSTED_PROXIES, a comma separated list e.g. "10.0.0.0/8, 127.0.0.1"
+func (config Config) TrustedProxyList() (proxies []string) {
+	for _, proxy := range strings.Split(config.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// ParseTrustedProxies parses ips & cidrs to networks, an ip to the network of just itself
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q", proxy)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Redacted returns a copy of config safe to print or log - secrets replaced, db urls keep all but their password
+func (config Config) Redacted() Config {
+	value := reflect.ValueOf(&config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		switch value.Type().Field(i).Tag.Get("secret") {
+		case "true":
+			if field.String() != "" {
+				field.SetString(redacted)
+			}
+		case "url":
+			// note* a key=value dsn (host=... password=...) isn't a url, it's redacted as a whole
+			if u, err := url.Parse(field.String()); err == nil && u.Scheme != "" {
+				field.SetString(u.Redacted())
+			} else if field.String() != "" {
+				field.SetString(redacted)
+			}
+		}
+	}
+	return config
+}
+
+// WriteEnv writes config in the app.env format, one KEY=value line per key
+func (config Config) WriteEnv(w io.Writer) error {
+	value := reflect.ValueOf(config)
+	for i := 0; i < value.NumField(); i++ {
+		key := value.Type().Field(i).Tag.Get("mapstructure")
+		if key == "-" {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "%s=%v\n", key, value.Field(i).Interface())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configKeys returns the keys of all config values
+func configKeys() (keys []string) {
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		if key := configType.Field(i).Tag.Get("mapstructure"); key != "-" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
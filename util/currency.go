@@ -1,16 +1,13 @@
 package util
 
+// currencies the currencies table is created with - which ones are enabled is up to the currency.Registry
 const (
 	USD = "USD"
 	EUR = "EUR"
 	INR = "INR"
 )
 
-// isSupportedCurrency returns true if tthe currency is supported
-func IsSupportedCurrency(currency string) bool {
-	switch currency {
-	case USD, EUR, INR:
-		return true
-	}
-	return false
+// SupportedCurrencies returns the currency codes the currencies table is created with
+func SupportedCurrencies() []string {
+	return []string{USD, EUR, INR}
 }
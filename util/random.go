@@ -13,21 +13,33 @@ const fullname_space = "abcdefghijklmnopqrstuvwxyz "
 
 var currencies = []string{EUR, USD, INR}
 
-var r *rand.Rand
+// Random generates random test data from its own source - deterministic for a given seed
+// Note* not safe for concurrent use, like the *rand.Rand it wraps
+type Random struct {
+	*rand.Rand
+}
+
+// NewRandom creates a Random generating the same data for the same seed
+func NewRandom(seed int64) *Random {
+	return &Random{rand.New(rand.NewSource(seed))}
+}
+
+// r backs the package level generators, randomly seeded
+var r *Random
 
 // init runs every time a package is used
 func init() {
-	r = rand.New(rand.NewSource(int64(new(maphash.Hash).Sum64())))
+	r = NewRandom(int64(new(maphash.Hash).Sum64()))
 	// rand.Seed(time.Now().UnixNano())
 }
 
 // RandomInt generates a random number between min and max
-func RandomInt(min, max int64) int64 {
+func (r *Random) RandomInt(min, max int64) int64 {
 	return min + r.Int63n(max-min+1)
 }
 
 // RandomString generates a random string of length n
-func RandomString(n int, space_optional ...string) string {
+func (r *Random) RandomString(n int, space_optional ...string) string {
 	space := alphanumeric_space
 	if len(space_optional) > 0 {
 		space = space_optional[0]
@@ -41,6 +53,22 @@ func RandomString(n int, space_optional ...string) string {
 	return sb.String()
 }
 
+// RandomCurrency generates a random currency code for account
+func (r *Random) RandomCurrency() string {
+	n := len(currencies)
+	return currencies[r.Intn(n)]
+}
+
+// RandomInt generates a random number between min and max
+func RandomInt(min, max int64) int64 {
+	return r.RandomInt(min, max)
+}
+
+// RandomString generates a random string of length n
+func RandomString(n int, space_optional ...string) string {
+	return r.RandomString(n, space_optional...)
+}
+
 // RandomOwner generates a random owner name of len 6 for account
 func RandomOwner() string {
 	return RandomString(6)
@@ -58,8 +86,7 @@ func RandomAmount() int64 {
 
 // RandomCurrency generates a random currency code for account
 func RandomCurrency() string {
-	n := len(currencies)
-	return currencies[r.Intn(n)]
+	return r.RandomCurrency()
 }
 
 // RandomEmail generates a random email
@@ -0,0 +1,8 @@
+package util
+
+// user roles - stored in users.role
+const (
+	DepositorRole = "depositor"
+	AdminRole     = "admin"
+	SystemRole    = "system" // the bank itself, owns its internal accounts - can't log in
+)
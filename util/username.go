@@ -0,0 +1,7 @@
+package util
+
+import "regexp"
+
+// IsValidUsername checks the characters of a username - lowercase letters, digits or underscore
+// note* the length is checked by the callers, 3-100
+var IsValidUsername = regexp.MustCompile(`^[a-z0-9_]+$`).MatchString
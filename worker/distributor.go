@@ -6,7 +6,9 @@ import (
 	"fmt"
 
 	"github.com/hibiken/asynq"
-	"github.com/rs/zerolog/log"
+	"github.com/web3dev6/simplebank/logging"
+	"github.com/web3dev6/simplebank/metrics"
+	"github.com/web3dev6/simplebank/tracing"
 )
 
 /*
@@ -18,6 +20,7 @@ const TaskSendVerifyEmail = "task:send_verify_email"
 // Makes code more generic & easier to mock and test
 type TaskDistributor interface {
 	DistributeTaskSendVerifyEmail(ctx context.Context, payload *PayloadSendVerifyEmail, opts ...asynq.Option) error
+	Close() error
 }
 
 // RedisTaskDistributor implements TaskDistributor
@@ -34,7 +37,15 @@ func NewRedisTaskDistributor(redisOpt asynq.RedisClientOpt) TaskDistributor {
 }
 
 // DistributeTaskSendVerifyEmail - create SendVerifyEmail task and send to redis queue
-func (distributor *RedisTaskDistributor) DistributeTaskSendVerifyEmail(ctx context.Context, payload *PayloadSendVerifyEmail, opts ...asynq.Option) error {
+func (distributor *RedisTaskDistributor) DistributeTaskSendVerifyEmail(ctx context.Context, payload *PayloadSendVerifyEmail, opts ...asynq.Option) (err error) {
+	// the worker picks the trace & request id up from the payload
+	ctx, span := startEnqueueSpan(ctx, TaskSendVerifyEmail, &payload.TaskTrace)
+	payload.RequestID = logging.RequestID(ctx)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	// serialize payload
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
@@ -50,8 +61,10 @@ func (distributor *RedisTaskDistributor) DistributeTaskSendVerifyEmail(ctx conte
 		return fmt.Errorf("failed to enqueue task: %w", err)
 	}
 
+	metrics.ObserveTaskEnqueued(tInfo.Type, tInfo.Queue)
+
 	// log enqueued task details
-	log.Info().
+	logging.Logger(ctx).Info().
 		Str("type", tInfo.Type).
 		RawJSON("payload", tInfo.Payload).
 		Str("queue", tInfo.Queue).
@@ -60,3 +73,8 @@ func (distributor *RedisTaskDistributor) DistributeTaskSendVerifyEmail(ctx conte
 
 	return nil
 }
+
+// Close closes the connection with redis - call only after all servers stopped distributing tasks
+func (distributor *RedisTaskDistributor) Close() error {
+	return distributor.client.Close()
+}
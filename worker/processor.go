@@ -8,9 +8,10 @@ import (
 
 	"github.com/hibiken/asynq"
 	"github.com/redis/go-redis/v9"
-	"github.com/rs/zerolog/log"
 	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/logging"
 	"github.com/web3dev6/simplebank/mail"
+	"github.com/web3dev6/simplebank/metrics"
 	util "github.com/web3dev6/simplebank/util"
 )
 
@@ -31,7 +32,13 @@ const (
 // Makes code more generic & easier to mock and test
 type TaskProcessor interface {
 	Start() error
+	Shutdown()
 	ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error
+	ProcessTaskVoidExpiredHolds(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendMonthlyStatements(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSnapshotBalances(ctx context.Context, task *asynq.Task) error
+	ProcessTaskAccrueInterest(ctx context.Context, task *asynq.Task) error
+	ProcessTaskPostInterest(ctx context.Context, task *asynq.Task) error
 }
 
 // RedisTaskProcessor implements TaskProcessor
@@ -61,7 +68,8 @@ func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, store db.Store, mailer
 			// ErrorHandler handles when task fails and returns error
 			ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
 				// log failed task details
-				log.Error().Err(err).
+				// note* runs outside of the mux middlewares, so the request id is picked from the payload again
+				logging.Logger(withTaskRequestID(ctx, task)).Error().Err(err).
 					Str("type", task.Type()).
 					RawJSON("payload", task.Payload()).
 					// Bytes("payload", task.Payload()).
@@ -73,6 +81,8 @@ func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, store db.Store, mailer
 			}),
 			// Logger - logger implements the asynq's Logger interface with zerolog logging at various log levels
 			Logger: logger,
+			// ShutdownTimeout - how long Shutdown waits for active tasks to finish before they are pushed back to the queue
+			ShutdownTimeout: config.ShutdownTimeout,
 		})
 	return &RedisTaskProcessor{
 		server: server,
@@ -96,7 +106,7 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 	user, err := processor.store.GetUser(ctx, payload.Username)
 	if err != nil {
 		// considering user_creation in db takes time, keep retrying, no need of asynq.SkipRetry
-		// if errors.Is(err, db.ErrRecordNotFound) || errors.Is(err, sql.ErrNoRows) {
+		// if errors.Is(err, db.ErrRecordNotFound) {
 		// 	// user doesn't exist, no need to retry
 		// 	return fmt.Errorf("user with username %s doesn't exist: %w", payload.Username, asynq.SkipRetry)
 		// }
@@ -117,7 +127,7 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 	subject := "Welcome to Simple Bank"
 	// verifyUrl should point to a frontend page who parses input arg from url & call api in backend for verification
 	var verifyUrl string
-	if processor.config.ServerType == "HTTP" {
+	if processor.config.ServerType == "HTTP" || processor.config.ServerType == "ALL" {
 		verifyUrl = fmt.Sprintf("http://localhost:8080/users/verify_email?email_id=%d&secret_code=%s", verifyEmail.ID, verifyEmail.SecretCode)
 	} else {
 		verifyUrl = fmt.Sprintf("http://localhost:8080/v1/verify_email?email_id=%d&secret_code=%s", verifyEmail.ID, verifyEmail.SecretCode)
@@ -127,13 +137,13 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 	Please <a href="%s">click here</a> to verify your email address.<br/> 
 	`, user.FullName, verifyUrl)
 	to := []string{user.Email}
-	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
+	err = processor.mailer.SendEmail(ctx, subject, content, to, nil, nil, nil)
 	if err != nil {
 		return fmt.Errorf("failed to send verify_email to user %s: %w", payload.Username, err)
 	}
 
 	// log processed task details
-	log.Info().
+	logging.Logger(ctx).Info().
 		Str("type", task.Type()).
 		RawJSON("payload", task.Payload()).
 		Str("user_email", user.Email).
@@ -145,10 +155,35 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 // Start - we will register the task@TaskSendVerifyEmail  in this func before starting the asynq server
 func (processor *RedisTaskProcessor) Start() error {
 	mux := asynq.NewServeMux()
+	// trace, log with the originating request id & measure every task - throughput, failures & retries per task type
+	mux.Use(tracingMiddleware, requestIDMiddleware, metricsMiddleware)
 	// we can use this mux to register each task with its handler function, similar to http-mux
 	// Register @TaskSendVerifyEmail
 	mux.HandleFunc(TaskSendVerifyEmail, processor.ProcessTaskSendVerifyEmail)
+	mux.HandleFunc(TaskVoidExpiredHolds, processor.ProcessTaskVoidExpiredHolds)
+	mux.HandleFunc(TaskSendMonthlyStatements, processor.ProcessTaskSendMonthlyStatements)
+	mux.HandleFunc(TaskSnapshotBalances, processor.ProcessTaskSnapshotBalances)
+	mux.HandleFunc(TaskAccrueInterest, processor.ProcessTaskAccrueInterest)
+	mux.HandleFunc(TaskPostInterest, processor.ProcessTaskPostInterest)
 
 	// start server
 	return processor.server.Start(mux)
 }
+
+// Shutdown stops pulling new tasks from redis and waits for active tasks to finish (bounded by config.ShutdownTimeout)
+// Note* tasks still running at the deadline are re-queued by asynq and picked up again on next start
+func (processor *RedisTaskProcessor) Shutdown() {
+	processor.server.Shutdown()
+}
+
+// metricsMiddleware records the outcome & latency of each processed task
+func metricsMiddleware(handler asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		startTime := time.Now()
+		err := handler.ProcessTask(ctx, task)
+		// retry count is 0 on the first run of a task
+		retryCount, _ := asynq.GetRetryCount(ctx)
+		metrics.ObserveTask(task.Type(), time.Since(startTime), retryCount > 0, err)
+		return err
+	})
+}
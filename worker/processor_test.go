@@ -0,0 +1,504 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+	"github.com/web3dev6/simplebank/apperr"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	mockdb "github.com/web3dev6/simplebank/db/sqlc/mock"
+	mockmail "github.com/web3dev6/simplebank/mail/mock"
+	"github.com/web3dev6/simplebank/util"
+)
+
+func TestProcessTaskVoidExpiredHolds(t *testing.T) {
+	holds := []db.Hold{{ID: 1}, {ID: 2}, {ID: 3}}
+	task := asynq.NewTask(TaskVoidExpiredHolds, []byte("{}"))
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkErr   func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListExpiredHolds(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListExpiredHoldsParams) ([]db.Hold, error) {
+						require.Equal(t, int32(maxExpiredHolds), arg.MaxHolds)
+						require.False(t, arg.Now.IsZero())
+						return holds, nil
+					})
+				for _, hold := range holds {
+					store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Eq(db.VoidHoldTxParams{HoldID: hold.ID, Expired: true})).Times(1)
+				}
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// captured in the meantime - skipped, not retried
+			name: "CapturedMeanwhile",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListExpiredHolds(gomock.Any(), gomock.Any()).Times(1).Return(holds[:2], nil)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.VoidHoldTxResult{}, apperr.Conflict("hold [1] is already captured"))
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// the others are still voided, the task is retried for the failed one
+			name: "VoidFailed",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListExpiredHolds(gomock.Any(), gomock.Any()).Times(1).Return(holds, nil)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Eq(db.VoidHoldTxParams{HoldID: 1, Expired: true})).Times(1)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Eq(db.VoidHoldTxParams{HoldID: 2, Expired: true})).Times(1).
+					Return(db.VoidHoldTxResult{}, errors.New("conn reset"))
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Eq(db.VoidHoldTxParams{HoldID: 3, Expired: true})).Times(1)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.NotErrorIs(t, err, asynq.SkipRetry)
+			},
+		},
+		{
+			name: "ListFailed",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListExpiredHolds(gomock.Any(), gomock.Any()).Times(1).Return(nil, errors.New("conn reset"))
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			processor := &RedisTaskProcessor{store: store}
+			tc.checkErr(t, processor.ProcessTaskVoidExpiredHolds(context.Background(), task))
+		})
+	}
+}
+
+func TestMonthPeriod(t *testing.T) {
+	now := time.Date(2026, time.January, 1, 6, 0, 0, 0, time.UTC)
+	from, to, err := monthPeriod("", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC), from)
+	require.Equal(t, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), to)
+
+	from, to, err = monthPeriod("2026-02", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC), from)
+	require.Equal(t, time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), to)
+
+	_, _, err = monthPeriod("2026-13", now)
+	require.Error(t, err)
+}
+
+func TestProcessTaskSendMonthlyStatements(t *testing.T) {
+	verified := db.User{Username: "alice", FullName: "Alice", Email: "alice@email.com", IsEmailVerified: true}
+	unverified := db.User{Username: "bob", FullName: "Bob", Email: "bob@email.com"}
+	accounts := []db.Account{
+		{ID: 1, Owner: verified.Username, Currency: util.USD},
+		{ID: 2, Owner: unverified.Username, Currency: util.USD},
+		{ID: 3, Owner: verified.Username, Currency: util.EUR},
+	}
+	from := time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	task := asynq.NewTask(TaskSendMonthlyStatements, []byte(`{"month":"2026-09"}`))
+
+	// the attachments are there while the email is sent
+	sendEmail := func(_ context.Context, subject, content string, to, cc, bcc, attachFiles []string) error {
+		require.Equal(t, []string{verified.Email}, to)
+		require.Len(t, attachFiles, 2)
+		for _, file := range attachFiles {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+			require.NotEmpty(t, data)
+		}
+		require.Equal(t, ".pdf", filepath.Ext(attachFiles[0]))
+		require.Equal(t, ".csv", filepath.Ext(attachFiles[1]))
+		return nil
+	}
+	buildStatementStubs := func(store *mockdb.MockStore, times int) {
+		store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any(), gomock.Any()).Times(times).Return(int64(1000), nil)
+		store.EXPECT().ListEntriesBetween(gomock.Any(), gomock.Any()).Times(times).
+			Return([]db.Entry{{ID: 1, Amount: 500, CreatedAt: from.Add(time.Hour)}}, nil)
+	}
+
+	testCases := []struct {
+		name       string
+		task       *asynq.Task
+		buildStubs func(store *mockdb.MockStore, mailer *mockmail.MockEmailSender)
+		checkErr   func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			task: task,
+			buildStubs: func(store *mockdb.MockStore, mailer *mockmail.MockEmailSender) {
+				arg := db.ListAccountsWithoutStatementEmailParams{PeriodStart: from, PeriodEnd: to, MaxAccounts: maxStatementAccounts}
+				store.EXPECT().ListAccountsWithoutStatementEmail(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts, nil)
+				// looked up once per owner
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(verified.Username)).Times(1).Return(verified, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(unverified.Username)).Times(1).Return(unverified, nil)
+				buildStatementStubs(store, 2)
+				mailer.EXPECT().SendEmail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(2).DoAndReturn(sendEmail)
+				for _, id := range []int64{1, 3} {
+					arg := db.CreateStatementEmailParams{AccountID: id, PeriodStart: from, PeriodEnd: to}
+					store.EXPECT().CreateStatementEmail(gomock.Any(), gomock.Eq(arg)).Times(1)
+				}
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// the next page starts after the last account of the full one
+			name: "Pages",
+			task: task,
+			buildStubs: func(store *mockdb.MockStore, mailer *mockmail.MockEmailSender) {
+				page := make([]db.Account, maxStatementAccounts)
+				for i := range page {
+					page[i] = db.Account{ID: int64(i + 1), Owner: unverified.Username}
+				}
+				gomock.InOrder(
+					store.EXPECT().ListAccountsWithoutStatementEmail(gomock.Any(), gomock.Any()).Times(1).Return(page, nil),
+					store.EXPECT().ListAccountsWithoutStatementEmail(gomock.Any(), gomock.Any()).Times(1).
+						DoAndReturn(func(_ context.Context, arg db.ListAccountsWithoutStatementEmailParams) ([]db.Account, error) {
+							require.Equal(t, int64(maxStatementAccounts), arg.AfterID)
+							return []db.Account{}, nil
+						}),
+				)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(unverified, nil)
+				mailer.EXPECT().SendEmail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// the other statements are still sent, not recorded so the retry sends the failed one again
+			name: "SendFailed",
+			task: task,
+			buildStubs: func(store *mockdb.MockStore, mailer *mockmail.MockEmailSender) {
+				store.EXPECT().ListAccountsWithoutStatementEmail(gomock.Any(), gomock.Any()).Times(1).Return(accounts, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(2).Return(verified, nil)
+				buildStatementStubs(store, 3)
+				gomock.InOrder(
+					mailer.EXPECT().SendEmail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(1).Return(errors.New("smtp unavailable")),
+					mailer.EXPECT().SendEmail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(2).DoAndReturn(sendEmail),
+				)
+				store.EXPECT().CreateStatementEmail(gomock.Any(), gomock.Any()).Times(2)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.NotErrorIs(t, err, asynq.SkipRetry)
+			},
+		},
+		{
+			name: "InvalidMonth",
+			task: asynq.NewTask(TaskSendMonthlyStatements, []byte(`{"month":"september"}`)),
+			buildStubs: func(store *mockdb.MockStore, mailer *mockmail.MockEmailSender) {
+				store.EXPECT().ListAccountsWithoutStatementEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, asynq.SkipRetry)
+			},
+		},
+		{
+			name: "ListFailed",
+			task: task,
+			buildStubs: func(store *mockdb.MockStore, mailer *mockmail.MockEmailSender) {
+				store.EXPECT().ListAccountsWithoutStatementEmail(gomock.Any(), gomock.Any()).Times(1).Return(nil, errors.New("conn reset"))
+				mailer.EXPECT().SendEmail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			mailer := mockmail.NewMockEmailSender(ctrl)
+			tc.buildStubs(store, mailer)
+
+			processor := &RedisTaskProcessor{store: store, mailer: mailer}
+			tc.checkErr(t, processor.ProcessTaskSendMonthlyStatements(context.Background(), tc.task))
+		})
+	}
+}
+
+func TestProcessTaskSnapshotBalances(t *testing.T) {
+	today := db.BalanceSnapshotDay(time.Now())
+	task := asynq.NewTask(TaskSnapshotBalances, []byte("{}"))
+
+	testCases := []struct {
+		name       string
+		task       *asynq.Task
+		buildStubs func(store *mockdb.MockStore)
+		checkErr   func(t *testing.T, err error)
+	}{
+		{
+			// pages until one isn't full, each after the last account of the previous one
+			name: "OK",
+			task: task,
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Eq(db.CreateBalanceSnapshotsParams{
+						AfterID: 0, SnapshotAt: today, MaxAccounts: maxSnapshotAccounts,
+					})).Times(1).Return(db.CreateBalanceSnapshotsRow{Accounts: maxSnapshotAccounts, LastAccountID: 1200, Snapshots: maxSnapshotAccounts}, nil),
+					store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Eq(db.CreateBalanceSnapshotsParams{
+						AfterID: 1200, SnapshotAt: today, MaxAccounts: maxSnapshotAccounts,
+					})).Times(1).Return(db.CreateBalanceSnapshotsRow{Accounts: 10, LastAccountID: 1300, Snapshots: 10}, nil),
+				)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "Backfill",
+			task: asynq.NewTask(TaskSnapshotBalances, []byte(`{"day":"2026-09-01"}`)),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateBalanceSnapshotsParams{
+					SnapshotAt:  time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC),
+					MaxAccounts: maxSnapshotAccounts,
+				}
+				store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.CreateBalanceSnapshotsRow{}, nil)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// a day not over yet can't be snapshotted
+			name: "FutureDay",
+			task: asynq.NewTask(TaskSnapshotBalances, []byte(fmt.Sprintf(`{"day":"%s"}`, today.AddDate(0, 0, 1).Format("2006-01-02")))),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, asynq.SkipRetry)
+			},
+		},
+		{
+			name: "Failed",
+			task: task,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.CreateBalanceSnapshotsRow{}, errors.New("conn reset"))
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.NotErrorIs(t, err, asynq.SkipRetry)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			processor := &RedisTaskProcessor{store: store}
+			tc.checkErr(t, processor.ProcessTaskSnapshotBalances(context.Background(), tc.task))
+		})
+	}
+}
+
+func TestProcessTaskAccrueInterest(t *testing.T) {
+	day := time.Date(2026, time.September, 30, 0, 0, 0, 0, time.UTC)
+	dayEnd := day.AddDate(0, 0, 1)
+	task := asynq.NewTask(TaskAccrueInterest, []byte(`{"day":"2026-09-30"}`))
+	rows := []db.ListAccountsForInterestAccrualRow{
+		{Account: db.Account{ID: 1, Product: db.ProductSavings}, AnnualInterestRateBps: 200},
+		{Account: db.Account{ID: 2, Product: db.ProductSavings}, AnnualInterestRateBps: 200},
+	}
+
+	testCases := []struct {
+		name       string
+		task       *asynq.Task
+		buildStubs func(store *mockdb.MockStore)
+		checkErr   func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			task: task,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsForInterestAccrualParams{DayEnd: dayEnd, AccruedOn: day, MaxAccounts: maxInterestAccounts}
+				store.EXPECT().ListAccountsForInterestAccrual(gomock.Any(), gomock.Eq(arg)).Times(1).Return(rows, nil)
+				// the balance at the end of the day
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Eq(int64(1)), gomock.Eq(dayEnd.Add(-time.Microsecond))).Times(1).Return(int64(100000), nil)
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Eq(int64(2)), gomock.Any()).Times(1).Return(int64(0), nil)
+				store.EXPECT().CreateInterestAccrual(gomock.Any(), gomock.Eq(db.CreateInterestAccrualParams{
+					AccountID: 1, AccruedOn: day, Balance: 100000, AnnualInterestRateBps: 200, AmountMicros: 5479452,
+				})).Times(1)
+				store.EXPECT().CreateInterestAccrual(gomock.Any(), gomock.Eq(db.CreateInterestAccrualParams{
+					AccountID: 2, AccruedOn: day, Balance: 0, AnnualInterestRateBps: 200, AmountMicros: 0,
+				})).Times(1)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// yesterday by default
+			name: "Yesterday",
+			task: asynq.NewTask(TaskAccrueInterest, []byte("{}")),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsForInterestAccrual(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListAccountsForInterestAccrualParams) ([]db.ListAccountsForInterestAccrualRow, error) {
+						require.Equal(t, db.BalanceSnapshotDay(time.Now()), arg.DayEnd)
+						require.Equal(t, arg.DayEnd.AddDate(0, 0, -1), arg.AccruedOn)
+						return nil, nil
+					})
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// the other accounts are still accrued, the task is retried for the failed one
+			name: "BalanceFailed",
+			task: task,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsForInterestAccrual(gomock.Any(), gomock.Any()).Times(1).Return(rows, nil)
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Eq(int64(1)), gomock.Any()).Times(1).Return(int64(0), errors.New("conn reset"))
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Eq(int64(2)), gomock.Any()).Times(1).Return(int64(100000), nil)
+				store.EXPECT().CreateInterestAccrual(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.NotErrorIs(t, err, asynq.SkipRetry)
+			},
+		},
+		{
+			// a day not over yet can't be accrued
+			name: "Today",
+			task: asynq.NewTask(TaskAccrueInterest, []byte(fmt.Sprintf(`{"day":"%s"}`, time.Now().UTC().Format("2006-01-02")))),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsForInterestAccrual(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, asynq.SkipRetry)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			processor := &RedisTaskProcessor{store: store}
+			tc.checkErr(t, processor.ProcessTaskAccrueInterest(context.Background(), tc.task))
+		})
+	}
+}
+
+func TestProcessTaskPostInterest(t *testing.T) {
+	from := time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	task := asynq.NewTask(TaskPostInterest, []byte(`{"month":"2026-09"}`))
+
+	testCases := []struct {
+		name       string
+		task       *asynq.Task
+		buildStubs func(store *mockdb.MockStore)
+		checkErr   func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			task: task,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsWithUnpostedInterestParams{PeriodEnd: to, MaxAccounts: maxInterestAccounts}
+				store.EXPECT().ListAccountsWithUnpostedInterest(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]int64{1, 2}, nil)
+				for _, id := range []int64{1, 2} {
+					store.EXPECT().PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: id, PeriodStart: from, PeriodEnd: to})).Times(1)
+				}
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// posted by another run - skipped, not retried
+			name: "AlreadyPosted",
+			task: task,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsWithUnpostedInterest(gomock.Any(), gomock.Any()).Times(1).Return([]int64{1}, nil)
+				store.EXPECT().PostInterestTx(gomock.Any(), gomock.Any()).Times(1).Return(db.PostInterestTxResult{AlreadyPosted: true}, nil)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "PostFailed",
+			task: task,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsWithUnpostedInterest(gomock.Any(), gomock.Any()).Times(1).Return([]int64{1, 2}, nil)
+				store.EXPECT().PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 1, PeriodStart: from, PeriodEnd: to})).Times(1).
+					Return(db.PostInterestTxResult{}, errors.New("conn reset"))
+				store.EXPECT().PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 2, PeriodStart: from, PeriodEnd: to})).Times(1)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.NotErrorIs(t, err, asynq.SkipRetry)
+			},
+		},
+		{
+			// a month not over yet can't be posted
+			name: "CurrentMonth",
+			task: asynq.NewTask(TaskPostInterest, []byte(fmt.Sprintf(`{"month":"%s"}`, time.Now().UTC().Format("2006-01")))),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsWithUnpostedInterest(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkErr: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, asynq.SkipRetry)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			processor := &RedisTaskProcessor{store: store}
+			tc.checkErr(t, processor.ProcessTaskPostInterest(context.Background(), tc.task))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/web3dev6/simplebank/util"
+)
+
+/*
+   Scheduler - enqueues the periodic tasks, each task_*.go file has its processor & cronspec
+   Note* every worker runs one, the tasks are unique per run so they're enqueued once all the same
+*/
+
+// NewScheduler creates the scheduler of the periodic tasks - started next to the task processor
+func NewScheduler(redisOpt asynq.RedisClientOpt, config util.Config) (*asynq.Scheduler, error) {
+	scheduler := asynq.NewScheduler(redisOpt, &asynq.SchedulerOpts{
+		Logger:   NewLogger(),
+		Location: time.UTC,
+	})
+
+	// note* empty payloads - every run works out what it's for (expired by then, the last day or month)
+	periodicTasks := []struct {
+		cronspec string
+		taskType string
+		payload  interface{}
+		opts     []asynq.Option
+	}{
+		{
+			cronspec: fmt.Sprintf("@every %s", config.HoldSweepInterval),
+			taskType: TaskVoidExpiredHolds,
+			payload:  PayloadVoidExpiredHolds{},
+			opts:     []asynq.Option{asynq.Queue(QueueDefault), asynq.Unique(config.HoldSweepInterval)},
+		},
+		{
+			cronspec: monthlyStatementsCronspec,
+			taskType: TaskSendMonthlyStatements,
+			payload:  PayloadSendMonthlyStatements{},
+			// a run emailing every account takes a while - the default 30m timeout is too short
+			opts: []asynq.Option{asynq.Queue(QueueLow), asynq.Timeout(6 * time.Hour), asynq.Unique(24 * time.Hour)},
+		},
+		{
+			cronspec: balanceSnapshotsCronspec,
+			taskType: TaskSnapshotBalances,
+			payload:  PayloadSnapshotBalances{},
+			opts:     []asynq.Option{asynq.Queue(QueueLow), asynq.Unique(time.Hour)},
+		},
+		{
+			cronspec: accrueInterestCronspec,
+			taskType: TaskAccrueInterest,
+			payload:  PayloadAccrueInterest{},
+			opts:     []asynq.Option{asynq.Queue(QueueLow), asynq.Timeout(2 * time.Hour), asynq.Unique(time.Hour)},
+		},
+		{
+			cronspec: postInterestCronspec,
+			taskType: TaskPostInterest,
+			payload:  PayloadPostInterest{},
+			opts:     []asynq.Option{asynq.Queue(QueueLow), asynq.Timeout(6 * time.Hour), asynq.Unique(24 * time.Hour)},
+		},
+	}
+
+	for _, periodic := range periodicTasks {
+		payload, err := json.Marshal(periodic.payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal task payload: %w", err)
+		}
+		_, err = scheduler.Register(periodic.cronspec, asynq.NewTask(periodic.taskType, payload), periodic.opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register %s: %w", periodic.taskType, err)
+		}
+	}
+	return scheduler, nil
+}
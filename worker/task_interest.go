@@ -0,0 +1,195 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/interest"
+	"github.com/web3dev6/simplebank/logging"
+)
+
+/*
+   Periodic tasks - interest, see the interest package
+	- TaskAccrueInterest accrues the interest of a day on every account of a product with a rate, daily
+	- TaskPostInterest pays the interest accrued over a month, on the 1st of the next one
+   Note* both are idempotent - a day is accrued & a month is posted at most once per account,
+        so a retried or rerun task only does what's left
+*/
+
+const (
+	TaskAccrueInterest = "task:accrue_interest"
+	TaskPostInterest   = "task:post_interest"
+)
+
+const (
+	// accrueInterestCronspec - 01:00 UTC, after the balance snapshot of the midnight before it
+	accrueInterestCronspec = "0 1 * * *"
+	// postInterestCronspec - 03:00 UTC on the 1st, after the last day of the month is accrued
+	postInterestCronspec = "0 3 1 * *"
+)
+
+// maxInterestAccounts is how many accounts are listed at a time, a run goes on until all of them are done
+const maxInterestAccounts = 100
+
+// PayloadAccrueInterest - the day to accrue, e.g. "2026-10-17", yesterday (UTC) if empty
+type PayloadAccrueInterest struct {
+	Day string `json:"day,omitempty"`
+	TaskTrace
+}
+
+// PayloadPostInterest - the month to post, e.g. "2026-09", the month before the run if empty
+type PayloadPostInterest struct {
+	Month string `json:"month,omitempty"`
+	TaskTrace
+}
+
+// ProcessTaskAccrueInterest - accrues a day's interest on the end of day balance of the accounts paying interest
+// Note* a failure for one account doesn't stop the others, the task fails at the end so it's retried for the failed ones
+func (processor *RedisTaskProcessor) ProcessTaskAccrueInterest(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadAccrueInterest
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal task payload: %w", asynq.SkipRetry)
+	}
+	today := db.BalanceSnapshotDay(time.Now())
+	day := today.AddDate(0, 0, -1)
+	if payload.Day != "" {
+		var err error
+		day, err = time.Parse("2006-01-02", payload.Day)
+		if err != nil || !day.Before(today) {
+			// note* a day is accrued once it's over
+			return fmt.Errorf("invalid interest day %q: %w", payload.Day, asynq.SkipRetry)
+		}
+	}
+	dayEnd := day.AddDate(0, 0, 1)
+
+	var afterID int64
+	var accrued int
+	var lastErr error
+	for {
+		rows, err := processor.store.ListAccountsForInterestAccrual(ctx, db.ListAccountsForInterestAccrualParams{
+			AfterID:     afterID,
+			DayEnd:      dayEnd,
+			AccruedOn:   day,
+			MaxAccounts: maxInterestAccounts,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list accounts for interest: %w", err)
+		}
+
+		for _, row := range rows {
+			if err := processor.accrueInterest(ctx, row, day, dayEnd); err != nil {
+				logging.Logger(ctx).Error().Err(err).Int64("account_id", row.Account.ID).Msg("failed to accrue interest")
+				lastErr = err
+				continue
+			}
+			accrued++
+		}
+
+		if len(rows) < maxInterestAccounts {
+			break
+		}
+		afterID = rows[len(rows)-1].Account.ID
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("stopped accruing interest: %w", err)
+		}
+	}
+
+	logging.Logger(ctx).Info().
+		Str("type", task.Type()).
+		Time("day", day).
+		Int("accrued", accrued).
+		Msg("processed task")
+
+	if lastErr != nil {
+		return fmt.Errorf("failed to accrue interest of some accounts: %w", lastErr)
+	}
+	return nil
+}
+
+// accrueInterest accrues the interest of day on the balance of an account at its end, at the rate of its product
+func (processor *RedisTaskProcessor) accrueInterest(ctx context.Context, row db.ListAccountsForInterestAccrualRow, day time.Time, dayEnd time.Time) error {
+	// note* the entries before dayEnd, timestamps are in microseconds
+	balance, err := processor.store.GetBalanceAt(ctx, row.Account.ID, dayEnd.Add(-time.Microsecond))
+	if err != nil {
+		return fmt.Errorf("failed to get balance: %w", err)
+	}
+	amount, err := interest.DailyAccrual(balance, row.AnnualInterestRateBps)
+	if err != nil {
+		return err
+	}
+	return processor.store.CreateInterestAccrual(ctx, db.CreateInterestAccrualParams{
+		AccountID:             row.Account.ID,
+		AccruedOn:             day,
+		Balance:               balance,
+		AnnualInterestRateBps: row.AnnualInterestRateBps,
+		AmountMicros:          amount,
+	})
+}
+
+// ProcessTaskPostInterest - pays the interest accrued by the end of the month to every account with some not posted yet
+// Note* a failure for one account doesn't stop the others, the task fails at the end so it's retried for the failed ones
+func (processor *RedisTaskProcessor) ProcessTaskPostInterest(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadPostInterest
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal task payload: %w", asynq.SkipRetry)
+	}
+	from, to, err := monthPeriod(payload.Month, time.Now())
+	if err != nil || to.After(time.Now()) {
+		return fmt.Errorf("invalid interest month %q: %w", payload.Month, asynq.SkipRetry)
+	}
+
+	var afterID int64
+	var posted, skipped int
+	var lastErr error
+	for {
+		accountIDs, err := processor.store.ListAccountsWithUnpostedInterest(ctx, db.ListAccountsWithUnpostedInterestParams{
+			PeriodEnd:   to,
+			AfterID:     afterID,
+			MaxAccounts: maxInterestAccounts,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list accounts with unposted interest: %w", err)
+		}
+
+		for _, accountID := range accountIDs {
+			result, err := processor.store.PostInterestTx(ctx, db.PostInterestTxParams{
+				AccountID:   accountID,
+				PeriodStart: from,
+				PeriodEnd:   to,
+			})
+			switch {
+			case err != nil:
+				logging.Logger(ctx).Error().Err(err).Int64("account_id", accountID).Msg("failed to post interest")
+				lastErr = err
+			case result.AlreadyPosted:
+				skipped++
+			default:
+				posted++
+			}
+		}
+
+		if len(accountIDs) < maxInterestAccounts {
+			break
+		}
+		afterID = accountIDs[len(accountIDs)-1]
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("stopped posting interest: %w", err)
+		}
+	}
+
+	logging.Logger(ctx).Info().
+		Str("type", task.Type()).
+		Time("from", from).
+		Int("posted", posted).
+		Int("skipped", skipped).
+		Msg("processed task")
+
+	if lastErr != nil {
+		return fmt.Errorf("failed to post interest of some accounts: %w", lastErr)
+	}
+	return nil
+}
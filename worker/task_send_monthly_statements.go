@@ -0,0 +1,197 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/web3dev6/simplebank/apperr"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/logging"
+	"github.com/web3dev6/simplebank/money"
+	"github.com/web3dev6/simplebank/statement"
+)
+
+/*
+   Periodic task - emails every account's statement of the last month to its owner, as pdf & csv attachments
+   Note* enqueued by the Scheduler on the 1st of every month (monthlyStatementsCronspec, in UTC)
+        every email sent is recorded in statement_emails, so a retried run only sends the ones that failed
+        owners with an unverified email are skipped - it may not be theirs
+*/
+
+const TaskSendMonthlyStatements = "task:send_monthly_statements"
+
+// monthlyStatementsCronspec - 06:00 UTC on the 1st, once the last month is over everywhere
+const monthlyStatementsCronspec = "0 6 1 * *"
+
+// maxStatementAccounts is how many accounts are listed at a time, the run goes on until all of them are done
+const maxStatementAccounts = 100
+
+// PayloadSendMonthlyStatements - the month of the statements, e.g. "2026-09", the month before the run if empty
+type PayloadSendMonthlyStatements struct {
+	Month string `json:"month,omitempty"`
+	TaskTrace
+}
+
+// monthPeriod returns the first day of month & of the month after it, of the month before now if month is empty
+func monthPeriod(month string, now time.Time) (time.Time, time.Time, error) {
+	var start time.Time
+	if month == "" {
+		now = now.UTC()
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	} else {
+		var err error
+		start, err = time.Parse("2006-01", month)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// ProcessTaskSendMonthlyStatements - emails the statement of the month to the owner of every account opened by its end
+// Note* a failure for one account doesn't stop the others, the task fails at the end so it's retried for the failed ones
+func (processor *RedisTaskProcessor) ProcessTaskSendMonthlyStatements(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendMonthlyStatements
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal task payload: %w", asynq.SkipRetry)
+	}
+	from, to, err := monthPeriod(payload.Month, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid statement month %q: %w", payload.Month, asynq.SkipRetry)
+	}
+
+	// note* the owners of several accounts are looked up once
+	users := make(map[string]db.User)
+	var afterID int64
+	var sent, skipped int
+	var lastErr error
+	for {
+		accounts, err := processor.store.ListAccountsWithoutStatementEmail(ctx, db.ListAccountsWithoutStatementEmailParams{
+			AfterID:     afterID,
+			PeriodStart: from,
+			PeriodEnd:   to,
+			MaxAccounts: maxStatementAccounts,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list accounts for statements: %w", err)
+		}
+
+		for _, account := range accounts {
+			user, ok := users[account.Owner]
+			if !ok {
+				user, err = processor.store.GetUser(ctx, account.Owner)
+				if err != nil {
+					logging.Logger(ctx).Error().Err(err).Int64("account_id", account.ID).Msg("failed to get owner of account")
+					lastErr = err
+					continue
+				}
+				users[account.Owner] = user
+			}
+			if !user.IsEmailVerified {
+				skipped++
+				continue
+			}
+
+			err = processor.sendStatement(ctx, user, account, from, to)
+			switch {
+			case err == nil:
+				sent++
+			case apperr.Is(err, apperr.CodeValidation):
+				// too many entries for a statement, retrying won't help
+				logging.Logger(ctx).Warn().Err(err).Int64("account_id", account.ID).Msg("skipped statement")
+				skipped++
+			default:
+				logging.Logger(ctx).Error().Err(err).Int64("account_id", account.ID).Msg("failed to send statement")
+				lastErr = err
+			}
+		}
+
+		if len(accounts) < maxStatementAccounts {
+			break
+		}
+		afterID = accounts[len(accounts)-1].ID
+		if err := ctx.Err(); err != nil {
+			// shutting down, the retry picks up the accounts left
+			return fmt.Errorf("stopped sending statements: %w", err)
+		}
+	}
+
+	logging.Logger(ctx).Info().
+		Str("type", task.Type()).
+		Time("from", from).
+		Int("sent", sent).
+		Int("skipped", skipped).
+		Msg("processed task")
+
+	if lastErr != nil {
+		return fmt.Errorf("failed to send some statements: %w", lastErr)
+	}
+	return nil
+}
+
+// sendStatement emails the statement of account from from to to to user, attached as pdf & csv files
+func (processor *RedisTaskProcessor) sendStatement(ctx context.Context, user db.User, account db.Account, from time.Time, to time.Time) error {
+	st, err := statement.Build(ctx, processor.store, account, from, to)
+	if err != nil {
+		return err
+	}
+
+	// note* the mailer attaches files by path, written to a temp dir removed once sent
+	dir, err := os.MkdirTemp("", "statement")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	pdfFile := filepath.Join(dir, st.Filename("pdf"))
+	if err := writeFile(pdfFile, st.WritePDF); err != nil {
+		return err
+	}
+	csvFile := filepath.Join(dir, st.Filename("csv"))
+	if err := writeFile(csvFile, st.WriteCSV); err != nil {
+		return err
+	}
+
+	month := from.Format("January 2006")
+	subject := fmt.Sprintf("Simple Bank - %s statement of account %d", month, account.ID)
+	content := fmt.Sprintf(`Hello %s,<br/>
+	Please find attached the statement of your %s account %d for %s.<br/>
+	Closing balance: %s<br/>
+	`, html.EscapeString(user.FullName), account.Currency, account.ID, month,
+		money.Money{Amount: st.ClosingBalance, Currency: account.Currency})
+	err = processor.mailer.SendEmail(ctx, subject, content, []string{user.Email}, nil, nil, []string{pdfFile, csvFile})
+	if err != nil {
+		return fmt.Errorf("failed to send statement email: %w", err)
+	}
+
+	// note* sent already - failing to record it only means it's sent again by a retry
+	err = processor.store.CreateStatementEmail(ctx, db.CreateStatementEmailParams{
+		AccountID:   account.ID,
+		PeriodStart: from,
+		PeriodEnd:   to,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record statement email: %w", err)
+	}
+	return nil
+}
+
+// writeFile creates the file at path with the content written by write
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/logging"
+)
+
+/*
+   Periodic task - snapshots the balance of every account at midnight UTC, see db/sqlc/balance_snapshot.go
+   Note* enqueued by the Scheduler daily at balanceSnapshotsCronspec, well after midnight,
+        so the txs that started before it (bounded by DB_TX_TIMEOUT) have committed their entries
+        a rerun only adds the snapshots missing, the ones already taken are kept
+*/
+
+const TaskSnapshotBalances = "task:snapshot_balances"
+
+// balanceSnapshotsCronspec - 00:30 UTC, snapshotting the midnight before it
+const balanceSnapshotsCronspec = "30 0 * * *"
+
+// maxSnapshotAccounts is how many accounts are snapshotted per statement, the run goes on until all of them are done
+const maxSnapshotAccounts = 1000
+
+// PayloadSnapshotBalances - the day of the snapshots, e.g. "2026-10-18", today (UTC) if empty
+// note* a past day can be given to backfill the snapshots of a day the task didn't run
+type PayloadSnapshotBalances struct {
+	Day string `json:"day,omitempty"`
+	TaskTrace
+}
+
+// ProcessTaskSnapshotBalances - snapshots the balances of all the accounts opened by the midnight of the day
+func (processor *RedisTaskProcessor) ProcessTaskSnapshotBalances(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSnapshotBalances
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal task payload: %w", asynq.SkipRetry)
+	}
+	snapshotAt := db.BalanceSnapshotDay(time.Now())
+	if payload.Day != "" {
+		day, err := time.Parse("2006-01-02", payload.Day)
+		if err != nil || day.After(snapshotAt) {
+			return fmt.Errorf("invalid snapshot day %q: %w", payload.Day, asynq.SkipRetry)
+		}
+		snapshotAt = day
+	}
+
+	var afterID, accounts, snapshots int64
+	for {
+		result, err := processor.store.CreateBalanceSnapshots(ctx, db.CreateBalanceSnapshotsParams{
+			AfterID:     afterID,
+			SnapshotAt:  snapshotAt,
+			MaxAccounts: maxSnapshotAccounts,
+		})
+		if err != nil {
+			// note* the snapshots so far are kept, the retry carries on from them
+			return fmt.Errorf("failed to snapshot balances after account [%d]: %w", afterID, err)
+		}
+		accounts += result.Accounts
+		snapshots += result.Snapshots
+		if result.Accounts < maxSnapshotAccounts {
+			break
+		}
+		afterID = result.LastAccountID
+	}
+
+	logging.Logger(ctx).Info().
+		Str("type", task.Type()).
+		Time("snapshot_at", snapshotAt).
+		Int64("accounts", accounts).
+		Int64("snapshots", snapshots).
+		Msg("processed task")
+	return nil
+}
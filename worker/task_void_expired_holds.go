@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/web3dev6/simplebank/apperr"
+	db "github.com/web3dev6/simplebank/db/sqlc"
+	"github.com/web3dev6/simplebank/logging"
+)
+
+/*
+   Periodic task - voids the authorized holds past their expiry, releasing the held funds
+   Note* enqueued by the Scheduler every HOLD_SWEEP_INTERVAL, not by the api servers
+        unique per interval, so a few workers each running a scheduler don't enqueue it more than once
+*/
+
+const TaskVoidExpiredHolds = "task:void_expired_holds"
+
+// maxExpiredHolds is the most holds voided per run, the rest are picked up by the next runs
+const maxExpiredHolds = 100
+
+// PayloadVoidExpiredHolds - empty, every run voids whatever expired by then
+type PayloadVoidExpiredHolds struct {
+	TaskTrace
+}
+
+// ProcessTaskVoidExpiredHolds - voids the expired holds, at most maxExpiredHolds of them
+// Note* a hold captured or voided since it was listed is skipped, a failure to void one fails the task so it's retried
+func (processor *RedisTaskProcessor) ProcessTaskVoidExpiredHolds(ctx context.Context, task *asynq.Task) error {
+	holds, err := processor.store.ListExpiredHolds(ctx, db.ListExpiredHoldsParams{
+		Now:      time.Now(),
+		MaxHolds: maxExpiredHolds,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list expired holds: %w", err)
+	}
+
+	var voided int
+	var lastErr error
+	for _, hold := range holds {
+		_, err := processor.store.VoidHoldTx(ctx, db.VoidHoldTxParams{HoldID: hold.ID, Expired: true})
+		switch {
+		case err == nil:
+			voided++
+		case apperr.Is(err, apperr.CodeConflict):
+			// captured or voided in the meantime
+		default:
+			logging.Logger(ctx).Error().Err(err).Int64("hold_id", hold.ID).Msg("failed to void expired hold")
+			lastErr = err
+		}
+	}
+
+	logging.Logger(ctx).Info().
+		Str("type", task.Type()).
+		Int("expired", len(holds)).
+		Int("voided", voided).
+		Msg("processed task")
+
+	if lastErr != nil {
+		return fmt.Errorf("failed to void some expired holds: %w", lastErr)
+	}
+	return nil
+}
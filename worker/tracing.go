@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hibiken/asynq"
+	"github.com/web3dev6/simplebank/logging"
+	"github.com/web3dev6/simplebank/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TaskTrace carries the trace context & request id of the request that enqueued a task - embedded in every task payload
+// Note* asynq v0.24 tasks have no headers, so they travel in the json payload itself
+type TaskTrace struct {
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+	RequestID    string            `json:"request_id,omitempty"`
+}
+
+// startEnqueueSpan starts the producer span of a task & injects its trace context in taskTrace
+func startEnqueueSpan(ctx context.Context, taskType string, taskTrace *TaskTrace) (context.Context, trace.Span) {
+	ctx, span := tracing.Tracer().Start(ctx, "enqueue "+taskType,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("task.type", taskType)),
+	)
+	taskTrace.TraceContext = tracing.Inject(ctx)
+	return ctx, span
+}
+
+// tracingMiddleware runs each task in a consumer span, joined to the trace of the request that enqueued it
+func tracingMiddleware(handler asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		// note* a payload that doesn't unmarshal just starts a new trace, the task handler reports the bad payload
+		var taskTrace TaskTrace
+		_ = json.Unmarshal(task.Payload(), &taskTrace)
+
+		taskID, _ := asynq.GetTaskID(ctx)
+		retryCount, _ := asynq.GetRetryCount(ctx)
+		ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, taskTrace.TraceContext), "process "+task.Type(),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("task.type", task.Type()),
+				attribute.String("task.id", taskID),
+				attribute.Int("task.retry_count", retryCount),
+			),
+		)
+		defer span.End()
+
+		err := handler.ProcessTask(ctx, task)
+		tracing.RecordError(span, err)
+		return err
+	})
+}
+
+// requestIDMiddleware runs each task with the request id of the request that enqueued it & a logger tagged with it
+func requestIDMiddleware(handler asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		return handler.ProcessTask(withTaskRequestID(ctx, task), task)
+	})
+}
+
+// withTaskRequestID returns ctx with the request id carried in the task payload
+// note* tasks enqueued outside of a request (none carried) get the task id instead
+func withTaskRequestID(ctx context.Context, task *asynq.Task) context.Context {
+	var taskTrace TaskTrace
+	_ = json.Unmarshal(task.Payload(), &taskTrace)
+	requestID := taskTrace.RequestID
+	if requestID == "" {
+		requestID, _ = asynq.GetTaskID(ctx)
+	}
+	return logging.WithRequestID(ctx, logging.RequestIDOrNew(requestID))
+}